@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"fiber_log/errhandler"
+	"fiber_log/jobs"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Admin Middleware
+// ============================================================================
+
+// adminAuthMiddleware bảo vệ các admin endpoints bằng ADMIN_TOKEN
+// Token được gửi qua header "X-Admin-Token" hoặc "Authorization: Bearer <token>"
+// Nếu ADMIN_TOKEN không được cấu hình, admin endpoints chỉ mở khi APP_ENV=development;
+// môi trường khác trả về 503 thay vì để lộ crash bundles (request body, config), jobs và event log
+func adminAuthMiddleware(c *fiber.Ctx) error {
+	if appConfig.AdminToken == "" {
+		if appConfig.IsDevelopment() {
+			return c.Next()
+		}
+		return goerrorkit.NewBusinessError(503, "Admin endpoints chưa được cấu hình ADMIN_TOKEN").WithData(map[string]interface{}{
+			"setting": "ADMIN_TOKEN",
+			"env":     appConfig.Env,
+		})
+	}
+
+	token := c.Get("X-Admin-Token")
+	if token == "" {
+		token = strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	}
+
+	if token == "" {
+		return goerrorkit.NewAuthError(401, "Unauthorized: Missing admin token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(appConfig.AdminToken)) != 1 {
+		return goerrorkit.NewAuthError(403, "Forbidden: Invalid admin token")
+	}
+	return c.Next()
+}
+
+// ============================================================================
+// Crash Bundles
+// ============================================================================
+
+// capturePanicBundle ghi crash bundle khi errhandler recover một panic
+// Lỗi khi ghi bundle được log riêng, không ảnh hưởng response của request bị panic
+func capturePanicBundle(c *fiber.Ctx, panicErr *goerrorkit.AppError, goroutines []byte) {
+	if crashStore == nil {
+		return
+	}
+
+	if _, err := crashStore.Capture(c, panicErr, goroutines); err != nil {
+		captureErr := goerrorkit.WrapWithMessage(err, "Không thể ghi crash bundle").WithData(map[string]interface{}{
+			"crash_dir": appConfig.CrashDir,
+		})
+		captureErr.RequestID = panicErr.RequestID
+		errhandler.LogError(captureErr, c)
+	}
+}
+
+// listCrashBundlesHandler - Danh sách crash bundles (mới nhất trước)
+// Test: GET /admin/crashes (sau khi gọi GET /panic/division)
+func listCrashBundlesHandler(c *fiber.Ctx) error {
+	if crashStore == nil {
+		return goerrorkit.NewBusinessError(404, "Crash bundles đang bị tắt").WithData(map[string]interface{}{
+			"env": "CRASH_BUNDLES=false",
+		})
+	}
+
+	bundles, err := crashStore.List()
+	if err != nil {
+		return goerrorkit.WrapWithMessage(err, "Không thể đọc danh sách crash bundles")
+	}
+
+	return c.JSON(fiber.Map{
+		"count":   len(bundles),
+		"bundles": bundles,
+	})
+}
+
+// downloadCrashBundleHandler - Tải crash bundle dạng .zip
+// Test: GET /admin/crashes/<id>
+func downloadCrashBundleHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if crashStore == nil || !crashStore.Exists(id) {
+		return goerrorkit.NewBusinessError(404, fmt.Sprintf("Crash bundle '%s' không tồn tại", id)).WithData(map[string]interface{}{
+			"bundle_id": id,
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="crash-%s.zip"`, id))
+	if err := crashStore.WriteZip(id, c.Response().BodyWriter()); err != nil {
+		return goerrorkit.WrapWithMessage(err, "Không thể nén crash bundle").WithData(map[string]interface{}{
+			"bundle_id": id,
+		})
+	}
+	return nil
+}
+
+// ============================================================================
+// Background Jobs
+// ============================================================================
+
+// listJobsHandler - Trạng thái background jobs, lỗi gần nhất và dead letters
+// Trình duyệt (Accept: text/html) nhận trang HTML với nút "Run now" / "Retry now", client khác nhận JSON
+// Test: GET /admin/jobs
+func listJobsHandler(c *fiber.Ctx) error {
+	view := struct {
+		Jobs        []jobs.JobStatus  `json:"jobs"`
+		DeadLetters []jobs.DeadLetter `json:"dead_letters"`
+	}{jobRunner.Jobs(), jobRunner.DeadLetters()}
+
+	if c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML) == fiber.MIMETextHTML {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return adminJobsTemplate.ExecuteTemplate(c.Response().BodyWriter(), "jobs", view)
+	}
+	return c.JSON(view)
+}
+
+// runJobHandler - Chạy job ngay (scheduled job) hoặc enqueue task với ?payload_id= (queued job)
+// Test: POST /admin/jobs/reservation_sweep/run
+func runJobHandler(c *fiber.Ctx) error {
+	name, payloadID := c.Params("name"), c.Query("payload_id")
+
+	queued, err := jobRunner.Enqueue(name, payloadID)
+	if err != nil {
+		return err
+	}
+
+	message := "Đã đưa job vào hàng đợi"
+	if !queued {
+		message = "Job đang chờ chạy hoặc nằm trong dead letters"
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":    message,
+		"job":        name,
+		"payload_id": payloadID,
+		"queued":     queued,
+	})
+}
+
+// retryDeadLetterHandler - Chạy lại ngay task trong dead letters (nút "Retry now")
+// Lỗi của lần chạy lại được log kèm job_context và ghi vào dead letter, response vẫn là 200 với succeeded=false
+// Test: POST /admin/jobs/dead-letters/DL-0001/retry
+func retryDeadLetterHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	succeeded, err := jobRunner.RetryDeadLetter(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	message := "Retry thành công, dead letter đã được xóa"
+	if !succeeded {
+		message = "Retry thất bại, xem lỗi mới trong dead letter"
+	}
+	return c.JSON(fiber.Map{
+		"message":        message,
+		"dead_letter_id": id,
+		"succeeded":      succeeded,
+	})
+}
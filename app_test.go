@@ -0,0 +1,2379 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fiber_log/audit"
+	"fiber_log/config"
+	"fiber_log/jobs"
+	"fiber_log/logging"
+	"fiber_log/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Test Harness - Dựng app như main() nhưng log vào memory sink và thư mục tạm
+// ============================================================================
+
+// testConfig trả về cấu hình không phụ thuộc biến môi trường, mọi file ghi vào dir
+func testConfig(dir string) config.Config {
+	return config.Config{
+		Addr:                     ":0",
+		Env:                      "development",
+		LogDir:                   filepath.Join(dir, "logs"),
+		CrashBundles:             true,
+		CrashDir:                 filepath.Join(dir, "crashes"),
+		CrashMaxBundles:          20,
+		CrashMaxBodyBytes:        64 * 1024,
+		SourceRoot:               ".",
+		ReservationTTL:           15 * time.Minute,
+		ReservationSweepInterval: 30 * time.Second,
+		PaymentWebhookSecret:     testWebhookSecret,
+		PaymentWebhookTolerance:  5 * time.Minute,
+	}
+}
+
+// testWebhookSecret là secret ký webhook của app trong tests
+const testWebhookSecret = "whsec_test"
+
+// signedWebhook trả về header chữ ký hợp lệ (tại thời điểm hiện tại) cho payload
+func signedWebhook(payload string) map[string]string {
+	return map[string]string{services.WebhookSignatureHeader: services.SignWebhook(testWebhookSecret, time.Now(), []byte(payload))}
+}
+
+// newTestApp dựng app với memory sink (để kiểm tra entries) và file sink trong thư mục tạm
+// (để trang /dev/errors đọc lại log)
+func newTestApp(t *testing.T, configure ...func(*config.Config)) (*fiber.App, *logging.MemoryOutput) {
+	t.Helper()
+
+	cfg := testConfig(t.TempDir())
+	for _, fn := range configure {
+		fn(&cfg)
+	}
+	setup(cfg)
+
+	// Trong test binary, package main có tên "fiber_log" nên stack trace filter cần include "fiber_log"
+	goerrorkit.ConfigureForApplication("fiber_log")
+
+	memory := logging.NewMemoryOutput()
+	appLogger = logging.Init(
+		&logging.Sink{Name: "memory", Output: memory, MinLevel: logging.InfoLevel},
+		&logging.Sink{
+			Name:     "file",
+			Output:   logging.NewFileOutput(logging.FileOptions{Path: errorLogPath()}),
+			MinLevel: logging.InfoLevel,
+		},
+	)
+	t.Cleanup(func() { appLogger.Close() })
+
+	return newApp(), memory
+}
+
+// frame là vị trí mong đợi trong source code
+type frame struct {
+	file     string
+	function string
+
+	// snippet - Đoạn code tại dòng mong đợi, tìm từ khai báo function trở xuống
+	// Nhiều dòng (phân cách bởi "\n") khớp với các dòng liên tiếp, dòng trả về là dòng đầu tiên
+	snippet string
+}
+
+// sourceLine trả về số dòng của snippet trong function
+// Dùng snippet thay vì hardcode số dòng để test không vỡ khi thêm code phía trên
+func sourceLine(t *testing.T, f frame) int {
+	t.Helper()
+	data, err := os.ReadFile(f.file)
+	if err != nil {
+		t.Fatalf("read %s: %v", f.file, err)
+	}
+	lines := strings.Split(string(data), "\n")
+
+	start := 0
+	if f.function != "" {
+		name, _, _ := strings.Cut(f.function, ".") // closure "handler.func1" → "handler"
+		decl := regexp.MustCompile(`^func (\([^)]*\) )?` + regexp.QuoteMeta(name) + `[\[(]`)
+		start = -1
+		for i, text := range lines {
+			if decl.MatchString(text) {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			t.Fatalf("không tìm thấy func %s trong %s", name, f.file)
+		}
+	}
+
+	parts := strings.Split(f.snippet, "\n")
+	for i := start; i+len(parts) <= len(lines); i++ {
+		matched := true
+		for j, part := range parts {
+			if !strings.Contains(lines[i+j], part) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i + 1
+		}
+	}
+	t.Fatalf("snippet %q không có trong %s (func %s)", f.snippet, f.file, f.function)
+	return 0
+}
+
+// routeCase mô tả một request tới app và kết quả mong đợi
+type routeCase struct {
+	name    string
+	method  string // mặc định GET
+	route   string // Route pattern đã đăng ký ("/product/:id"), dùng để kiểm tra mọi route đều có test
+	path    string // URL thực tế ("/product/999")
+	body    string // JSON body
+	headers map[string]string
+	config  func(*config.Config)
+
+	status      int
+	contentType string                 // Kiểm tra Content-Type cho response không phải JSON
+	wantBody    map[string]interface{} // Các trường phải có trong JSON response (response thành công)
+
+	// Lỗi mong đợi (errorType rỗng nghĩa là request thành công, không có error nào được log)
+	errorType goerrorkit.ErrorType
+	message   string
+	location  frame
+	callChain []frame
+	spawnedBy []frame
+	data      map[string]interface{}
+	cause     string   // Chuỗi con của cause
+	causes    []string // Chuỗi errors.Unwrap của cause (trường "causes")
+	children  []childCase
+}
+
+// childCase là lỗi con mong đợi của lỗi gộp (errors.Join), theo đúng thứ tự
+type childCase struct {
+	errorType goerrorkit.ErrorType
+	status    int
+	message   string
+	location  frame
+}
+
+// sendRouteCase dựng app mới, gửi request của tc và trả về response cùng memory sink
+func sendRouteCase(t *testing.T, tc routeCase) (*http.Response, []byte, *logging.MemoryOutput) {
+	t.Helper()
+
+	var configure []func(*config.Config)
+	if tc.config != nil {
+		configure = append(configure, tc.config)
+	}
+	app, memory := newTestApp(t, configure...)
+	memory.Reset()
+
+	method := tc.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req := httptest.NewRequest(method, tc.path, strings.NewReader(tc.body))
+	if tc.body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	for k, v := range tc.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp, raw, memory
+}
+
+// runRouteCase gửi request của tc tới app và kiểm tra response cùng log entry
+func runRouteCase(t *testing.T, tc routeCase) {
+	t.Helper()
+	resp, raw, memory := sendRouteCase(t, tc)
+
+	if resp.StatusCode != tc.status {
+		t.Errorf("status = %d, want %d\nbody: %s", resp.StatusCode, tc.status, raw)
+	}
+	if tc.contentType != "" {
+		if got := resp.Header.Get(fiber.HeaderContentType); !strings.HasPrefix(got, tc.contentType) {
+			t.Errorf("Content-Type = %q, want %q", got, tc.contentType)
+		}
+	}
+
+	var body map[string]interface{}
+	if strings.HasPrefix(resp.Header.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Fatalf("decode response: %v\n%s", err, raw)
+		}
+	}
+	for key, want := range tc.wantBody {
+		assertJSONEqual(t, "body."+key, body[key], want)
+	}
+
+	errs := memory.Errors()
+	if tc.errorType == "" {
+		for _, e := range errs {
+			t.Errorf("unexpected error logged: %s %v", e.Message, e.Fields)
+		}
+		return
+	}
+
+	// Error response chỉ có error, type, request_id (không lộ internal details)
+	// Lỗi gộp có thêm "errors" liệt kê từng lỗi con
+	if tc.children != nil {
+		assertResponseShape(t, body, "errors")
+		assertChildrenResponse(t, body["errors"], tc.children)
+	} else {
+		assertResponseShape(t, body)
+	}
+	if body["type"] != string(tc.errorType) {
+		t.Errorf("body.type = %v, want %s", body["type"], tc.errorType)
+	}
+	if tc.message != "" && body["error"] != tc.message {
+		t.Errorf("body.error = %v, want %q", body["error"], tc.message)
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("logged %d errors, want 1", len(errs))
+	}
+	assertEntry(t, errs[0], tc)
+	if id := errs[0].RequestID(); id != body["request_id"] {
+		t.Errorf("entry.request_id = %q, body.request_id = %v", id, body["request_id"])
+	}
+}
+
+// assertResponseShape kiểm tra error response có đúng các trường error, type, request_id và extra
+func assertResponseShape(t *testing.T, body map[string]interface{}, extra ...string) {
+	t.Helper()
+	keys := append([]string{"error", "type", "request_id"}, extra...)
+	for _, key := range keys {
+		if _, ok := body[key]; !ok {
+			t.Errorf("error response thiếu %q: %v", key, body)
+		}
+	}
+	if len(body) != len(keys) {
+		t.Errorf("error response có trường thừa: %v", body)
+	}
+}
+
+// assertChildrenResponse kiểm tra trường "errors" của response lỗi gộp: error, type, code, location của từng lỗi con
+func assertChildrenResponse(t *testing.T, errs interface{}, want []childCase) {
+	t.Helper()
+	list, _ := errs.([]interface{})
+	if len(list) != len(want) {
+		t.Fatalf("body.errors có %d phần tử, want %d: %v", len(list), len(want), errs)
+	}
+	for i, child := range want {
+		got, _ := list[i].(map[string]interface{})
+		if got["type"] != string(child.errorType) || intValue(got["code"]) != child.status || got["error"] != child.message {
+			t.Errorf("body.errors[%d] = %v, want %s %d %q", i, got, child.errorType, child.status, child.message)
+		}
+		location, _ := got["location"].(string)
+		assertLocation(t, location, child.location)
+	}
+}
+
+// assertChildren kiểm tra trường "children" của log entry lỗi gộp
+func assertChildren(t *testing.T, children []*logging.Entry, want []childCase) {
+	t.Helper()
+	if len(children) != len(want) {
+		t.Fatalf("entry.children có %d phần tử, want %d", len(children), len(want))
+	}
+	for i, child := range want {
+		got := children[i]
+		if got.ErrorType() != child.errorType || got.StatusCode() != child.status || got.Message != child.message {
+			t.Errorf("entry.children[%d] = %s %d %q, want %s %d %q",
+				i, got.ErrorType(), got.StatusCode(), got.Message, child.errorType, child.status, child.message)
+		}
+		assertLocation(t, got.Location(), child.location)
+	}
+}
+
+// intValue đọc số từ JSON (float64) thành int
+func intValue(v interface{}) int {
+	n, _ := v.(float64)
+	return int(n)
+}
+
+// assertEntry kiểm tra log entry: type, message, status, location, call_chain, data, cause
+func assertEntry(t *testing.T, entry *logging.Entry, tc routeCase) {
+	t.Helper()
+
+	if got := entry.ErrorType(); got != tc.errorType {
+		t.Errorf("entry.error_type = %s, want %s", got, tc.errorType)
+	}
+	if tc.message != "" && entry.Message != tc.message {
+		t.Errorf("entry.message = %q, want %q", entry.Message, tc.message)
+	}
+	if got := entry.StatusCode(); got != tc.status {
+		t.Errorf("entry.status_code = %d, want %d", got, tc.status)
+	}
+	if tc.errorType == goerrorkit.PanicError && entry.Fields["panic_value"] == nil {
+		t.Error("entry thiếu panic_value")
+	}
+
+	if tc.location.file != "" {
+		assertLocation(t, entry.Location(), tc.location)
+	}
+	assertFrames(t, "call_chain", entry.CallChain(), tc.callChain)
+	spawned, _ := entry.Fields["spawned_by"].([]string)
+	assertFrames(t, "spawned_by", spawned, tc.spawnedBy)
+
+	for key, want := range tc.data {
+		assertJSONEqual(t, "data."+key, entry.Data()[key], want)
+	}
+	if tc.cause != "" && !strings.Contains(entry.Cause(), tc.cause) {
+		t.Errorf("entry.cause = %q, want chứa %q", entry.Cause(), tc.cause)
+	}
+	if tc.causes != nil {
+		assertJSONEqual(t, "causes", entry.Causes(), tc.causes)
+	}
+	if tc.children != nil {
+		assertChildren(t, entry.Children(), tc.children)
+	}
+}
+
+// assertLocation kiểm tra location "file:function:line"
+// Trong test binary, file của package main có dạng "fiber_log/main.go" nên chỉ so phần cuối đường dẫn
+func assertLocation(t *testing.T, location string, want frame) {
+	t.Helper()
+	line := sourceLine(t, want)
+
+	parts := strings.Split(location, ":")
+	if len(parts) != 3 ||
+		!strings.HasSuffix("/"+parts[0], "/"+want.file) ||
+		parts[1] != want.function ||
+		parts[2] != fmt.Sprint(line) {
+		t.Errorf("location = %q, want %s:%s:%d (%s)", location, want.file, want.function, line, want.snippet)
+	}
+}
+
+// assertFrames kiểm tra chain chứa từng frame mong đợi dạng "pkg.function (file:line)"
+func assertFrames(t *testing.T, field string, chain []string, want []frame) {
+	t.Helper()
+	for _, f := range want {
+		suffix := fmt.Sprintf("(%s:%d)", filepath.Base(f.file), sourceLine(t, f))
+		if f.function != "" {
+			suffix = "." + f.function + " " + suffix
+		}
+
+		found := false
+		for _, entry := range chain {
+			if strings.HasSuffix(entry, suffix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%s thiếu frame %q\n%s", field, suffix, strings.Join(chain, "\n"))
+		}
+	}
+}
+
+// usd là dạng JSON của services.Money với tiền tệ USD
+func usd(amount string) map[string]interface{} {
+	return map[string]interface{}{"amount": amount, "currency": "USD"}
+}
+
+// warehouses là dạng JSON của tồn kho một sản phẩm tại WH-01, WH-02, WH-03
+func warehouses(wh01, wh02, wh03 int) []interface{} {
+	return []interface{}{
+		map[string]interface{}{"id": "WH-01", "name": "Kho Hà Nội", "region": "north", "available": wh01},
+		map[string]interface{}{"id": "WH-02", "name": "Kho Đà Nẵng", "region": "central", "available": wh02},
+		map[string]interface{}{"id": "WH-03", "name": "Kho TP.HCM", "region": "south", "available": wh03},
+	}
+}
+
+// assertJSONEqual so sánh hai giá trị sau khi encode JSON (bỏ qua khác biệt int/float64
+// và thứ tự trường của struct so với map)
+func assertJSONEqual(t *testing.T, field string, got, want interface{}) {
+	t.Helper()
+	gotJSON, wantJSON := normalizeJSON(got), normalizeJSON(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("%s = %s, want %s", field, gotJSON, wantJSON)
+	}
+}
+
+// normalizeJSON encode v rồi decode lại thành map để các key được sắp xếp giống nhau
+func normalizeJSON(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	var decoded interface{}
+	if json.Unmarshal(data, &decoded) != nil {
+		return data
+	}
+	data, _ = json.Marshal(decoded)
+	return data
+}
+
+// ============================================================================
+// Route Cases
+// ============================================================================
+
+// routeCases bao phủ mọi route không phải panic (panic routes ở panic_test.go)
+var routeCases = []routeCase{
+	// Home
+	{name: "home", route: "/", path: "/", status: 200, contentType: "text/html"},
+	{name: "favicon", route: "/favicon.ico", path: "/favicon.ico", status: 200, contentType: "image/svg+xml"},
+
+	// Custom Errors
+	{
+		name: "business out of stock", route: "/error/business", path: "/error/business?product_id=123",
+		status: 400, errorType: goerrorkit.BusinessError,
+		message:  "Sản phẩm 'iPhone 15' đã hết hàng",
+		location: frame{"services/product_service.go", "CheckStock", `"Sản phẩm '%s' đã hết hàng"`},
+		data:     map[string]interface{}{"product_id": "123", "product_name": "iPhone 15"},
+	},
+	{
+		name: "business in stock", route: "/error/business", path: "/error/business?product_id=456",
+		status:   200,
+		wantBody: map[string]interface{}{"message": "Sản phẩm còn hàng", "product_id": "456"},
+	},
+	{
+		name: "system", route: "/error/system", path: "/error/system",
+		status: 500, errorType: goerrorkit.SystemError,
+		message:  "Internal server error",
+		location: frame{"main.go", "systemErrorHandler", "goerrorkit.NewSystemError(err)"},
+		data:     map[string]interface{}{"database": "postgres", "host": "localhost:5432"},
+		cause:    "connection refused: database is down",
+	},
+	{
+		name: "validation missing age", route: "/error/validation", path: "/error/validation",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Thiếu tham số 'age'",
+		location: frame{"main.go", "validationErrorHandler", `NewValidationError("Thiếu tham số 'age'"`},
+		data:     map[string]interface{}{"field": "age", "required": true},
+	},
+	{
+		name: "validation age not integer", route: "/error/validation", path: "/error/validation?age=abc",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Tham số 'age' phải là số nguyên",
+		location: frame{"main.go", "validationErrorHandler", `"Tham số 'age' phải là số nguyên"`},
+		data:     map[string]interface{}{"field": "age", "type": "integer", "received": "abc"},
+	},
+	{
+		name: "validation age under 18", route: "/error/validation", path: "/error/validation?age=15",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Tuổi phải >= 18",
+		location: frame{"main.go", "validationErrorHandler", `NewValidationError("Tuổi phải >= 18"`},
+		data:     map[string]interface{}{"field": "age", "min": 18, "received": 15},
+	},
+	{
+		name: "validation ok", route: "/error/validation", path: "/error/validation?age=20",
+		status:   200,
+		wantBody: map[string]interface{}{"message": "Validation thành công", "age": 20},
+	},
+	{
+		name: "validation body malformed", method: http.MethodPost, route: "/error/validation-body", path: "/error/validation-body",
+		body:   `{"name":`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Request body không hợp lệ",
+		location: frame{"main.go", "validationBodyHandler", `NewValidationError("Request body không hợp lệ"`},
+	},
+	{
+		name: "validation body missing name", method: http.MethodPost, route: "/error/validation-body", path: "/error/validation-body",
+		body:   `{"email":"an@example.com","age":20}`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Tên không được để trống",
+		location: frame{"main.go", "validationBodyHandler", `NewValidationError("Tên không được để trống"`},
+		data:     map[string]interface{}{"field": "name", "required": true},
+	},
+	{
+		name: "validation body missing email", method: http.MethodPost, route: "/error/validation-body", path: "/error/validation-body",
+		body:   `{"name":"An","age":20}`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Email không được để trống",
+		location: frame{"main.go", "validationBodyHandler", `NewValidationError("Email không được để trống"`},
+		data:     map[string]interface{}{"field": "email", "required": true},
+	},
+	{
+		name: "validation body under 18", method: http.MethodPost, route: "/error/validation-body", path: "/error/validation-body",
+		body:   `{"name":"An","email":"an@example.com","age":16}`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Tuổi phải >= 18",
+		location: frame{"main.go", "validationBodyHandler", `NewValidationError("Tuổi phải >= 18"`},
+		data:     map[string]interface{}{"field": "age", "min": 18, "received": 16},
+	},
+	{
+		name: "validation body ok", method: http.MethodPost, route: "/error/validation-body", path: "/error/validation-body",
+		body:   `{"name":"An","email":"an@example.com","age":20}`,
+		status: 200,
+		wantBody: map[string]interface{}{
+			"message": "Tạo user thành công",
+			"user":    map[string]interface{}{"name": "An", "email": "an@example.com", "age": 20},
+		},
+	},
+	{
+		name: "auth missing token", route: "/error/auth", path: "/error/auth",
+		status: 401, errorType: goerrorkit.AuthError,
+		message:  "Unauthorized: Missing authorization token",
+		location: frame{"main.go", "authErrorHandler", `"Unauthorized: Missing authorization token"`},
+	},
+	{
+		name: "auth invalid token", route: "/error/auth", path: "/error/auth",
+		headers: map[string]string{"Authorization": "Bearer wrong"},
+		status:  401, errorType: goerrorkit.AuthError,
+		message:  "Unauthorized: Invalid token",
+		location: frame{"main.go", "authErrorHandler", `"Unauthorized: Invalid token"`},
+		data:     map[string]interface{}{"token_length": 12},
+	},
+	{
+		name: "auth forbidden", route: "/error/auth", path: "/error/auth",
+		headers: map[string]string{"Authorization": "Bearer valid-token-123", "X-User-Role": "viewer"},
+		status:  403, errorType: goerrorkit.AuthError,
+		message:  "Forbidden: Insufficient permissions",
+		location: frame{"main.go", "authErrorHandler", `"Forbidden: Insufficient permissions"`},
+		data:     map[string]interface{}{"required_role": "admin", "user_role": "viewer"},
+	},
+	{
+		name: "auth ok", route: "/error/auth", path: "/error/auth",
+		headers:  map[string]string{"Authorization": "Bearer valid-token-123", "X-User-Role": "admin"},
+		status:   200,
+		wantBody: map[string]interface{}{"message": "Authentication thành công", "role": "admin"},
+	},
+	{
+		name: "external payment", route: "/error/external", path: "/error/external",
+		status: 502, errorType: goerrorkit.ExternalError,
+		message:  "Payment gateway không phản hồi",
+		location: frame{"main.go", "externalErrorHandler", "goerrorkit.NewExternalError(statusCode, message, err)"},
+		data:     map[string]interface{}{"service": "payment", "timeout": "30s"},
+		cause:    "timeout after 30s",
+	},
+	{
+		name: "external shipping", route: "/error/external", path: "/error/external?service=shipping",
+		status: 503, errorType: goerrorkit.ExternalError,
+		message:  "Shipping service đang bảo trì",
+		location: frame{"main.go", "externalErrorHandler", "goerrorkit.NewExternalError(statusCode, message, err)"},
+		data:     map[string]interface{}{"service": "shipping"},
+	},
+	{
+		name: "complex with call chain", route: "/error/complex", path: "/error/complex",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Dữ liệu đơn hàng không hợp lệ",
+		location: frame{"main.go", "validateOrderData", `NewValidationError("Dữ liệu đơn hàng không hợp lệ"`},
+		callChain: []frame{
+			{"main.go", "processOrderData", "if err := validateOrderData(); err != nil"},
+			{"main.go", "complexErrorWithCallChainHandler", "result, err := processOrderData()"},
+		},
+		data: map[string]interface{}{"reason": "invalid_order_data"},
+	},
+
+	// Wrap Errors
+	{
+		name: "wrap", route: "/error/wrap", path: "/error/wrap",
+		status: 500, errorType: goerrorkit.SystemError,
+		message:  "file not found: config.json",
+		location: frame{"main.go", "wrapErrorHandler", "goerrorkit.Wrap(originalErr)"},
+		cause:    "file not found: config.json",
+	},
+	{
+		name: "wrap with message", route: "/error/wrap-message", path: "/error/wrap-message",
+		status: 500, errorType: goerrorkit.SystemError,
+		message:  "Không thể kết nối đến database để lấy thông tin user",
+		location: frame{"main.go", "wrapWithMessageHandler", "goerrorkit.WrapWithMessage(dbErr"},
+		cause:    "connection timeout",
+	},
+	{
+		name: "wrap with data", route: "/error/wrap-data", path: "/error/wrap-data",
+		status: 500, errorType: goerrorkit.SystemError,
+		message:  "HTTP 500: Internal Server Error",
+		location: frame{"main.go", "wrapWithDataHandler", "goerrorkit.Wrap(apiErr)"},
+		data: map[string]interface{}{
+			"api_endpoint": "https://api.example.com/users",
+			"method":       "GET",
+			"user_id":      "USER-123",
+			"retry_count":  3,
+			"timeout":      "30s",
+		},
+	},
+	{
+		name: "wrap with call chain", route: "/error/wrap-callchain", path: "/error/wrap-callchain",
+		status: 500, errorType: goerrorkit.SystemError,
+		message:  "email format invalid",
+		location: frame{"main.go", "validateUserData", "goerrorkit.Wrap(validationErr)"},
+		callChain: []frame{
+			{"main.go", "processUserData", "if err := validateUserData(); err != nil"},
+			{"main.go", "wrapWithCallChainHandler", "result, err := processUserData()"},
+		},
+		data: map[string]interface{}{"field": "email", "value": "invalid-email", "reason": "missing @ symbol"},
+	},
+
+	// Service Layer
+	{
+		name: "product not found", route: "/product/:id", path: "/product/999",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Sản phẩm ID=999 không tồn tại",
+		location: frame{"services/product_service.go", "GetProduct", "goerrorkit.NewBusinessError(404"},
+		data:     map[string]interface{}{"product_id": "999"},
+		causes:   []string{"product 999: product not found", "product not found"},
+	},
+	{
+		name: "product found", route: "/product/:id", path: "/product/456",
+		status: 200,
+		wantBody: map[string]interface{}{
+			"product": map[string]interface{}{"ID": "456", "Name": "MacBook Pro", "Stock": 5, "Price": usd("2499.99")},
+		},
+	},
+	{
+		name: "list products", route: "/products", path: "/products?q=pro&sort=-price&limit=2",
+		status: 200,
+		wantBody: map[string]interface{}{
+			"count": 2,
+			"products": []map[string]interface{}{
+				{"ID": "456", "Name": "MacBook Pro", "Stock": 5, "Price": usd("2499.99")},
+				{"ID": "789", "Name": "AirPods Pro", "Stock": 10, "Price": usd("249.99")},
+			},
+		},
+	},
+	{
+		// Mỗi tham số sai là một lỗi con, client sửa tất cả trong một lần
+		name: "list products invalid filters", route: "/products", path: "/products?min_price=abc&sort=rating&limit=0",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Có 3 lỗi trong request",
+		location: frame{"services/product_catalog.go", "ParseProductQuery.func1", "errs = append(errs, goerrorkit.NewValidationError(message, data))"},
+		children: []childCase{
+			{goerrorkit.ValidationError, 400, "min_price phải là số >= 0",
+				frame{"services/product_catalog.go", "ParseProductQuery.func1", "errs = append(errs, goerrorkit.NewValidationError(message, data))"}},
+			{goerrorkit.ValidationError, 400, "sort 'rating' không hỗ trợ",
+				frame{"services/product_catalog.go", "ParseProductQuery.func1", "errs = append(errs, goerrorkit.NewValidationError(message, data))"}},
+			{goerrorkit.ValidationError, 400, "limit phải là số nguyên từ 1 đến 100",
+				frame{"services/product_catalog.go", "ParseProductQuery.func1", "errs = append(errs, goerrorkit.NewValidationError(message, data))"}},
+		},
+	},
+	{
+		name: "create product", method: http.MethodPost, route: "/products", path: "/products",
+		body:   `{"id":"P1","name":"Keyboard","price":49.9,"stock":10}`,
+		status: 201,
+		wantBody: map[string]interface{}{
+			"product": map[string]interface{}{"ID": "P1", "Name": "Keyboard", "Stock": 10, "Price": usd("49.90")},
+		},
+	},
+	{
+		name: "create product duplicate", method: http.MethodPost, route: "/products", path: "/products",
+		body:   `{"id":"456","name":"MacBook Air","price":999,"stock":1}`,
+		status: 409, errorType: goerrorkit.BusinessError,
+		message:  "Sản phẩm ID=456 đã tồn tại",
+		location: frame{"services/product_catalog.go", "CreateProduct", "goerrorkit.NewBusinessError(409"},
+		causes:   []string{"product 456: product already exists", "product already exists"},
+	},
+	{
+		name: "create product invalid", method: http.MethodPost, route: "/products", path: "/products",
+		body:   `{"id":"P1","name":"","price":-1,"stock":1}`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message: "Có 2 lỗi trong request",
+		children: []childCase{
+			{goerrorkit.ValidationError, 400, "Tên sản phẩm không được để trống",
+				frame{"services/product_catalog.go", "validateProduct.func1", "errs = append(errs, goerrorkit.NewValidationError(message"}},
+			{goerrorkit.ValidationError, 400, "Giá phải lớn hơn hoặc bằng 0",
+				frame{"services/product_catalog.go", "validateProduct.func1", "errs = append(errs, goerrorkit.NewValidationError(message"}},
+		},
+	},
+	{
+		name: "update product", method: http.MethodPut, route: "/product/:id", path: "/product/456",
+		body:   `{"name":"MacBook Pro M4","price":2599,"stock":4}`,
+		status: 200,
+		wantBody: map[string]interface{}{
+			"product": map[string]interface{}{"ID": "456", "Name": "MacBook Pro M4", "Stock": 4, "Price": usd("2599.00")},
+		},
+	},
+	{
+		name: "update product stale etag", method: http.MethodPut, route: "/product/:id", path: "/product/456",
+		body:    `{"name":"MacBook Pro M4","price":2599,"stock":4}`,
+		headers: map[string]string{fiber.HeaderIfMatch: `"stale"`},
+		status:  412, errorType: goerrorkit.BusinessError,
+		message:  "Sản phẩm đã bị thay đổi, hãy tải lại trước khi cập nhật",
+		location: frame{"services/product_catalog.go", "checkPrecondition", "goerrorkit.NewBusinessError(412"},
+		data:     map[string]interface{}{"product_id": "456", "if_match": `"stale"`},
+		causes:   []string{"product 456: product modified since last read", "product modified since last read"},
+	},
+	{
+		name: "delete product", method: http.MethodDelete, route: "/product/:id", path: "/product/789",
+		status: 204,
+	},
+	{
+		name: "delete product not found", method: http.MethodDelete, route: "/product/:id", path: "/product/999",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Sản phẩm ID=999 không tồn tại",
+		location: frame{"services/product_service.go", "GetProduct", "goerrorkit.NewBusinessError(404"},
+	},
+	{
+		name: "check stock out of stock", route: "/product/:id/check-stock", path: "/product/123/check-stock",
+		status: 400, errorType: goerrorkit.BusinessError,
+		message:  "Sản phẩm 'iPhone 15' đã hết hàng",
+		location: frame{"services/product_service.go", "CheckStock", `"Sản phẩm '%s' đã hết hàng"`},
+		data:     map[string]interface{}{"product_id": "123", "product_name": "iPhone 15"},
+		causes:   []string{"product 123: product out of stock", "product out of stock"},
+	},
+	{
+		name: "availability out of stock", route: "/product/:id/availability", path: "/product/123/availability",
+		status:   200,
+		wantBody: map[string]interface{}{"product_id": "123", "available": false},
+	},
+	{
+		name: "availability in stock", route: "/product/:id/availability", path: "/product/789/availability",
+		status:   200,
+		wantBody: map[string]interface{}{"product_id": "789", "available": true},
+	},
+	{
+		name: "availability not found", route: "/product/:id/availability", path: "/product/999/availability",
+		status: 404, errorType: goerrorkit.BusinessError,
+		location: frame{"services/product_service.go", "GetProduct", "goerrorkit.NewBusinessError(404"},
+		causes:   []string{"product 999: product not found", "product not found"},
+	},
+	{
+		name: "check stock not found", route: "/product/:id/check-stock", path: "/product/999/check-stock",
+		status: 404, errorType: goerrorkit.BusinessError,
+		location: frame{"services/product_service.go", "GetProduct", "goerrorkit.NewBusinessError(404"},
+	},
+	{
+		name: "check stock ok", route: "/product/:id/check-stock", path: "/product/789/check-stock",
+		status:   200,
+		wantBody: map[string]interface{}{"message": "Sản phẩm còn hàng"},
+	},
+	{
+		name: "reserve not enough stock", method: http.MethodPost, route: "/product/:id/reserve", path: "/product/456/reserve?quantity=10",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Không đủ hàng: yêu cầu 10, còn lại 5",
+		location: frame{"services/product_service.go", "ReserveProduct", "return nil, goerrorkit.NewValidationError("},
+		data: map[string]interface{}{
+			"product_id": "456", "product_name": "MacBook Pro", "requested": 10, "available_stock": 5,
+			"warehouses": warehouses(2, 0, 3),
+		},
+	},
+	{
+		name: "reserve ok", method: http.MethodPost, route: "/product/:id/reserve", path: "/product/456/reserve?quantity=2",
+		status: 200,
+		wantBody: map[string]interface{}{"message": "Đặt hàng thành công", "quantity": 2, "reservation_id": "RSV-0001", "allocations": []interface{}{
+			map[string]interface{}{"warehouse_id": "WH-01", "quantity": 2},
+		}},
+	},
+	{
+		name: "reserve split nearest first", method: http.MethodPost, route: "/product/:id/reserve", path: "/product/456/reserve?quantity=4&region=south",
+		status: 200,
+		wantBody: map[string]interface{}{"allocations": []interface{}{
+			map[string]interface{}{"warehouse_id": "WH-03", "quantity": 3},
+			map[string]interface{}{"warehouse_id": "WH-01", "quantity": 1},
+		}},
+	},
+	{
+		name: "reserve no single warehouse", method: http.MethodPost, route: "/product/:id/reserve", path: "/product/456/reserve?quantity=4&strategy=nearest",
+		status: 409, errorType: goerrorkit.BusinessError,
+		message:  "Không kho nào đủ 4 sản phẩm 'MacBook Pro' (strategy nearest)",
+		location: frame{"services/inventory.go", "allocate", "appErr := goerrorkit.NewBusinessError("},
+		data: map[string]interface{}{
+			"product_id": "456", "requested": 4, "strategy": "nearest", "total_available": 5,
+			"warehouses": warehouses(2, 0, 3), "suggestion": "split",
+		},
+		causes: []string{"product 456: insufficient stock in warehouse", "insufficient stock in warehouse"},
+	},
+	{
+		name: "reserve invalid policy", method: http.MethodPost, route: "/product/:id/reserve", path: "/product/456/reserve?strategy=cheapest&region=mars",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message: "Có 2 lỗi trong request",
+		children: []childCase{
+			{goerrorkit.ValidationError, 400, "Strategy 'cheapest' không hợp lệ",
+				frame{"services/inventory.go", "ParseAllocationPolicy", "goerrorkit.NewValidationError(fmt.Sprintf(\"Strategy"}},
+			{goerrorkit.ValidationError, 400, "Vùng giao hàng 'mars' không hợp lệ",
+				frame{"services/inventory.go", "ParseAllocationPolicy", "goerrorkit.NewValidationError(fmt.Sprintf(\"Vùng giao hàng"}},
+		},
+	},
+	{
+		name: "reservation not found", route: "/reservations/:id", path: "/reservations/RSV-404",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Reservation RSV-404 không tồn tại",
+		location: frame{"services/reservation_service.go", "find", "goerrorkit.NewBusinessError(404"},
+		data:     map[string]interface{}{"reservation_id": "RSV-404"},
+		causes:   []string{"reservation RSV-404: reservation not found", "reservation not found"},
+	},
+	{
+		name: "inventory ok", route: "/product/:id/inventory", path: "/product/456/inventory",
+		status:   200,
+		wantBody: map[string]interface{}{"product_id": "456", "total": 5, "warehouses": warehouses(2, 0, 3)},
+	},
+	{
+		name: "inventory not found", route: "/product/:id/inventory", path: "/product/999/inventory",
+		status: 404, errorType: goerrorkit.BusinessError,
+		location: frame{"services/product_service.go", "GetProduct", "goerrorkit.NewBusinessError(404"},
+	},
+	{
+		name: "adjust stock ok", method: http.MethodPost, route: "/product/:id/inventory/adjust", path: "/product/789/inventory/adjust",
+		body:     `{"warehouse_id":"WH-03","delta":5,"reason":"Nhập hàng PO-42"}`,
+		status:   200,
+		wantBody: map[string]interface{}{"message": "Đã điều chỉnh tồn kho"},
+	},
+	{
+		name: "adjust stock insufficient", method: http.MethodPost, route: "/product/:id/inventory/adjust", path: "/product/789/inventory/adjust",
+		body:   `{"warehouse_id":"WH-01","delta":-5,"reason":"Hàng hỏng"}`,
+		status: 409, errorType: goerrorkit.BusinessError,
+		message:  "Không thể xuất 5 sản phẩm 'AirPods Pro' khỏi kho WH-01: còn lại 4",
+		location: frame{"services/inventory.go", "AdjustStock", "appErr := goerrorkit.NewBusinessError(\n409"},
+		data:     map[string]interface{}{"product_id": "789", "warehouse_id": "WH-01", "delta": -5, "available": 4},
+	},
+	{
+		name: "adjust stock unknown warehouse", method: http.MethodPost, route: "/product/:id/inventory/adjust", path: "/product/789/inventory/adjust",
+		body:   `{"warehouse_id":"WH-99","delta":1,"reason":"Nhập hàng"}`,
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Kho WH-99 không tồn tại",
+		location: frame{"services/inventory.go", "AdjustStock", "goerrorkit.NewBusinessError(404"},
+		causes:   []string{"warehouse WH-99: warehouse not found", "warehouse not found"},
+	},
+	{
+		name: "stock movements", route: "/product/:id/inventory/movements", path: "/product/456/inventory/movements",
+		status:   200,
+		wantBody: map[string]interface{}{"product_id": "456", "count": 2},
+	},
+	{
+		name: "discount invalid percent", route: "/product/:id/discount", path: "/product/456/discount?percent=150",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Phần trăm giảm giá không hợp lệ",
+		location: frame{"services/product_service.go", "CalculateDiscount", "return Money{}, goerrorkit.NewValidationError("},
+		data:     map[string]interface{}{"field": "discount_percent", "min": 0, "max": 100, "received": 150},
+	},
+	{
+		name: "discount NaN percent", route: "/product/:id/discount", path: "/product/456/discount?percent=NaN",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Phần trăm giảm giá không hợp lệ",
+		location: frame{"services/product_service.go", "CalculateDiscount", "return Money{}, goerrorkit.NewValidationError("},
+		data:     map[string]interface{}{"field": "discount_percent", "min": 0, "max": 100, "received": "NaN"},
+	},
+	{
+		name: "discount ok", route: "/product/:id/discount", path: "/product/789/discount?percent=50",
+		status:   200,
+		wantBody: map[string]interface{}{"discount": 50, "final_price": usd("124.99")}, // Giảm 124.995 -> 125.00 (half-up)
+	},
+	{
+		name: "discount coupon preview", route: "/product/:id/discount", path: "/product/456/discount?coupon=apple20",
+		status: 200,
+		wantBody: map[string]interface{}{"preview": true, "quote": map[string]interface{}{
+			"applied":  []interface{}{map[string]interface{}{"amount": usd("500.00"), "code": "APPLE20", "kind": "percent", "percent": 20}},
+			"discount": usd("500.00"), "subtotal": usd("2499.99"), "total": usd("1999.99"),
+		}},
+	},
+	{
+		name: "discount coupon expired", route: "/product/:id/discount", path: "/product/456/discount?coupon=SUMMER2024",
+		status: 410, errorType: goerrorkit.BusinessError,
+		message:  "Mã giảm giá 'SUMMER2024' đã hết hạn",
+		location: frame{"services/coupon_service.go", "checkCoupon", "appErr := goerrorkit.NewBusinessError(410"},
+		data:     map[string]interface{}{"error_code": "COUPON_EXPIRED", "coupon_code": "SUMMER2024", "expired_at": "2024-08-31T23:59:59Z"},
+		causes:   []string{"coupon SUMMER2024: coupon expired", "coupon expired"},
+	},
+	{
+		name: "apply coupon ok", method: http.MethodPost, route: "/cart/apply-coupon", path: "/cart/apply-coupon",
+		body:   `{"user_id":"U1","items":[{"product_id":"456","quantity":1},{"product_id":"789","quantity":2}],"coupons":["SAVE10","welcome50"]}`,
+		status: 200,
+		wantBody: map[string]interface{}{"quote": map[string]interface{}{
+			"applied": []interface{}{
+				map[string]interface{}{"amount": usd("300.00"), "code": "SAVE10", "kind": "percent", "percent": 10},
+				map[string]interface{}{"amount": usd("50.00"), "code": "WELCOME50", "kind": "fixed"},
+			},
+			"discount": usd("350.00"), "subtotal": usd("2999.97"), "total": usd("2649.97"),
+		}},
+	},
+	{
+		name: "apply coupon not applicable", method: http.MethodPost, route: "/cart/apply-coupon", path: "/cart/apply-coupon",
+		body:   `{"user_id":"U1","items":[{"product_id":"789","quantity":1}],"coupons":["APPLE20"]}`,
+		status: 422, errorType: goerrorkit.BusinessError,
+		message:  "Mã giảm giá 'APPLE20' không áp dụng cho sản phẩm trong giỏ hàng",
+		location: frame{"services/coupon_service.go", "checkCoupon", "không áp dụng cho sản phẩm trong giỏ hàng"},
+		data:     map[string]interface{}{"error_code": "COUPON_NOT_APPLICABLE", "coupon_code": "APPLE20", "product_ids": []interface{}{"456"}},
+	},
+	{
+		name: "apply coupon exhausted", method: http.MethodPost, route: "/cart/apply-coupon", path: "/cart/apply-coupon",
+		body:   `{"user_id":"U1","items":[{"product_id":"789","quantity":1}],"coupons":["FLASH100"]}`,
+		status: 409, errorType: goerrorkit.BusinessError,
+		message:  "Mã giảm giá 'FLASH100' đã hết lượt sử dụng",
+		location: frame{"services/coupon_service.go", "checkCoupon", "appErr := goerrorkit.NewBusinessError(409"},
+		data:     map[string]interface{}{"error_code": "COUPON_EXHAUSTED", "max_uses": 2, "used": 2},
+	},
+	{
+		name: "apply coupon rejected", method: http.MethodPost, route: "/cart/apply-coupon", path: "/cart/apply-coupon",
+		body:   `{"user_id":"U1","items":[{"product_id":"456","quantity":1}],"coupons":["APPLE20","SUMMER2024","SAVE10"]}`,
+		status: 410, errorType: goerrorkit.BusinessError,
+		message: "Có 2 lỗi trong request",
+		children: []childCase{
+			{goerrorkit.BusinessError, 410, "Mã giảm giá 'SUMMER2024' đã hết hạn",
+				frame{"services/coupon_service.go", "checkCoupon", "appErr := goerrorkit.NewBusinessError(410"}},
+			{goerrorkit.BusinessError, 422, "Mã giảm giá 'APPLE20' không dùng chung với mã khác",
+				frame{"services/coupon_service.go", "checkStacking", "appErr := goerrorkit.NewBusinessError(422"}},
+		},
+	},
+	{
+		name: "apply coupon empty cart", method: http.MethodPost, route: "/cart/apply-coupon", path: "/cart/apply-coupon",
+		body:   `{"user_id":"U1","items":[],"coupons":["SAVE10"]}`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Giỏ hàng trống",
+		location: frame{"services/coupon_service.go", "validateCart", `goerrorkit.NewValidationError("Giỏ hàng trống"`},
+		data:     map[string]interface{}{"field": "items", "min": 1},
+	},
+	{
+		name: "register user", method: http.MethodPost, route: "/users", path: "/users",
+		body:   `{"name":"Lan","email":"Lan@Example.com","age":22,"password":"secret123"}`,
+		status: 201,
+	},
+	{
+		name: "register user duplicate email", method: http.MethodPost, route: "/users", path: "/users",
+		body:   `{"name":"An","email":"AN@example.com","age":20,"password":"secret123"}`,
+		status: 409, errorType: goerrorkit.BusinessError,
+		message:  "Email an@example.com đã được đăng ký",
+		location: frame{"services/user_service.go", "checkEmail", "goerrorkit.NewBusinessError(409"},
+		data:     map[string]interface{}{"field": "email", "email": "an@example.com"},
+		causes:   []string{"user an@example.com: email already registered", "email already registered"},
+	},
+	{
+		name: "register user invalid", method: http.MethodPost, route: "/users", path: "/users",
+		body:   `{"name":"","email":"lan@","age":20,"password":"123"}`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message: "Có 3 lỗi trong request",
+		children: []childCase{
+			{goerrorkit.ValidationError, 400, "Tên không được để trống",
+				frame{"services/user_service.go", "validateUser.func1", "errs = append(errs, goerrorkit.NewValidationError(message, data))"}},
+			{goerrorkit.ValidationError, 400, "Email không hợp lệ",
+				frame{"services/user_service.go", "validateUser.func1", "errs = append(errs, goerrorkit.NewValidationError(message, data))"}},
+			{goerrorkit.ValidationError, 400, "Mật khẩu phải có ít nhất 8 ký tự",
+				frame{"services/user_service.go", "validateUser.func1", "errs = append(errs, goerrorkit.NewValidationError(message, data))"}},
+		},
+	},
+	{
+		name: "get user", route: "/users/:id", path: "/users/USER001",
+		status: 200,
+		wantBody: map[string]interface{}{
+			"user": map[string]interface{}{
+				"id": "USER001", "name": "Nguyễn Văn An", "email": "an@example.com", "phone": "+84901234567", "age": 30,
+				"created_at": "2024-01-01T00:00:00Z", "updated_at": "2024-01-01T00:00:00Z",
+			},
+		},
+	},
+	{
+		name: "get user not found", route: "/users/:id", path: "/users/USER999",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "User USER999 không tồn tại",
+		location: frame{"services/user_service.go", "find", "goerrorkit.NewBusinessError(404"},
+		data:     map[string]interface{}{"user_id": "USER999"},
+		causes:   []string{"user USER999: user not found", "user not found"},
+	},
+	{
+		name: "update user", method: http.MethodPut, route: "/users/:id", path: "/users/USER002",
+		body:   `{"name":"Trần Bình","email":"binh.tran@example.com","age":26}`,
+		status: 200,
+	},
+	{
+		name: "update user email taken", method: http.MethodPut, route: "/users/:id", path: "/users/USER002",
+		body:   `{"name":"Trần Bình","email":"an@example.com","age":26}`,
+		status: 409, errorType: goerrorkit.BusinessError,
+		message:  "Email an@example.com đã được đăng ký",
+		location: frame{"services/user_service.go", "checkEmail", "goerrorkit.NewBusinessError(409"},
+	},
+	{
+		name: "create order unknown user", method: http.MethodPost, route: "/order/create", path: "/order/create?product_id=456&user_id=USER999",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "User USER999 không tồn tại",
+		location: frame{"services/user_service.go", "find", "goerrorkit.NewBusinessError(404"},
+	},
+	{
+		name: "create order out of stock", method: http.MethodPost, route: "/order/create", path: "/order/create?product_id=123&quantity=1",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Không đủ hàng: yêu cầu 1, còn lại 0",
+		location: frame{"services/product_service.go", "ReserveProduct", "return nil, goerrorkit.NewValidationError("},
+	},
+	{
+		name: "create order invalid quantity", method: http.MethodPost, route: "/order/create", path: "/order/create?product_id=456&quantity=0",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:   "Số lượng phải lớn hơn 0",
+		location:  frame{"services/order_service.go", "CreateOrder", "return nil, goerrorkit.NewValidationError("},
+		callChain: []frame{{"main.go", "createOrderHandler", "orderService.CreateOrder(productID, userID, quantity, policy)"}},
+		data:      map[string]interface{}{"field": "quantity", "min": 1, "received": 0},
+	},
+	{
+		name: "create order product not found", method: http.MethodPost, route: "/order/create", path: "/order/create?product_id=999",
+		status: 404, errorType: goerrorkit.BusinessError,
+		location: frame{"services/product_service.go", "GetProduct", "goerrorkit.NewBusinessError(404"},
+	},
+	{
+		name: "create order ok", method: http.MethodPost, route: "/order/create", path: "/order/create?product_id=456&quantity=1",
+		status: 200,
+		wantBody: map[string]interface{}{
+			"message": "Đơn hàng đã được tạo",
+			"order": map[string]interface{}{
+				"ID": "ORD-USER001-456", "ProductID": "456", "Quantity": 1, "UserID": "USER001", "Status": "confirmed",
+				"ReservationID": "RSV-0001", "Total": usd("2499.99"),
+				"Allocations": []interface{}{map[string]interface{}{"warehouse_id": "WH-01", "quantity": 1}},
+			},
+		},
+	},
+	{
+		name: "create order nearest warehouse", method: http.MethodPost, route: "/order/create",
+		path:   "/order/create?product_id=456&quantity=3&strategy=nearest&region=north",
+		status: 200,
+		wantBody: map[string]interface{}{
+			"order": map[string]interface{}{
+				"ID": "ORD-USER001-456", "ProductID": "456", "Quantity": 3, "UserID": "USER001", "Status": "confirmed",
+				"ReservationID": "RSV-0001", "Total": usd("7499.97"),
+				"Allocations": []interface{}{map[string]interface{}{"warehouse_id": "WH-03", "quantity": 3}},
+			},
+		},
+	},
+	{
+		name: "validate orders ok", method: http.MethodPost, route: "/orders/validate", path: "/orders/validate",
+		body:     `{"orders":[{"product_id":"456","user_id":"U1","quantity":2},{"product_id":"789","user_id":"U2","quantity":10}]}`,
+		status:   200,
+		wantBody: map[string]interface{}{"message": "Tất cả đơn hàng hợp lệ", "count": 2},
+	},
+	{
+		name: "validate orders empty", method: http.MethodPost, route: "/orders/validate", path: "/orders/validate",
+		body:   `{"orders":[]}`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Danh sách đơn hàng trống",
+		location: frame{"services/order_service.go", "ValidateOrders", `goerrorkit.NewValidationError("Danh sách đơn hàng trống"`},
+	},
+	{
+		name: "validate orders malformed body", method: http.MethodPost, route: "/orders/validate", path: "/orders/validate",
+		body:   `{"orders":`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Request body không hợp lệ",
+		location: frame{"main.go", "validateOrdersHandler", `goerrorkit.NewValidationError("Request body không hợp lệ"`},
+	},
+	{
+		// Lỗi gộp: status theo lỗi con nghiêm trọng nhất (BUSINESS 404), location là của lỗi con đó
+		name: "validate orders multiple errors", method: http.MethodPost, route: "/orders/validate", path: "/orders/validate",
+		body: `{"orders":[{"product_id":"456","user_id":"U1","quantity":1},` +
+			`{"product_id":"456","user_id":"U1","quantity":0},` +
+			`{"product_id":"999","user_id":"U1","quantity":1},` +
+			`{"product_id":"123","user_id":"U1","quantity":1},` +
+			`{"product_id":"456","user_id":"","quantity":1}]}`,
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Có 4 lỗi trong request",
+		location: frame{"services/product_service.go", "GetProduct", "goerrorkit.NewBusinessError(404"},
+		children: []childCase{
+			{goerrorkit.ValidationError, 400, "order[1]: Số lượng phải lớn hơn 0",
+				frame{"services/order_service.go", "ValidateOrder", "return goerrorkit.NewValidationError(\n\"Số lượng phải lớn hơn 0\""}},
+			{goerrorkit.BusinessError, 404, "order[2]: Sản phẩm ID=999 không tồn tại",
+				frame{"services/product_service.go", "GetProduct", "goerrorkit.NewBusinessError(404"}},
+			{goerrorkit.ValidationError, 400, "order[3]: Không đủ hàng: yêu cầu 1, còn lại 0",
+				frame{"services/order_service.go", "ValidateOrder", "return goerrorkit.NewValidationError(\nfmt.Sprintf(\"Không đủ hàng"}},
+			{goerrorkit.ValidationError, 400, "order[4]: User ID không được để trống",
+				frame{"services/order_service.go", "ValidateOrder", `goerrorkit.NewValidationError("User ID không được để trống"`}},
+		},
+	},
+	{
+		name: "order batch ok", method: http.MethodPost, route: "/orders/batch", path: "/orders/batch",
+		body:     `[{"product_id":"456","user_id":"U1","quantity":2},{"product_id":"789","user_id":"U2","quantity":1}]`,
+		status:   200,
+		wantBody: map[string]interface{}{"mode": "all_or_nothing", "succeeded": 2, "failed": 0},
+	},
+	{
+		name: "order batch invalid mode", method: http.MethodPost, route: "/orders/batch", path: "/orders/batch?mode=sometimes",
+		body:   `[{"product_id":"456","user_id":"U1","quantity":1}]`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Batch mode không hợp lệ",
+		location: frame{"services/order_service.go", "CreateOrders", `goerrorkit.NewValidationError("Batch mode không hợp lệ"`},
+		data:     map[string]interface{}{"field": "mode", "received": "sometimes"},
+	},
+	{
+		name: "order batch empty", method: http.MethodPost, route: "/orders/batch", path: "/orders/batch",
+		body:   `[]`,
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Danh sách đơn hàng trống",
+		location: frame{"services/order_service.go", "CreateOrders", `goerrorkit.NewValidationError("Danh sách đơn hàng trống"`},
+	},
+	{
+		name: "product import json ok", method: http.MethodPost, route: "/products/import", path: "/products/import",
+		body:     `[{"id":"P1","name":"Keyboard","price":49.9,"stock":10}]`,
+		status:   200,
+		wantBody: map[string]interface{}{"format": "json", "total": 1, "imported": 1, "failed": 0, "committed": true},
+	},
+	{
+		name: "product import missing file", method: http.MethodPost, route: "/products/import", path: "/products/import",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Thiếu file import",
+		location: frame{"main.go", "uploadedFile", `goerrorkit.NewValidationError("Thiếu file import"`},
+	},
+	{
+		name: "product import unsupported format", method: http.MethodPost, route: "/products/import", path: "/products/import",
+		body:    "<products/>",
+		headers: map[string]string{fiber.HeaderContentType: "application/xml"},
+		status:  400, errorType: goerrorkit.ValidationError,
+		message:  "Định dạng import không hỗ trợ",
+		location: frame{"services/product_import.go", "ImportProducts", `goerrorkit.NewValidationError("Định dạng import không hỗ trợ"`},
+		data:     map[string]interface{}{"field": "format", "received": ""},
+	},
+	{
+		name: "product import missing column", method: http.MethodPost, route: "/products/import", path: "/products/import?format=csv",
+		body:   "id,name,price\nP1,Keyboard,49.9\n",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "File CSV thiếu cột 'stock'",
+		location: frame{"services/product_import.go", "readCSVRecords", "goerrorkit.NewValidationError(fmt.Sprintf(\"File CSV thiếu cột"},
+	},
+	{
+		name: "cancel shipped order", method: http.MethodDelete, route: "/order/:id/cancel", path: "/order/ORD-shipped/cancel",
+		status: 400, errorType: goerrorkit.BusinessError,
+		message:  "Không thể hủy đơn hàng đã được giao cho đơn vị vận chuyển",
+		location: frame{"services/order_service.go", "CancelOrder", "appErr := goerrorkit.NewBusinessError(\n400,"},
+		data:     map[string]interface{}{"order_id": "ORD-shipped", "status": "shipped"},
+		causes:   []string{"order ORD-shipped: order already shipped", "order already shipped"},
+	},
+	{
+		name: "cancel order ok", method: http.MethodDelete, route: "/order/:id/cancel", path: "/order/ORD-123/cancel",
+		status:   200,
+		wantBody: map[string]interface{}{"message": "Đơn hàng đã được hủy", "order_id": "ORD-123"},
+	},
+	{
+		name: "payment invalid amount", method: http.MethodPost, route: "/order/:id/payment", path: "/order/ORD-123/payment?amount=0",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Số tiền thanh toán phải lớn hơn 0",
+		location: frame{"services/order_service.go", "ProcessPayment", "return goerrorkit.NewValidationError("},
+		data:     map[string]interface{}{"field": "amount", "min": usd("0.01"), "received": usd("0.00")},
+	},
+	{
+		name: "payment amount precision", method: http.MethodPost, route: "/order/:id/payment", path: "/order/ORD-123/payment?amount=10.005",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Số tiền USD có tối đa 2 chữ số thập phân",
+		location: frame{"services/order_service.go", "ParsePaymentAmount", "return Money{}, goerrorkit.NewValidationError(\nfmt.Sprintf(\"Số tiền %s có tối đa"},
+		data:     map[string]interface{}{"field": "amount", "currency": "USD", "max_decimals": 2, "received": "10.005"},
+	},
+	{
+		name: "payment timeout", method: http.MethodPost, route: "/order/:id/payment", path: "/order/ORD-123/payment?amount=20000",
+		status: 504, errorType: goerrorkit.ExternalError,
+		message:  "Payment gateway timeout: Giao dịch quá lớn cần xác nhận thêm",
+		location: frame{"services/order_service.go", "callPaymentGateway", "return goerrorkit.NewExternalError(\n504,"},
+		data:     map[string]interface{}{"order_id": "ORD-123", "amount_minor": 2000000, "currency": "USD", "timeout": "30s"},
+		cause:    "timeout after 30s waiting for payment confirmation",
+	},
+	{
+		name: "payment card declined", method: http.MethodPost, route: "/order/:id/payment", path: "/order/ORD-invalid-card/payment?amount=100",
+		status: 502, errorType: goerrorkit.ExternalError,
+		message:  "Payment failed: Thẻ thanh toán không hợp lệ",
+		location: frame{"services/order_service.go", "callPaymentGateway", "return goerrorkit.NewExternalError(\n502,"},
+		data:     map[string]interface{}{"order_id": "ORD-invalid-card", "service": "payment_gateway"},
+		causes:   []string{"payment for order ORD-invalid-card: card declined by bank", "payment declined"},
+	},
+	{
+		name: "payment ok", method: http.MethodPost, route: "/order/:id/payment", path: "/order/ORD-123/payment?amount=100",
+		status:   200,
+		wantBody: map[string]interface{}{"message": "Thanh toán thành công", "order_id": "ORD-123", "amount": usd("100.00")},
+	},
+	{
+		name: "payment ok VND", method: http.MethodPost, route: "/order/:id/payment", path: "/order/ORD-123/payment?amount=250000&currency=vnd",
+		status:   200,
+		wantBody: map[string]interface{}{"amount": map[string]interface{}{"amount": "250000", "currency": "VND"}},
+	},
+	{
+		name: "refund order not found", method: http.MethodPost, route: "/order/:id/refund", path: "/order/ORD-123/refund?amount=10",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Đơn hàng ORD-123 không tồn tại",
+		location: frame{"services/order_service.go", "find", "goerrorkit.NewBusinessError(404"},
+		data:     map[string]interface{}{"order_id": "ORD-123"},
+		causes:   []string{"order ORD-123: order not found", "order not found"},
+	},
+	{
+		name: "payment webhook missing signature", method: http.MethodPost, route: "/webhooks/payment", path: "/webhooks/payment",
+		body:   `{"id":"evt_1","type":"payment.succeeded","order_id":"ORD-USER001-456","amount":{"amount":"100","currency":"USD"}}`,
+		status: 401, errorType: goerrorkit.AuthError,
+		message:  "Thiếu chữ ký webhook",
+		location: frame{"services/payment_webhook.go", "verify", `goerrorkit.NewAuthError(401, "Thiếu chữ ký webhook")`},
+		data:     map[string]interface{}{"header": "X-Payment-Signature"},
+		causes:   []string{"webhook: missing signature: invalid webhook signature", "invalid webhook signature"},
+	},
+	{
+		name: "payment webhook unknown order", method: http.MethodPost, route: "/webhooks/payment", path: "/webhooks/payment",
+		body:    `{"id":"evt_2","type":"payment.succeeded","order_id":"ORD-123","amount":{"amount":"100","currency":"USD"}}`,
+		headers: signedWebhook(`{"id":"evt_2","type":"payment.succeeded","order_id":"ORD-123","amount":{"amount":"100","currency":"USD"}}`),
+		status:  404, errorType: goerrorkit.BusinessError,
+		message:  "Đơn hàng ORD-123 không tồn tại",
+		location: frame{"services/order_service.go", "find", "goerrorkit.NewBusinessError(404"},
+		data:     map[string]interface{}{"order_id": "ORD-123"},
+		causes:   []string{"order ORD-123: order not found", "order not found"},
+	},
+
+	// Admin
+	{
+		name: "admin list crashes empty", route: "/admin/crashes", path: "/admin/crashes",
+		status:   200,
+		wantBody: map[string]interface{}{"count": 0, "bundles": []interface{}{}},
+	},
+	{
+		name: "admin missing token", route: "/admin/crashes", path: "/admin/crashes",
+		config: func(cfg *config.Config) { cfg.AdminToken = "secret" },
+		status: 401, errorType: goerrorkit.AuthError,
+		message:  "Unauthorized: Missing admin token",
+		location: frame{"admin_handlers.go", "adminAuthMiddleware", `"Unauthorized: Missing admin token"`},
+	},
+	{
+		name: "admin invalid token", route: "/admin/crashes", path: "/admin/crashes",
+		config:  func(cfg *config.Config) { cfg.AdminToken = "secret" },
+		headers: map[string]string{"X-Admin-Token": "wrong"},
+		status:  403, errorType: goerrorkit.AuthError,
+		message:  "Forbidden: Invalid admin token",
+		location: frame{"admin_handlers.go", "adminAuthMiddleware", `"Forbidden: Invalid admin token"`},
+	},
+	{
+		name: "admin no token in production", route: "/admin/crashes", path: "/admin/crashes",
+		config: func(cfg *config.Config) { cfg.Env = "production" },
+		status: 503, errorType: goerrorkit.BusinessError,
+		message:  "Admin endpoints chưa được cấu hình ADMIN_TOKEN",
+		location: frame{"admin_handlers.go", "adminAuthMiddleware", "goerrorkit.NewBusinessError(503"},
+		data:     map[string]interface{}{"setting": "ADMIN_TOKEN", "env": "production"},
+	},
+	{
+		name: "admin bearer token", route: "/admin/crashes", path: "/admin/crashes",
+		config:  func(cfg *config.Config) { cfg.AdminToken = "secret" },
+		headers: map[string]string{"Authorization": "Bearer secret"},
+		status:  200,
+	},
+	{
+		name: "admin crash bundle not found", route: "/admin/crashes/:id", path: "/admin/crashes/20250101-000000-000",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Crash bundle '20250101-000000-000' không tồn tại",
+		location: frame{"admin_handlers.go", "downloadCrashBundleHandler", "goerrorkit.NewBusinessError(404"},
+		data:     map[string]interface{}{"bundle_id": "20250101-000000-000"},
+	},
+	{
+		name: "admin jobs", route: "/admin/jobs", path: "/admin/jobs",
+		status: 200,
+		wantBody: map[string]interface{}{
+			"jobs": []interface{}{
+				map[string]interface{}{"name": "order_fulfillment", "max_attempts": 5, "runs": 0, "failures": 0, "pending": 0},
+				map[string]interface{}{"name": "reservation_expire", "max_attempts": 5, "runs": 0, "failures": 0, "pending": 0},
+				map[string]interface{}{"name": "reservation_sweep", "every": "30s", "max_attempts": 1, "runs": 0, "failures": 0, "pending": 0},
+			},
+			"dead_letters": []interface{}{},
+		},
+	},
+	{
+		name: "admin jobs page", route: "/admin/jobs", path: "/admin/jobs",
+		headers: map[string]string{"Accept": "text/html"},
+		status:  200, contentType: "text/html",
+	},
+	{
+		name: "admin run job", method: http.MethodPost, route: "/admin/jobs/:name/run", path: "/admin/jobs/reservation_sweep/run",
+		status:   202,
+		wantBody: map[string]interface{}{"job": "reservation_sweep", "queued": true},
+	},
+	{
+		name: "admin run unknown job", method: http.MethodPost, route: "/admin/jobs/:name/run", path: "/admin/jobs/send_email/run",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Job send_email không tồn tại",
+		location: frame{"jobs/runner.go", "find", "goerrorkit.NewBusinessError(404"},
+		data:     map[string]interface{}{"job": "send_email"},
+	},
+	{
+		name: "admin retry dead letter not found", method: http.MethodPost, route: "/admin/jobs/dead-letters/:id/retry",
+		path:   "/admin/jobs/dead-letters/DL-0404/retry",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Dead letter DL-0404 không tồn tại",
+		location: frame{"jobs/runner.go", "RetryDeadLetter", "goerrorkit.NewBusinessError(404"},
+		data:     map[string]interface{}{"dead_letter_id": "DL-0404"},
+	},
+	{
+		name: "admin events empty", route: "/admin/events", path: "/admin/events?entity=order&id=ORD-123",
+		status:   200,
+		wantBody: map[string]interface{}{"count": 0, "events": []interface{}{}},
+	},
+	{
+		name: "admin events invalid entity", route: "/admin/events", path: "/admin/events?entity=invoice",
+		status: 400, errorType: goerrorkit.ValidationError,
+		message:  "Entity 'invoice' không hỗ trợ",
+		location: frame{"event_log.go", "listEventsHandler", "goerrorkit.NewValidationError("},
+		data:     map[string]interface{}{"field": "entity", "allowed": []interface{}{"order", "product"}, "received": "invoice"},
+	},
+
+	// Dev Tools
+	{name: "dev errors empty", route: "/dev/errors", path: "/dev/errors", status: 200, contentType: "text/html"},
+	{
+		name: "dev error not found", route: "/dev/errors/:request_id", path: "/dev/errors/unknown-id",
+		status: 404, errorType: goerrorkit.BusinessError,
+		message:  "Không tìm thấy lỗi với request_id 'unknown-id'",
+		location: frame{"dev_handlers.go", "devErrorDetailHandler", "goerrorkit.NewBusinessError(404"},
+	},
+}
+
+// ============================================================================
+// Tests
+// ============================================================================
+
+// TestRoutes gọi từng route và kiểm tra status, response và log entry
+func TestRoutes(t *testing.T) {
+	for _, tc := range routeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runRouteCase(t, tc)
+		})
+	}
+}
+
+// TestAllRoutesCovered đảm bảo mọi route đăng ký trong newApp đều có ít nhất một test case
+func TestAllRoutesCovered(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	covered := make(map[string]bool)
+	for _, tc := range append(append([]routeCase{}, routeCases...), panicCases...) {
+		method := tc.method
+		if method == "" {
+			method = http.MethodGet
+		}
+		covered[method+" "+tc.route] = true
+	}
+
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == http.MethodHead {
+			continue // Fiber tự đăng ký HEAD cho mỗi GET route
+		}
+		if key := route.Method + " " + route.Path; !covered[key] {
+			t.Errorf("route %s chưa có test case", key)
+		}
+	}
+}
+
+// TestDevRoutesProduction kiểm tra /dev/* không được đăng ký khi production (mặc định khi không set APP_ENV)
+func TestDevRoutesProduction(t *testing.T) {
+	t.Setenv("APP_ENV", "")
+	os.Unsetenv("APP_ENV")
+	if cfg := config.Load(); cfg.Env != "production" || cfg.IsDevelopment() {
+		t.Fatalf("Env = %q, want production khi không set APP_ENV", cfg.Env)
+	}
+
+	app, _ := newTestApp(t, func(cfg *config.Config) { cfg.Env = "production" })
+
+	for _, route := range app.GetRoutes(true) {
+		if strings.HasPrefix(route.Path, "/dev") {
+			t.Errorf("route %s %s không được đăng ký khi production", route.Method, route.Path)
+		}
+	}
+}
+
+// TestDevErrorDetail kiểm tra trang chi tiết lỗi hiển thị source code tại location và call_chain
+func TestDevErrorDetail(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/error/complex", nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	requestID, _ := body["request_id"].(string)
+	if requestID == "" {
+		t.Fatalf("response thiếu request_id: %v", body)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/dev/errors/"+requestID, nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	detail, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d\n%s", resp.StatusCode, detail)
+	}
+
+	page := string(detail)
+	for _, want := range []string{
+		"Dữ liệu đơn hàng không hợp lệ",
+		"source-line highlight",
+		"result, err := processOrderData()",
+	} {
+		if !strings.Contains(page, html.EscapeString(want)) {
+			t.Errorf("trang chi tiết thiếu %q", want)
+		}
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/dev/errors", nil))
+	list, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(list), "/dev/errors/"+requestID) {
+		t.Errorf("trang /dev/errors thiếu link tới %s", requestID)
+	}
+}
+
+// TestDevErrorDetailChildren kiểm tra trang chi tiết của lỗi gộp đọc lại "children" từ file log
+// và hiển thị từng lỗi con kèm source code tại location
+func TestDevErrorDetailChildren(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/orders/validate",
+		strings.NewReader(`{"orders":[{"product_id":"456","user_id":"U1","quantity":0},{"product_id":"999","user_id":"U1","quantity":1}]}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	requestID, _ := body["request_id"].(string)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/dev/errors/"+requestID, nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	detail, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d\n%s", resp.StatusCode, detail)
+	}
+
+	page := string(detail)
+	for _, want := range []string{
+		"Có 2 lỗi trong request",
+		"Children (2 lỗi con)",
+		"order[0]: Số lượng phải lớn hơn 0",
+		"order[1]: Sản phẩm ID=999 không tồn tại",
+		"services/order_service.go",
+		"services/product_service.go",
+	} {
+		if !strings.Contains(page, html.EscapeString(want)) {
+			t.Errorf("trang chi tiết thiếu %q", want)
+		}
+	}
+}
+
+// TestCrashBundleRoundTrip kiểm tra panic tạo crash bundle, liệt kê và tải được qua admin endpoints
+func TestCrashBundleRoundTrip(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/panic/division", nil)); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/admin/crashes", nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	var list struct {
+		Count   int `json:"count"`
+		Bundles []struct {
+			ID   string `json:"id"`
+			Path string `json:"path"`
+		} `json:"bundles"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	if list.Count != 1 || list.Bundles[0].Path != "/panic/division" {
+		t.Fatalf("crash bundles = %+v, want 1 bundle cho /panic/division", list)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/admin/crashes/"+list.Bundles[0].ID, nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || resp.Header.Get(fiber.HeaderContentType) != "application/zip" {
+		t.Fatalf("download status = %d, Content-Type = %q", resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	files := make(map[string]bool)
+	for _, f := range archive.File {
+		files[filepath.Base(f.Name)] = true
+	}
+	for _, name := range []string{"manifest.json", "error.json", "goroutines.txt", "request.json"} {
+		if !files[name] {
+			t.Errorf("crash bundle thiếu %s", name)
+		}
+	}
+}
+
+// TestOrderBatchPartialFailure kiểm tra 207 Multi-Status của POST /orders/batch ở cả hai mode:
+// payload lỗi từng dòng, stock sau rollback và log entry với children
+func TestOrderBatchPartialFailure(t *testing.T) {
+	const lines = `[{"product_id":"456","user_id":"U1","quantity":2},` +
+		`{"product_id":"999","user_id":"U1","quantity":1},` +
+		`{"product_id":"789","user_id":"U1","quantity":0},` +
+		`{"product_id":"789","user_id":"U1","quantity":3}]`
+
+	tests := []struct {
+		mode     string
+		statuses []string
+		failed   int
+		stock456 int // Stock của sản phẩm 456 (ban đầu 5) sau batch
+	}{
+		{"best_effort", []string{"created", "failed", "failed", "created"}, 2, 3},
+		{"all_or_nothing", []string{"rolled_back", "failed", "skipped", "skipped"}, 1, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			app, memory := newTestApp(t)
+
+			req := httptest.NewRequest(http.MethodPost, "/orders/batch?mode="+tt.mode, strings.NewReader(lines))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			if resp.StatusCode != fiber.StatusMultiStatus {
+				t.Fatalf("status = %d, want 207", resp.StatusCode)
+			}
+
+			var body struct {
+				Mode      string `json:"mode"`
+				Failed    int    `json:"failed"`
+				RequestID string `json:"request_id"`
+				Results   []struct {
+					Index  int                    `json:"index"`
+					Status string                 `json:"status"`
+					Error  map[string]interface{} `json:"error"`
+				} `json:"results"`
+			}
+			json.NewDecoder(resp.Body).Decode(&body)
+
+			if body.Mode != tt.mode || body.Failed != tt.failed || len(body.Results) != len(tt.statuses) {
+				t.Fatalf("body = %+v", body)
+			}
+			for i, want := range tt.statuses {
+				if got := body.Results[i].Status; got != want {
+					t.Errorf("results[%d].status = %s, want %s", i, got, want)
+				}
+			}
+
+			// Dòng 1: payload đầy đủ của BusinessError từ GetProduct
+			notFound := body.Results[1].Error
+			assertJSONEqual(t, "results[1].error.type", notFound["type"], "BUSINESS")
+			assertJSONEqual(t, "results[1].error.code", notFound["code"], 404)
+			assertJSONEqual(t, "results[1].error.data", notFound["data"], map[string]interface{}{"product_id": "999"})
+			location, _ := notFound["location"].(string)
+			assertLocation(t, location, frame{"services/product_service.go", "GetProduct", "goerrorkit.NewBusinessError(404"})
+
+			errs := memory.Errors()
+			if len(errs) != 1 {
+				t.Fatalf("logged %d errors, want 1", len(errs))
+			}
+			if errs[0].StatusCode() != fiber.StatusMultiStatus || len(errs[0].Children()) != tt.failed {
+				t.Errorf("entry status=%d children=%d", errs[0].StatusCode(), len(errs[0].Children()))
+			}
+			if errs[0].RequestID() != body.RequestID {
+				t.Errorf("entry.request_id = %q, body.request_id = %q", errs[0].RequestID(), body.RequestID)
+			}
+
+			product, _ := productService.GetProduct("456")
+			if product.Stock != tt.stock456 {
+				t.Errorf("stock 456 = %d, want %d", product.Stock, tt.stock456)
+			}
+		})
+	}
+}
+
+// TestProductImport kiểm tra import CSV qua HTTP: 207 khi có dòng lỗi, strict không thêm dòng nào,
+// báo cáo CSV tải về và upload multipart
+func TestProductImport(t *testing.T) {
+	const csvFile = "id,name,price,stock\nP1,Keyboard,49.9,10\nP2,Mouse,-5,abc\n"
+
+	send := func(t *testing.T, app *fiber.App, query string) (*http.Response, []byte) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/products/import"+query, strings.NewReader(csvFile))
+		req.Header.Set(fiber.HeaderContentType, "text/csv")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		return resp, raw
+	}
+
+	t.Run("partial", func(t *testing.T) {
+		app, memory := newTestApp(t)
+		resp, raw := send(t, app, "")
+		if resp.StatusCode != fiber.StatusMultiStatus {
+			t.Fatalf("status = %d\n%s", resp.StatusCode, raw)
+		}
+
+		var body map[string]interface{}
+		json.Unmarshal(raw, &body)
+		assertJSONEqual(t, "imported", body["imported"], 1)
+		assertJSONEqual(t, "failed", body["failed"], 1)
+		errs, _ := body["errors"].([]interface{})
+		if len(errs) != 2 {
+			t.Fatalf("errors = %v", body["errors"])
+		}
+		first, _ := errs[0].(map[string]interface{})
+		assertJSONEqual(t, "errors[0].row", first["row"], 3)
+		assertJSONEqual(t, "errors[0].column", first["column"], "price")
+		assertJSONEqual(t, "errors[0].type", first["type"], "VALIDATION")
+		assertJSONEqual(t, "errors[0].data.value", first["data"].(map[string]interface{})["value"], "-5")
+
+		if _, err := productService.GetProduct("P1"); err != nil {
+			t.Errorf("P1 chưa được import: %v", err)
+		}
+
+		logged := memory.Errors()
+		if len(logged) != 1 || len(logged[0].Children()) != 2 {
+			t.Fatalf("logged %d errors, want 1 entry với 2 children", len(logged))
+		}
+		if got := logged[0].Children()[0].Message; got != "row 3, column price: Giá phải lớn hơn hoặc bằng 0" {
+			t.Errorf("children[0].message = %q", got)
+		}
+		if logged[0].RequestID() != body["request_id"] {
+			t.Errorf("entry.request_id = %q, body.request_id = %v", logged[0].RequestID(), body["request_id"])
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		app, _ := newTestApp(t)
+		resp, raw := send(t, app, "?strict=true")
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Fatalf("status = %d\n%s", resp.StatusCode, raw)
+		}
+		var body map[string]interface{}
+		json.Unmarshal(raw, &body)
+		assertJSONEqual(t, "committed", body["committed"], false)
+		if _, err := productService.GetProduct("P1"); err == nil {
+			t.Error("strict: P1 không được import khi có dòng lỗi")
+		}
+	})
+
+	t.Run("csv report", func(t *testing.T) {
+		app, _ := newTestApp(t)
+		resp, raw := send(t, app, "?report=csv")
+		if got := resp.Header.Get(fiber.HeaderContentDisposition); !strings.Contains(got, "import-report.csv") {
+			t.Errorf("Content-Disposition = %q", got)
+		}
+		want := "row,column,value,type,code,error\n" +
+			"3,price,-5,VALIDATION,400,Giá phải lớn hơn hoặc bằng 0\n" +
+			"3,stock,abc,VALIDATION,400,Stock phải là số nguyên\n"
+		if string(raw) != want {
+			t.Errorf("report:\n%s\nwant:\n%s", raw, want)
+		}
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		app, _ := newTestApp(t)
+
+		var buf bytes.Buffer
+		form := multipart.NewWriter(&buf)
+		part, _ := form.CreateFormFile("file", "products.json")
+		part.Write([]byte(`[{"id":"M1","name":"Webcam","price":59,"stock":3}]`))
+		form.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/products/import", &buf)
+		req.Header.Set(fiber.HeaderContentType, form.FormDataContentType())
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			raw, _ := io.ReadAll(resp.Body)
+			t.Fatalf("status = %d\n%s", resp.StatusCode, raw)
+		}
+		if product, err := productService.GetProduct("M1"); err != nil || product.Stock != 3 {
+			t.Errorf("M1 = %+v, %v", product, err)
+		}
+	})
+}
+
+// TestProductETagFlow kiểm tra optimistic concurrency: GET trả ETag, PUT với ETag đó thành công và trả ETag mới,
+// PUT thứ hai với ETag cũ (client khác đã sửa) bị từ chối với 412
+func TestProductETagFlow(t *testing.T) {
+	app, memory := newTestApp(t)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/product/456", nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	etag := resp.Header.Get(fiber.HeaderETag)
+	if etag == "" {
+		t.Fatal("GET /product/456 thiếu ETag")
+	}
+
+	put := func(body string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, "/product/456", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderIfMatch, etag)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		return resp
+	}
+
+	first := put(`{"name":"MacBook Pro M4","price":2599,"stock":4}`)
+	if first.StatusCode != 200 {
+		t.Fatalf("PUT #1 status = %d", first.StatusCode)
+	}
+	if newETag := first.Header.Get(fiber.HeaderETag); newETag == "" || newETag == etag {
+		t.Errorf("PUT #1 ETag = %q, want khác %q", newETag, etag)
+	}
+
+	second := put(`{"name":"MacBook Pro (old tab)","price":2499.99,"stock":5}`)
+	if second.StatusCode != fiber.StatusPreconditionFailed {
+		t.Fatalf("PUT #2 status = %d, want 412", second.StatusCode)
+	}
+	if product, _ := productService.GetProduct("456"); product.Name != "MacBook Pro M4" {
+		t.Errorf("PUT #2 ghi đè thay đổi: %+v", product)
+	}
+
+	errs := memory.Errors()
+	if len(errs) != 1 || errs[0].StatusCode() != 412 {
+		t.Fatalf("logged %d errors, want một lỗi 412", len(errs))
+	}
+}
+
+// TestUserRegistration kiểm tra user đăng ký qua POST /users được lưu vào USERS_FILE và dùng được để tạo đơn hàng
+func TestUserRegistration(t *testing.T) {
+	usersFile := filepath.Join(t.TempDir(), "users.json")
+	app, _ := newTestApp(t, func(cfg *config.Config) { cfg.UsersFile = usersFile })
+
+	send := func(method, path, body string) (*http.Response, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		var decoded map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp, decoded
+	}
+
+	resp, body := send(http.MethodPost, "/users", `{"name":"Lan","email":"lan@example.com","age":22,"password":"secret123"}`)
+	if resp.StatusCode != fiber.StatusCreated || resp.Header.Get(fiber.HeaderLocation) != "/users/USER003" {
+		t.Fatalf("POST /users = %d %s, body %v", resp.StatusCode, resp.Header.Get(fiber.HeaderLocation), body)
+	}
+	if _, leaked := body["user"].(map[string]interface{})["password_hash"]; leaked {
+		t.Errorf("response chứa password_hash: %v", body)
+	}
+
+	resp, body = send(http.MethodPost, "/order/create?product_id=456&user_id=USER003", "")
+	if resp.StatusCode != 200 {
+		t.Fatalf("POST /order/create = %d, body %v", resp.StatusCode, body)
+	}
+
+	// Khởi động lại: user được đọc từ USERS_FILE
+	setup(appConfig)
+	users, err := services.LoadUsers(usersFile)
+	if err != nil || len(users) != 3 || users[2].PasswordHash == "" {
+		t.Fatalf("LoadUsers = %d users, err %v", len(users), err)
+	}
+	if user, err := userService.GetUser("USER003"); err != nil || user.Email != "lan@example.com" {
+		t.Errorf("GetUser(USER003) = %+v, %v", user, err)
+	}
+	if _, err := userService.Authenticate("lan@example.com", "secret123"); err != nil {
+		t.Errorf("Authenticate: %v", err)
+	}
+}
+
+// TestDetachedGoroutinePanic kiểm tra panic trong safego.Go được log sau khi response đã trả về
+func TestDetachedGoroutinePanic(t *testing.T) {
+	app, memory := newTestApp(t)
+	memory.Reset()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/panic/goroutine?mode=detached", nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusAccepted {
+		t.Fatalf("status = %d, want 202", resp.StatusCode)
+	}
+	requestID := resp.Header.Get(fiber.HeaderXRequestID)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(memory.Errors()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	errs := memory.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("logged %d errors, want 1", len(errs))
+	}
+
+	entry := errs[0]
+	if entry.ErrorType() != goerrorkit.PanicError || entry.RequestID() != requestID {
+		t.Errorf("entry = %s %s, want PANIC %s", entry.ErrorType(), entry.RequestID(), requestID)
+	}
+	assertLocation(t, entry.Location(), frame{"main.go", "stockSnapshot", "return shelves[0] + shelves[1]"})
+	spawned, _ := entry.Fields["spawned_by"].([]string)
+	assertFrames(t, "spawned_by", spawned, []frame{{"main.go", "goroutinePanicHandler", "safego.Go(ctx, func() {"}})
+}
+
+// startJobs chạy jobRunner của app test cho đến khi test kết thúc
+func startJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	jobRunner.Start(ctx)
+}
+
+// waitJobs chờ jobRunner chạy xong mọi task (kể cả các lần retry)
+func waitJobs(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for jobRunner.Pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("jobs chưa chạy xong: %+v", jobRunner.Jobs())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestReservationFlow kiểm tra vòng đời giữ hàng qua HTTP: thanh toán chuyển hold thành sold,
+// hold quá hạn bị từ chối thanh toán (410) và được job hoàn trả về kho, lỗi của job được log kèm job_context
+// và chuyển vào dead letters, "retry now" xóa dead letter
+func TestReservationFlow(t *testing.T) {
+	send := func(t *testing.T, app *fiber.App, method, path string) map[string]interface{} {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(method, path, nil))
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		body["status_code"] = resp.StatusCode
+		return body
+	}
+
+	t.Run("payment confirms hold", func(t *testing.T) {
+		app, _ := newTestApp(t)
+		startJobs(t)
+
+		send(t, app, http.MethodPost, "/order/create?product_id=456&quantity=2")
+		if body := send(t, app, http.MethodPost, "/order/ORD-USER001-456/payment?amount=4999.98"); body["status_code"] != 200 {
+			t.Fatalf("payment = %v", body)
+		}
+
+		hold := send(t, app, http.MethodGet, "/reservations/RSV-0001")
+		if hold["status"] != services.HoldSold || hold["order_id"] != "ORD-USER001-456" {
+			t.Errorf("reservation = %v", hold)
+		}
+
+		// Hàng đã bán không được hoàn trả
+		send(t, app, http.MethodPost, "/admin/jobs/reservation_sweep/run")
+		waitJobs(t)
+		if product, _ := productService.GetProduct("456"); product.Stock != 3 {
+			t.Errorf("stock 456 = %d, want 3", product.Stock)
+		}
+	})
+
+	t.Run("expired hold", func(t *testing.T) {
+		app, memory := newTestApp(t, func(cfg *config.Config) { cfg.ReservationTTL = time.Millisecond })
+		startJobs(t)
+
+		send(t, app, http.MethodPost, "/order/create?product_id=456&quantity=2")
+		send(t, app, http.MethodPost, "/product/789/reserve?quantity=3")
+		time.Sleep(5 * time.Millisecond)
+
+		body := send(t, app, http.MethodPost, "/order/ORD-USER001-456/payment?amount=4999.98")
+		if body["status_code"] != fiber.StatusGone || body["type"] != "BUSINESS" {
+			t.Fatalf("payment = %v, want 410", body)
+		}
+
+		// Sản phẩm 789 bị xóa trước khi job chạy: RSV-0002 không hoàn trả được
+		if err := productService.DeleteProduct("789", ""); err != nil {
+			t.Fatal(err)
+		}
+		memory.Reset()
+		send(t, app, http.MethodPost, "/admin/jobs/reservation_sweep/run")
+		waitJobs(t)
+
+		if product, _ := productService.GetProduct("456"); product.Stock != 5 {
+			t.Errorf("stock 456 = %d, want 5", product.Stock)
+		}
+		for _, id := range []string{"RSV-0001", "RSV-0002"} {
+			if hold := send(t, app, http.MethodGet, "/reservations/"+id); hold["status"] != services.HoldExpired {
+				t.Errorf("reservation = %v", hold)
+			}
+		}
+
+		// ErrProductNotFound không retry: một log entry, chuyển thẳng vào dead letters
+		errs := memory.Errors()
+		if len(errs) != 1 {
+			t.Fatalf("logged %d errors, want 1", len(errs))
+		}
+		entry := errs[0]
+		assertLocation(t, entry.Location(), frame{"services/reservation_service.go", "ExpireHold", "goerrorkit.WrapWithMessage(err"})
+		assertJSONEqual(t, "job_context", entry.JobContext(), map[string]interface{}{
+			"name": "reservation_expire", "attempt": 1, "payload_id": "RSV-0002",
+		})
+		if entry.HTTPContext() != nil {
+			t.Errorf("http_context = %v, want nil", entry.HTTPContext())
+		}
+
+		letters := jobRunner.DeadLetters()
+		if len(letters) != 1 || letters[0].Job != "reservation_expire" || letters[0].PayloadID != "RSV-0002" || letters[0].Attempts != 1 {
+			t.Fatalf("dead letters = %+v", letters)
+		}
+
+		// Reservation đã kết thúc nên retry now thành công
+		retry := send(t, app, http.MethodPost, "/admin/jobs/dead-letters/"+letters[0].ID+"/retry")
+		if retry["succeeded"] != true || len(jobRunner.DeadLetters()) != 0 {
+			t.Errorf("retry = %v, dead letters = %+v", retry, jobRunner.DeadLetters())
+		}
+	})
+}
+
+// TestRefundFlow kiểm tra POST /order/:id/refund: hoàn một phần, vượt quá, hoàn toàn bộ kèm nhập lại kho, gateway lỗi
+func TestRefundFlow(t *testing.T) {
+	send := func(t *testing.T, app *fiber.App, path string) map[string]interface{} {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, path, nil))
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		body["status_code"] = resp.StatusCode
+		return body
+	}
+
+	t.Run("partial then full", func(t *testing.T) {
+		app, memory := newTestApp(t)
+
+		send(t, app, "/order/create?product_id=456&quantity=2")
+		if body := send(t, app, "/order/ORD-USER001-456/refund"); body["status_code"] != 409 {
+			t.Fatalf("refund chưa thanh toán = %v, want 409", body)
+		}
+		send(t, app, "/order/ORD-USER001-456/payment?amount=4999.98")
+
+		body := send(t, app, "/order/ORD-USER001-456/refund?amount=30&reason=giao%20tr%E1%BB%85")
+		if body["status_code"] != 200 || body["order"].(map[string]interface{})["Status"] != services.OrderPartiallyRefunded {
+			t.Fatalf("refund một phần = %v", body)
+		}
+		assertJSONEqual(t, "refund.amount", body["refund"].(map[string]interface{})["amount"], usd("30.00"))
+
+		memory.Reset()
+		body = send(t, app, "/order/ORD-USER001-456/refund?amount=4970")
+		if body["status_code"] != 422 {
+			t.Fatalf("refund vượt quá = %v, want 422", body)
+		}
+		errs := memory.Errors()
+		if len(errs) != 1 || !strings.Contains(errs[0].Location(), "services/refund.go:RefundOrder") {
+			t.Fatalf("logged %d errors, want một lỗi từ RefundOrder", len(errs))
+		}
+		assertJSONEqual(t, "data.refundable", errs[0].Data()["refundable"], usd("4969.98"))
+
+		body = send(t, app, "/order/ORD-USER001-456/refund")
+		refund := body["refund"].(map[string]interface{})
+		if body["status_code"] != 200 || fmt.Sprint(refund["restocked"]) != "2" {
+			t.Fatalf("refund toàn bộ = %v", body)
+		}
+		if product, _ := productService.GetProduct("456"); product.Stock != 5 {
+			t.Errorf("stock 456 = %d, want 5", product.Stock)
+		}
+		movements, _ := productService.StockMovements("456")
+		if last := movements[len(movements)-1]; last.Type != services.MovementRefund || last.Reason != "RFD-0002 ORD-USER001-456" {
+			t.Errorf("movement cuối = %+v", last)
+		}
+	})
+
+	t.Run("gateway declined", func(t *testing.T) {
+		app, memory := newTestApp(t)
+
+		// Gateway không hoàn tiền JPY: cần sản phẩm có giá JPY vì số tiền thanh toán phải khớp tổng đơn hàng
+		if _, err := productService.CreateProduct(services.Product{ID: "JP1", Name: "Nintendo Switch", Price: services.MustParseMoney("15000", services.JPY), Stock: 5}); err != nil {
+			t.Fatal(err)
+		}
+		send(t, app, "/order/create?product_id=JP1&quantity=1")
+		send(t, app, "/order/ORD-USER001-JP1/payment?amount=15000&currency=JPY")
+		memory.Reset()
+
+		if body := send(t, app, "/order/ORD-USER001-JP1/refund?amount=5000"); body["status_code"] != 502 {
+			t.Fatalf("refund = %v, want 502", body)
+		}
+		errs := memory.Errors()
+		if len(errs) != 1 || errs[0].ErrorType() != goerrorkit.ExternalError || errs[0].Cause() != "payment for order ORD-USER001-JP1: refunds not supported for JPY" {
+			t.Fatalf("logged %v", errs)
+		}
+		if order, _ := orderService.GetOrder("ORD-USER001-JP1"); order.Status != services.OrderPaid || len(order.Refunds) != 0 {
+			t.Errorf("order = %+v", order)
+		}
+	})
+}
+
+// TestPaymentWebhook kiểm tra webhook xác nhận thanh toán: cập nhật đơn hàng, dedup theo event ID, chữ ký và tolerance
+func TestPaymentWebhook(t *testing.T) {
+	app, memory := newTestApp(t)
+	deliver := func(t *testing.T, payload string, signature string) map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/payment", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(services.WebhookSignatureHeader, signature)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		body["status_code"] = resp.StatusCode
+		return body
+	}
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodPost, "/order/create?product_id=456&quantity=2", nil)); err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	payload := `{"id":"evt_1","type":"payment.succeeded","order_id":"ORD-USER001-456","amount":{"amount":"4999.98","currency":"USD"}}`
+
+	body := deliver(t, payload, signedWebhook(payload)[services.WebhookSignatureHeader])
+	if body["status_code"] != 200 || body["duplicate"] != false || body["event_id"] != "evt_1" {
+		t.Fatalf("webhook = %v", body)
+	}
+	order, _ := orderService.GetOrder("ORD-USER001-456")
+	if order.Status != services.OrderPaid || order.Paid == nil || order.Paid.Decimal() != "4999.98" {
+		t.Fatalf("order = %+v", order)
+	}
+
+	// Gateway gửi lại: 200 duplicate, đơn hàng không đổi
+	body = deliver(t, payload, signedWebhook(payload)[services.WebhookSignatureHeader])
+	if body["status_code"] != 200 || body["duplicate"] != true {
+		t.Fatalf("replay = %v", body)
+	}
+	if again, _ := orderService.GetOrder("ORD-USER001-456"); !again.PaidAt.Equal(*order.PaidAt) {
+		t.Errorf("PaidAt = %v, want %v", again.PaidAt, order.PaidAt)
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		message   string
+	}{
+		{"wrong secret", services.SignWebhook("whsec_other", time.Now(), []byte(payload)), "Chữ ký webhook không hợp lệ"},
+		{"stale timestamp", services.SignWebhook(testWebhookSecret, time.Now().Add(-10*time.Minute), []byte(payload)), "Webhook nằm ngoài khoảng thời gian cho phép"},
+		{"malformed", "sha256=abc", "Chữ ký webhook không đúng định dạng"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory.Reset()
+			if body := deliver(t, payload, tt.signature); body["status_code"] != 401 {
+				t.Fatalf("webhook = %v, want 401", body)
+			}
+			errs := memory.Errors()
+			if len(errs) != 1 || errs[0].ErrorType() != goerrorkit.AuthError || errs[0].Message != tt.message {
+				t.Fatalf("logged %v", errs)
+			}
+		})
+	}
+
+	// Ngoài development không có secret mặc định: webhook bị tắt (503) thay vì nhận chữ ký bằng "whsec_dev"
+	t.Run("disabled without secret", func(t *testing.T) {
+		t.Setenv("APP_ENV", "production")
+		t.Setenv("PAYMENT_WEBHOOK_SECRET", "")
+		cfg := config.Load()
+		if cfg.PaymentWebhookSecret != "" {
+			t.Fatalf("PaymentWebhookSecret = %q, want rỗng", cfg.PaymentWebhookSecret)
+		}
+		t.Setenv("APP_ENV", "development")
+		if secret := config.Load().PaymentWebhookSecret; secret != "whsec_dev" {
+			t.Errorf("PaymentWebhookSecret (development) = %q, want whsec_dev", secret)
+		}
+
+		app, _ := newTestApp(t, func(c *config.Config) { c.Env, c.PaymentWebhookSecret = cfg.Env, cfg.PaymentWebhookSecret })
+		forged := services.SignWebhook("whsec_dev", time.Now(), []byte(payload))
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/payment", strings.NewReader(payload))
+		req.Header.Set(services.WebhookSignatureHeader, forged)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		if resp.StatusCode != 503 {
+			t.Errorf("status = %d, want 503", resp.StatusCode)
+		}
+	})
+}
+
+// TestEventLog kiểm tra event log: thay đổi kèm actor, request ID, before/after và failed attempt trỏ tới log entry
+func TestEventLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	app, memory := newTestApp(t, func(cfg *config.Config) { cfg.EventLogFile = path })
+	t.Cleanup(func() { eventLog.Close() })
+
+	send := func(t *testing.T, method, target string, headers map[string]string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		return resp
+	}
+	events := func(t *testing.T, query string) []audit.Event {
+		t.Helper()
+		var body struct {
+			Count  int           `json:"count"`
+			Events []audit.Event `json:"events"`
+		}
+		json.NewDecoder(send(t, http.MethodGet, "/admin/events?"+query, nil).Body).Decode(&body)
+		if body.Count != len(body.Events) {
+			t.Fatalf("count = %d, len(events) = %d", body.Count, len(body.Events))
+		}
+		return body.Events
+	}
+
+	send(t, http.MethodPost, "/order/create?product_id=456&quantity=2&user_id=USER002", nil)
+	memory.Reset()
+	payer := map[string]string{"X-User-ID": "USER002"}
+	// Số tiền không khớp tổng đơn hàng (4999.98): failed attempt
+	if resp := send(t, http.MethodPost, "/order/ORD-USER002-456/payment?amount=100", payer); resp.StatusCode != 422 {
+		t.Fatalf("payment status = %d, want 422", resp.StatusCode)
+	}
+	errs := memory.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("logged %d errors, want 1", len(errs))
+	}
+	send(t, http.MethodPost, "/order/ORD-USER002-456/payment?amount=4999.98", payer)
+	send(t, http.MethodDelete, "/order/ORD-USER002-456/cancel", nil)
+
+	got := events(t, "entity=order&id=ORD-USER002-456")
+	want := []struct{ typ, outcome, actor string }{
+		{audit.OrderCreated, audit.OutcomeSucceeded, "claimed:USER002"},
+		{audit.PaymentFailed, audit.OutcomeFailed, "claimed:USER002"},
+		{audit.PaymentSucceeded, audit.OutcomeSucceeded, "claimed:USER002"},
+		{audit.OrderCancelled, audit.OutcomeFailed, "anonymous"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events = %+v, want %d", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Type != w.typ || got[i].Outcome != w.outcome || got[i].Actor != w.actor || got[i].RequestID == "" {
+			t.Errorf("events[%d] = %s %s %s (request %q), want %s %s %s", i, got[i].Type, got[i].Outcome, got[i].Actor, got[i].RequestID, w.typ, w.outcome, w.actor)
+		}
+	}
+
+	// Failed attempt trỏ tới log entry của request
+	failed := got[1]
+	if failed.Error == nil || failed.Error.RequestID != errs[0].RequestID() || failed.RequestID != errs[0].RequestID() ||
+		failed.Error.Location != errs[0].Location() || failed.Error.StatusCode != 422 || failed.After != nil {
+		t.Errorf("failed attempt = %+v, error = %+v, log entry %s at %s", failed, failed.Error, errs[0].RequestID(), errs[0].Location())
+	}
+
+	// Before / after là snapshot tại thời điểm thay đổi
+	var before, after services.Order
+	json.Unmarshal(got[2].Before, &before)
+	json.Unmarshal(got[2].After, &after)
+	if before.Status != services.OrderConfirmed || after.Status != services.OrderPaid {
+		t.Errorf("payment before/after status = %s/%s", before.Status, after.Status)
+	}
+
+	// Ai đã giữ hàng của sản phẩm 456
+	reserved := events(t, "entity=product&id=456&type=stock.reserved")
+	if len(reserved) != 1 || reserved[0].Actor != "claimed:USER002" || reserved[0].Refs["order_id"] != "ORD-USER002-456" ||
+		reserved[0].Before == nil || reserved[0].After == nil {
+		t.Fatalf("stock.reserved = %+v", reserved)
+	}
+	if latest := events(t, "limit=1"); len(latest) != 1 || latest[0].Type != audit.OrderCancelled {
+		t.Errorf("limit=1 = %+v", latest)
+	}
+
+	// Event log được ghi ra file và đọc lại được
+	eventLog.Close()
+	reopened, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("audit.Open: %v", err)
+	}
+	defer reopened.Close()
+	if all := reopened.Events(audit.Query{}); len(all) != 5 || all[4].ID != "EV-000005" {
+		t.Errorf("reopened = %d events", len(all))
+	}
+}
+
+// TestJobRunnerRetries kiểm tra retry theo RetryPolicy, panic trong job và dead letters
+func TestJobRunnerRetries(t *testing.T) {
+	_, memory := newTestApp(t)
+	memory.Reset()
+
+	var calls atomic.Int32
+	jobRunner.Register(jobs.Job{
+		Name: "flaky",
+		Handler: func(ctx context.Context, payloadID string) error {
+			if calls.Add(1) < 3 {
+				return goerrorkit.NewExternalError(503, "Upstream không phản hồi", fmt.Errorf("connection reset"))
+			}
+			return nil
+		},
+		Retry: jobs.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+	})
+	jobRunner.Register(jobs.Job{
+		Name: "broken",
+		Handler: func(ctx context.Context, payloadID string) error {
+			var receipts map[string]string
+			receipts[payloadID] = "sent" // panic: assignment to entry in nil map
+			return nil
+		},
+		Retry: jobs.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+	})
+	startJobs(t)
+
+	jobRunner.Enqueue("flaky", "F-1")
+	jobRunner.Enqueue("broken", "B-1")
+	waitJobs(t)
+
+	// flaky: lỗi 2 lần rồi thành công, broken: panic cả 2 lần rồi vào dead letters
+	var flaky, broken []interface{}
+	for _, entry := range memory.Errors() {
+		job := entry.JobContext()
+		switch job["name"] {
+		case "flaky":
+			flaky = append(flaky, job["attempt"])
+		case "broken":
+			broken = append(broken, job["attempt"])
+			if entry.ErrorType() != goerrorkit.PanicError {
+				t.Errorf("broken error_type = %s, want PANIC", entry.ErrorType())
+			}
+			assertLocation(t, entry.Location(), frame{"app_test.go", "TestJobRunnerRetries.func2", `receipts[payloadID] = "sent"`})
+		}
+	}
+	if fmt.Sprint(flaky) != "[1 2]" || fmt.Sprint(broken) != "[1 2]" {
+		t.Errorf("attempts flaky = %v, broken = %v", flaky, broken)
+	}
+
+	letters := jobRunner.DeadLetters()
+	if len(letters) != 1 || letters[0].Job != "broken" || letters[0].Attempts != 2 || letters[0].Error.ErrorType != "PANIC" {
+		t.Fatalf("dead letters = %+v", letters)
+	}
+	// Task trong dead letters không được enqueue lại cho đến khi retry thủ công
+	if queued, _ := jobRunner.Enqueue("broken", "B-1"); queued {
+		t.Error("Enqueue task đang nằm trong dead letters")
+	}
+
+	// Retry now vẫn panic: dead letter được cập nhật attempts
+	if ok, err := jobRunner.RetryDeadLetter(context.Background(), letters[0].ID); ok || err != nil {
+		t.Fatalf("RetryDeadLetter = %v, %v", ok, err)
+	}
+	if letters = jobRunner.DeadLetters(); letters[0].Attempts != 3 {
+		t.Errorf("attempts = %d, want 3", letters[0].Attempts)
+	}
+
+	for _, status := range jobRunner.Jobs() {
+		switch status.Name {
+		case "flaky":
+			if status.Runs != 3 || status.Failures != 2 || status.LastError.Attempt != 2 {
+				t.Errorf("flaky = %+v", status)
+			}
+		case "broken":
+			if status.Runs != 3 || status.Failures != 3 {
+				t.Errorf("broken = %+v", status)
+			}
+		}
+	}
+}
+
+// TestFulfillmentFlow kiểm tra job order_fulfillment sau khi thanh toán: vận đơn và email / SMS qua simulators,
+// lỗi của dependency được log là ExternalError kèm dependency, operation, upstream_status và job_context
+func TestFulfillmentFlow(t *testing.T) {
+	send := func(t *testing.T, app *fiber.App, path string) map[string]interface{} {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, path, nil))
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		body["status_code"] = resp.StatusCode
+		return body
+	}
+
+	t.Run("payment and webhook", func(t *testing.T) {
+		app, memory := newTestApp(t)
+		startJobs(t)
+
+		send(t, app, "/order/create?product_id=456&quantity=2")
+		send(t, app, "/order/create?product_id=789&quantity=1&user_id=USER002")
+		if body := send(t, app, "/order/ORD-USER001-456/payment?amount=4999.98"); body["fulfillment_queued"] != true {
+			t.Fatalf("payment = %v", body)
+		}
+		payload := `{"id":"evt_1","type":"payment.succeeded","order_id":"ORD-USER002-789","amount":{"amount":"249.99","currency":"USD"}}`
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/payment", strings.NewReader(payload))
+		req.Header.Set(services.WebhookSignatureHeader, signedWebhook(payload)[services.WebhookSignatureHeader])
+		if _, err := app.Test(req); err != nil {
+			t.Fatalf("webhook: %v", err)
+		}
+		// Order ID giả lập không có hàng để giao
+		if body := send(t, app, "/order/ORD-123/payment?amount=10"); body["fulfillment_queued"] != false {
+			t.Errorf("payment ORD-123 = %v", body)
+		}
+		waitJobs(t)
+
+		// USER001 có số điện thoại: email và SMS, USER002 chỉ có email
+		for id, channels := range map[string]int{"ORD-USER001-456": 2, "ORD-USER002-789": 1} {
+			order, _ := orderService.GetOrder(id)
+			if order.Status != services.OrderShipped || order.Shipment == nil || len(order.Notifications) != channels {
+				t.Errorf("%s = %+v", id, order)
+			}
+		}
+		if errs := memory.Errors(); len(errs) != 0 {
+			t.Errorf("logged %d errors, want 0", len(errs))
+		}
+
+		// Hàng đã giao cho đơn vị vận chuyển không hủy được
+		resp, _ := app.Test(httptest.NewRequest(http.MethodDelete, "/order/ORD-USER001-456/cancel", nil))
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("cancel = %d, want 400", resp.StatusCode)
+		}
+	})
+
+	t.Run("dependency failures", func(t *testing.T) {
+		app, memory := newTestApp(t, func(cfg *config.Config) {
+			cfg.ShippingSimFailures = "create_label=rejected:1"
+			cfg.NotificationSimFailures = "send_sms=rejected:1"
+		})
+		startJobs(t)
+
+		send(t, app, "/order/create?product_id=456&quantity=2")
+		memory.Reset()
+		send(t, app, "/order/ORD-USER001-456/payment?amount=4999.98")
+		waitJobs(t)
+
+		// Upstream từ chối request: không retry, chuyển thẳng vào dead letters
+		letters := jobRunner.DeadLetters()
+		if len(letters) != 1 || letters[0].Job != "order_fulfillment" || letters[0].PayloadID != "ORD-USER001-456" || letters[0].Attempts != 1 {
+			t.Fatalf("dead letters = %+v", letters)
+		}
+
+		// Retry now: vận đơn và email thành công, SMS lỗi; lần sau chỉ gửi lại SMS
+		if retry := send(t, app, "/admin/jobs/dead-letters/"+letters[0].ID+"/retry"); retry["succeeded"] != false {
+			t.Fatalf("retry = %v", retry)
+		}
+		if retry := send(t, app, "/admin/jobs/dead-letters/"+letters[0].ID+"/retry"); retry["succeeded"] != true {
+			t.Fatalf("retry = %v", retry)
+		}
+		order, _ := orderService.GetOrder("ORD-USER001-456")
+		if order.Status != services.OrderShipped || order.Shipment.TrackingNumber != "SIM00000001" || len(order.Notifications) != 2 {
+			t.Errorf("order = %+v", order)
+		}
+
+		errs := memory.Errors()
+		if len(errs) != 2 {
+			t.Fatalf("logged %d errors, want 2", len(errs))
+		}
+		for i, want := range []map[string]interface{}{
+			{"dependency": "shipping", "operation": "create_label", "upstream_status": 422, "order_id": "ORD-USER001-456", "rate_id": "ORD-USER001-456-standard"},
+			{"dependency": "notification", "operation": "send_sms", "upstream_status": 422},
+		} {
+			entry := errs[i]
+			if entry.ErrorType() != goerrorkit.ExternalError || entry.Fields["status_code"] != 502 {
+				t.Errorf("entry %d = %s %v, want EXTERNAL 502", i, entry.ErrorType(), entry.Fields["status_code"])
+			}
+			assertLocation(t, entry.Location(), frame{"services/dependency.go", "dependencyFailure", "goerrorkit.NewExternalError("})
+			assertJSONEqual(t, "data", entry.Data(), want)
+			assertJSONEqual(t, "job_context", entry.JobContext(), map[string]interface{}{
+				"name": "order_fulfillment", "attempt": i + 1, "payload_id": "ORD-USER001-456",
+			})
+		}
+
+		// Mỗi lần chạy được ghi vào event log, failed attempt trỏ tới lỗi của dependency
+		var outcomes []string
+		for _, event := range eventLog.Events(audit.Query{Type: audit.OrderFulfilled}) {
+			outcomes = append(outcomes, event.Outcome)
+			if event.Actor != "system:order_fulfillment" {
+				t.Errorf("actor = %s", event.Actor)
+			}
+		}
+		if fmt.Sprint(outcomes) != "[failed failed succeeded]" {
+			t.Errorf("outcomes = %v", outcomes)
+		}
+	})
+}
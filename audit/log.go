@@ -0,0 +1,281 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"fiber_log/errhandler"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Audit trail - Event log append-only của các thay đổi domain
+// ============================================================================
+
+// Services chỉ thay đổi state, không biết ai gọi. Tầng HTTP ghi lại mỗi thay đổi kèm actor, request ID
+// và snapshot trước / sau; thao tác bị lỗi được ghi thành failed attempt có ErrorRef trỏ tới log entry
+// cùng request ID (fiberlog show <request_id>, /dev/errors/<request_id>)
+//
+// Example:
+//
+//	before := audit.Snapshot(order)
+//	err := orderService.CancelOrder(order.ID)
+//	eventLog.Append(audit.Event{
+//	    Type: audit.OrderCancelled, Entity: audit.EntityOrder, EntityID: order.ID,
+//	    Actor: "claimed:USER001", RequestID: requestID, Before: before, After: audit.Snapshot(order),
+//	}.Result(err, requestID))
+
+// Loại entity
+const (
+	EntityOrder   = "order"
+	EntityProduct = "product"
+)
+
+// Entities là các entity có thể truy vấn qua GET /admin/events?entity=
+var Entities = []string{EntityOrder, EntityProduct}
+
+// Loại event
+const (
+	OrderCreated     = "order.created"
+	StockReserved    = "stock.reserved"
+	PaymentSucceeded = "payment.succeeded"
+	PaymentFailed    = "payment.failed"
+	OrderCancelled   = "order.cancelled"
+	OrderRefunded    = "order.refunded"
+	OrderFulfilled   = "order.fulfilled" // Vận đơn / thông báo của đơn hàng đã thanh toán (job order_fulfillment)
+)
+
+// Kết quả của thao tác
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed" // Thao tác bị lỗi, xem Error
+)
+
+// Event là một thay đổi (hoặc một lần thay đổi thất bại) của entity
+type Event struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Entity    string            `json:"entity"`
+	EntityID  string            `json:"entity_id"`
+	Outcome   string            `json:"outcome"`
+	Actor     string            `json:"actor"`
+	RequestID string            `json:"request_id,omitempty"`
+	Refs      map[string]string `json:"refs,omitempty"` // Entity liên quan, ví dụ reservation_id, event_id của webhook
+	Before    json.RawMessage   `json:"before,omitempty"`
+	After     json.RawMessage   `json:"after,omitempty"`
+	Error     *ErrorRef         `json:"error,omitempty"`
+	At        time.Time         `json:"at"`
+}
+
+// ErrorRef tóm tắt lỗi của failed attempt, log entry đầy đủ được tìm theo RequestID
+type ErrorRef struct {
+	Message    string `json:"message"`
+	ErrorType  string `json:"error_type"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+// Result trả về event với Outcome theo err: lỗi thì là failed attempt kèm ErrorRef
+// After của failed attempt chỉ nên có khi thao tác đã thay đổi state một phần trước khi lỗi
+func (e Event) Result(err error, requestID string) Event {
+	if err == nil {
+		e.Outcome = OutcomeSucceeded
+		return e
+	}
+	e.Outcome = OutcomeFailed
+	e.Error = NewErrorRef(err, requestID)
+	return e
+}
+
+// NewErrorRef tạo ErrorRef giống log entry mà errhandler sẽ ghi cho err (lỗi gộp lấy type, status của lỗi con nghiêm trọng nhất)
+func NewErrorRef(err error, requestID string) *ErrorRef {
+	appErr, _ := errhandler.Aggregate(err, requestID)
+	if appErr == nil {
+		appErr = goerrorkit.ConvertToAppError(err, requestID)
+	}
+	location, _ := errhandler.Fields(appErr, nil)["location"].(string)
+	return &ErrorRef{
+		Message:    appErr.Message,
+		ErrorType:  string(appErr.Type),
+		StatusCode: appErr.Code,
+		Location:   location,
+		RequestID:  requestID,
+	}
+}
+
+// Snapshot chụp lại state của entity dưới dạng JSON tại thời điểm gọi
+// (entity là pointer có thể bị sửa sau đó), nil nếu v là nil hoặc không encode được
+func Snapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// Query lọc event, các trường rỗng không được dùng để lọc
+type Query struct {
+	Entity   string
+	EntityID string
+	Type     string
+	Limit    int // > 0: chỉ lấy Limit event mới nhất
+}
+
+// Log là event log append-only: event chỉ được thêm, không sửa hay xóa
+// Nếu có path, mỗi event được ghi thêm một dòng JSON vào file (NDJSON) trước khi trả về
+type Log struct {
+	mu     sync.RWMutex
+	events []Event
+	seq    int
+	file   *os.File
+	now    func() time.Time
+}
+
+// New tạo Log chỉ lưu trong bộ nhớ
+func New() *Log {
+	return &Log{now: time.Now}
+}
+
+// Open tạo Log ghi vào path, đọc lại các event đã có trong file (nếu tồn tại)
+// Dòng cuối ghi dở (process dừng giữa lúc ghi) bị cắt khỏi file; dòng hỏng ở giữa file thì trả về lỗi
+func Open(path string) (*Log, error) {
+	l := New()
+	unterminated, err := l.load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create event log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open event log: %w", err)
+	}
+	// Event cuối đầy đủ nhưng thiếu newline: thêm vào để event mới không nối vào cùng dòng
+	if unterminated {
+		if _, err := file.Write([]byte{'\n'}); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("write event log: %w", err)
+		}
+	}
+	l.file = file
+	return l, nil
+}
+
+// load đọc các event trong file NDJSON, file chưa tồn tại thì bỏ qua
+// Dòng cuối không có newline và không decode được là event ghi dở: cắt file về cuối dòng trước đó
+// Trả về true nếu dòng cuối là event hợp lệ nhưng thiếu newline
+func (l *Log) load(path string) (unterminated bool, err error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read event log: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	truncateAt := int64(-1)
+	for line := 1; ; line++ {
+		data, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return false, fmt.Errorf("read event log: %w", readErr)
+		}
+		complete := bytes.HasSuffix(data, []byte{'\n'})
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 {
+			var event Event
+			if err := json.Unmarshal(trimmed, &event); err != nil {
+				if complete {
+					return false, fmt.Errorf("event log %s line %d: %w", path, line, err)
+				}
+				truncateAt = offset
+				break
+			}
+			l.events = append(l.events, event)
+			unterminated = !complete
+		}
+		offset += int64(len(data))
+		if readErr == io.EOF {
+			break
+		}
+	}
+	l.seq = len(l.events)
+
+	if truncateAt >= 0 {
+		file.Close()
+		if err := os.Truncate(path, truncateAt); err != nil {
+			return false, fmt.Errorf("truncate partial event in %s: %w", path, err)
+		}
+	}
+	return unterminated, nil
+}
+
+// Append gán ID ("EV-000001") và thời điểm rồi thêm event vào log
+// Lỗi ghi file thì event không được thêm vào bộ nhớ để bộ nhớ và file luôn giống nhau
+func (l *Log) Append(event Event) (Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.ID = fmt.Sprintf("EV-%06d", l.seq+1)
+	event.At = l.now()
+	if event.Outcome == "" {
+		event.Outcome = OutcomeSucceeded
+	}
+
+	if l.file != nil {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return event, fmt.Errorf("encode event %s: %w", event.ID, err)
+		}
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return event, fmt.Errorf("write event %s: %w", event.ID, err)
+		}
+	}
+
+	l.seq++
+	l.events = append(l.events, event)
+	return event, nil
+}
+
+// Events trả về các event khớp q, cũ nhất trước
+func (l *Log) Events(q Query) []Event {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := []Event{}
+	for _, event := range l.events {
+		if q.Entity != "" && event.Entity != q.Entity ||
+			q.EntityID != "" && event.EntityID != q.EntityID ||
+			q.Type != "" && event.Type != q.Type {
+			continue
+		}
+		result = append(result, event)
+	}
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result
+}
+
+// Close đóng file của event log (nếu có)
+func (l *Log) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// appendEvents mở log tại path và ghi n event order.created
+func appendEvents(t *testing.T, path string, n int) {
+	t.Helper()
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer l.Close()
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(Event{Type: OrderCreated, Entity: EntityOrder, EntityID: "ORD-1", Actor: "system"}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		tail    string // Thêm vào sau 2 event hợp lệ
+		events  int    // Số event đọc lại được
+		wantErr string
+	}{
+		{"clean", "", 2, ""},
+		// Process dừng giữa lúc ghi: dòng cuối bị cắt khỏi file
+		{"partial last line", `{"id":"EV-000003","type":"order.cr`, 2, ""},
+		// Event cuối đầy đủ nhưng thiếu newline: vẫn đọc được, newline được thêm vào trước event mới
+		{"unterminated last event", `{"id":"EV-000003","type":"order.created","entity":"order","entity_id":"ORD-1"}`, 3, ""},
+		{"blank lines", "\n\n", 2, ""},
+		// Dòng hỏng đã có newline (không phải ghi dở): không tự sửa, báo lỗi kèm số dòng
+		{"corrupt line", "not json\n" + `{"id":"EV-000004"}` + "\n", 0, "line 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events.ndjson")
+			appendEvents(t, path, 2)
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.WriteString(tt.tail)
+			file.Close()
+
+			l, err := Open(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Open err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if got := len(l.Events(Query{})); got != tt.events {
+				t.Errorf("events = %d, want %d", got, tt.events)
+			}
+			next, err := l.Append(Event{Type: OrderCancelled, Entity: EntityOrder, EntityID: "ORD-1", Actor: "system"})
+			if err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+			l.Close()
+
+			// Event mới nằm trên dòng riêng và file đọc lại được
+			reopened, err := Open(path)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			defer reopened.Close()
+			events := reopened.Events(Query{})
+			if len(events) != tt.events+1 || events[len(events)-1].ID != next.ID {
+				t.Errorf("reopened events = %+v, want %d events ending with %s", events, tt.events+1, next.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"fiber_log/logging"
+	"fiber_log/logquery"
+)
+
+// maxSkippedWarnings là số entries hỏng được in chi tiết, còn lại chỉ được đếm
+const maxSkippedWarnings = 5
+
+// readAll đọc entries của file log và các backup, cảnh báo ra stderr các entries hỏng bị bỏ qua
+// (stdout chỉ chứa kết quả để vẫn dùng được với --json | jq)
+func readAll(path string) ([]*logging.Entry, error) {
+	entries, skipped, err := logquery.ReadAll(path)
+	if err != nil {
+		return nil, err
+	}
+	warnSkipped(skipped)
+	return entries, nil
+}
+
+// warnSkipped in vị trí các entries hỏng ra stderr
+func warnSkipped(skipped []logquery.Skipped) {
+	for i, s := range skipped {
+		if i == maxSkippedWarnings {
+			fmt.Fprintf(os.Stderr, "fiberlog: warning: ... và %d entries hỏng khác\n", len(skipped)-i)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "fiberlog: warning: bỏ qua entry hỏng: %s\n", s)
+	}
+}
+
+// runTail hiển thị N entries mới nhất, với -f tiếp tục theo dõi file hiện tại
+func runTail(args []string, opts *options, stdout io.Writer) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	registerCommon(fs, opts)
+	n := fs.Int("n", 20, "số entries hiển thị (số âm = tất cả)")
+	follow := fs.Bool("f", false, "theo dõi entries mới được ghi vào file")
+	interval := fs.Duration("interval", time.Second, "chu kỳ kiểm tra file khi dùng -f")
+	filters := registerFilters(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter, err := filters.build()
+	if err != nil {
+		return err
+	}
+
+	entries, err := readAll(opts.file)
+	if err != nil {
+		return err
+	}
+	entries = filter.Apply(entries)
+	if *n >= 0 && len(entries) > *n {
+		entries = entries[len(entries)-*n:]
+	}
+	for _, e := range entries {
+		if err := printEntry(stdout, e, opts.json); err != nil {
+			return err
+		}
+	}
+
+	if !*follow {
+		return nil
+	}
+	return followFile(opts.file, *interval, func(e *logging.Entry) error {
+		if !filter.Match(e) {
+			return nil
+		}
+		return printEntry(stdout, e, opts.json)
+	})
+}
+
+// followFile đọc các entries mới được ghi thêm vào file (giống tail -f)
+// Khi file bị rotate (kích thước nhỏ hơn vị trí đã đọc) sẽ đọc lại từ đầu
+func followFile(path string, interval time.Duration, fn func(*logging.Entry) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+
+	for {
+		time.Sleep(interval)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // File đang được rotate
+		}
+		if info.Size() < offset {
+			offset = 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		data, err := readFrom(path, offset)
+		if err != nil {
+			return err
+		}
+
+		// Chỉ tiến offset tới entry cuối cùng decode được; phần dở dang đọc lại ở lần sau
+		consumed, err := logquery.Decode(bytes.NewReader(data), fn, func(skipped logquery.Skipped) {
+			skipped.File = path
+			warnSkipped([]logquery.Skipped{skipped})
+		})
+		if err != nil {
+			return err
+		}
+		offset += consumed
+	}
+}
+
+// readFrom đọc nội dung file từ offset tới cuối file
+func readFrom(path string, offset int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(file)
+}
+
+// runGrep lọc entries theo type/status/location/request ID/time/message
+func runGrep(args []string, opts *options, stdout io.Writer) error {
+	fs := flag.NewFlagSet("grep", flag.ContinueOnError)
+	registerCommon(fs, opts)
+	filters := registerFilters(fs)
+	limit := fs.Int("limit", 0, "giới hạn số entries (0 = không giới hạn, lấy các entries mới nhất)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter, err := filters.build()
+	if err != nil {
+		return err
+	}
+	filter.ErrorsOnly = true
+
+	entries, err := readAll(opts.file)
+	if err != nil {
+		return err
+	}
+	entries = filter.Apply(entries)
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+
+	for _, e := range entries {
+		if err := printEntry(stdout, e, opts.json); err != nil {
+			return err
+		}
+	}
+	if !opts.json {
+		fmt.Fprintf(stdout, "\n%d entries\n", len(entries))
+	}
+	return nil
+}
+
+// runStats thống kê top locations và số lỗi theo loại mỗi giờ
+func runStats(args []string, opts *options, stdout io.Writer) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	registerCommon(fs, opts)
+	top := fs.Int("top", 10, "số locations hiển thị")
+	filters := registerFilters(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter, err := filters.build()
+	if err != nil {
+		return err
+	}
+
+	entries, err := readAll(opts.file)
+	if err != nil {
+		return err
+	}
+	stats := logquery.ComputeStats(filter.Apply(entries), *top)
+
+	if opts.json {
+		return writeJSON(stdout, stats)
+	}
+	printStats(stdout, stats)
+	return nil
+}
+
+// runShow hiển thị đầy đủ các entries của một request ID
+func runShow(args []string, opts *options, stdout io.Writer) error {
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	registerCommon(fs, opts)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: fiberlog show <request_id>")
+	}
+	requestID := fs.Arg(0)
+
+	entries, err := readAll(opts.file)
+	if err != nil {
+		return err
+	}
+	filter := &logquery.Filter{RequestID: requestID}
+	entries = filter.Apply(entries)
+	if len(entries) == 0 {
+		return fmt.Errorf("no entry found for request ID %q", requestID)
+	}
+
+	if opts.json {
+		docs := make([]interface{}, 0, len(entries))
+		for _, e := range entries {
+			docs = append(docs, entryDoc(e))
+		}
+		return writeJSON(stdout, docs)
+	}
+
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		printDetail(stdout, e)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Command fiberlog tra cứu error log do ứng dụng fiber_log ghi ra (logs/errors.log),
+// bao gồm cả các file backup đã rotate và nén gzip
+//
+// Usage:
+//
+//	fiberlog [--file logs/errors.log] [--json] <command> [options]
+//
+//	fiberlog tail -n 20 -f
+//	fiberlog grep --type PANIC,SYSTEM --status 5xx --since 2h
+//	fiberlog grep --location CheckStock --request-id 3f1c...
+//	fiberlog stats --top 5 --since 1d
+//	fiberlog show <request_id>
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"fiber_log/logquery"
+)
+
+const usage = `fiberlog - tra cứu error log của fiber_log
+
+Usage:
+  fiberlog [--file PATH] [--json] <command> [options]
+
+Commands:
+  tail    Hiển thị N entries mới nhất (-n 20), theo dõi file mới với -f
+  grep    Lọc entries theo --type, --status, --location, --request-id, --since, --until, --message
+  stats   Top locations và số lỗi theo loại mỗi giờ (--top 10)
+  show    Hiển thị đầy đủ entry (kèm call chain) của một request: show <request_id>
+
+Global options:
+  --file PATH   File log hiện tại, các backup cùng thư mục được đọc tự động (mặc định logs/errors.log)
+  --json        Output dạng JSON thay vì dạng dễ đọc
+
+Chạy "fiberlog <command> -h" để xem options của từng command.
+`
+
+// options là các options chung cho mọi command
+type options struct {
+	file string
+	json bool
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "fiberlog: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parse global options và chuyển tới command tương ứng
+func run(args []string, stdout io.Writer) error {
+	opts := &options{}
+
+	global := flag.NewFlagSet("fiberlog", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(global.Output(), usage) }
+	registerCommon(global, opts)
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+
+	if global.NArg() == 0 {
+		global.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	command, rest := global.Arg(0), global.Args()[1:]
+	switch command {
+	case "tail":
+		return runTail(rest, opts, stdout)
+	case "grep":
+		return runGrep(rest, opts, stdout)
+	case "stats":
+		return runStats(rest, opts, stdout)
+	case "show":
+		return runShow(rest, opts, stdout)
+	case "help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q (run fiberlog help)", command)
+	}
+}
+
+// registerCommon đăng ký --file và --json (cho phép đặt trước hoặc sau command)
+func registerCommon(fs *flag.FlagSet, opts *options) {
+	if opts.file == "" {
+		opts.file = "logs/errors.log"
+	}
+	fs.StringVar(&opts.file, "file", opts.file, "file log hiện tại")
+	fs.BoolVar(&opts.json, "json", opts.json, "output dạng JSON")
+}
+
+// filterFlags giữ giá trị raw của các filter flags trước khi parse
+type filterFlags struct {
+	types     string
+	status    string
+	location  string
+	requestID string
+	since     string
+	until     string
+	message   string
+}
+
+// registerFilters đăng ký các filter flags dùng chung cho tail, grep, stats
+func registerFilters(fs *flag.FlagSet) *filterFlags {
+	f := &filterFlags{}
+	fs.StringVar(&f.types, "type", "", "loại lỗi, phân cách bằng dấu phẩy (BUSINESS,PANIC,...)")
+	fs.StringVar(&f.status, "status", "", "status code hoặc nhóm, phân cách bằng dấu phẩy (404,5xx)")
+	fs.StringVar(&f.location, "location", "", "chuỗi con của location (CheckStock, product_service.go)")
+	fs.StringVar(&f.requestID, "request-id", "", "request ID")
+	fs.StringVar(&f.since, "since", "", "từ thời điểm (RFC3339, YYYY-MM-DD[ HH:MM], hoặc 2h, 7d)")
+	fs.StringVar(&f.until, "until", "", "đến thời điểm (cùng format với --since)")
+	fs.StringVar(&f.message, "message", "", "regex áp dụng lên message")
+	return f
+}
+
+// build chuyển filter flags thành logquery.Filter
+func (f *filterFlags) build() (*logquery.Filter, error) {
+	now := time.Now()
+	filter := &logquery.Filter{
+		Types:     splitList(f.types),
+		Status:    splitList(f.status),
+		Location:  f.location,
+		RequestID: f.requestID,
+	}
+
+	var err error
+	if filter.Since, err = logquery.ParseTime(f.since, now); err != nil {
+		return nil, fmt.Errorf("--since: %w", err)
+	}
+	if filter.Until, err = logquery.ParseTime(f.until, now); err != nil {
+		return nil, fmt.Errorf("--until: %w", err)
+	}
+	if f.message != "" {
+		if filter.Message, err = regexp.Compile(f.message); err != nil {
+			return nil, fmt.Errorf("--message: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// splitList tách danh sách phân cách bằng dấu phẩy, bỏ phần tử rỗng
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
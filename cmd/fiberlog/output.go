@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"fiber_log/logging"
+	"fiber_log/logquery"
+)
+
+// printEntry in một entry trên một dòng (hoặc một dòng JSON với --json)
+//
+// Ví dụ:
+//
+//	2025-11-11 10:30:45  BUSINESS  400  services/product_service.go:CheckStock:57  GET /product/123/check-stock  Sản phẩm 'iPhone 15' đã hết hàng  req=3f1c...
+func printEntry(w io.Writer, e *logging.Entry, asJSON bool) error {
+	if asJSON {
+		line, err := logging.JSONEncoder{}.Encode(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", line)
+		return err
+	}
+
+	parts := []string{e.Time.Local().Format("2006-01-02 15:04:05")}
+
+	if errorType := e.ErrorType(); errorType != "" {
+		parts = append(parts, fmt.Sprintf("%-10s", errorType))
+	} else {
+		parts = append(parts, fmt.Sprintf("%-10s", strings.ToUpper(e.Level.String())))
+	}
+	if status := e.StatusCode(); status != 0 {
+		parts = append(parts, fmt.Sprint(status))
+	}
+	if location := e.Location(); location != "" {
+		parts = append(parts, location)
+	}
+	if ctx := e.HTTPContext(); ctx != nil {
+		parts = append(parts, strings.TrimSpace(fmt.Sprintf("%v %v", valueOr(ctx["method"]), valueOr(ctx["path"]))))
+	}
+	if job := e.JobContext(); job != nil {
+		parts = append(parts, fmt.Sprintf("job=%v#%v", valueOr(job["name"]), valueOr(job["attempt"])))
+	}
+	parts = append(parts, e.Message)
+	if requestID := e.RequestID(); requestID != "" {
+		parts = append(parts, "req="+requestID)
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(parts, "  "))
+	return err
+}
+
+// printDetail in toàn bộ thông tin của entry, gồm data và call chain
+func printDetail(w io.Writer, e *logging.Entry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	row := func(label string, value interface{}) {
+		if s := fmt.Sprint(value); s != "" && s != "0" {
+			fmt.Fprintf(tw, "%s:\t%s\n", label, s)
+		}
+	}
+
+	row("Request ID", e.RequestID())
+	row("Time", e.Time.Local().Format("2006-01-02 15:04:05 -07:00"))
+	row("Level", e.Level.String())
+	row("Type", e.ErrorType())
+	row("Status", e.StatusCode())
+	row("Message", e.Message)
+	row("Location", e.Location())
+
+	if ctx := e.HTTPContext(); ctx != nil {
+		request := strings.TrimSpace(fmt.Sprintf("%v %v", valueOr(ctx["method"]), valueOr(ctx["path"])))
+		if ip := valueOr(ctx["ip"]); ip != "" {
+			request += " (ip " + ip + ")"
+		}
+		row("Request", request)
+		row("User-Agent", valueOr(ctx["user_agent"]))
+	}
+	if job := e.JobContext(); job != nil {
+		row("Job", fmt.Sprintf("%v (attempt %v)", valueOr(job["name"]), valueOr(job["attempt"])))
+		row("Payload", valueOr(job["payload_id"]))
+	}
+
+	row("Cause", e.Cause())
+	if panicValue, ok := e.Fields["panic_value"]; ok {
+		row("Panic value", panicValue)
+	}
+	tw.Flush()
+
+	if data := e.Data(); len(data) > 0 {
+		fmt.Fprintln(w, "\nData:")
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %s: %v\n", k, data[k])
+		}
+	}
+
+	if children := e.Children(); len(children) > 0 {
+		fmt.Fprintln(w, "\nChildren:")
+		for i, child := range children {
+			fmt.Fprintf(w, "  %d. [%s %d] %s\n", i+1, child.ErrorType(), child.StatusCode(), child.Message)
+			if location := child.Location(); location != "" {
+				fmt.Fprintf(w, "     at %s\n", location)
+			}
+		}
+	}
+
+	if chain := e.CallChain(); len(chain) > 0 {
+		fmt.Fprintln(w, "\nCall chain:")
+		for i, frame := range chain {
+			fmt.Fprintf(w, "  %d. %s\n", i+1, frame)
+		}
+	}
+}
+
+// printStats in thống kê dạng bảng
+func printStats(w io.Writer, stats *logquery.Stats) {
+	fmt.Fprintf(w, "Total errors: %d\n", stats.Total)
+	if stats.Total == 0 {
+		return
+	}
+
+	types := make([]string, 0, len(stats.ByType))
+	for t := range stats.ByType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	fmt.Fprintln(w, "\nBy type:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, t := range types {
+		fmt.Fprintf(tw, "  %s\t%d\n", t, stats.ByType[t])
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nTop locations:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, l := range stats.TopLocations {
+		fmt.Fprintf(tw, "  %d\t%s\n", l.Count, l.Location)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nPer hour:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  HOUR\t%s\tTOTAL\n", strings.Join(types, "\t"))
+	for _, bucket := range stats.PerHour {
+		counts := make([]string, 0, len(types))
+		for _, t := range types {
+			counts = append(counts, fmt.Sprint(bucket.Counts[t]))
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d\n", bucket.Hour.Format("2006-01-02 15:00"), strings.Join(counts, "\t"), bucket.Total)
+	}
+	tw.Flush()
+}
+
+// entryDoc trả về entry dạng JSON (cùng format với file log) để nhúng vào output --json
+func entryDoc(e *logging.Entry) json.RawMessage {
+	raw, err := logging.JSONEncoder{}.Encode(e)
+	if err != nil {
+		return json.RawMessage(`null`)
+	}
+	return raw
+}
+
+// writeJSON ghi value dạng JSON có indent
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(value)
+}
+
+// valueOr trả về chuỗi rỗng cho nil thay vì "<nil>"
+func valueOr(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
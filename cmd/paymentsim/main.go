@@ -0,0 +1,211 @@
+// Command paymentsim giả lập payment gateway gửi webhook tới POST /webhooks/payment của fiber_log
+//
+// Usage:
+//
+//	paymentsim [--url URL] [--secret SECRET] send [--type payment.succeeded|payment.failed] --order ID --amount N [--currency USD] [--save PATH]
+//	paymentsim [--url URL] [--secret SECRET] replay [--skew 10m] [--bad-signature] <file>
+//
+//	paymentsim send --order ORD-USER001-456 --amount 4999.98 --save evt.json
+//	paymentsim replay evt.json               # Cùng event ID: duplicate=true
+//	paymentsim replay --skew 10m evt.json    # Timestamp ngoài tolerance: 401
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"fiber_log/services"
+)
+
+const usage = `paymentsim - gửi webhook payment gateway (đã ký HMAC) tới fiber_log
+
+Usage:
+  paymentsim [--url URL] [--secret SECRET] <command> [options]
+
+Commands:
+  send    Tạo event mới và gửi: send [--type TYPE] --order ID --amount N [--currency USD] [--reason TEXT] [--id ID] [--save PATH] [signing options]
+  replay  Ký lại và gửi event đã lưu (kiểm tra dedup theo event ID): replay [signing options] <file>
+
+Signing options:
+  --skew DURATION    Lệch timestamp của chữ ký (ví dụ -10m) để thử tolerance
+  --bad-signature    Ký bằng secret sai để thử lỗi 401
+
+Global options:
+  --url URL          Webhook endpoint (mặc định $PAYMENT_WEBHOOK_URL hoặc http://localhost:8081/webhooks/payment)
+  --secret SECRET    Secret dùng chung (mặc định $PAYMENT_WEBHOOK_SECRET hoặc whsec_dev)
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "paymentsim: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parse global options và chuyển tới command tương ứng
+func run(args []string, stdout io.Writer) error {
+	url := envOr("PAYMENT_WEBHOOK_URL", "http://localhost:8081/webhooks/payment")
+	secret := envOr("PAYMENT_WEBHOOK_SECRET", "whsec_dev")
+
+	global := flag.NewFlagSet("paymentsim", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(global.Output(), usage) }
+	global.StringVar(&url, "url", url, "webhook endpoint")
+	global.StringVar(&secret, "secret", secret, "webhook secret")
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+
+	if global.NArg() == 0 {
+		global.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	command, rest := global.Arg(0), global.Args()[1:]
+	switch command {
+	case "send":
+		return runSend(rest, url, secret, stdout)
+	case "replay":
+		return runReplay(rest, url, secret, stdout)
+	case "help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q (run paymentsim help)", command)
+	}
+}
+
+// signing là các option làm sai chữ ký để thử các nhánh lỗi của webhook
+type signing struct {
+	skew         time.Duration
+	badSignature bool
+}
+
+func (o *signing) register(fs *flag.FlagSet) {
+	fs.DurationVar(&o.skew, "skew", 0, "lệch timestamp của chữ ký")
+	fs.BoolVar(&o.badSignature, "bad-signature", false, "ký bằng secret sai")
+}
+
+// runSend tạo event mới, lưu payload ra --save (nếu có) để replay rồi gửi tới webhook endpoint
+func runSend(args []string, url, secret string, stdout io.Writer) error {
+	var (
+		event            services.WebhookEvent
+		amount, currency string
+		save             string
+		opts             signing
+	)
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	fs.StringVar(&event.ID, "id", "", "event ID (mặc định ngẫu nhiên)")
+	fs.StringVar(&event.Type, "type", services.WebhookPaymentSucceeded, "payment.succeeded hoặc payment.failed")
+	fs.StringVar(&event.OrderID, "order", "", "order ID")
+	fs.StringVar(&event.Reason, "reason", "", "lý do thất bại (payment.failed)")
+	fs.StringVar(&amount, "amount", "", "số tiền, ví dụ 100 hoặc 19.99")
+	fs.StringVar(&currency, "currency", string(services.USD), "tiền tệ")
+	fs.StringVar(&save, "save", "", "lưu payload ra file để replay")
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if event.OrderID == "" {
+		return fmt.Errorf("send: missing --order")
+	}
+
+	if amount != "" {
+		money, err := services.ParseMoney(amount, services.Currency(currency))
+		if err != nil {
+			return fmt.Errorf("send: %w", err)
+		}
+		event.Amount = money
+	}
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	event.Created = time.Now().UTC()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if save != "" {
+		if err := os.WriteFile(save, payload, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "saved %s to %s\n", event.ID, save)
+	}
+	return deliver(url, secret, payload, opts, stdout)
+}
+
+// runReplay gửi lại payload đã lưu với chữ ký mới, giống gateway retry cùng event
+func runReplay(args []string, url, secret string, stdout io.Writer) error {
+	var opts signing
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("replay: expected 1 file, got %d", fs.NArg())
+	}
+
+	payload, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return deliver(url, secret, payload, opts, stdout)
+}
+
+// deliver ký payload và POST tới url, in status và response body
+// Trả về error nếu server không trả 2xx để script có thể kiểm tra exit code
+func deliver(url, secret string, payload []byte, opts signing, stdout io.Writer) error {
+	if opts.badSignature {
+		secret += "-wrong"
+	}
+	signature := services.SignWebhook(secret, time.Now().Add(opts.skew), payload)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(services.WebhookSignatureHeader, signature)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%s: %s\n", services.WebhookSignatureHeader, signature)
+	fmt.Fprintf(stdout, "%s\n%s\n", resp.Status, bytes.TrimSpace(body))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook rejected: %s", resp.Status)
+	}
+	return nil
+}
+
+// newEventID tạo event ID ngẫu nhiên dạng evt_<hex>
+func newEventID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
+
+// envOr đọc biến môi trường, trả về fallback nếu rỗng
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
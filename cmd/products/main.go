@@ -0,0 +1,178 @@
+// Command products quản lý file sản phẩm (PRODUCTS_FILE) mà ứng dụng fiber_log đọc khi khởi động
+//
+// Usage:
+//
+//	products [--file data/products.json] import [--format csv|json] [--strict] [--report report.csv] <file>
+//
+//	products import products.csv
+//	products import --strict --report import-report.csv products.csv
+//	products --file /tmp/products.json import --format json products.json
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"fiber_log/services"
+)
+
+const usage = `products - import sản phẩm vào file sản phẩm của fiber_log
+
+Usage:
+  products [--file PATH] <command> [options]
+
+Commands:
+  import  Import sản phẩm từ CSV/JSON, báo cáo lỗi từng dòng: import [--format csv|json] [--strict] [--report PATH] <file>
+
+Global options:
+  --file PATH   File sản phẩm (mặc định $PRODUCTS_FILE hoặc data/products.json)
+                Nếu file chưa tồn tại, import vào 3 sản phẩm mẫu rồi ghi ra file
+
+Chạy ứng dụng với PRODUCTS_FILE=<PATH> để dùng sản phẩm đã import.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "products: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parse global options và chuyển tới command tương ứng
+func run(args []string, stdout io.Writer) error {
+	file := os.Getenv("PRODUCTS_FILE")
+	if file == "" {
+		file = "data/products.json"
+	}
+
+	global := flag.NewFlagSet("products", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(global.Output(), usage) }
+	global.StringVar(&file, "file", file, "file sản phẩm")
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+
+	if global.NArg() == 0 {
+		global.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	command, rest := global.Arg(0), global.Args()[1:]
+	switch command {
+	case "import":
+		return runImport(rest, file, stdout)
+	case "help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q (run products help)", command)
+	}
+}
+
+// runImport import file vào file sản phẩm, in báo cáo và ghi báo cáo CSV nếu có --report
+// Trả về error nếu có dòng lỗi để script có thể kiểm tra exit code
+func runImport(args []string, productsFile string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "csv hoặc json (mặc định theo phần mở rộng của file)")
+	strict := fs.Bool("strict", false, "không import dòng nào nếu có dòng lỗi")
+	reportPath := fs.String("report", "", "ghi báo cáo lỗi dạng CSV ra file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: products import [--format csv|json] [--strict] [--report PATH] <file>")
+	}
+	input := fs.Arg(0)
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(input)), ".")
+	}
+
+	productService, err := loadProductService(productsFile)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	report, err := productService.ImportProducts(f, *format, *strict)
+	if err != nil {
+		return err
+	}
+
+	if report.Committed && report.Imported > 0 {
+		if err := services.SaveProducts(productsFile, productService.Products()); err != nil {
+			return err
+		}
+	}
+
+	if *reportPath != "" {
+		if err := writeReport(*reportPath, report); err != nil {
+			return err
+		}
+	}
+
+	printReport(stdout, report, productsFile)
+	if report.Failed > 0 {
+		return fmt.Errorf("%d/%d dòng lỗi", report.Failed, report.Total)
+	}
+	return nil
+}
+
+// loadProductService đọc file sản phẩm, dùng sản phẩm mẫu nếu file chưa tồn tại
+func loadProductService(path string) (*services.ProductService, error) {
+	products, err := services.LoadProducts(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return services.NewProductService(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return services.NewProductServiceWith(products), nil
+}
+
+// writeReport ghi báo cáo lỗi dạng CSV
+func writeReport(path string, report *services.ImportReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteCSV(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printReport in tổng kết và bảng lỗi
+func printReport(w io.Writer, report *services.ImportReport, productsFile string) {
+	fmt.Fprintf(w, "Rows: %d, imported: %d, failed: %d\n", report.Total, report.Imported, report.Failed)
+	switch {
+	case !report.Committed:
+		fmt.Fprintln(w, "Strict mode: không import dòng nào vì có dòng lỗi")
+	case report.Imported > 0:
+		fmt.Fprintf(w, "Saved: %s\n", productsFile)
+	}
+
+	if len(report.Errors) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nErrors:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ROW\tCOLUMN\tVALUE\tERROR")
+	for _, rowErr := range report.Errors {
+		fmt.Fprintf(tw, "  %d\t%s\t%q\t%s\n", rowErr.Row, rowErr.Column, rowErr.Value, rowErr.Err.Message)
+	}
+	tw.Flush()
+}
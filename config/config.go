@@ -0,0 +1,190 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config là cấu hình của ứng dụng, đọc từ biến môi trường
+type Config struct {
+	// Addr - Địa chỉ HTTP server lắng nghe (APP_ADDR, mặc định ":8081")
+	Addr string `json:"addr"`
+
+	// Env - Môi trường chạy: development hoặc production (APP_ENV, mặc định "production")
+	// Phải set APP_ENV=development để mở /dev/*, admin không cần token và webhook secret mặc định
+	Env string `json:"env"`
+
+	// LogDir - Thư mục chứa errors.log, validation.log, alerts/ (LOG_DIR, mặc định "logs")
+	LogDir string `json:"log_dir"`
+
+	// AccessLog - Ghi access log của mọi request ra stdout (ACCESS_LOG, mặc định true)
+	AccessLog bool `json:"access_log"`
+
+	// AdminToken - Token cho các admin endpoints (ADMIN_TOKEN)
+	// Nếu rỗng, admin endpoints chỉ mở khi development, môi trường khác trả về 503
+	AdminToken string `json:"admin_token"`
+
+	// LogHTTPURL - Endpoint Loki/Elasticsearch cho HTTP sink (LOG_HTTP_URL)
+	LogHTTPURL string `json:"log_http_url"`
+
+	// LogHTTPFormat - loki hoặc elasticsearch (LOG_HTTP_FORMAT)
+	LogHTTPFormat string `json:"log_http_format"`
+
+	// LogSyslogSocket - Unix socket của syslog daemon (LOG_SYSLOG_SOCKET)
+	LogSyslogSocket string `json:"log_syslog_socket"`
+
+	// CrashBundles - Ghi crash bundle khi recover panic (CRASH_BUNDLES, mặc định true)
+	CrashBundles bool `json:"crash_bundles"`
+
+	// CrashDir - Thư mục chứa crash bundles (CRASH_DIR, mặc định "<LogDir>/crashes")
+	CrashDir string `json:"crash_dir"`
+
+	// CrashMaxBundles - Số crash bundles tối đa được giữ lại (CRASH_MAX_BUNDLES, mặc định 20)
+	CrashMaxBundles int `json:"crash_max_bundles"`
+
+	// CrashMaxBodyBytes - Số bytes tối đa của request body lưu trong bundle (CRASH_MAX_BODY_BYTES, mặc định 64KB)
+	CrashMaxBodyBytes int `json:"crash_max_body_bytes"`
+
+	// SourceRoot - Thư mục gốc chứa source code cho trang /dev/errors (SOURCE_ROOT, mặc định ".")
+	SourceRoot string `json:"source_root"`
+
+	// ProductsFile - File JSON chứa danh sách sản phẩm, ghi bởi "products import" (PRODUCTS_FILE)
+	// Nếu rỗng hoặc file chưa tồn tại, dùng 3 sản phẩm mẫu
+	// App chỉ đọc file khi khởi động: CRUD, import qua HTTP và tồn kho thay đổi do đơn hàng không được ghi lại
+	// (đơn hàng chỉ nằm trong bộ nhớ, ghi tồn kho đã trừ ra file sẽ làm mất hàng sau khi restart)
+	ProductsFile string `json:"products_file"`
+
+	// UsersFile - File JSON chứa user đã đăng ký kèm password hash (USERS_FILE)
+	// Nếu rỗng, user chỉ được lưu trong bộ nhớ; nếu file chưa tồn tại, dùng user mẫu và ghi ra file khi có thay đổi
+	UsersFile string `json:"users_file"`
+
+	// EventLogFile - File NDJSON của event log domain (EVENT_LOG_FILE), chỉ được ghi thêm
+	// Nếu rỗng, event chỉ được lưu trong bộ nhớ; nếu file đã có, event cũ được đọc lại khi khởi động
+	EventLogFile string `json:"event_log_file"`
+
+	// ReservationTTL - Thời gian giữ hàng khi reserve / tạo đơn hàng (RESERVATION_TTL, mặc định 15m)
+	ReservationTTL time.Duration `json:"reservation_ttl"`
+
+	// ReservationSweepInterval - Chu kỳ chạy job hoàn trả hàng giữ quá hạn (RESERVATION_SWEEP_INTERVAL, mặc định 30s)
+	ReservationSweepInterval time.Duration `json:"reservation_sweep_interval"`
+
+	// RefundWindow - Thời hạn hoàn tiền tính từ lúc thanh toán đơn hàng (REFUND_WINDOW, mặc định 720h)
+	RefundWindow time.Duration `json:"refund_window"`
+
+	// PaymentWebhookSecret - Secret dùng chung với payment gateway để ký webhook (PAYMENT_WEBHOOK_SECRET)
+	// Khi development mặc định "whsec_dev" khớp với cmd/paymentsim; môi trường khác không có mặc định,
+	// nếu rỗng thì POST /webhooks/payment bị tắt để không ai ký được webhook bằng secret công khai
+	PaymentWebhookSecret string `json:"payment_webhook_secret"`
+
+	// PaymentWebhookTolerance - Độ lệch tối đa giữa timestamp trong chữ ký webhook và lúc nhận (PAYMENT_WEBHOOK_TOLERANCE, mặc định 5m)
+	PaymentWebhookTolerance time.Duration `json:"payment_webhook_tolerance"`
+
+	// ShippingSimFailures - Lỗi giả lập của shipping simulator (SHIPPING_SIM_FAILURES), dạng "operation=mode[:times],..."
+	// Operation: quote, create_label; mode: timeout, unavailable, rate_limited, rejected. Ví dụ "create_label=timeout:2"
+	ShippingSimFailures string `json:"shipping_sim_failures"`
+
+	// NotificationSimFailures - Lỗi giả lập của notification simulator (NOTIFICATION_SIM_FAILURES), cùng dạng
+	// với ShippingSimFailures, operation: send_email, send_sms. Ví dụ "send_sms=rejected"
+	NotificationSimFailures string `json:"notification_sim_failures"`
+}
+
+// devWebhookSecret là secret ký webhook mặc định khi development (giống mặc định của cmd/paymentsim)
+const devWebhookSecret = "whsec_dev"
+
+// Load đọc cấu hình từ biến môi trường, dùng giá trị mặc định cho biến không được set
+func Load() Config {
+	cfg := Config{
+		Addr:              getEnv("APP_ADDR", ":8081"),
+		Env:               getEnv("APP_ENV", "production"),
+		LogDir:            getEnv("LOG_DIR", "logs"),
+		AccessLog:         getEnvBool("ACCESS_LOG", true),
+		AdminToken:        os.Getenv("ADMIN_TOKEN"),
+		LogHTTPURL:        os.Getenv("LOG_HTTP_URL"),
+		LogHTTPFormat:     os.Getenv("LOG_HTTP_FORMAT"),
+		LogSyslogSocket:   os.Getenv("LOG_SYSLOG_SOCKET"),
+		CrashBundles:      getEnvBool("CRASH_BUNDLES", true),
+		CrashDir:          os.Getenv("CRASH_DIR"),
+		CrashMaxBundles:   getEnvInt("CRASH_MAX_BUNDLES", 20),
+		CrashMaxBodyBytes: getEnvInt("CRASH_MAX_BODY_BYTES", 64*1024),
+		SourceRoot:        getEnv("SOURCE_ROOT", "."),
+		ProductsFile:      os.Getenv("PRODUCTS_FILE"),
+		UsersFile:         os.Getenv("USERS_FILE"),
+		EventLogFile:      os.Getenv("EVENT_LOG_FILE"),
+
+		ReservationTTL:           getEnvDuration("RESERVATION_TTL", 15*time.Minute),
+		ReservationSweepInterval: getEnvDuration("RESERVATION_SWEEP_INTERVAL", 30*time.Second),
+		RefundWindow:             getEnvDuration("REFUND_WINDOW", 30*24*time.Hour),
+
+		PaymentWebhookSecret:    os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+		PaymentWebhookTolerance: getEnvDuration("PAYMENT_WEBHOOK_TOLERANCE", 5*time.Minute),
+
+		ShippingSimFailures:     os.Getenv("SHIPPING_SIM_FAILURES"),
+		NotificationSimFailures: os.Getenv("NOTIFICATION_SIM_FAILURES"),
+	}
+	if cfg.CrashDir == "" {
+		cfg.CrashDir = filepath.Join(cfg.LogDir, "crashes")
+	}
+	if cfg.PaymentWebhookSecret == "" && cfg.IsDevelopment() {
+		cfg.PaymentWebhookSecret = devWebhookSecret
+	}
+	return cfg
+}
+
+// IsDevelopment cho biết ứng dụng đang chạy ở môi trường development
+func (c Config) IsDevelopment() bool {
+	return strings.EqualFold(c.Env, "development") || strings.EqualFold(c.Env, "dev")
+}
+
+// Redacted trả về bản sao cấu hình đã che các giá trị bí mật (dùng khi ghi ra file/log)
+func (c Config) Redacted() Config {
+	if c.AdminToken != "" {
+		c.AdminToken = "[REDACTED]"
+	}
+	if c.PaymentWebhookSecret != "" {
+		c.PaymentWebhookSecret = "[REDACTED]"
+	}
+	if u, err := url.Parse(c.LogHTTPURL); err == nil && u.User != nil {
+		u.User = url.User("[REDACTED]")
+		c.LogHTTPURL = u.String()
+	}
+	return c
+}
+
+// getEnv đọc biến môi trường, trả về fallback nếu không được set
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// getEnvInt đọc biến môi trường dạng số nguyên
+func getEnvInt(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return fallback
+}
+
+// getEnvDuration đọc biến môi trường dạng time.Duration ("90s", "15m"), giá trị <= 0 dùng fallback
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// getEnvBool đọc biến môi trường dạng bool (true/false, 1/0, on/off)
+func getEnvBool(key string, fallback bool) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "1", "true", "on", "yes":
+		return true
+	case "0", "false", "off", "no":
+		return false
+	}
+	return fallback
+}
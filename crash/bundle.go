@@ -0,0 +1,461 @@
+package crash
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fiber_log/errhandler"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// Các file trong một crash bundle
+const (
+	manifestFile   = "manifest.json"
+	goroutinesFile = "goroutines.txt"
+	requestFile    = "request.json"
+	configFile     = "config.json"
+	buildFile      = "build.json"
+	errorFile      = "error.json"
+)
+
+// redactedValue thay thế giá trị của header, trường body nhạy cảm
+const redactedValue = "[REDACTED]"
+
+// defaultRedactHeaders là các headers luôn bị che trong bundle
+var defaultRedactHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"Proxy-Authorization",
+	"X-Admin-Token",
+	"X-Api-Key",
+}
+
+// defaultRedactBodyFields là các trường body (JSON, form) luôn bị che trong bundle
+// Khớp khi tên trường chứa một trong các chuỗi này, không phân biệt hoa thường
+// ("new_password", "access_token", "card_number", ...)
+var defaultRedactBodyFields = []string{
+	"password",
+	"token",
+	"secret",
+	"card",
+	"cvv",
+}
+
+// bundleIDPattern chặn path traversal khi đọc bundle theo ID từ URL
+var bundleIDPattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// Options cấu hình cho crash bundle store
+type Options struct {
+	// Dir - Thư mục chứa bundles (mỗi bundle là một thư mục con)
+	Dir string
+
+	// MaxBundles - Số bundles tối đa được giữ lại, bundle cũ nhất bị xóa trước (0 = không giới hạn)
+	MaxBundles int
+
+	// MaxBodyBytes - Số bytes tối đa của request body được lưu (mặc định 64KB)
+	MaxBodyBytes int
+
+	// RedactHeaders - Headers cần che thêm ngoài danh sách mặc định (Authorization, Cookie, ...)
+	RedactHeaders []string
+
+	// RedactBodyFields - Trường body JSON/form cần che thêm ngoài danh sách mặc định (password, token, ...)
+	// Khớp khi tên trường chứa chuỗi này, không phân biệt hoa thường
+	RedactBodyFields []string
+
+	// AppConfig - Cấu hình ứng dụng được ghi vào config.json (nên truyền bản đã redact)
+	AppConfig interface{}
+}
+
+// Manifest là thông tin tóm tắt của một bundle, dùng khi liệt kê
+type Manifest struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Message   string    `json:"message"`
+	Location  string    `json:"location"`
+	Files     []string  `json:"files"`
+}
+
+// Request là thông tin HTTP request được lưu trong bundle
+type Request struct {
+	Method        string              `json:"method"`
+	URL           string              `json:"url"`
+	Path          string              `json:"path"`
+	IP            string              `json:"ip"`
+	Headers       map[string][]string `json:"headers"`
+	Body          string              `json:"body"`
+	BodySize      int                 `json:"body_size"`
+	BodyTruncated bool                `json:"body_truncated"`
+}
+
+// BuildInfo là thông tin build từ debug.ReadBuildInfo
+type BuildInfo struct {
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path"`
+	Main      string            `json:"main"`
+	Settings  map[string]string `json:"settings"`
+	Deps      []string          `json:"deps"`
+}
+
+// Store ghi và quản lý crash bundles trong một thư mục
+type Store struct {
+	mu         sync.Mutex
+	opts       Options
+	redact     map[string]bool
+	bodyFields []string
+	now        func() time.Time
+}
+
+// NewStore tạo crash bundle store
+func NewStore(opts Options) *Store {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 64 * 1024
+	}
+
+	redact := make(map[string]bool)
+	for _, h := range append(defaultRedactHeaders, opts.RedactHeaders...) {
+		redact[strings.ToLower(h)] = true
+	}
+
+	var bodyFields []string
+	for _, field := range append(defaultRedactBodyFields, opts.RedactBodyFields...) {
+		bodyFields = append(bodyFields, strings.ToLower(field))
+	}
+
+	return &Store{opts: opts, redact: redact, bodyFields: bodyFields, now: time.Now}
+}
+
+// Capture ghi crash bundle cho panic vừa được recover và trả về ID của bundle
+// Dùng làm errhandler.Config.OnPanic:
+//
+//	errhandler.New(errhandler.Config{
+//	    OnPanic: func(c *fiber.Ctx, panicErr *goerrorkit.AppError, goroutines []byte) {
+//	        store.Capture(c, panicErr, goroutines)
+//	    },
+//	})
+func (s *Store) Capture(c *fiber.Ctx, panicErr *goerrorkit.AppError, goroutines []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	createdAt := s.now()
+	id := bundleID(createdAt, panicErr.RequestID)
+	dir := filepath.Join(s.opts.Dir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("create crash bundle directory: %w", err)
+	}
+
+	errorEntry := errhandler.Fields(panicErr, c)
+	errorEntry["message"] = panicErr.Message
+	location, _ := errorEntry["location"].(string)
+
+	manifest := Manifest{
+		ID:        id,
+		CreatedAt: createdAt,
+		RequestID: panicErr.RequestID,
+		Method:    c.Method(),
+		Path:      c.Path(),
+		Message:   panicErr.Message,
+		Location:  location,
+		Files:     []string{manifestFile, errorFile, goroutinesFile, requestFile, configFile, buildFile},
+	}
+
+	files := map[string]interface{}{
+		manifestFile: manifest,
+		errorFile:    errorEntry,
+		requestFile:  s.request(c),
+		configFile:   s.opts.AppConfig,
+		buildFile:    buildInfo(),
+	}
+	for name, value := range files {
+		if err := writeJSON(filepath.Join(dir, name), value); err != nil {
+			return "", err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, goroutinesFile), goroutines, 0644); err != nil {
+		return "", fmt.Errorf("write goroutine dump: %w", err)
+	}
+
+	if err := s.enforceRetention(); err != nil {
+		return id, err
+	}
+	return id, nil
+}
+
+// List trả về manifests của các bundles, mới nhất trước
+func (s *Store) List() ([]Manifest, error) {
+	entries, err := os.ReadDir(s.opts.Dir)
+	if os.IsNotExist(err) {
+		return []Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read crash directory: %w", err)
+	}
+
+	manifests := []Manifest{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		manifest, err := s.manifest(entry.Name())
+		if err != nil {
+			continue // Bundle đang được ghi hoặc bị hỏng
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.After(manifests[j].CreatedAt)
+	})
+	return manifests, nil
+}
+
+// Exists kiểm tra bundle có tồn tại không
+func (s *Store) Exists(id string) bool {
+	if !bundleIDPattern.MatchString(id) {
+		return false
+	}
+	_, err := s.manifest(id)
+	return err == nil
+}
+
+// WriteZip nén toàn bộ bundle thành file zip và ghi vào w
+func (s *Store) WriteZip(id string, w io.Writer) error {
+	if !s.Exists(id) {
+		return fmt.Errorf("crash bundle %q not found", id)
+	}
+
+	dir := filepath.Join(s.opts.Dir, id)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read crash bundle: %w", err)
+	}
+
+	archive := zip.NewWriter(w)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := addZipFile(archive, filepath.Join(dir, entry.Name()), id+"/"+entry.Name()); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// manifest đọc manifest.json của bundle
+func (s *Store) manifest(id string) (Manifest, error) {
+	var manifest Manifest
+	data, err := os.ReadFile(filepath.Join(s.opts.Dir, id, manifestFile))
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(data, &manifest)
+	return manifest, err
+}
+
+// request tạo bản ghi request đã redact headers, body và giới hạn body
+// Body được redact trước khi cắt để trường nhạy cảm không lọt qua phần bị cắt dở
+func (s *Store) request(c *fiber.Ctx) Request {
+	headers := make(map[string][]string)
+	for name, values := range c.GetReqHeaders() {
+		if s.redact[strings.ToLower(name)] {
+			headers[name] = []string{redactedValue}
+			continue
+		}
+		headers[name] = values
+	}
+
+	body := s.redactBody(string(c.Request().Header.ContentType()), c.Body())
+	req := Request{
+		Method:   c.Method(),
+		URL:      c.OriginalURL(),
+		Path:     c.Path(),
+		IP:       c.IP(),
+		Headers:  headers,
+		BodySize: len(c.Body()),
+	}
+	if len(body) > s.opts.MaxBodyBytes {
+		body = body[:s.opts.MaxBodyBytes]
+		req.BodyTruncated = true
+	}
+	req.Body = string(body)
+	return req
+}
+
+// redactBody che giá trị các trường nhạy cảm trong body JSON hoặc form (x-www-form-urlencoded)
+// Body JSON không đọc được mà có nhắc tới tên trường nhạy cảm bị che toàn bộ;
+// các loại body khác được giữ nguyên
+func (s *Store) redactBody(contentType string, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	contentType = strings.ToLower(contentType)
+
+	switch {
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationForm):
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			break
+		}
+		for name := range values {
+			if s.sensitiveField(name) {
+				values[name] = []string{redactedValue}
+			}
+		}
+		return []byte(values.Encode())
+
+	case strings.Contains(contentType, "json"):
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			break
+		}
+		data, err := json.Marshal(s.redactJSON(value))
+		if err != nil {
+			break
+		}
+		return data
+
+	default:
+		return body
+	}
+
+	if s.sensitiveField(string(body)) {
+		return []byte(redactedValue)
+	}
+	return body
+}
+
+// redactJSON thay giá trị của các key nhạy cảm (ở mọi cấp) bằng redactedValue
+func (s *Store) redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if s.sensitiveField(key) {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = s.redactJSON(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = s.redactJSON(item)
+		}
+	}
+	return value
+}
+
+// sensitiveField cho biết name có chứa tên trường cần che
+func (s *Store) sensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range s.bodyFields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceRetention xóa các bundles cũ nhất khi vượt quá MaxBundles
+func (s *Store) enforceRetention() error {
+	if s.opts.MaxBundles <= 0 {
+		return nil
+	}
+
+	manifests, err := s.List()
+	if err != nil {
+		return err
+	}
+	for _, m := range manifests[min(len(manifests), s.opts.MaxBundles):] {
+		if err := os.RemoveAll(filepath.Join(s.opts.Dir, m.ID)); err != nil {
+			return fmt.Errorf("remove expired crash bundle %s: %w", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// bundleID tạo ID dạng 20251111-103045-123-<8 ký tự đầu request ID>, an toàn để dùng làm tên thư mục
+func bundleID(t time.Time, requestID string) string {
+	id := t.Format("20060102-150405") + "-" + fmt.Sprintf("%03d", t.Nanosecond()/int(time.Millisecond))
+	suffix := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, requestID)
+	if len(suffix) > 8 {
+		suffix = suffix[:8]
+	}
+	if suffix != "" {
+		id += "-" + suffix
+	}
+	return id
+}
+
+// buildInfo đọc thông tin build của binary
+func buildInfo() BuildInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return BuildInfo{}
+	}
+
+	result := BuildInfo{
+		GoVersion: info.GoVersion,
+		Path:      info.Path,
+		Main:      info.Main.Path + "@" + info.Main.Version,
+		Settings:  make(map[string]string),
+	}
+	for _, setting := range info.Settings {
+		result.Settings[setting.Key] = setting.Value
+	}
+	for _, dep := range info.Deps {
+		result.Deps = append(result.Deps, dep.Path+"@"+dep.Version)
+	}
+	return result
+}
+
+// writeJSON ghi value ra file JSON có indent
+func writeJSON(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// addZipFile thêm một file vào archive
+func addZipFile(archive *zip.Writer, path, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
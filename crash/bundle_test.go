@@ -0,0 +1,139 @@
+package crash
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// capture gửi request qua handler gọi Capture, trả về thư mục bundle
+func capture(t *testing.T, store *Store, contentType, body string) string {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/checkout", func(c *fiber.Ctx) error {
+		appErr := goerrorkit.NewSystemError(nil)
+		appErr.Message = "panic: card declined"
+		appErr.RequestID = "req-1"
+		id, err := store.Capture(c, appErr, []byte("goroutine 1 [running]:\n"))
+		if err != nil {
+			t.Errorf("Capture: %v", err)
+		}
+		return c.SendString(id)
+	})
+
+	req := httptest.NewRequest(fiber.MethodPost, "/checkout", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, contentType)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer abc")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	id := make([]byte, 64)
+	n, _ := resp.Body.Read(id)
+	return filepath.Join(store.opts.Dir, string(id[:n]))
+}
+
+// readJSON đọc file JSON trong bundle vào v
+func readJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+}
+
+func TestCaptureRedactsRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		hidden      []string // Không được xuất hiện trong body đã lưu
+		kept        []string // Phải được giữ lại
+	}{
+		{
+			name:        "json",
+			contentType: fiber.MIMEApplicationJSON,
+			body:        `{"email":"lan@example.com","Password":"secret123","payment":{"card_number":"4111111111111111","amount":4999.98},"items":[{"api_token":"t-1"}]}`,
+			hidden:      []string{"secret123", "4111111111111111", "t-1"},
+			kept:        []string{"lan@example.com", "4999.98"},
+		},
+		{
+			name:        "form",
+			contentType: fiber.MIMEApplicationForm,
+			body:        "email=lan%40example.com&new_password=secret123",
+			hidden:      []string{"secret123"},
+			kept:        []string{"lan%40example.com"},
+		},
+		{
+			name:        "malformed json mentioning a sensitive field",
+			contentType: fiber.MIMEApplicationJSON,
+			body:        `{"password":"secret123",`,
+			hidden:      []string{"secret123"},
+		},
+		{
+			name:        "plain text",
+			contentType: fiber.MIMETextPlain,
+			body:        "hello",
+			kept:        []string{"hello"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(Options{Dir: t.TempDir()})
+			dir := capture(t, store, tt.contentType, tt.body)
+
+			var req Request
+			readJSON(t, filepath.Join(dir, requestFile), &req)
+			for _, value := range tt.hidden {
+				if strings.Contains(req.Body, value) {
+					t.Errorf("body chứa %q: %s", value, req.Body)
+				}
+			}
+			for _, value := range tt.kept {
+				if !strings.Contains(req.Body, value) {
+					t.Errorf("body thiếu %q: %s", value, req.Body)
+				}
+			}
+			if len(tt.hidden) > 0 && !strings.Contains(req.Body, redactedValue) && !strings.Contains(req.Body, url.QueryEscape(redactedValue)) {
+				t.Errorf("body không có %s: %s", redactedValue, req.Body)
+			}
+			if req.BodySize != len(tt.body) {
+				t.Errorf("body_size = %d, want %d", req.BodySize, len(tt.body))
+			}
+			if got := req.Headers[fiber.HeaderAuthorization]; len(got) != 1 || got[0] != redactedValue {
+				t.Errorf("Authorization = %v", got)
+			}
+		})
+	}
+
+	// Trường thêm qua Options, body bị cắt sau khi đã redact
+	store := NewStore(Options{Dir: t.TempDir(), RedactBodyFields: []string{"OTP"}, MaxBodyBytes: 20})
+	var req Request
+	readJSON(t, filepath.Join(capture(t, store, fiber.MIMEApplicationJSON, `{"otp":"123456","note":"giao giờ hành chính"}`), requestFile), &req)
+	if strings.Contains(req.Body, "123456") || !req.BodyTruncated || len(req.Body) != 20 {
+		t.Errorf("body = %q, truncated = %v", req.Body, req.BodyTruncated)
+	}
+}
+
+func TestCaptureManifestLocation(t *testing.T) {
+	store := NewStore(Options{Dir: t.TempDir()})
+	var manifest Manifest
+	readJSON(t, filepath.Join(capture(t, store, fiber.MIMETextPlain, ""), manifestFile), &manifest)
+	if strings.Contains(manifest.Location, "<nil>") {
+		t.Errorf("location = %q", manifest.Location)
+	}
+	if manifest.RequestID != "req-1" || manifest.Path != "/checkout" || manifest.Message != "panic: card declined" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"fiber_log/logging"
+	"fiber_log/logquery"
+	"fiber_log/sourceview"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Dev Tools - Error detail với source code context (chỉ bật khi APP_ENV=development)
+// ============================================================================
+
+// sourceContextLines là số dòng hiển thị trước và sau dòng lỗi
+const sourceContextLines = 5
+
+// recentErrorsLimit là số lỗi gần nhất hiển thị trên trang /dev/errors
+const recentErrorsLimit = 50
+
+// errorDetailView là dữ liệu render trang chi tiết lỗi
+type errorDetailView struct {
+	Entry           *logging.Entry
+	RequestID       string
+	ErrorType       string
+	StatusCode      int
+	Location        string
+	HTTP            map[string]interface{}
+	Data            map[string]interface{}
+	Cause           string
+	Causes          []string
+	PanicValue      interface{}
+	SourceAvailable bool
+	SourceRoot      string
+	LocationSource  *sourceview.Snippet
+	CallChain       []sourceview.Snippet
+	SpawnedBy       []sourceview.Snippet
+	Children        []childErrorView
+}
+
+// errorListView là dữ liệu của trang danh sách lỗi
+type errorListView struct {
+	Entries []*logging.Entry
+	Skipped []logquery.Skipped // Entries hỏng trong log, bị bỏ qua khi đọc
+}
+
+// childErrorView là một lỗi con của lỗi gộp (errors.Join) kèm source code tại location
+type childErrorView struct {
+	Entry    *logging.Entry
+	Location string
+	Source   *sourceview.Snippet
+}
+
+// devErrorsHandler - Danh sách lỗi gần nhất, link tới trang chi tiết
+// Test: GET /dev/errors
+func devErrorsHandler(c *fiber.Ctx) error {
+	entries, skipped, err := logquery.ReadAll(errorLogPath())
+	if err != nil {
+		return goerrorkit.WrapWithMessage(err, "Không thể đọc error log").WithData(map[string]interface{}{
+			"log_file": errorLogPath(),
+		})
+	}
+
+	filter := logquery.Filter{ErrorsOnly: true}
+	entries = filter.Apply(entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	if len(entries) > recentErrorsLimit {
+		entries = entries[:recentErrorsLimit]
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return devErrorsTemplate.ExecuteTemplate(c.Response().BodyWriter(), "list", errorListView{Entries: entries, Skipped: skipped})
+}
+
+// devErrorDetailHandler - Chi tiết một lỗi theo request ID, kèm source code của location và từng frame trong call_chain
+// Test: GET /dev/errors/<request_id> (request_id có trong JSON response của mọi lỗi)
+func devErrorDetailHandler(c *fiber.Ctx) error {
+	requestID := c.Params("request_id")
+
+	entries, _, err := logquery.ReadAll(errorLogPath())
+	if err != nil {
+		return goerrorkit.WrapWithMessage(err, "Không thể đọc error log").WithData(map[string]interface{}{
+			"log_file": errorLogPath(),
+		})
+	}
+
+	filter := logquery.Filter{RequestID: requestID, ErrorsOnly: true}
+	matched := filter.Apply(entries)
+	if len(matched) == 0 {
+		return goerrorkit.NewBusinessError(404, fmt.Sprintf("Không tìm thấy lỗi với request_id '%s'", requestID)).WithData(map[string]interface{}{
+			"request_id": requestID,
+			"log_file":   errorLogPath(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return devErrorsTemplate.ExecuteTemplate(c.Response().BodyWriter(), "detail", buildErrorDetail(matched[len(matched)-1]))
+}
+
+// buildErrorDetail resolve location và call_chain của entry thành các đoạn source code
+// Nếu không có source (chạy binary production), trang vẫn hiển thị thông tin lỗi và lý do thiếu source
+func buildErrorDetail(entry *logging.Entry) errorDetailView {
+	view := errorDetailView{
+		Entry:           entry,
+		RequestID:       entry.RequestID(),
+		ErrorType:       string(entry.ErrorType()),
+		StatusCode:      entry.StatusCode(),
+		Location:        entry.Location(),
+		HTTP:            entry.HTTPContext(),
+		Data:            entry.Data(),
+		Cause:           entry.Cause(),
+		Causes:          entry.Causes(),
+		PanicValue:      entry.Fields["panic_value"],
+		SourceAvailable: sourceResolver.Available(),
+		SourceRoot:      appConfig.SourceRoot,
+	}
+
+	if frame, ok := sourceview.ParseFrame(view.Location); ok {
+		snippet := sourceResolver.Snippet(frame, sourceContextLines)
+		view.LocationSource = &snippet
+	}
+
+	view.CallChain = frameSnippets(entry.CallChain())
+
+	// Panic trong goroutine (safego): call chain tại nơi tạo goroutine
+	spawned := logging.Entry{Fields: map[string]interface{}{"call_chain": entry.Fields["spawned_by"]}}
+	view.SpawnedBy = frameSnippets(spawned.CallChain())
+
+	// Lỗi gộp: mỗi lỗi con có type, code, location riêng
+	for _, child := range entry.Children() {
+		childView := childErrorView{Entry: child, Location: child.Location()}
+		if frame, ok := sourceview.ParseFrame(childView.Location); ok {
+			snippet := sourceResolver.Snippet(frame, sourceContextLines)
+			childView.Source = &snippet
+		}
+		view.Children = append(view.Children, childView)
+	}
+
+	return view
+}
+
+// frameSnippets resolve từng frame của call chain thành đoạn source code
+func frameSnippets(chain []string) []sourceview.Snippet {
+	var snippets []sourceview.Snippet
+	for _, raw := range chain {
+		frame, ok := sourceview.ParseFrame(raw)
+		if !ok {
+			snippets = append(snippets, sourceview.Snippet{
+				Frame: frame,
+				Error: "Không nhận dạng được định dạng frame",
+			})
+			continue
+		}
+		snippets = append(snippets, sourceResolver.Snippet(frame, sourceContextLines))
+	}
+	return snippets
+}
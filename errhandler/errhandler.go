@@ -0,0 +1,188 @@
+package errhandler
+
+import (
+	"errors"
+	"runtime"
+
+	"fiber_log/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// Config cấu hình cho error handler middleware
+type Config struct {
+	// RequestIDKey - Key của request ID trong c.Locals (mặc định "requestid", giống middleware requestid)
+	RequestIDKey string
+
+	// OnPanic - Callback sau khi recover panic, trước khi gửi response
+	// goroutines là goroutine dump của toàn bộ process tại thời điểm panic
+	OnPanic func(c *fiber.Ctx, panicErr *goerrorkit.AppError, goroutines []byte)
+}
+
+// New tạo Fiber middleware xử lý panic và errors, thay cho goerrorkit.FiberErrorHandler()
+//
+// Vẫn dùng core logic của goerrorkit (HandlePanic, ConvertToAppError) để capture chính xác
+// vị trí lỗi, nhưng log entry có thêm các trường mà goerrorkit.LogError không ghi:
+// request_id, status_code, location và http_context
+//
+// Example:
+//
+//	app.Use(requestid.New())
+//	app.Use(errhandler.New())
+func New(config ...Config) fiber.Handler {
+	cfg := Config{RequestIDKey: "requestid"}
+	if len(config) > 0 {
+		cfg.OnPanic = config[0].OnPanic
+		if config[0].RequestIDKey != "" {
+			cfg.RequestIDKey = config[0].RequestIDKey
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		requestID := "unknown"
+		if rid, ok := c.Locals(cfg.RequestIDKey).(string); ok {
+			requestID = rid
+		}
+
+		// Panic recovery với chính xác panic location
+		defer func() {
+			if r := recover(); r != nil {
+				panicErr := goerrorkit.HandlePanic(r, requestID)
+				if cfg.OnPanic != nil {
+					cfg.OnPanic(c, panicErr, goroutineDump())
+				}
+				logAndRespond(c, panicErr)
+			}
+		}()
+
+		if err := c.Next(); err != nil {
+			// Lỗi gộp (errors.Join): một log entry với children, response liệt kê từng lỗi con
+			if appErr, children := Aggregate(err, requestID); appErr != nil {
+				logAndRespond(c, appErr, children...)
+				return nil
+			}
+
+			appErr := goerrorkit.ConvertToAppError(err, requestID)
+			logAndRespond(c, appErr)
+		}
+		return nil
+	}
+}
+
+// goroutineDump trả về stack của tất cả goroutines (giống output khi process crash)
+func goroutineDump() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		if len(buf) >= 16*1024*1024 {
+			return buf // Giới hạn 16MB
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// logAndRespond log error với HTTP context và gửi JSON response cho client
+// children (nếu có) là các lỗi con của lỗi gộp, được liệt kê trong trường "errors"
+func logAndRespond(c *fiber.Ctx, appErr *goerrorkit.AppError, children ...*goerrorkit.AppError) {
+	LogError(appErr, c)
+
+	response := goerrorkit.FormatErrorResponse(appErr)
+	if appErr.RequestID != "" && appErr.RequestID != "unknown" {
+		// Khác goerrorkit.FiberErrorHandler: cho phép client/support tra cứu log entry (fiberlog show <request_id>)
+		response["request_id"] = appErr.RequestID
+	}
+	if len(children) > 0 {
+		errs := make([]map[string]interface{}, 0, len(children))
+		for _, child := range children {
+			errs = append(errs, childResponse(child))
+		}
+		response["errors"] = errs
+	}
+	c.Status(appErr.Code).JSON(response)
+}
+
+// LogError log AppError giống goerrorkit.LogError và bổ sung thông tin request
+//
+// Các trường được thêm:
+//   - request_id:   ID của request (từ middleware requestid)
+//   - status_code:  HTTP status code trả về cho client
+//   - location:     "services/product_service.go:CheckStock:57"
+//   - causes:       toàn bộ chuỗi errors.Unwrap của cause
+//   - children:     log fields của từng lỗi con (lỗi gộp, xem Aggregate)
+//   - http_context: method, path, ip, user_agent
+func LogError(appErr *goerrorkit.AppError, c *fiber.Ctx) {
+	logger := goerrorkit.GetLogger()
+	if logger == nil {
+		return
+	}
+
+	logger.Error(appErr.Message, Fields(appErr, c))
+}
+
+// Fields tạo log fields cho AppError (c có thể nil khi lỗi xảy ra ngoài HTTP request)
+func Fields(appErr *goerrorkit.AppError, c *fiber.Ctx) map[string]interface{} {
+	fields := map[string]interface{}{
+		"error_type":  string(appErr.Type),
+		"status_code": appErr.Code,
+	}
+
+	// Metadata hệ thống từ Details (function, file, call_chain, panic_value)
+	for k, v := range appErr.Details {
+		fields[k] = v
+	}
+
+	if location := location(appErr); location != "" {
+		fields["location"] = location
+	}
+	if appErr.RequestID != "" {
+		fields["request_id"] = appErr.RequestID
+	}
+	if len(appErr.Data) > 0 {
+		fields["data"] = appErr.Data
+	}
+	if appErr.Cause != nil {
+		fields["cause"] = appErr.Cause.Error()
+		fields["causes"] = Causes(appErr.Cause)
+	}
+
+	if c != nil {
+		fields["path"] = c.Method() + " " + c.Path()
+		fields["http_context"] = map[string]interface{}{
+			"method":     c.Method(),
+			"path":       c.Path(),
+			"ip":         c.IP(),
+			"user_agent": c.Get(fiber.HeaderUserAgent),
+		}
+	}
+
+	return fields
+}
+
+// maxCauses giới hạn độ sâu khi unwrap (tránh vòng lặp nếu Unwrap trả về chính nó)
+const maxCauses = 32
+
+// Causes trả về message của err và từng lỗi bên trong theo chuỗi errors.Unwrap
+//
+// Example:
+//
+//	// AppError (CheckStock) → *services.ProductError → services.ErrOutOfStock
+//	Causes(appErr.Cause)
+//	// → ["product 123: product out of stock", "product out of stock"]
+func Causes(err error) []string {
+	var causes []string
+	for err != nil && len(causes) < maxCauses {
+		causes = append(causes, err.Error())
+		err = errors.Unwrap(err)
+	}
+	return causes
+}
+
+// location tạo location từ Details của AppError (rỗng nếu không xác định được)
+func location(appErr *goerrorkit.AppError) string {
+	entry := logging.Entry{Fields: appErr.Details}
+	return entry.Location()
+}
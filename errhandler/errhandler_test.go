@@ -0,0 +1,92 @@
+package errhandler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"fiber_log/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// serve chạy GET /product/999 (BusinessError 404) qua middleware, trả về response và log entry
+func serve(t *testing.T, middleware fiber.Handler) (map[string]interface{}, *logging.Entry) {
+	t.Helper()
+	memory := logging.NewMemoryOutput()
+	previous := goerrorkit.GetLogger()
+	goerrorkit.SetLogger(logging.New(&logging.Sink{Name: "memory", Output: memory}))
+	defer goerrorkit.SetLogger(previous)
+
+	app := fiber.New()
+	app.Use(requestid.New())
+	app.Use(middleware)
+	app.Get("/product/:id", func(c *fiber.Ctx) error {
+		return goerrorkit.NewBusinessError(404, "Không tìm thấy sản phẩm").WithData(map[string]interface{}{"product_id": c.Params("id")})
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/product/999", nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if resp.StatusCode != 404 {
+		t.Errorf("status = %d, want 404", resp.StatusCode)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	errs := memory.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("logged %d errors, want 1", len(errs))
+	}
+	return body, errs[0]
+}
+
+// added trả về các key có trong got nhưng không có trong base, đã sắp xếp
+func added(got, base map[string]interface{}) []string {
+	var keys []string
+	for k := range got {
+		if _, ok := base[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TestNewComparedToFiberErrorHandler ghi lại khác biệt của New so với goerrorkit.FiberErrorHandler
+// mà mọi endpoint nhận được: response chỉ thêm request_id, log entry thêm các trường để tra cứu
+// (fiberlog show <request_id>, /dev/errors, crash bundle); các trường còn lại giữ nguyên
+func TestNewComparedToFiberErrorHandler(t *testing.T) {
+	baseBody, baseEntry := serve(t, goerrorkit.FiberErrorHandler())
+	body, entry := serve(t, New())
+
+	if got := added(body, baseBody); !reflect.DeepEqual(got, []string{"request_id"}) {
+		t.Errorf("response thêm %v, want [request_id]", got)
+	}
+	for k, v := range baseBody {
+		if !reflect.DeepEqual(body[k], v) {
+			t.Errorf("response[%s] = %v, FiberErrorHandler trả về %v", k, body[k], v)
+		}
+	}
+	if body["request_id"] != entry.RequestID() || entry.RequestID() == "" {
+		t.Errorf("response request_id = %v, log request_id = %q", body["request_id"], entry.RequestID())
+	}
+
+	want := []string{"http_context", "location", "request_id", "status_code"}
+	if got := added(entry.Fields, baseEntry.Fields); !reflect.DeepEqual(got, want) {
+		t.Errorf("log entry thêm %v, want %v", got, want)
+	}
+	if entry.Message != baseEntry.Message || entry.ErrorType() != baseEntry.ErrorType() || entry.StatusCode() != 404 {
+		t.Errorf("entry = %+v, FiberErrorHandler log %+v", entry, baseEntry)
+	}
+	if ctx := entry.HTTPContext(); ctx["method"] != "GET" || ctx["path"] != "/product/999" {
+		t.Errorf("http_context = %v", ctx)
+	}
+}
@@ -0,0 +1,47 @@
+package errhandler
+
+import (
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Background jobs - Log lỗi của worker chạy nền (không có HTTP request)
+// ============================================================================
+
+// JobContext mô tả lần chạy job gây ra lỗi, thay cho http_context của lỗi trong request
+type JobContext struct {
+	Name      string // Tên job, ví dụ "reservation_expiry"
+	Attempt   int    // Lần chạy thứ mấy của job (bắt đầu từ 1)
+	PayloadID string // ID của dữ liệu job đang xử lý, ví dụ reservation ID
+}
+
+// LogJobError log AppError xảy ra trong background job
+// Log entry có các trường như LogError (không có http_context) và thêm job_context: name, attempt, payload_id
+//
+// Example:
+//
+//	errhandler.LogJobError(appErr, errhandler.JobContext{
+//	    Name: "reservation_expiry", Attempt: 3, PayloadID: "RSV-0001",
+//	})
+func LogJobError(appErr *goerrorkit.AppError, job JobContext) {
+	logger := goerrorkit.GetLogger()
+	if logger == nil {
+		return
+	}
+
+	fields := Fields(appErr, nil)
+	fields["job_context"] = job.fields()
+	logger.Error(appErr.Message, fields)
+}
+
+// fields chuyển JobContext thành log fields
+func (j JobContext) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"name":    j.Name,
+		"attempt": j.Attempt,
+	}
+	if j.PayloadID != "" {
+		fields["payload_id"] = j.PayloadID
+	}
+	return fields
+}
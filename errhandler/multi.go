@@ -0,0 +1,169 @@
+package errhandler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Multi-error - Lỗi gộp (errors.Join, fmt.Errorf với nhiều %w)
+// ============================================================================
+
+// multiError là interface của lỗi gộp nhiều lỗi con (errors.Join trả về kiểu này)
+type multiError interface {
+	Unwrap() []error
+}
+
+// typePrecedence xác định lỗi con nào quyết định type và status của response gộp
+// Lỗi phía server (PANIC, SYSTEM, EXTERNAL) được ưu tiên hơn lỗi phía client
+var typePrecedence = map[goerrorkit.ErrorType]int{
+	goerrorkit.PanicError:      6,
+	goerrorkit.SystemError:     5,
+	goerrorkit.ExternalError:   4,
+	goerrorkit.AuthError:       3,
+	goerrorkit.BusinessError:   2,
+	goerrorkit.ValidationError: 1,
+}
+
+// Aggregate chuyển lỗi gộp thành AppError tổng và danh sách AppError con
+// Trả về nil nếu err không phải lỗi gộp
+//
+// Hỗ trợ hai dạng:
+//   - errors.Join(err1, err2, ...): type và status lấy từ lỗi con có độ ưu tiên cao nhất
+//     (PANIC > SYSTEM > EXTERNAL > AUTH > BUSINESS > VALIDATION), location là của lỗi con đó
+//   - AppError có Cause là lỗi gộp (ví dụ NewValidationError(...) với Cause = errors.Join(...)):
+//     giữ nguyên type, status, message của AppError
+//
+// Mỗi lỗi con giữ type, code, location của AppError bên trong (tìm bằng errors.As),
+// message là err.Error() của lỗi con nên context như fmt.Errorf("order[%d]: %w", i, err) được giữ lại.
+// Details["children"] của AppError tổng chứa log fields của từng lỗi con.
+//
+// Example:
+//
+//	var errs []error
+//	for i, order := range orders {
+//	    if err := validate(order); err != nil {
+//	        errs = append(errs, fmt.Errorf("order[%d]: %w", i, err))
+//	    }
+//	}
+//	return errors.Join(errs...) // → response có "errors": [...], log entry có "children"
+func Aggregate(err error, requestID string) (*goerrorkit.AppError, []*goerrorkit.AppError) {
+	var overall *goerrorkit.AppError
+	var joined error
+
+	if _, ok := err.(multiError); ok {
+		joined = err
+	} else if appErr, ok := err.(*goerrorkit.AppError); ok {
+		if _, ok := appErr.Cause.(multiError); ok {
+			overall, joined = appErr, appErr.Cause
+		}
+	}
+	if joined == nil {
+		return nil, nil
+	}
+
+	var children []*goerrorkit.AppError
+	for _, child := range flatten(joined) {
+		children = append(children, childAppError(child, requestID))
+	}
+	if len(children) == 0 {
+		return nil, nil
+	}
+
+	if overall == nil {
+		primary := children[0]
+		for _, child := range children[1:] {
+			if typePrecedence[child.Type] > typePrecedence[primary.Type] {
+				primary = child
+			}
+		}
+		overall = &goerrorkit.AppError{
+			Type:    primary.Type,
+			Code:    primary.Code,
+			Message: fmt.Sprintf("Có %d lỗi trong request", len(children)),
+			Details: map[string]interface{}{
+				"function": primary.Details["function"],
+				"file":     primary.Details["file"],
+			},
+		}
+	}
+	overall.RequestID = requestID
+
+	childFields := make([]map[string]interface{}, 0, len(children))
+	for _, child := range children {
+		childFields = append(childFields, childLogFields(child))
+	}
+	if overall.Details == nil {
+		overall.Details = make(map[string]interface{})
+	}
+	overall.Details["children"] = childFields
+
+	return overall, children
+}
+
+// flatten trả về các lỗi lá của lỗi gộp (lỗi gộp lồng nhau được mở hết)
+func flatten(err error) []error {
+	multi, ok := err.(multiError)
+	if !ok {
+		return []error{err}
+	}
+
+	var leaves []error
+	for _, child := range multi.Unwrap() {
+		if child != nil {
+			leaves = append(leaves, flatten(child)...)
+		}
+	}
+	return leaves
+}
+
+// childAppError tìm AppError trong lỗi con, giữ message đầy đủ của lỗi con
+// Lỗi con không chứa AppError được coi là SystemError (giống ConvertToAppError)
+func childAppError(err error, requestID string) *goerrorkit.AppError {
+	var appErr *goerrorkit.AppError
+	if !errors.As(err, &appErr) {
+		return goerrorkit.ConvertToAppError(err, requestID)
+	}
+
+	// Copy để không sửa AppError gốc
+	child := *appErr
+	child.Message = err.Error()
+	child.RequestID = requestID
+	return &child
+}
+
+// childLogFields tạo log fields cho một lỗi con (không có request_id, http_context của request)
+func childLogFields(child *goerrorkit.AppError) map[string]interface{} {
+	fields := Fields(child, nil)
+	delete(fields, "request_id")
+	fields["message"] = child.Message
+	return fields
+}
+
+// childResponse tạo phần tử trong "errors" của response gộp
+func childResponse(child *goerrorkit.AppError) map[string]interface{} {
+	response := map[string]interface{}{
+		"error": child.Message,
+		"type":  string(child.Type),
+		"code":  child.Code,
+	}
+	if location := location(child); location != "" {
+		response["location"] = location
+	}
+	return response
+}
+
+// ErrorPayload tạo payload đầy đủ của một lỗi (error, type, code, location, data) để nhúng
+// vào response thành công một phần, ví dụ kết quả từng dòng của batch (207 Multi-Status)
+// Lỗi không chứa AppError được coi là SystemError
+func ErrorPayload(err error) map[string]interface{} {
+	appErr := childAppError(err, "")
+
+	payload := childResponse(appErr)
+	if len(appErr.Data) > 0 {
+		payload["data"] = appErr.Data
+	}
+	return payload
+}
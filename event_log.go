@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"fiber_log/audit"
+	"fiber_log/errhandler"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Domain Event Log - Ai thay đổi gì, khi nào (GET /admin/events)
+// ============================================================================
+
+// recordEvent ghi thay đổi (err == nil) hoặc failed attempt (err != nil) kèm request ID của c
+// Lỗi khi ghi event log được log riêng, không ảnh hưởng response của thao tác
+func recordEvent(c *fiber.Ctx, event audit.Event, err error) {
+	requestID, _ := c.Locals("requestid").(string)
+	event.RequestID = requestID
+	appendEvent(event.Result(err, requestID), c)
+}
+
+// recordJobEvent ghi thay đổi do background job thực hiện (không có request ID)
+// Lỗi khi ghi event log được log riêng, không làm job bị retry
+func recordJobEvent(event audit.Event, err error) {
+	appendEvent(event.Result(err, ""), nil)
+}
+
+// appendEvent thêm event vào event log, lỗi được log kèm http_context của c (nil ngoài HTTP request)
+func appendEvent(event audit.Event, c *fiber.Ctx) {
+	if _, err := eventLog.Append(event); err != nil {
+		logErr := goerrorkit.WrapWithMessage(err, "Không thể ghi event log").WithData(map[string]interface{}{
+			"event_type": event.Type,
+			"entity":     event.Entity,
+			"entity_id":  event.EntityID,
+		})
+		logErr.RequestID = event.RequestID
+		errhandler.LogError(logErr, c)
+	}
+}
+
+// eventActor trả về actor của thay đổi: "claimed:<id>" nếu request cho biết user
+// (userID của thao tác hoặc header X-User-ID), ngược lại "anonymous"
+// App chưa xác thực user nên ID chỉ là giá trị client tự khai, không được ghi như actor đã xác thực ("user:<id>")
+func eventActor(c *fiber.Ctx, userID string) string {
+	if userID == "" {
+		userID = c.Get("X-User-ID")
+	}
+	if userID == "" {
+		return "anonymous"
+	}
+	return "claimed:" + userID
+}
+
+// orderSnapshot chụp lại đơn hàng, nil nếu đơn hàng không tồn tại (order ID giả lập như ORD-123)
+func orderSnapshot(orderID string) json.RawMessage {
+	order, err := orderService.GetOrder(orderID)
+	if err != nil {
+		return nil
+	}
+	return audit.Snapshot(order)
+}
+
+// inventorySnapshot chụp lại tồn kho từng kho của sản phẩm, nil nếu sản phẩm không tồn tại
+func inventorySnapshot(productID string) json.RawMessage {
+	stock, err := productService.Inventory(productID)
+	if err != nil {
+		return nil
+	}
+	return audit.Snapshot(stock)
+}
+
+// listEventsHandler - Event log của domain, cũ nhất trước
+// Query: entity (order, product), id (cần entity), type (order.created, ...), limit (N event mới nhất)
+// Test: GET /admin/events?entity=order&id=ORD-USER001-456
+// Test: GET /admin/events?entity=product&id=456 -> ai đã giữ hàng của sản phẩm 456, khi nào
+func listEventsHandler(c *fiber.Ctx) error {
+	query := audit.Query{
+		Entity:   c.Query("entity"),
+		EntityID: c.Query("id"),
+		Type:     c.Query("type"),
+	}
+
+	if query.Entity != "" && !slices.Contains(audit.Entities, query.Entity) {
+		return goerrorkit.NewValidationError(fmt.Sprintf("Entity '%s' không hỗ trợ", query.Entity), map[string]interface{}{
+			"field":    "entity",
+			"allowed":  audit.Entities,
+			"received": query.Entity,
+		})
+	}
+	if query.EntityID != "" && query.Entity == "" {
+		return goerrorkit.NewValidationError("Tham số 'id' phải đi kèm 'entity'", map[string]interface{}{
+			"field":    "entity",
+			"required": true,
+			"allowed":  audit.Entities,
+		})
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return goerrorkit.NewValidationError("Tham số 'limit' phải là số nguyên dương", map[string]interface{}{
+				"field":    "limit",
+				"min":      1,
+				"received": limit,
+			})
+		}
+		query.Limit = n
+	}
+
+	events := eventLog.Events(query)
+	return c.JSON(fiber.Map{
+		"count":  len(events),
+		"events": events,
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"fiber_log/audit"
+	"fiber_log/errhandler"
+	"fiber_log/jobs"
+	"fiber_log/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Fulfillment Jobs - Tạo vận đơn và báo cho khách sau khi thanh toán
+// ============================================================================
+
+// orderFulfillmentJob - Queued: giao đơn hàng đã thanh toán, payload_id là order ID
+// (job_context.name trong log entry, actor "system:order_fulfillment" trong event log)
+const orderFulfillmentJob = "order_fulfillment"
+
+// registerFulfillmentJobs đăng ký job giao đơn hàng qua shipping / notification
+// Lỗi tạm thời của dependency (timeout, 503, 429) được retry, Fulfill chỉ chạy lại bước bị lỗi;
+// upstream từ chối request hoặc đơn hàng không giao được thì chuyển thẳng vào dead letters
+func registerFulfillmentJobs(runner *jobs.Runner) {
+	runner.Register(jobs.Job{
+		Name: orderFulfillmentJob,
+		Handler: func(ctx context.Context, orderID string) error {
+			before := orderSnapshot(orderID)
+			_, err := fulfillmentService.Fulfill(orderID)
+
+			// Ghi lần chạy có thay đổi (kể cả thay đổi một phần trước khi lỗi) hoặc bị lỗi
+			event := audit.Event{
+				Type: audit.OrderFulfilled, Entity: audit.EntityOrder, EntityID: orderID, Actor: "system:" + orderFulfillmentJob,
+				Before: before,
+			}
+			if after := orderSnapshot(orderID); !bytes.Equal(before, after) {
+				event.After = after
+			}
+			if err != nil || event.After != nil {
+				recordJobEvent(event, err)
+			}
+			return err
+		},
+		Retry: jobs.RetryPolicy{
+			MaxAttempts: 5,
+			Backoff:     time.Second,
+			MaxBackoff:  time.Minute,
+			Retryable: func(err error) bool {
+				var depErr *services.DependencyError
+				return errors.As(err, &depErr) && depErr.Temporary()
+			},
+		},
+	})
+}
+
+// enqueueFulfillment đưa đơn hàng vừa thanh toán vào job order_fulfillment, trả về true nếu đã vào hàng đợi
+// Order ID giả lập (ORD-123) không có hàng để giao; lỗi enqueue được log và không làm thanh toán thất bại,
+// đơn hàng có thể được giao lại qua POST /admin/jobs/order_fulfillment/run?payload_id=<order_id>
+func enqueueFulfillment(c *fiber.Ctx, orderID string) bool {
+	if _, err := orderService.GetOrder(orderID); err != nil {
+		return false
+	}
+	queued, err := jobRunner.Enqueue(orderFulfillmentJob, orderID)
+	if err != nil {
+		requestID, _ := c.Locals("requestid").(string)
+		errhandler.LogError(goerrorkit.ConvertToAppError(err, requestID), c)
+		return false
+	}
+	return queued
+}
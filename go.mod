@@ -5,6 +5,7 @@ go 1.25.3
 require (
 	github.com/gofiber/fiber/v2 v2.52.9
 	github.com/techmaster-vietnam/goerrorkit v0.1.6
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 )
 
 require (
@@ -21,5 +22,4 @@ require (
 	github.com/valyala/fasthttp v1.51.0 // indirect
 	github.com/valyala/tcplisten v1.0.0 // indirect
 	golang.org/x/sys v0.38.0 // indirect
-	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 )
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"fiber_log/logging"
+)
+
+var update = flag.Bool("update", false, "ghi lại các golden files trong testdata/")
+
+// snapshotTime thay cho thời điểm log thật để snapshot không đổi giữa các lần chạy
+var snapshotTime = time.Date(2025, 11, 11, 10, 30, 45, 0, time.FixedZone("ICT", 7*60*60))
+
+// TestLogSnapshots ghi lại entry đã chuẩn hóa của mọi demo scenario có lỗi và so với golden file
+// Bắt các thay đổi ngầm của stack trace filter (ConfigureForApplication, skip patterns) làm lệch
+// location / call_chain mà README mô tả. Cập nhật bằng: go test -run TestLogSnapshots . -update
+func TestLogSnapshots(t *testing.T) {
+	dir := filepath.Join("testdata", "snapshots")
+	expected := make(map[string]bool)
+
+	cases := append(append([]routeCase{}, routeCases...), panicCases...)
+	for _, tc := range cases {
+		if tc.errorType == "" {
+			continue
+		}
+		golden := snapshotName(tc.name) + ".golden"
+		expected[golden] = true
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, memory := sendRouteCase(t, tc)
+			errs := memory.Errors()
+			if len(errs) != 1 {
+				t.Fatalf("logged %d errors, want 1", len(errs))
+			}
+
+			got, err := logging.JSONEncoder{Pretty: true}.Encode(normalizeEntry(errs[0]))
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			assertGolden(t, filepath.Join(dir, golden), got)
+		})
+	}
+
+	// Snapshot của case đã bị đổi tên/xóa phải được xóa theo
+	files, _ := filepath.Glob(filepath.Join(dir, "*.golden"))
+	for _, file := range files {
+		if name := filepath.Base(file); !expected[name] {
+			if *update {
+				os.Remove(file)
+				continue
+			}
+			t.Errorf("snapshot %s không thuộc test case nào (run with -update để xóa)", file)
+		}
+	}
+}
+
+// normalizeEntry trả về bản sao của entry với các giá trị thay đổi mỗi lần chạy đã được thay thế:
+//   - timestamp, request_id (cả trong http_context nếu có)
+//   - goroutine_stack (chứa goroutine ID và địa chỉ bộ nhớ)
+//   - thư mục log tạm của test trong data (đổi về "logs")
+//   - tên package "fiber_log" của test binary được đổi về "main" như khi chạy go run .
+//   - số dòng trong file, location, call_chain, spawned_by và children (đổi thành "[line]") để sửa code
+//     không làm thay đổi hàng loạt snapshot; số dòng đã được kiểm tra bằng snippet trong app_test.go
+func normalizeEntry(e *logging.Entry) *logging.Entry {
+	fields := make(map[string]interface{}, len(e.Fields))
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+
+	if _, ok := fields["request_id"]; ok {
+		fields["request_id"] = "[request_id]"
+	}
+	if _, ok := fields["goroutine_stack"]; ok {
+		fields["goroutine_stack"] = "[goroutine_stack]"
+	}
+	if data, ok := fields["data"].(map[string]interface{}); ok {
+		scrubbed := make(map[string]interface{}, len(data))
+		for k, v := range data {
+			if s, ok := v.(string); ok {
+				v = strings.ReplaceAll(s, appConfig.LogDir, "logs")
+			}
+			scrubbed[k] = v
+		}
+		fields["data"] = scrubbed
+	}
+	if httpContext, ok := fields["http_context"].(map[string]interface{}); ok {
+		scrubbed := make(map[string]interface{}, len(httpContext))
+		for k, v := range httpContext {
+			scrubbed[k] = v
+		}
+		if _, ok := scrubbed["request_id"]; ok {
+			scrubbed["request_id"] = "[request_id]"
+		}
+		fields["http_context"] = scrubbed
+	}
+
+	for _, key := range []string{"file", "location", "function"} {
+		if s, ok := fields[key].(string); ok {
+			fields[key] = lineNumber.ReplaceAllString(mainPackage(s), ":[line]")
+		}
+	}
+	for _, key := range []string{"call_chain", "spawned_by"} {
+		if chain, ok := fields[key].([]string); ok {
+			normalized := make([]string, len(chain))
+			for i, frame := range chain {
+				normalized[i] = frameLineNumber.ReplaceAllString(mainPackage(frame), ":[line])")
+			}
+			fields[key] = normalized
+		}
+	}
+
+	if children := e.Children(); len(children) > 0 {
+		normalized := make([]map[string]interface{}, len(children))
+		for i, child := range children {
+			normalized[i] = normalizeEntry(child).Fields
+		}
+		fields["children"] = normalized
+	}
+
+	return &logging.Entry{Time: snapshotTime, Level: e.Level, Message: e.Message, Fields: fields}
+}
+
+// lineNumber khớp số dòng ở cuối file/location: "main.go:606", "main.go:systemErrorHandler:606"
+var lineNumber = regexp.MustCompile(`:\d+$`)
+
+// frameLineNumber khớp số dòng của một frame trong call chain: "main.validateOrderData (main.go:829)"
+var frameLineNumber = regexp.MustCompile(`:\d+\)$`)
+
+// mainPackage đổi frame của test binary về dạng của binary thật:
+// "fiber_log.validateOrderData (main.go:829)" → "main.validateOrderData (main.go:829)"
+// "fiber_log/main.go:validateOrderData:827" → "main.go:validateOrderData:827"
+func mainPackage(s string) string {
+	if rest, ok := strings.CutPrefix(s, "fiber_log."); ok {
+		return "main." + rest
+	}
+	if rest, ok := strings.CutPrefix(s, "fiber_log/"); ok && !strings.Contains(rest, "/") {
+		return rest
+	}
+	return s
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// snapshotName đổi tên test case thành tên file: "panic(customError)" → "panic_customerror"
+func snapshotName(name string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// assertGolden so got với nội dung golden file (ghi lại file khi chạy với -update)
+func assertGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, append(got, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create): %v", err)
+	}
+	if string(want) != string(got)+"\n" {
+		t.Errorf("snapshot mismatch for %s (run with -update nếu thay đổi là cố ý)\n got: %s\nwant: %s", golden, got, want)
+	}
+}
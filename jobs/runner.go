@@ -0,0 +1,450 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"fiber_log/errhandler"
+	"fiber_log/safego"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// Lỗi trong HTTP request được errhandler log kèm http_context. Job chạy nền không có request,
+// nên Runner tự recover panic, log lỗi qua errhandler.LogJobError (job_context: name, attempt, payload_id),
+// retry theo RetryPolicy và chuyển task vào dead letters khi hết số lần thử
+
+// Handler xử lý một task của job, payloadID rỗng với scheduled job
+type Handler func(ctx context.Context, payloadID string) error
+
+// RetryPolicy quyết định task lỗi có được chạy lại hay không và chờ bao lâu
+type RetryPolicy struct {
+	// MaxAttempts - Tổng số lần chạy, tính cả lần đầu (<= 0 nghĩa là chỉ chạy 1 lần)
+	MaxAttempts int
+
+	// Backoff - Thời gian chờ trước lần retry đầu tiên, nhân đôi sau mỗi lần
+	Backoff time.Duration
+
+	// MaxBackoff - Thời gian chờ tối đa giữa hai lần chạy (0: không giới hạn)
+	MaxBackoff time.Duration
+
+	// Retryable - Lỗi có nên retry không (nil: mọi lỗi đều retry)
+	// Lỗi không retry được chuyển thẳng vào dead letters
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy chạy tối đa 3 lần, chờ 1s rồi 2s
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 30 * time.Second}
+
+// backoff trả về thời gian chờ sau lần chạy thứ attempt bị lỗi
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return d
+}
+
+// maxAttempts trả về số lần chạy tối đa (ít nhất 1)
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Job là một loại công việc chạy nền
+type Job struct {
+	Name    string
+	Handler Handler
+	Retry   RetryPolicy
+
+	// Every - Chu kỳ của scheduled job (0: queued job, chỉ chạy khi Enqueue)
+	Every time.Duration
+}
+
+// JobError là lỗi gần nhất của job / dead letter
+type JobError struct {
+	Message   string    `json:"message"`
+	ErrorType string    `json:"error_type"`
+	Location  string    `json:"location,omitempty"`
+	PayloadID string    `json:"payload_id,omitempty"`
+	Attempt   int       `json:"attempt"`
+	At        time.Time `json:"at"`
+}
+
+// JobStatus là trạng thái của một job, hiển thị trên trang /admin/jobs
+type JobStatus struct {
+	Name        string     `json:"name"`
+	Every       string     `json:"every,omitempty"` // Chu kỳ của scheduled job ("30s")
+	MaxAttempts int        `json:"max_attempts"`
+	Runs        int        `json:"runs"`     // Số lần chạy (mỗi attempt một lần)
+	Failures    int        `json:"failures"` // Số lần chạy lỗi
+	Pending     int        `json:"pending"`  // Số task đang chờ chạy hoặc chờ retry
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	LastError   *JobError  `json:"last_error,omitempty"`
+}
+
+// DeadLetter là task đã hết số lần thử (hoặc lỗi không retry được), chờ retry thủ công
+type DeadLetter struct {
+	ID        string    `json:"id"`
+	Job       string    `json:"job"`
+	PayloadID string    `json:"payload_id,omitempty"`
+	Attempts  int       `json:"attempts"`
+	Error     JobError  `json:"error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// task là một lần chạy job với payload
+type task struct {
+	job       *registeredJob
+	payloadID string
+	attempt   int
+}
+
+// key định danh task để không enqueue trùng (job + payload)
+func (t *task) key() string {
+	return t.job.Name + "/" + t.payloadID
+}
+
+// registeredJob là job kèm thống kê
+type registeredJob struct {
+	Job
+	runs      int
+	failures  int
+	lastRunAt *time.Time
+	lastError *JobError
+}
+
+// queueSize là số task tối đa chờ trong hàng đợi
+const queueSize = 1024
+
+// Runner chạy scheduled jobs (theo chu kỳ) và queued jobs (qua Enqueue) trong process
+//
+// Example:
+//
+//	runner := jobs.NewRunner()
+//	runner.Register(jobs.Job{Name: "reservation_sweep", Every: 30 * time.Second, Handler: sweep})
+//	runner.Register(jobs.Job{Name: "reservation_expire", Handler: expire, Retry: jobs.DefaultRetryPolicy})
+//	runner.Start(ctx)
+//	runner.Enqueue("reservation_expire", "RSV-0001")
+type Runner struct {
+	mu      sync.Mutex
+	jobs    map[string]*registeredJob
+	pending map[string]string // task.key() của task đang chờ chạy / chờ retry -> tên job
+	dead    []*DeadLetter
+	deadSeq int
+	queue   chan *task
+	now     func() time.Time
+}
+
+// NewRunner tạo Runner, cần Register các jobs rồi Start
+func NewRunner() *Runner {
+	return &Runner{
+		jobs:    make(map[string]*registeredJob),
+		pending: make(map[string]string),
+		queue:   make(chan *task, queueSize),
+		now:     time.Now,
+	}
+}
+
+// Register đăng ký job, đăng ký lại cùng tên sẽ thay thế job cũ
+func (r *Runner) Register(job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.Name] = &registeredJob{Job: job}
+}
+
+// Start chạy worker xử lý hàng đợi và scheduler của từng scheduled job cho đến khi ctx bị cancel
+// Worker và scheduler chạy qua safego.Go: panic ngoài Handler cũng không làm crash server
+func (r *Runner) Start(ctx context.Context) {
+	safego.Go(ctx, func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t := <-r.queue:
+				r.process(ctx, t)
+			}
+		}
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, job := range r.jobs {
+		if job.Every <= 0 {
+			continue
+		}
+		name, every := job.Name, job.Every
+		safego.Go(ctx, func() {
+			ticker := time.NewTicker(every)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					r.Enqueue(name, "")
+				}
+			}
+		})
+	}
+}
+
+// Enqueue đưa task vào hàng đợi, trả về false nếu task cùng job và payload đang chờ chạy
+// hoặc (với queued job) đang nằm trong dead letters chờ retry thủ công
+func (r *Runner) Enqueue(name, payloadID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, err := r.find(name)
+	if err != nil {
+		return false, err
+	}
+
+	t := &task{job: job, payloadID: payloadID, attempt: 1}
+	if _, waiting := r.pending[t.key()]; waiting {
+		return false, nil
+	}
+	if job.Every <= 0 && r.deadLetterFor(t) != nil {
+		return false, nil
+	}
+
+	select {
+	case r.queue <- t:
+		r.pending[t.key()] = job.Name
+		return true, nil
+	default:
+		return false, goerrorkit.NewSystemError(fmt.Errorf("job queue full (%d tasks)", queueSize)).WithData(map[string]interface{}{
+			"job":        name,
+			"payload_id": payloadID,
+		})
+	}
+}
+
+// RetryDeadLetter chạy lại ngay task trong dead letters (nút "retry now" trên trang /admin/jobs)
+// Thành công thì dead letter bị xóa; lỗi thì dead letter được cập nhật attempts và lỗi mới, trả về false
+func (r *Runner) RetryDeadLetter(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	var letter *DeadLetter
+	for _, d := range r.dead {
+		if d.ID == id {
+			letter = d
+		}
+	}
+	if letter == nil {
+		r.mu.Unlock()
+		return false, goerrorkit.NewBusinessError(404, fmt.Sprintf("Dead letter %s không tồn tại", id)).WithData(map[string]interface{}{
+			"dead_letter_id": id,
+		})
+	}
+	job := r.jobs[letter.Job]
+	t := &task{job: job, payloadID: letter.PayloadID, attempt: letter.Attempts + 1}
+	r.mu.Unlock()
+
+	appErr := r.execute(ctx, t)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if appErr != nil {
+		letter.Attempts = t.attempt
+		letter.Error = *job.lastError
+		letter.FailedAt = job.lastError.At
+		return false, nil
+	}
+	r.removeDeadLetter(id)
+	return true, nil
+}
+
+// Jobs trả về trạng thái của các jobs đã đăng ký, sắp xếp theo tên
+func (r *Runner) Jobs() []JobStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending := make(map[string]int)
+	for _, name := range r.pending {
+		pending[name]++
+	}
+
+	statuses := make([]JobStatus, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		status := JobStatus{
+			Name:        job.Name,
+			MaxAttempts: job.Retry.maxAttempts(),
+			Runs:        job.runs,
+			Failures:    job.failures,
+			Pending:     pending[job.Name],
+			LastRunAt:   job.lastRunAt,
+			LastError:   job.lastError,
+		}
+		if job.Every > 0 {
+			status.Every = job.Every.String()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// DeadLetters trả về các dead letters, mới nhất trước
+func (r *Runner) DeadLetters() []DeadLetter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	letters := make([]DeadLetter, 0, len(r.dead))
+	for i := len(r.dead) - 1; i >= 0; i-- {
+		letters = append(letters, *r.dead[i])
+	}
+	return letters
+}
+
+// Pending trả về số task đang chờ chạy hoặc chờ retry của tất cả jobs
+func (r *Runner) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
+
+// process chạy task, lỗi thì lên lịch retry hoặc chuyển vào dead letters
+func (r *Runner) process(ctx context.Context, t *task) {
+	appErr := r.execute(ctx, t)
+	if appErr == nil {
+		r.done(t)
+		return
+	}
+
+	policy := t.job.Retry
+	retryable := policy.Retryable == nil || policy.Retryable(appErr)
+	if !retryable || t.attempt >= policy.maxAttempts() {
+		r.deadLetter(t)
+		return
+	}
+
+	delay := policy.backoff(t.attempt)
+	next := &task{job: t.job, payloadID: t.payloadID, attempt: t.attempt + 1}
+	safego.Go(ctx, func() {
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+			r.queue <- next
+		}
+	})
+}
+
+// execute chạy Handler một lần, recover panic và log lỗi kèm job_context
+// Trả về AppError của lần chạy (nil nếu thành công)
+func (r *Runner) execute(ctx context.Context, t *task) (appErr *goerrorkit.AppError) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			appErr = goerrorkit.HandlePanic(rec, "")
+		}
+		r.record(t, appErr)
+		if appErr != nil {
+			errhandler.LogJobError(appErr, errhandler.JobContext{
+				Name:      t.job.Name,
+				Attempt:   t.attempt,
+				PayloadID: t.payloadID,
+			})
+		}
+	}()
+
+	if err := t.job.Handler(ctx, t.payloadID); err != nil {
+		return goerrorkit.ConvertToAppError(err, "")
+	}
+	return nil
+}
+
+// record cập nhật thống kê của job sau một lần chạy
+func (r *Runner) record(t *task, appErr *goerrorkit.AppError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	t.job.runs++
+	t.job.lastRunAt = &now
+	if appErr == nil {
+		return
+	}
+
+	t.job.failures++
+	location, _ := errhandler.Fields(appErr, nil)["location"].(string)
+	t.job.lastError = &JobError{
+		Message:   appErr.Message,
+		ErrorType: string(appErr.Type),
+		Location:  location,
+		PayloadID: t.payloadID,
+		Attempt:   t.attempt,
+		At:        now,
+	}
+}
+
+// done đánh dấu task đã kết thúc (không còn chờ chạy)
+func (r *Runner) done(t *task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, t.key())
+}
+
+// deadLetter chuyển task đã hết số lần thử vào dead letters
+// Task cùng job và payload đã có dead letter (scheduled job lỗi ở mỗi chu kỳ) thì cộng dồn attempts,
+// cập nhật lỗi mới nhất và đưa dead letter lên đầu danh sách thay vì thêm dead letter mới
+func (r *Runner) deadLetter(t *task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.pending, t.key())
+	if existing := r.deadLetterFor(t); existing != nil {
+		r.removeDeadLetter(existing.ID)
+		existing.Attempts += t.attempt
+		existing.Error = *t.job.lastError
+		existing.FailedAt = t.job.lastError.At
+		r.dead = append(r.dead, existing)
+		return
+	}
+	r.deadSeq++
+	r.dead = append(r.dead, &DeadLetter{
+		ID:        fmt.Sprintf("DL-%04d", r.deadSeq),
+		Job:       t.job.Name,
+		PayloadID: t.payloadID,
+		Attempts:  t.attempt,
+		Error:     *t.job.lastError,
+		FailedAt:  t.job.lastError.At,
+	})
+}
+
+// find tìm job theo tên, r.mu phải đang được giữ
+func (r *Runner) find(name string) (*registeredJob, error) {
+	job, exists := r.jobs[name]
+	if !exists {
+		return nil, goerrorkit.NewBusinessError(404, fmt.Sprintf("Job %s không tồn tại", name)).WithData(map[string]interface{}{
+			"job": name,
+		})
+	}
+	return job, nil
+}
+
+// deadLetterFor trả về dead letter cùng job và payload với t, r.mu phải đang được giữ
+func (r *Runner) deadLetterFor(t *task) *DeadLetter {
+	for _, d := range r.dead {
+		if d.Job == t.job.Name && d.PayloadID == t.payloadID {
+			return d
+		}
+	}
+	return nil
+}
+
+// removeDeadLetter xóa dead letter theo ID, r.mu phải đang được giữ
+func (r *Runner) removeDeadLetter(id string) {
+	for i, d := range r.dead {
+		if d.ID == id {
+			r.dead = append(r.dead[:i], r.dead[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fiber_log/logging"
+	"fiber_log/testkit"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// errTemporary là lỗi của handler trong các test
+var errTemporary = errors.New("temporary failure")
+
+// newTestRunner tạo Runner với jobs đã đăng ký, log lỗi của job vào memory sink
+// Runner chỉ chạy khi start = true, dừng khi test kết thúc
+func newTestRunner(t *testing.T, start bool, jobs ...Job) (*Runner, *logging.MemoryOutput) {
+	t.Helper()
+	memory := logging.NewMemoryOutput()
+	previous := goerrorkit.GetLogger()
+	goerrorkit.SetLogger(logging.New(&logging.Sink{Name: "memory", Output: memory}))
+
+	runner := NewRunner()
+	for _, job := range jobs {
+		runner.Register(job)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if start {
+		runner.Start(ctx)
+	}
+	t.Cleanup(func() {
+		cancel()
+		goerrorkit.SetLogger(previous)
+	})
+	return runner, memory
+}
+
+// waitFor chờ tới khi cond đúng, quá 2s thì test thất bại
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout chờ %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// failing trả về handler lỗi n lần đầu rồi thành công (n < 0: luôn lỗi), kèm bộ đếm số lần chạy
+func failing(n int64) (Handler, *atomic.Int64) {
+	calls := new(atomic.Int64)
+	return func(ctx context.Context, payloadID string) error {
+		if call := calls.Add(1); n < 0 || call <= n {
+			return errTemporary
+		}
+		return nil
+	}, calls
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   []time.Duration // Backoff sau attempt 1, 2, 3, 4
+	}{
+		{"doubling", RetryPolicy{Backoff: time.Second}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{"capped", RetryPolicy{Backoff: time.Second, MaxBackoff: 3 * time.Second}, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{"default", DefaultRetryPolicy, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.policy.backoff(i + 1); got != want {
+					t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+
+	for maxAttempts, want := range map[int]int{-1: 1, 0: 1, 1: 1, 5: 5} {
+		if got := (RetryPolicy{MaxAttempts: maxAttempts}).maxAttempts(); got != want {
+			t.Errorf("maxAttempts(%d) = %d, want %d", maxAttempts, got, want)
+		}
+	}
+}
+
+func TestRunnerRetriesUntilSuccess(t *testing.T) {
+	handler, calls := failing(2)
+	runner, memory := newTestRunner(t, true, Job{
+		Name:    "sync",
+		Handler: handler,
+		Retry:   RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+	})
+
+	ok, err := runner.Enqueue("sync", "P-1")
+	testkit.AssertNoError(t, err)
+	if !ok {
+		t.Fatal("Enqueue = false, want true")
+	}
+	waitFor(t, "task chạy xong", func() bool { return calls.Load() == 3 && runner.Pending() == 0 })
+
+	if letters := runner.DeadLetters(); len(letters) != 0 {
+		t.Errorf("dead letters = %+v, want none", letters)
+	}
+	status := runner.Jobs()[0]
+	if status.Runs != 3 || status.Failures != 2 || status.LastError == nil || status.LastError.Attempt != 2 {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Mỗi lần lỗi được log kèm job_context
+	errs := memory.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("logged %d errors, want 2", len(errs))
+	}
+	for i, entry := range errs {
+		ctx, _ := entry.Fields["job_context"].(map[string]interface{})
+		if ctx["name"] != "sync" || ctx["attempt"] != i+1 || ctx["payload_id"] != "P-1" {
+			t.Errorf("errs[%d] job_context = %v", i, ctx)
+		}
+	}
+}
+
+func TestRunnerDeadLetters(t *testing.T) {
+	tests := []struct {
+		name     string
+		retry    RetryPolicy
+		attempts int
+	}{
+		{"max attempts", RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}, 3},
+		// Lỗi không retry được: chuyển thẳng vào dead letters sau lần chạy đầu
+		{"not retryable", RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, Retryable: func(err error) bool {
+			return !errors.Is(err, errTemporary)
+		}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, calls := failing(-1)
+			runner, _ := newTestRunner(t, true, Job{Name: "expire", Handler: handler, Retry: tt.retry})
+
+			runner.Enqueue("expire", "RSV-1")
+			waitFor(t, "dead letter", func() bool { return len(runner.DeadLetters()) == 1 })
+
+			letter := runner.DeadLetters()[0]
+			if letter.Job != "expire" || letter.PayloadID != "RSV-1" || letter.Attempts != tt.attempts ||
+				letter.Error.ErrorType != "SYSTEM" || letter.Error.Attempt != tt.attempts {
+				t.Errorf("dead letter = %+v", letter)
+			}
+			if got := calls.Load(); got != int64(tt.attempts) || runner.Pending() != 0 {
+				t.Errorf("calls = %d, pending = %d", got, runner.Pending())
+			}
+		})
+	}
+}
+
+func TestEnqueueDedupe(t *testing.T) {
+	handler, _ := failing(-1)
+	runner, _ := newTestRunner(t, false,
+		Job{Name: "expire", Handler: handler},
+		Job{Name: "sweep", Handler: handler, Every: time.Hour},
+	)
+
+	enqueue := func(name, payloadID string, want bool) {
+		t.Helper()
+		ok, err := runner.Enqueue(name, payloadID)
+		testkit.AssertNoError(t, err)
+		if ok != want {
+			t.Errorf("Enqueue(%s, %s) = %v, want %v", name, payloadID, ok, want)
+		}
+	}
+
+	// Runner chưa Start nên task nằm trong hàng đợi: cùng job + payload bị bỏ qua
+	enqueue("expire", "RSV-1", true)
+	enqueue("expire", "RSV-1", false)
+	enqueue("expire", "RSV-2", true)
+	enqueue("sweep", "", true)
+	enqueue("sweep", "", false)
+	if runner.Pending() != 3 {
+		t.Errorf("pending = %d, want 3", runner.Pending())
+	}
+
+	// Queued job trong dead letters không được enqueue lại; scheduled job vẫn chạy ở chu kỳ sau
+	for _, failed := range []*task{{job: runner.jobs["expire"], payloadID: "RSV-3", attempt: 1}, {job: runner.jobs["sweep"], attempt: 1}} {
+		runner.record(failed, goerrorkit.ConvertToAppError(errTemporary, ""))
+		runner.deadLetter(failed)
+	}
+	enqueue("expire", "RSV-3", false)
+	runner.done(&task{job: runner.jobs["sweep"]})
+	enqueue("sweep", "", true)
+
+	_, err := runner.Enqueue("unknown", "")
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 404)
+}
+
+// Scheduled job lỗi ở mỗi chu kỳ: chỉ có một dead letter, attempts cộng dồn
+func TestScheduledJobDeadLetterDedupe(t *testing.T) {
+	handler, _ := failing(-1)
+	runner, _ := newTestRunner(t, true, Job{Name: "sweep", Handler: handler, Every: 2 * time.Millisecond})
+
+	waitFor(t, "3 chu kỳ lỗi", func() bool {
+		letters := runner.DeadLetters()
+		return len(letters) > 0 && letters[0].Attempts >= 3
+	})
+	letters := runner.DeadLetters()
+	if len(letters) != 1 || letters[0].ID != "DL-0001" || letters[0].Job != "sweep" {
+		t.Errorf("dead letters = %+v, want 1 dead letter DL-0001", letters)
+	}
+}
+
+func TestRetryDeadLetter(t *testing.T) {
+	var healthy atomic.Bool
+	runner, _ := newTestRunner(t, true, Job{
+		Name: "notify",
+		Handler: func(ctx context.Context, payloadID string) error {
+			if !healthy.Load() {
+				return errTemporary
+			}
+			return nil
+		},
+		Retry: RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+	})
+
+	runner.Enqueue("notify", "ORD-1")
+	waitFor(t, "dead letter", func() bool { return len(runner.DeadLetters()) == 1 })
+	id := runner.DeadLetters()[0].ID
+
+	// Vẫn lỗi: dead letter ghi nhận attempt mới
+	ok, err := runner.RetryDeadLetter(context.Background(), id)
+	testkit.AssertNoError(t, err)
+	if letter := runner.DeadLetters()[0]; ok || letter.Attempts != 3 || letter.Error.Attempt != 3 {
+		t.Errorf("retry = %v, dead letter = %+v", ok, letter)
+	}
+
+	// Thành công: dead letter bị xóa, task được enqueue lại bình thường
+	healthy.Store(true)
+	ok, err = runner.RetryDeadLetter(context.Background(), id)
+	testkit.AssertNoError(t, err)
+	if !ok || len(runner.DeadLetters()) != 0 {
+		t.Errorf("retry = %v, dead letters = %+v", ok, runner.DeadLetters())
+	}
+	if ok, _ := runner.Enqueue("notify", "ORD-1"); !ok {
+		t.Error("Enqueue sau khi retry thành công = false, want true")
+	}
+
+	_, err = runner.RetryDeadLetter(context.Background(), id)
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 404)
+}
@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Encoder chuyển một Entry thành bytes để ghi ra Output
+// Mỗi Sink có encoder riêng nên cùng một lỗi có thể được ghi ở nhiều format khác nhau
+type Encoder interface {
+	Encode(e *Entry) ([]byte, error)
+}
+
+// ============================================================================
+// JSON Encoder
+// ============================================================================
+
+// JSONEncoder encode entry thành JSON với cùng field names như goerrorkit.InitLogger
+// (timestamp, level, message + các fields của goerrorkit)
+type JSONEncoder struct {
+	// Pretty - Format JSON nhiều dòng, dễ đọc (giống PrettyPrint của logrus)
+	Pretty bool
+}
+
+// Encode implements Encoder
+func (enc JSONEncoder) Encode(e *Entry) ([]byte, error) {
+	data := make(map[string]interface{}, len(e.Fields)+3)
+	for k, v := range e.Fields {
+		// Tránh ghi đè các trường cơ bản (cùng cách xử lý với logrus)
+		switch k {
+		case "timestamp", "level", "message":
+			k = "fields." + k
+		}
+		data[k] = jsonValue(v)
+	}
+	data["timestamp"] = e.Time.Format(time.RFC3339)
+	data["level"] = e.Level.String()
+	data["message"] = e.Message
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	if enc.Pretty {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(data); err != nil {
+		return nil, fmt.Errorf("encode json log entry: %w", err)
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// jsonValue đảm bảo error values được ghi dưới dạng message thay vì {}
+func jsonValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return v
+}
+
+// ============================================================================
+// Text Encoder
+// ============================================================================
+
+// TextEncoder encode entry thành một dòng text dễ đọc cho console
+//
+// Ví dụ:
+//
+//	2025-11-11 10:30:45 ERROR Sản phẩm đã hết hàng error_type=BUSINESS function=services.(*ProductService).CheckStock
+type TextEncoder struct {
+	// Color - Tô màu level bằng ANSI escape codes
+	Color bool
+}
+
+// Encode implements Encoder
+func (enc TextEncoder) Encode(e *Entry) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString(e.Time.Format("2006-01-02 15:04:05"))
+	buf.WriteByte(' ')
+
+	level := strings.ToUpper(e.Level.String())
+	if enc.Color {
+		fmt.Fprintf(&buf, "\x1b[%dm%-5s\x1b[0m", levelColor(e.Level), level)
+	} else {
+		fmt.Fprintf(&buf, "%-5s", level)
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(e.Message)
+
+	for _, k := range sortedKeys(e.Fields) {
+		buf.WriteByte(' ')
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(textValue(e.Fields[k]))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// levelColor trả về mã màu ANSI cho từng level
+func levelColor(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 37 // gray
+	case InfoLevel:
+		return 36 // cyan
+	case WarnLevel:
+		return 33 // yellow
+	default:
+		return 31 // red
+	}
+}
+
+// textValue format giá trị của field: string có khoảng trắng được quote,
+// map/slice được encode thành JSON một dòng
+func textValue(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			return fmt.Sprintf("%q", value)
+		}
+		return value
+	case error:
+		return fmt.Sprintf("%q", value.Error())
+	case fmt.Stringer:
+		return textValue(value.String())
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return fmt.Sprint(value)
+	default:
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("%q", fmt.Sprint(value))
+		}
+		return string(raw)
+	}
+}
+
+// sortedKeys trả về danh sách keys đã sắp xếp để output ổn định
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ecsVersion là phiên bản Elastic Common Schema mà ECSEncoder tuân theo
+const ecsVersion = "8.11.0"
+
+// ECSEncoder encode entry theo Elastic Common Schema (JSON một dòng)
+// để ingest trực tiếp vào Elasticsearch/Kibana mà không cần ingest pipeline
+//
+// Mapping các trường goerrorkit:
+//   - error_type   → error.type
+//   - call_chain   → error.stack_trace (mỗi frame một dòng)
+//   - file/line    → log.origin.file.name, log.origin.file.line
+//   - function     → log.origin.function
+//   - status_code  → http.response.status_code
+//   - request_id   → http.request.id
+//   - http_context → http.request.method, url.path, client.ip, user_agent.original
+//   - job_context  → fiber_log.job.name, fiber_log.job.attempt, fiber_log.job.payload_id
+//   - location, data, cause → fiber_log.* (custom namespace theo khuyến nghị của ECS)
+type ECSEncoder struct {
+	// ServiceName - Giá trị của service.name (bỏ qua nếu rỗng)
+	ServiceName string
+}
+
+// Encode implements Encoder
+func (enc ECSEncoder) Encode(e *Entry) ([]byte, error) {
+	// Mọi trường ECS đều nằm trong object lồng nhau: key có dấu chấm ("log.level") cạnh object "log"
+	// bị Elasticsearch coi là hai mapping xung đột
+	doc := map[string]interface{}{
+		"@timestamp": e.Time.Format("2006-01-02T15:04:05.000Z07:00"),
+		"message":    e.Message,
+	}
+	setPath(doc, "log.level", e.Level.String())
+	setPath(doc, "ecs.version", ecsVersion)
+
+	if enc.ServiceName != "" {
+		setPath(doc, "service.name", enc.ServiceName)
+	}
+
+	if errorType := e.ErrorType(); errorType != "" {
+		setPath(doc, "error.type", string(errorType))
+		setPath(doc, "error.message", e.Message)
+	}
+	if chain := e.CallChain(); len(chain) > 0 {
+		setPath(doc, "error.stack_trace", strings.Join(chain, "\n"))
+	}
+
+	if file, line, function := e.Origin(); file != "" {
+		setPath(doc, "log.origin.file.name", file)
+		setPath(doc, "log.origin.file.line", line)
+		setPath(doc, "log.origin.function", function)
+	}
+
+	if status := e.StatusCode(); status != 0 {
+		setPath(doc, "http.response.status_code", status)
+	}
+	if requestID := e.RequestID(); requestID != "" {
+		setPath(doc, "http.request.id", requestID)
+	}
+
+	for k, v := range e.HTTPContext() {
+		switch k {
+		case "method":
+			setPath(doc, "http.request.method", v)
+		case "path":
+			setPath(doc, "url.path", v)
+		case "ip":
+			setPath(doc, "client.ip", v)
+		case "user_agent":
+			setPath(doc, "user_agent.original", v)
+		default:
+			setPath(doc, "fiber_log.http."+k, v)
+		}
+	}
+
+	for k, v := range e.JobContext() {
+		setPath(doc, "fiber_log.job."+k, v)
+	}
+
+	if location := e.Location(); location != "" {
+		setPath(doc, "fiber_log.location", location)
+	}
+	if data := e.Data(); len(data) > 0 {
+		setPath(doc, "fiber_log.data", data)
+	}
+	if cause := e.Cause(); cause != "" {
+		setPath(doc, "fiber_log.cause", cause)
+	}
+	if panicValue, ok := e.Fields["panic_value"]; ok {
+		setPath(doc, "fiber_log.panic_value", fmt.Sprint(panicValue))
+	}
+	for k, v := range e.ExtraFields() {
+		setPath(doc, "fiber_log."+k, jsonValue(v))
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("encode ecs log entry: %w", err)
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// setPath gán value vào map lồng nhau theo đường dẫn "a.b.c"
+func setPath(doc map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	current := doc
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := current[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[k] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GELFEncoder encode entry theo Graylog Extended Log Format 1.1
+//
+// Mapping các trường goerrorkit (additional fields có tiền tố "_"):
+//   - message      → short_message; full_message = message + call chain + cause
+//   - error_type   → _error_type
+//   - location     → _location, _file, _line, _function
+//   - call_chain   → _call_chain (mỗi frame một dòng)
+//   - http_context → _http_method, _http_path, _http_<key>
+//   - job_context  → _job_name, _job_attempt, _job_payload_id
+//   - data         → _data_<key> (giá trị lồng nhau được encode thành JSON string)
+//   - request_id   → _request_id, status_code → _status_code
+type GELFEncoder struct {
+	// Host - Giá trị trường host (mặc định os.Hostname)
+	Host string
+}
+
+// Encode implements Encoder
+func (enc GELFEncoder) Encode(e *Entry) ([]byte, error) {
+	host := enc.Host
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	msg := map[string]interface{}{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": e.Message,
+		"timestamp":     float64(e.Time.UnixMilli()) / 1000,
+		"level":         syslogSeverity(e.Level),
+	}
+
+	if full := gelfFullMessage(e); full != e.Message {
+		msg["full_message"] = full
+	}
+
+	if errorType := e.ErrorType(); errorType != "" {
+		msg["_error_type"] = string(errorType)
+	}
+	if status := e.StatusCode(); status != 0 {
+		msg["_status_code"] = status
+	}
+	if location := e.Location(); location != "" {
+		msg["_location"] = location
+	}
+	if file, line, function := e.Origin(); file != "" {
+		msg["_file"] = file
+		msg["_line"] = line
+		msg["_function"] = function
+	}
+	if requestID := e.RequestID(); requestID != "" {
+		msg["_request_id"] = requestID
+	}
+	for k, v := range e.HTTPContext() {
+		msg[gelfKey("http_"+k)] = gelfValue(v)
+	}
+	for k, v := range e.JobContext() {
+		msg[gelfKey("job_"+k)] = gelfValue(v)
+	}
+	for k, v := range e.Data() {
+		msg[gelfKey("data_"+k)] = gelfValue(v)
+	}
+	if chain := e.CallChain(); len(chain) > 0 {
+		msg["_call_chain"] = strings.Join(chain, "\n")
+	}
+	if cause := e.Cause(); cause != "" {
+		msg["_cause"] = cause
+	}
+	if panicValue, ok := e.Fields["panic_value"]; ok {
+		msg["_panic_value"] = fmt.Sprint(panicValue)
+	}
+	for k, v := range e.ExtraFields() {
+		msg[gelfKey(k)] = gelfValue(v)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(msg); err != nil {
+		return nil, fmt.Errorf("encode gelf log entry: %w", err)
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// gelfFullMessage ghép message với call chain và cause để hiển thị trong Graylog
+func gelfFullMessage(e *Entry) string {
+	var sb strings.Builder
+	sb.WriteString(e.Message)
+	for _, frame := range e.CallChain() {
+		sb.WriteString("\n  at ")
+		sb.WriteString(frame)
+	}
+	if cause := e.Cause(); cause != "" {
+		sb.WriteString("\ncaused by: ")
+		sb.WriteString(cause)
+	}
+	return sb.String()
+}
+
+// gelfKey tạo tên additional field hợp lệ (^_[\w.\-]*$, không được là "_id")
+func gelfKey(name string) string {
+	key := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+	if key == "id" {
+		key = "id_"
+	}
+	return "_" + key
+}
+
+// gelfValue giữ nguyên string/number; các kiểu khác được encode thành JSON string
+// vì GELF chỉ cho phép additional fields là string hoặc number
+func gelfValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return value
+	case bool:
+		return fmt.Sprint(value)
+	case error:
+		return value.Error()
+	default:
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(raw)
+	}
+}
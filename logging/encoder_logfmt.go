@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogfmtEncoder encode entry theo logfmt (key=value), phù hợp với Loki/Grafana và Heroku-style pipelines
+//
+// Mapping các trường goerrorkit:
+//   - error_type   → error_type
+//   - status_code  → status
+//   - location     → location (services/product_service.go:CheckStock:57)
+//   - http_context → http.method, http.path, http.ip, ...
+//   - job_context  → job.name, job.attempt, job.payload_id
+//   - data         → data.<key>
+//   - call_chain   → call_chain (các frame nối bằng " > ")
+//
+// Ví dụ:
+//
+//	time=2025-11-11T10:30:45+07:00 level=error msg="Sản phẩm 'iPhone 15' đã hết hàng" error_type=BUSINESS location=services/product_service.go:CheckStock:57 data.product_id=123
+type LogfmtEncoder struct{}
+
+// Encode implements Encoder
+func (LogfmtEncoder) Encode(e *Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	w := logfmtWriter{buf: &buf}
+
+	w.pair("time", e.Time.Format(time.RFC3339))
+	w.pair("level", e.Level.String())
+	w.pair("msg", e.Message)
+
+	if errorType := e.ErrorType(); errorType != "" {
+		w.pair("error_type", string(errorType))
+	}
+	if status := e.StatusCode(); status != 0 {
+		w.pair("status", status)
+	}
+	if location := e.Location(); location != "" {
+		w.pair("location", location)
+	}
+	if requestID := e.RequestID(); requestID != "" {
+		w.pair("request_id", requestID)
+	}
+
+	httpContext := e.HTTPContext()
+	for _, k := range sortedKeys(httpContext) {
+		w.pair("http."+k, httpContext[k])
+	}
+
+	jobContext := e.JobContext()
+	for _, k := range sortedKeys(jobContext) {
+		w.pair("job."+k, jobContext[k])
+	}
+
+	data := e.Data()
+	for _, k := range sortedKeys(data) {
+		w.pair("data."+k, data[k])
+	}
+
+	if chain := e.CallChain(); len(chain) > 0 {
+		w.pair("call_chain", strings.Join(chain, " > "))
+	}
+	if cause := e.Cause(); cause != "" {
+		w.pair("cause", cause)
+	}
+	if panicValue, ok := e.Fields["panic_value"]; ok {
+		w.pair("panic_value", panicValue)
+	}
+
+	extra := e.ExtraFields()
+	for _, k := range sortedKeys(extra) {
+		w.pair(k, extra[k])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// logfmtWriter ghi các cặp key=value, cách nhau bởi khoảng trắng
+type logfmtWriter struct {
+	buf *bytes.Buffer
+}
+
+// pair ghi một cặp key=value
+func (w logfmtWriter) pair(key string, value interface{}) {
+	if w.buf.Len() > 0 {
+		w.buf.WriteByte(' ')
+	}
+	w.buf.WriteString(logfmtKey(key))
+	w.buf.WriteByte('=')
+	w.buf.WriteString(logfmtValue(value))
+}
+
+// logfmtKey thay các ký tự không hợp lệ trong key bằng "_"
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue format value: string được quote khi cần, map/slice encode thành JSON
+func logfmtValue(v interface{}) string {
+	var s string
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		s = value
+	case error:
+		s = value.Error()
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return fmt.Sprint(value)
+	default:
+		raw, err := json.Marshal(value)
+		if err != nil {
+			s = fmt.Sprint(value)
+		} else {
+			s = string(raw)
+		}
+	}
+
+	if s == "" || strings.ContainsAny(s, " =\"\\\t\n\r") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,273 @@
+package logging
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"fiber_log/services"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+var update = flag.Bool("update", false, "ghi lại các golden files trong testdata/")
+
+// captureLogger lưu lại fields mà goerrorkit.LogError truyền vào logger
+type captureLogger struct {
+	entries []*Entry
+}
+
+func (l *captureLogger) Error(msg string, fields map[string]interface{}) {
+	l.entries = append(l.entries, &Entry{Level: ErrorLevel, Message: msg, Fields: fields})
+}
+func (l *captureLogger) Info(msg string, fields map[string]interface{})  {}
+func (l *captureLogger) Debug(msg string, fields map[string]interface{}) {}
+func (l *captureLogger) Warn(msg string, fields map[string]interface{})  {}
+
+// demoEntry chạy một demo error từ services và trả về entry mà goerrorkit log ra
+func demoEntry(t *testing.T, path string, run func() error) *Entry {
+	t.Helper()
+
+	err := run()
+	if err == nil {
+		t.Fatalf("%s: expected error, got nil", path)
+	}
+
+	capture := &captureLogger{}
+	previous := goerrorkit.GetLogger()
+	goerrorkit.SetLogger(capture)
+	defer goerrorkit.SetLogger(previous)
+
+	goerrorkit.LogError(goerrorkit.ConvertToAppError(err, "req-0001"), path)
+	if len(capture.entries) != 1 {
+		t.Fatalf("%s: expected 1 log entry, got %d", path, len(capture.entries))
+	}
+
+	entry := capture.entries[0]
+	entry.Time = time.Date(2025, 11, 11, 10, 30, 45, 0, time.FixedZone("ICT", 7*60*60))
+	pinLineNumbers(entry)
+	return entry
+}
+
+// demoLine thay cho số dòng thật trong services để sửa services không làm đổi golden files của encoder
+const demoLine = "100"
+
+var (
+	lineNumber      = regexp.MustCompile(`:\d+$`)
+	frameLineNumber = regexp.MustCompile(`:\d+\)$`)
+)
+
+// pinLineNumbers đổi số dòng trong file, location và call_chain của entry thành demoLine
+func pinLineNumbers(e *Entry) {
+	for _, key := range []string{"file", "location"} {
+		if s, ok := e.Fields[key].(string); ok {
+			e.Fields[key] = lineNumber.ReplaceAllString(s, ":"+demoLine)
+		}
+	}
+	if chain, ok := e.Fields["call_chain"].([]string); ok {
+		pinned := make([]string, len(chain))
+		for i, frame := range chain {
+			pinned[i] = frameLineNumber.ReplaceAllString(frame, ":"+demoLine+")")
+		}
+		e.Fields["call_chain"] = pinned
+	}
+}
+
+// demoEntries trả về các entries mẫu từ những demo errors của ứng dụng
+func demoEntries(t *testing.T) map[string]*Entry {
+	// Giống main.go: chỉ giữ frames của application code trong call chain
+	goerrorkit.ConfigureForApplication("services")
+
+	productService := services.NewProductService()
+	orderService := services.NewOrderService(productService)
+
+	return map[string]*Entry{
+		"product_not_found": demoEntry(t, "GET /product/999", func() error {
+			_, err := productService.GetProduct("999")
+			return err
+		}),
+		"out_of_stock": demoEntry(t, "GET /product/123/check-stock", func() error {
+			return productService.CheckStock("123")
+		}),
+		"reserve_validation": demoEntry(t, "POST /product/456/reserve", func() error {
+			_, err := productService.ReserveProduct("456", 10, services.AllocationPolicy{})
+			return err
+		}),
+		"order_quantity_callchain": demoEntry(t, "POST /order/create", func() error {
+			_, err := orderService.CreateOrder("456", "USER001", 0, services.AllocationPolicy{})
+			return err
+		}),
+		"payment_timeout": demoEntry(t, "POST /order/ORD-123/payment", func() error {
+			return orderService.ProcessPayment("ORD-123", services.MustParseMoney("20000", services.USD))
+		}),
+		// Entry của GET /panic/division (main package không import được từ test này)
+		"panic_division": {
+			Time:    time.Date(2025, 11, 11, 10, 30, 45, 0, time.FixedZone("ICT", 7*60*60)),
+			Level:   ErrorLevel,
+			Message: "Panic recovered: runtime error: integer divide by zero",
+			Fields: map[string]interface{}{
+				"error_type":  "PANIC",
+				"file":        "main.go:190",
+				"function":    "main.panicDivisionHandler",
+				"call_chain":  []string{"main.panicDivisionHandler (main.go:190)"},
+				"panic_value": "runtime error: integer divide by zero",
+				"path":        "GET /panic/division",
+			},
+		},
+		// Lỗi của background job: job_context thay cho http_context (errhandler.LogJobError)
+		"job_reservation_expire": {
+			Time:    time.Date(2025, 11, 11, 10, 30, 45, 0, time.FixedZone("ICT", 7*60*60)),
+			Level:   ErrorLevel,
+			Message: "Không thể hoàn trả stock của reservation RSV-0002",
+			Fields: map[string]interface{}{
+				"error_type":  "SYSTEM",
+				"status_code": 500,
+				"file":        "reservation_service.go:210",
+				"function":    "services.(*ReservationService).ExpireHold",
+				"data":        map[string]interface{}{"reservation_id": "RSV-0002", "product_id": "789"},
+				"cause":       "product 789: product not found",
+				"job_context": map[string]interface{}{"name": "reservation_expire", "attempt": 3, "payload_id": "RSV-0002"},
+			},
+		},
+	}
+}
+
+func TestEncodersGolden(t *testing.T) {
+	encoders := map[string]Encoder{
+		"logfmt": LogfmtEncoder{},
+		"ecs":    ECSEncoder{ServiceName: "fiber_log"},
+		"gelf":   GELFEncoder{Host: "demo-host"},
+	}
+
+	for name, entry := range demoEntries(t) {
+		for encName, enc := range encoders {
+			t.Run(encName+"/"+name, func(t *testing.T) {
+				got, err := enc.Encode(entry)
+				if err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+
+				golden := filepath.Join("testdata", encName, name+".golden")
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(golden, append(got, '\n'), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("read golden file (run with -update to create): %v", err)
+				}
+				if string(want) != string(got)+"\n" {
+					t.Errorf("output mismatch for %s\n got: %s\nwant: %s", golden, got, want)
+				}
+			})
+		}
+	}
+}
+
+// ecsFieldTypes là kiểu JSON của các trường ECS mà ECSEncoder sinh ra, theo ECS field reference 8.11
+// (keyword/text/date → string, long → number); fiber_log.* là custom namespace nên không kiểm tra
+var ecsFieldTypes = map[string]string{
+	"@timestamp":                "string", // date
+	"message":                   "string", // match_only_text
+	"ecs.version":               "string", // keyword
+	"log.level":                 "string", // keyword
+	"log.origin.file.name":      "string", // keyword
+	"log.origin.file.line":      "number", // long
+	"log.origin.function":       "string", // keyword
+	"service.name":              "string", // keyword
+	"error.type":                "string", // keyword
+	"error.message":             "string", // match_only_text
+	"error.stack_trace":         "string", // wildcard
+	"http.request.id":           "string", // keyword
+	"http.request.method":       "string", // keyword
+	"http.response.status_code": "number", // long
+	"url.path":                  "string", // wildcard
+	"client.ip":                 "string", // ip
+	"user_agent.original":       "string", // keyword
+}
+
+// TestECSEncoderFieldReference kiểm tra output của ECSEncoder là document lồng nhau hợp lệ theo ECS:
+// không có key chứa dấu chấm (Elasticsearch coi "log.level" và object "log" là hai mapping xung đột)
+// và mọi trường ngoài fiber_log.* đều có trong ECS field reference với đúng kiểu
+func TestECSEncoderFieldReference(t *testing.T) {
+	for name, entry := range demoEntries(t) {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := ECSEncoder{ServiceName: "fiber_log"}.Encode(entry)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			var doc map[string]interface{}
+			if err := json.Unmarshal(encoded, &doc); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			var walk func(prefix string, obj map[string]interface{})
+			walk = func(prefix string, obj map[string]interface{}) {
+				for k, v := range obj {
+					path := prefix + k
+					if strings.Contains(k, ".") {
+						t.Errorf("key %q chứa dấu chấm, phải là object lồng nhau", path)
+					}
+					if path == "fiber_log" {
+						continue
+					}
+					if child, ok := v.(map[string]interface{}); ok {
+						walk(path+".", child)
+						continue
+					}
+
+					want, ok := ecsFieldTypes[path]
+					if !ok {
+						t.Errorf("trường %q không có trong ECS field reference", path)
+						continue
+					}
+					got := "string"
+					if _, isNumber := v.(float64); isNumber {
+						got = "number"
+					} else if _, isString := v.(string); !isString {
+						got = fmt.Sprintf("%T", v)
+					}
+					if got != want {
+						t.Errorf("%s = %v (%s), want %s", path, v, got, want)
+					}
+				}
+			}
+			walk("", doc)
+
+			if level := doc["log"].(map[string]interface{})["level"]; level != "error" {
+				t.Errorf("log.level = %v, want error", level)
+			}
+		})
+	}
+}
+
+func TestFormatLocation(t *testing.T) {
+	tests := []struct {
+		function string
+		file     string
+		line     int
+		want     string
+	}{
+		{"services.(*ProductService).CheckStock", "product_service.go", 57, "services/product_service.go:CheckStock:57"},
+		{"main.validateOrderData", "main.go", 574, "main.go:validateOrderData:574"},
+		{"fiber_log/services.(*OrderService).callPaymentGateway", "order_service.go", 124, "services/order_service.go:callPaymentGateway:124"},
+		{"main.panicDeferHandler.func1", "main.go", 10, "main.go:panicDeferHandler.func1:10"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatLocation(tt.function, tt.file, tt.line); got != tt.want {
+			t.Errorf("FormatLocation(%q, %q, %d) = %q, want %q", tt.function, tt.file, tt.line, got, tt.want)
+		}
+	}
+}
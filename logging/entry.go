@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"strings"
+	"time"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// Level là mức độ nghiêm trọng của một log entry
+type Level int
+
+const (
+	DebugLevel Level = iota // Thông tin debug chi tiết
+	InfoLevel               // Thông tin vận hành
+	WarnLevel               // Cảnh báo
+	ErrorLevel              // Lỗi (mọi AppError đều được log ở level này)
+)
+
+// String trả về tên level dạng chữ thường (debug, info, warn, error)
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// ParseLevel chuyển tên level thành Level
+// Tên không hợp lệ sẽ trả về ErrorLevel (giống goerrorkit.InitLogger)
+func ParseLevel(name string) Level {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return DebugLevel
+	case "info":
+		return InfoLevel
+	case "warn", "warning":
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
+
+// Entry là một bản ghi log trước khi được encode
+// Fields chứa nguyên các trường do goerrorkit truyền vào (error_type, file, function, data, ...)
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  map[string]interface{}
+}
+
+// ErrorType trả về loại lỗi goerrorkit của entry
+// Trả về chuỗi rỗng nếu entry không phải log lỗi (ví dụ: log khởi tạo)
+func (e *Entry) ErrorType() goerrorkit.ErrorType {
+	if e.Fields == nil {
+		return ""
+	}
+	errorType, _ := e.Fields["error_type"].(string)
+	return goerrorkit.ErrorType(errorType)
+}
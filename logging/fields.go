@@ -0,0 +1,229 @@
+package logging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// knownFields là các trường goerrorkit mà encoders map sang schema riêng
+// Các trường còn lại được giữ nguyên dưới dạng extra fields
+var knownFields = map[string]bool{
+	"error_type":   true,
+	"location":     true,
+	"file":         true,
+	"function":     true,
+	"call_chain":   true,
+	"data":         true,
+	"path":         true,
+	"http_context": true,
+	"job_context":  true,
+	"request_id":   true,
+	"status_code":  true,
+	"cause":        true,
+	"panic_value":  true,
+}
+
+// Origin trả về file, line và function nơi lỗi phát sinh
+// Dựa trên trường "file" (dạng "product_service.go:57") và "function" của goerrorkit
+func (e *Entry) Origin() (file string, line int, function string) {
+	function = e.stringField("function")
+	file = e.stringField("file")
+	if idx := strings.LastIndex(file, ":"); idx > 0 {
+		if n, err := strconv.Atoi(file[idx+1:]); err == nil {
+			line = n
+			file = file[:idx]
+		}
+	}
+	return file, line, function
+}
+
+// Location trả về vị trí lỗi dạng "services/product_service.go:CheckStock:57"
+// Nếu entry đã có trường "location" thì dùng trực tiếp
+func (e *Entry) Location() string {
+	if location := e.stringField("location"); location != "" {
+		return location
+	}
+
+	file, line, function := e.Origin()
+	if file == "" || file == "unknown" {
+		return ""
+	}
+	return FormatLocation(function, file, line)
+}
+
+// FormatLocation tạo location từ function name và file của goerrorkit
+//
+// Example:
+//
+//	FormatLocation("services.(*ProductService).CheckStock", "product_service.go", 57)
+//	// → "services/product_service.go:CheckStock:57"
+//	FormatLocation("main.validateOrderData", "main.go", 574)
+//	// → "main.go:validateOrderData:574"
+func FormatLocation(function, file string, line int) string {
+	pkg, name := splitFunction(function)
+	if pkg != "" && pkg != "main" {
+		file = pkg + "/" + file
+	}
+	return fmt.Sprintf("%s:%s:%d", file, name, line)
+}
+
+// splitFunction tách "services.(*ProductService).CheckStock" thành ("services", "CheckStock")
+// Closure giữ hậu tố: "main.handler.func1" → ("main", "handler.func1")
+func splitFunction(function string) (pkg, name string) {
+	if slash := strings.LastIndex(function, "/"); slash >= 0 {
+		function = function[slash+1:]
+	}
+
+	dot := strings.Index(function, ".")
+	if dot < 0 {
+		return "", function
+	}
+	pkg, name = function[:dot], function[dot+1:]
+
+	// Bỏ receiver: "(*ProductService).CheckStock" → "CheckStock"
+	if strings.HasPrefix(name, "(") {
+		if end := strings.Index(name, ")."); end > 0 {
+			name = name[end+2:]
+		}
+	}
+	return pkg, name
+}
+
+// CallChain trả về call chain của lỗi (rỗng nếu không có)
+func (e *Entry) CallChain() []string {
+	return e.stringsField("call_chain")
+}
+
+// Causes trả về chuỗi errors.Unwrap của cause (trường "causes", rỗng nếu không có)
+func (e *Entry) Causes() []string {
+	return e.stringsField("causes")
+}
+
+// Children trả về các lỗi con của lỗi gộp (trường "children", rỗng nếu không có)
+// Mỗi lỗi con là một Entry với Message lấy từ trường "message", Time và Level của entry cha
+// Hỗ trợ cả []map[string]interface{} (log trực tiếp) và []interface{} (đọc lại từ JSON)
+func (e *Entry) Children() []*Entry {
+	var values []map[string]interface{}
+	switch children := e.Fields["children"].(type) {
+	case []map[string]interface{}:
+		values = children
+	case []interface{}:
+		for _, v := range children {
+			if fields, ok := v.(map[string]interface{}); ok {
+				values = append(values, fields)
+			}
+		}
+	}
+
+	result := make([]*Entry, 0, len(values))
+	for _, fields := range values {
+		child := &Entry{Time: e.Time, Level: e.Level, Fields: fields}
+		child.Message = child.stringField("message")
+		result = append(result, child)
+	}
+	return result
+}
+
+// Data trả về dữ liệu đặc thù của lỗi (trường "data")
+func (e *Entry) Data() map[string]interface{} {
+	data, _ := e.Fields["data"].(map[string]interface{})
+	return data
+}
+
+// HTTPContext trả về thông tin HTTP request (method, path, ip, ...)
+// Nếu không có trường "http_context", method và path được tách từ trường "path" ("GET /product/123")
+func (e *Entry) HTTPContext() map[string]interface{} {
+	if ctx, ok := e.Fields["http_context"].(map[string]interface{}); ok {
+		return ctx
+	}
+
+	path := e.stringField("path")
+	if path == "" {
+		return nil
+	}
+	if method, p, ok := strings.Cut(path, " "); ok {
+		return map[string]interface{}{"method": method, "path": p}
+	}
+	return map[string]interface{}{"path": path}
+}
+
+// JobContext trả về thông tin background job gây ra lỗi (name, attempt, payload_id)
+// Lỗi trong job không có http_context, nil nếu entry không phải lỗi của job
+func (e *Entry) JobContext() map[string]interface{} {
+	ctx, _ := e.Fields["job_context"].(map[string]interface{})
+	return ctx
+}
+
+// RequestID trả về request ID của lỗi (rỗng nếu không có)
+func (e *Entry) RequestID() string {
+	return e.stringField("request_id")
+}
+
+// StatusCode trả về HTTP status code của lỗi (0 nếu không có)
+func (e *Entry) StatusCode() int {
+	return intValue(e.Fields["status_code"])
+}
+
+// Cause trả về message của lỗi gốc (rỗng nếu không có)
+func (e *Entry) Cause() string {
+	return e.stringField("cause")
+}
+
+// ExtraFields trả về các trường không thuộc knownFields
+func (e *Entry) ExtraFields() map[string]interface{} {
+	extra := make(map[string]interface{})
+	for k, v := range e.Fields {
+		if !knownFields[k] {
+			extra[k] = v
+		}
+	}
+	return extra
+}
+
+// stringField đọc trường dạng string (rỗng nếu không có hoặc sai kiểu)
+func (e *Entry) stringField(key string) string {
+	if e.Fields == nil {
+		return ""
+	}
+	switch v := e.Fields[key].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// stringsField trả về trường dạng danh sách chuỗi
+// Hỗ trợ cả []string (log trực tiếp) và []interface{} (đọc lại từ JSON)
+func (e *Entry) stringsField(key string) []string {
+	switch values := e.Fields[key].(type) {
+	case []string:
+		return values
+	case []interface{}:
+		result := make([]string, 0, len(values))
+		for _, v := range values {
+			result = append(result, fmt.Sprint(v))
+		}
+		return result
+	}
+	return nil
+}
+
+// intValue chuyển số từ các kiểu khác nhau (int, float64 khi đọc JSON) sang int
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
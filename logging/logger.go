@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// Logger implement goerrorkit.Logger và phân phối mỗi entry tới nhiều Sink
+// Mỗi sink tự quyết định có ghi entry hay không dựa trên bộ lọc riêng
+type Logger struct {
+	mu    sync.Mutex
+	sinks []*Sink
+
+	// errOutput - Nơi báo lỗi khi một sink ghi thất bại (mặc định os.Stderr)
+	errOutput io.Writer
+
+	// now - Cho phép thay thế nguồn thời gian (dùng trong test)
+	now func() time.Time
+}
+
+// New tạo Logger với danh sách sinks
+func New(sinks ...*Sink) *Logger {
+	return &Logger{
+		sinks:     sinks,
+		errOutput: os.Stderr,
+		now:       time.Now,
+	}
+}
+
+// Init tạo Logger, đăng ký vào goerrorkit bằng goerrorkit.SetLogger
+// và trả về Logger để caller có thể Close khi shutdown
+//
+// Example:
+//
+//	logger := logging.Init(
+//	    &logging.Sink{Name: "console", Output: logging.NewConsoleOutput(os.Stdout), Encoder: logging.TextEncoder{Color: true}},
+//	    &logging.Sink{Name: "file", Output: logging.NewFileOutput(logging.FileOptions{Path: "logs/errors.log"})},
+//	)
+//	defer logger.Close()
+func Init(sinks ...*Sink) *Logger {
+	logger := New(sinks...)
+	goerrorkit.SetLogger(logger)
+	logger.Info("✓ GoErrorKit logger initialized", map[string]interface{}{
+		"sinks": logger.sinkNames(),
+	})
+	return logger
+}
+
+// Error implements goerrorkit.Logger
+func (l *Logger) Error(msg string, fields map[string]interface{}) {
+	l.log(ErrorLevel, msg, fields)
+}
+
+// Info implements goerrorkit.Logger
+func (l *Logger) Info(msg string, fields map[string]interface{}) {
+	l.log(InfoLevel, msg, fields)
+}
+
+// Debug implements goerrorkit.Logger
+func (l *Logger) Debug(msg string, fields map[string]interface{}) {
+	l.log(DebugLevel, msg, fields)
+}
+
+// Warn implements goerrorkit.Logger
+func (l *Logger) Warn(msg string, fields map[string]interface{}) {
+	l.log(WarnLevel, msg, fields)
+}
+
+// Close đóng tất cả outputs, trả về lỗi đầu tiên gặp phải (nếu có)
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var errs []error
+	for _, sink := range l.sinks {
+		if err := sink.Output.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close sink %q: %w", sink.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// log tạo Entry và ghi tới mọi sink chấp nhận entry đó
+func (l *Logger) log(level Level, msg string, fields map[string]interface{}) {
+	entry := &Entry{
+		Time:    l.now(),
+		Level:   level,
+		Message: msg,
+		Fields:  fields,
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, sink := range l.sinks {
+		if !sink.Accepts(entry) {
+			continue
+		}
+
+		encoded, err := sink.encoder().Encode(entry)
+		if err == nil {
+			err = sink.Output.Write(entry, encoded)
+		}
+		if err != nil {
+			// Không thể log lỗi qua chính logger này → báo ra stderr
+			fmt.Fprintf(l.errOutput, "logging: sink %q: %v\n", sink.Name, err)
+		}
+	}
+}
+
+// sinkNames trả về tên các sinks đã cấu hình
+func (l *Logger) sinkNames() []string {
+	names := make([]string, 0, len(l.sinks))
+	for _, sink := range l.sinks {
+		names = append(names, sink.Name)
+	}
+	return names
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dailyDateFormat là format ngày trong tên file của DailyFileOutput
+const dailyDateFormat = "2006-01-02"
+
+// DailyFileOutput ghi log vào một file mới mỗi ngày: <Dir>/<Prefix>-2006-01-02.log
+// Các file cũ hơn MaxAge ngày sẽ bị xóa khi chuyển sang ngày mới
+type DailyFileOutput struct {
+	mu     sync.Mutex
+	dir    string
+	prefix string
+	maxAge int
+
+	day  string
+	file *os.File
+}
+
+// NewDailyFileOutput tạo Output rotate theo ngày
+// maxAge <= 0 nghĩa là giữ lại tất cả các file cũ
+func NewDailyFileOutput(dir, prefix string, maxAge int) *DailyFileOutput {
+	return &DailyFileOutput{
+		dir:    dir,
+		prefix: prefix,
+		maxAge: maxAge,
+	}
+}
+
+// Write implements Output
+// Entry được ghi vào file ứng với ngày của entry (theo giờ local)
+func (o *DailyFileOutput) Write(e *Entry, encoded []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	day := e.Time.Local().Format(dailyDateFormat)
+	if o.file == nil || day != o.day {
+		if err := o.rotate(day); err != nil {
+			return err
+		}
+	}
+
+	line := make([]byte, 0, len(encoded)+1)
+	line = append(line, encoded...)
+	line = append(line, '\n')
+	_, err := o.file.Write(line)
+	return err
+}
+
+// Close implements Output
+func (o *DailyFileOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
+
+// rotate đóng file hiện tại, mở file của ngày mới và dọn các file quá hạn
+func (o *DailyFileOutput) rotate(day string) error {
+	if o.file != nil {
+		o.file.Close()
+		o.file = nil
+	}
+
+	if err := os.MkdirAll(o.dir, 0755); err != nil {
+		return fmt.Errorf("create log directory %s: %w", o.dir, err)
+	}
+
+	path := filepath.Join(o.dir, fmt.Sprintf("%s-%s.log", o.prefix, day))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open daily log file: %w", err)
+	}
+
+	o.file = file
+	o.day = day
+	o.removeExpired(day)
+	return nil
+}
+
+// removeExpired xóa các file log cũ hơn maxAge ngày so với ngày hiện tại
+func (o *DailyFileOutput) removeExpired(today string) {
+	if o.maxAge <= 0 {
+		return
+	}
+
+	current, err := time.ParseInLocation(dailyDateFormat, today, time.Local)
+	if err != nil {
+		return
+	}
+	cutoff := current.AddDate(0, 0, -o.maxAge)
+
+	matches, err := filepath.Glob(filepath.Join(o.dir, o.prefix+"-*.log"))
+	if err != nil {
+		return
+	}
+
+	for _, path := range matches {
+		name := strings.TrimSuffix(filepath.Base(path), ".log")
+		day, err := time.ParseInLocation(dailyDateFormat, strings.TrimPrefix(name, o.prefix+"-"), time.Local)
+		if err != nil {
+			continue // Không phải file do output này tạo ra
+		}
+		if day.Before(cutoff) {
+			os.Remove(path)
+		}
+	}
+}
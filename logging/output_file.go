@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// ============================================================================
+// Writer Output (console, buffer, ...)
+// ============================================================================
+
+// WriterOutput ghi mỗi entry thành một dòng vào io.Writer
+type WriterOutput struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConsoleOutput tạo Output ghi ra console (thường là os.Stdout hoặc os.Stderr)
+func NewConsoleOutput(w io.Writer) *WriterOutput {
+	return &WriterOutput{w: w}
+}
+
+// Write implements Output
+func (o *WriterOutput) Write(e *Entry, encoded []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	line := make([]byte, 0, len(encoded)+1)
+	line = append(line, encoded...)
+	line = append(line, '\n')
+	_, err := o.w.Write(line)
+	return err
+}
+
+// Close implements Output
+// Không đóng os.Stdout/os.Stderr; các writer khác được đóng nếu implement io.Closer
+func (o *WriterOutput) Close() error {
+	if o.w == os.Stdout || o.w == os.Stderr {
+		return nil
+	}
+	if closer, ok := o.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ============================================================================
+// File Output (rotate theo kích thước)
+// ============================================================================
+
+// FileOptions cấu hình cho file output với rotation theo kích thước
+type FileOptions struct {
+	// Path - Đường dẫn file log
+	Path string
+
+	// MaxFileSize - Kích thước tối đa của file log (MB) trước khi rotate
+	MaxFileSize int
+
+	// MaxBackups - Số lượng file backup giữ lại
+	MaxBackups int
+
+	// MaxAge - Số ngày giữ file log cũ
+	MaxAge int
+
+	// DisableCompress - Không nén gzip các file backup
+	DisableCompress bool
+}
+
+// NewFileOutput tạo Output ghi vào file với rotation tự động (lumberjack)
+// Thư mục chứa file được lumberjack tạo ở lần ghi đầu tiên
+func NewFileOutput(opts FileOptions) *WriterOutput {
+	return &WriterOutput{
+		w: &lumberjack.Logger{
+			Filename:   opts.Path,
+			MaxSize:    opts.MaxFileSize,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAge,
+			Compress:   !opts.DisableCompress,
+			LocalTime:  true,
+		},
+	}
+}
@@ -0,0 +1,372 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPFormat là format payload gửi tới log backend
+type HTTPFormat string
+
+const (
+	// HTTPFormatLoki - Loki push API (POST /loki/api/v1/push)
+	HTTPFormatLoki HTTPFormat = "loki"
+
+	// HTTPFormatElasticsearch - Elasticsearch bulk API (POST /_bulk)
+	HTTPFormatElasticsearch HTTPFormat = "elasticsearch"
+)
+
+// HTTPBatchOptions cấu hình cho HTTP batch output
+type HTTPBatchOptions struct {
+	// URL - Endpoint nhận log (ví dụ http://localhost:3100/loki/api/v1/push)
+	URL string
+
+	// Format - Loki hoặc Elasticsearch (mặc định Loki)
+	Format HTTPFormat
+
+	// BatchSize - Số entries tối đa mỗi request (mặc định 100)
+	BatchSize int
+
+	// FlushInterval - Thời gian tối đa giữ entries trong buffer (mặc định 2s)
+	FlushInterval time.Duration
+
+	// Labels - Loki stream labels cố định (ví dụ {"app": "fiber_log"})
+	Labels map[string]string
+
+	// Index - Elasticsearch index (mặc định "fiber-log")
+	Index string
+
+	// Headers - HTTP headers bổ sung (ví dụ Authorization)
+	Headers map[string]string
+
+	// Client - HTTP client (mặc định timeout 5s)
+	Client *http.Client
+
+	// MaxRetries - Số lần gửi lại một batch khi lỗi kết nối, 429 hoặc 5xx (mặc định 3, số âm để không gửi lại)
+	// Batch vẫn lỗi sau MaxRetries lần hoặc bị từ chối với status 4xx khác thì bị bỏ và báo qua OnError
+	MaxRetries int
+
+	// RetryBackoff - Thời gian chờ trước lần gửi lại đầu tiên, nhân đôi sau mỗi lần (mặc định 500ms)
+	RetryBackoff time.Duration
+
+	// OnError - Callback khi một batch bị bỏ sau khi gửi thất bại (mặc định in ra stderr)
+	OnError func(err error)
+}
+
+// ErrOutputClosed được trả về khi Write được gọi sau Close
+var ErrOutputClosed = errors.New("logging: output closed")
+
+// httpSendError là lỗi khi POST một batch: lỗi kết nối (Err) hoặc status không phải 2xx (Status)
+type httpSendError struct {
+	records int
+	url     string
+	status  int
+	err     error
+}
+
+func (e *httpSendError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("send %d entries to %s: %v", e.records, e.url, e.err)
+	}
+	return fmt.Sprintf("send %d entries to %s: unexpected status %d", e.records, e.url, e.status)
+}
+
+func (e *httpSendError) Unwrap() error {
+	return e.err
+}
+
+// retryable cho biết gửi lại có thể thành công: lỗi kết nối, 429 hoặc 5xx
+// Lỗi build payload/request và các status 4xx khác (payload sai, thiếu quyền) sẽ lỗi y như vậy nếu gửi lại
+func retryable(err error) bool {
+	var sendErr *httpSendError
+	if !errors.As(err, &sendErr) {
+		return false
+	}
+	return sendErr.err != nil || sendErr.status == http.StatusTooManyRequests || sendErr.status >= 500
+}
+
+// httpRecord là một entry đã encode đang chờ gửi
+type httpRecord struct {
+	entry   *Entry
+	encoded []byte
+}
+
+// HTTPBatchOutput gom entries và gửi theo batch tới Loki/Elasticsearch-compatible endpoint
+// Việc gửi diễn ra ở background goroutine nên không làm chậm request đang xử lý
+type HTTPBatchOutput struct {
+	opts HTTPBatchOptions
+
+	mu      sync.Mutex
+	pending []httpRecord
+	closed  bool
+
+	flushCh chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewHTTPBatchOutput tạo HTTP batch output và khởi động goroutine gửi log
+func NewHTTPBatchOutput(opts HTTPBatchOptions) *HTTPBatchOutput {
+	if opts.Format == "" {
+		opts.Format = HTTPFormatLoki
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 2 * time.Second
+	}
+	if opts.Index == "" {
+		opts.Index = "fiber-log"
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 500 * time.Millisecond
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(err error) {
+			fmt.Fprintf(os.Stderr, "logging: http sink: %v\n", err)
+		}
+	}
+
+	o := &HTTPBatchOutput{
+		opts:    opts,
+		flushCh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go o.loop()
+	return o
+}
+
+// Write implements Output
+// Entry được đưa vào buffer; batch được gửi khi đủ BatchSize hoặc hết FlushInterval
+// Sau Close, Write trả về ErrOutputClosed vì buffer không còn được gửi đi
+func (o *HTTPBatchOutput) Write(e *Entry, encoded []byte) error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return ErrOutputClosed
+	}
+	o.pending = append(o.pending, httpRecord{entry: e, encoded: append([]byte(nil), encoded...)})
+	full := len(o.pending) >= o.opts.BatchSize
+	o.mu.Unlock()
+
+	if full {
+		select {
+		case o.flushCh <- struct{}{}:
+		default: // Đã có yêu cầu flush đang chờ
+		}
+	}
+	return nil
+}
+
+// Close implements Output
+// Dừng goroutine nền và gửi nốt các entries còn trong buffer
+// Trả về lỗi của các batch bị bỏ trong lần gửi cuối (đã được báo qua OnError)
+func (o *HTTPBatchOutput) Close() error {
+	o.once.Do(func() {
+		o.mu.Lock()
+		o.closed = true
+		o.mu.Unlock()
+		close(o.done)
+	})
+	<-o.stopped
+	return o.flush()
+}
+
+// loop gửi batch định kỳ hoặc khi buffer đầy
+func (o *HTTPBatchOutput) loop() {
+	defer close(o.stopped)
+
+	ticker := time.NewTicker(o.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.done:
+			return
+		case <-ticker.C:
+		case <-o.flushCh:
+		}
+		o.flush() // Batch bị bỏ đã được báo qua OnError
+	}
+}
+
+// flush gửi toàn bộ entries đang chờ, chia thành các batch BatchSize
+// Một batch lỗi không chặn các batch sau; mỗi batch bị bỏ được báo qua OnError
+func (o *HTTPBatchOutput) flush() error {
+	o.mu.Lock()
+	records := o.pending
+	o.pending = nil
+	o.mu.Unlock()
+
+	var errs []error
+	for len(records) > 0 {
+		n := min(len(records), o.opts.BatchSize)
+		if err := o.sendWithRetry(records[:n]); err != nil {
+			err = fmt.Errorf("drop %d entries: %w", n, err)
+			o.opts.OnError(err)
+			errs = append(errs, err)
+		}
+		records = records[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// sendWithRetry gửi một batch, gửi lại tối đa MaxRetries lần với backoff nhân đôi nếu lỗi có thể thử lại
+func (o *HTTPBatchOutput) sendWithRetry(records []httpRecord) error {
+	backoff := o.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := o.send(records)
+		if err == nil || attempt >= o.opts.MaxRetries || !retryable(err) {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// send build payload theo format và POST tới endpoint
+func (o *HTTPBatchOutput) send(records []httpRecord) error {
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+
+	switch o.opts.Format {
+	case HTTPFormatElasticsearch:
+		body, err = o.elasticsearchPayload(records)
+		contentType = "application/x-ndjson"
+	default:
+		body, err = o.lokiPayload(records)
+		contentType = "application/json"
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, o.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range o.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.opts.Client.Do(req)
+	if err != nil {
+		return &httpSendError{records: len(records), url: o.opts.URL, err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return &httpSendError{records: len(records), url: o.opts.URL, status: resp.StatusCode}
+	}
+	return nil
+}
+
+// lokiPayload tạo body cho Loki push API
+// Entries được nhóm thành streams theo labels (labels cố định + level + error_type)
+func (o *HTTPBatchOutput) lokiPayload(records []httpRecord) ([]byte, error) {
+	type lokiStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	streams := make(map[string]*lokiStream)
+	var order []string
+
+	for _, r := range records {
+		labels := make(map[string]string, len(o.opts.Labels)+2)
+		for k, v := range o.opts.Labels {
+			labels[k] = v
+		}
+		labels["level"] = r.entry.Level.String()
+		if errorType := r.entry.ErrorType(); errorType != "" {
+			labels["error_type"] = string(errorType)
+		}
+
+		key := labelKey(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			order = append(order, key)
+		}
+		stream.Values = append(stream.Values, [2]string{
+			strconv.FormatInt(r.entry.Time.UnixNano(), 10),
+			string(r.encoded),
+		})
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range order {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode loki payload: %w", err)
+	}
+	return body, nil
+}
+
+// elasticsearchPayload tạo body NDJSON cho Elasticsearch bulk API
+// Encoder của sink phải sinh JSON; JSON nhiều dòng sẽ được compact lại
+func (o *HTTPBatchOutput) elasticsearchPayload(records []httpRecord) ([]byte, error) {
+	action, err := json.Marshal(map[string]interface{}{
+		"index": map[string]string{"_index": o.opts.Index},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode bulk action: %w", err)
+	}
+
+	var buf bytes.Buffer
+	for _, r := range records {
+		buf.Write(action)
+		buf.WriteByte('\n')
+		if err := json.Compact(&buf, r.encoded); err != nil {
+			return nil, fmt.Errorf("elasticsearch sink requires a JSON encoder: %w", err)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// labelKey tạo key ổn định cho một tập labels
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(labels[k])
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
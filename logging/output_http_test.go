@@ -0,0 +1,190 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logBackend là Loki giả lập: ghi lại body của mỗi request và trả về status do respond quyết định
+type logBackend struct {
+	mu      sync.Mutex
+	bodies  []string
+	respond func(body string, attempt int) int
+}
+
+func newLogBackend(t *testing.T, respond func(body string, attempt int) int) (*logBackend, *httptest.Server) {
+	t.Helper()
+	backend := &logBackend{respond: respond}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		backend.mu.Lock()
+		backend.bodies = append(backend.bodies, string(body))
+		attempt := len(backend.bodies)
+		backend.mu.Unlock()
+		w.WriteHeader(backend.respond(string(body), attempt))
+	}))
+	t.Cleanup(server.Close)
+	return backend, server
+}
+
+func (b *logBackend) requests() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.bodies...)
+}
+
+// errorHook ghi lại các lỗi được báo qua OnError (gọi từ goroutine nền hoặc Close)
+type errorHook struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (h *errorHook) report(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errs = append(h.errs, err)
+}
+
+func (h *errorHook) reported() []error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]error(nil), h.errs...)
+}
+
+// newTestHTTPOutput tạo output không tự flush theo thời gian
+// Batch chỉ được gửi khi đủ batchSize (ở goroutine nền) hoặc khi Close
+func newTestHTTPOutput(url string, batchSize, maxRetries int) (*HTTPBatchOutput, *errorHook) {
+	hook := &errorHook{}
+	output := NewHTTPBatchOutput(HTTPBatchOptions{
+		URL:           url,
+		BatchSize:     batchSize,
+		FlushInterval: time.Hour,
+		MaxRetries:    maxRetries,
+		RetryBackoff:  time.Millisecond,
+		OnError:       hook.report,
+	})
+	return output, hook
+}
+
+func writeMessages(t *testing.T, output *HTTPBatchOutput, msgs ...string) {
+	t.Helper()
+	for _, msg := range msgs {
+		entry := &Entry{Time: time.Date(2025, 11, 11, 10, 30, 45, 0, time.UTC), Level: ErrorLevel, Message: msg}
+		if err := output.Write(entry, []byte("msg="+msg)); err != nil {
+			t.Fatalf("Write(%s): %v", msg, err)
+		}
+	}
+}
+
+func TestHTTPBatchOutputFlushOnClose(t *testing.T) {
+	backend, server := newLogBackend(t, func(string, int) int { return http.StatusNoContent })
+	output, hook := newTestHTTPOutput(server.URL, 100, 0)
+
+	writeMessages(t, output, "first", "second", "third")
+	if got := backend.requests(); len(got) != 0 {
+		t.Fatalf("requests trước Close = %d, want 0", len(got))
+	}
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	got := backend.requests()
+	if len(got) != 1 || !strings.Contains(got[0], "msg=first") || !strings.Contains(got[0], "msg=third") {
+		t.Fatalf("requests = %q, want một batch chứa 3 entries", got)
+	}
+	if errs := hook.reported(); len(errs) != 0 {
+		t.Errorf("OnError = %v", errs)
+	}
+
+	// Buffer không còn được gửi sau Close: Write báo lỗi thay vì âm thầm giữ entry
+	err := output.Write(&Entry{Level: ErrorLevel, Message: "late"}, []byte("msg=late"))
+	if !errors.Is(err, ErrOutputClosed) {
+		t.Errorf("Write sau Close = %v, want ErrOutputClosed", err)
+	}
+	if err := output.Close(); err != nil {
+		t.Errorf("Close lần hai = %v", err)
+	}
+}
+
+func TestHTTPBatchOutputRetry(t *testing.T) {
+	// Backend lỗi tạm thời hai lần rồi nhận batch
+	backend, server := newLogBackend(t, func(_ string, attempt int) int {
+		if attempt == 1 {
+			return http.StatusServiceUnavailable
+		}
+		if attempt == 2 {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusNoContent
+	})
+	output, hook := newTestHTTPOutput(server.URL, 100, 3)
+
+	writeMessages(t, output, "first")
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := backend.requests(); len(got) != 3 {
+		t.Errorf("requests = %d, want 3", len(got))
+	}
+	if errs := hook.reported(); len(errs) != 0 {
+		t.Errorf("OnError = %v", errs)
+	}
+}
+
+func TestHTTPBatchOutputDrop(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		// Lỗi tạm thời: gửi lại MaxRetries lần rồi bỏ
+		{"server error", http.StatusInternalServerError, 3},
+		// Payload bị từ chối: gửi lại cũng vô ích
+		{"rejected", http.StatusBadRequest, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Chỉ batch "bad" lỗi: batch sau vẫn được gửi
+			backend, server := newLogBackend(t, func(body string, _ int) int {
+				if strings.Contains(body, "msg=bad") {
+					return tt.status
+				}
+				return http.StatusNoContent
+			})
+			output, hook := newTestHTTPOutput(server.URL, 1, 2)
+
+			// BatchSize 1: batch được gửi ở goroutine nền, Close chờ gửi xong
+			writeMessages(t, output, "bad", "good")
+			output.Close()
+			errs := hook.reported()
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), "drop 1 entries") {
+				t.Errorf("OnError = %v, want drop 1 entries", errs)
+			}
+
+			got := backend.requests()
+			if len(got) != tt.attempts+1 || !strings.Contains(got[len(got)-1], "msg=good") {
+				t.Errorf("requests = %q, want %d lần gửi batch lỗi rồi batch good", got, tt.attempts)
+			}
+		})
+	}
+
+	t.Run("connection refused", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		output, hook := newTestHTTPOutput(server.URL, 100, 1)
+
+		writeMessages(t, output, "first", "second")
+		if err := output.Close(); err == nil || !strings.Contains(err.Error(), "drop 2 entries") {
+			t.Errorf("Close = %v, want drop 2 entries", err)
+		}
+		if errs := hook.reported(); len(errs) != 1 {
+			t.Errorf("OnError = %v, want 1 lỗi", errs)
+		}
+	})
+}
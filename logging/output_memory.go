@@ -0,0 +1,64 @@
+package logging
+
+import "sync"
+
+// ============================================================================
+// Memory Output (dùng trong tests)
+// ============================================================================
+
+// MemoryOutput giữ entries trong bộ nhớ thay vì ghi ra file
+// Dùng trong integration tests để kiểm tra chính xác entry đã được log
+//
+// Example:
+//
+//	memory := logging.NewMemoryOutput()
+//	logging.Init(&logging.Sink{Name: "memory", Output: memory})
+//	// ... gọi code cần test ...
+//	entries := memory.Errors()
+type MemoryOutput struct {
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+// NewMemoryOutput tạo Output lưu entries trong bộ nhớ
+func NewMemoryOutput() *MemoryOutput {
+	return &MemoryOutput{}
+}
+
+// Write implements Output
+func (o *MemoryOutput) Write(e *Entry, encoded []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries = append(o.entries, e)
+	return nil
+}
+
+// Close implements Output
+func (o *MemoryOutput) Close() error {
+	return nil
+}
+
+// Entries trả về tất cả entries đã ghi, theo thứ tự ghi
+func (o *MemoryOutput) Entries() []*Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*Entry(nil), o.entries...)
+}
+
+// Errors trả về các entries ở level error (bỏ qua log khởi tạo, info, ...)
+func (o *MemoryOutput) Errors() []*Entry {
+	var errs []*Entry
+	for _, e := range o.Entries() {
+		if e.Level == ErrorLevel {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
+
+// Reset xóa tất cả entries đã ghi
+func (o *MemoryOutput) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries = nil
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+)
+
+// Syslog facilities thường dùng (RFC 5424)
+const (
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityLocal0 = 16
+)
+
+// SyslogOptions cấu hình cho syslog output
+type SyslogOptions struct {
+	// Socket - Đường dẫn unix socket của syslog daemon (mặc định /dev/log)
+	Socket string
+
+	// Tag - Tên ứng dụng hiển thị trong syslog (mặc định tên process)
+	Tag string
+
+	// Facility - Syslog facility (mặc định FacilityUser)
+	Facility int
+}
+
+// SyslogOutput gửi log tới syslog daemon qua unix datagram socket (RFC 3164)
+// Kết nối được mở lại tự động nếu daemon restart
+type SyslogOutput struct {
+	mu   sync.Mutex
+	opts SyslogOptions
+	conn net.Conn
+}
+
+// NewSyslogOutput tạo syslog output; kết nối được mở ở lần ghi đầu tiên
+func NewSyslogOutput(opts SyslogOptions) *SyslogOutput {
+	if opts.Socket == "" {
+		opts.Socket = "/dev/log"
+	}
+	if opts.Tag == "" {
+		opts.Tag = "fiber_log"
+	}
+	if opts.Facility == 0 {
+		opts.Facility = FacilityUser
+	}
+	return &SyslogOutput{opts: opts}
+}
+
+// Write implements Output
+func (o *SyslogOutput) Write(e *Entry, encoded []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	priority := o.opts.Facility*8 + syslogSeverity(e.Level)
+	msg := fmt.Sprintf("<%d>%s %s[%d]: %s",
+		priority,
+		e.Time.Format("Jan _2 15:04:05"),
+		o.opts.Tag,
+		os.Getpid(),
+		encoded,
+	)
+
+	// Thử gửi lại một lần với kết nối mới nếu kết nối cũ đã hỏng
+	for attempt := 0; attempt < 2; attempt++ {
+		if o.conn == nil {
+			conn, err := net.Dial("unixgram", o.opts.Socket)
+			if err != nil {
+				return fmt.Errorf("connect syslog socket %s: %w", o.opts.Socket, err)
+			}
+			o.conn = conn
+		}
+
+		if _, err := o.conn.Write([]byte(msg)); err != nil {
+			o.conn.Close()
+			o.conn = nil
+			if attempt == 1 {
+				return fmt.Errorf("write syslog: %w", err)
+			}
+			continue
+		}
+		return nil
+	}
+	return nil
+}
+
+// Close implements Output
+func (o *SyslogOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.conn == nil {
+		return nil
+	}
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
+
+// syslogSeverity map Level sang syslog severity
+func syslogSeverity(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 7 // debug
+	case InfoLevel:
+		return 6 // informational
+	case WarnLevel:
+		return 4 // warning
+	default:
+		return 3 // err
+	}
+}
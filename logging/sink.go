@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// Output là đích ghi log đã được encode (console, file, syslog, HTTP, ...)
+// Output nhận cả Entry gốc để có thể dùng metadata (level, thời gian) khi ghi,
+// ví dụ syslog cần level để tính priority
+type Output interface {
+	// Write ghi một entry đã được encode
+	Write(e *Entry, encoded []byte) error
+
+	// Close flush dữ liệu còn trong buffer và giải phóng tài nguyên
+	Close() error
+}
+
+// Sink gắn một Output với encoder và bộ lọc riêng
+//
+// Example:
+//
+//	// Chỉ ghi lỗi SYSTEM và PANIC vào file cảnh báo
+//	&logging.Sink{
+//	    Name:       "alerts",
+//	    Output:     logging.NewFileOutput(logging.FileOptions{Path: "logs/alerts.log"}),
+//	    Encoder:    logging.JSONEncoder{},
+//	    MinLevel:   logging.ErrorLevel,
+//	    ErrorTypes: []goerrorkit.ErrorType{goerrorkit.SystemError, goerrorkit.PanicError},
+//	}
+type Sink struct {
+	// Name - Tên sink, dùng khi báo lỗi ghi log
+	Name string
+
+	// Output - Đích ghi log
+	Output Output
+
+	// Encoder - Format của entry (mặc định JSONEncoder)
+	Encoder Encoder
+
+	// MinLevel - Level tối thiểu để ghi
+	MinLevel Level
+
+	// ErrorTypes - Chỉ ghi các loại lỗi này
+	// Nếu empty, ghi tất cả entries (kể cả log không phải lỗi)
+	ErrorTypes []goerrorkit.ErrorType
+}
+
+// Accepts kiểm tra entry có thỏa bộ lọc của sink hay không
+func (s *Sink) Accepts(e *Entry) bool {
+	if e.Level < s.MinLevel {
+		return false
+	}
+
+	if len(s.ErrorTypes) == 0 {
+		return true
+	}
+
+	errorType := e.ErrorType()
+	for _, t := range s.ErrorTypes {
+		if t == errorType {
+			return true
+		}
+	}
+	return false
+}
+
+// encoder trả về encoder của sink, mặc định là JSON một dòng
+func (s *Sink) encoder() Encoder {
+	if s.Encoder == nil {
+		return JSONEncoder{}
+	}
+	return s.Encoder
+}
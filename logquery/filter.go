@@ -0,0 +1,137 @@
+package logquery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"fiber_log/logging"
+)
+
+// Filter là bộ lọc entries theo kiểu grep
+// Các điều kiện được AND với nhau; trường rỗng/zero nghĩa là không lọc
+type Filter struct {
+	// Types - Loại lỗi (BUSINESS, PANIC, ...), không phân biệt hoa thường
+	Types []string
+
+	// Status - Status code cụ thể ("404") hoặc nhóm ("4xx", "5xx")
+	Status []string
+
+	// Location - Chuỗi con của location (ví dụ "CheckStock" hoặc "product_service.go")
+	Location string
+
+	// RequestID - Request ID chính xác
+	RequestID string
+
+	// Since, Until - Khoảng thời gian [Since, Until)
+	Since time.Time
+	Until time.Time
+
+	// Message - Regex áp dụng lên message
+	Message *regexp.Regexp
+
+	// ErrorsOnly - Bỏ qua entries không phải lỗi (ví dụ log khởi tạo)
+	ErrorsOnly bool
+}
+
+// Match kiểm tra entry có thỏa tất cả điều kiện của filter không
+func (f *Filter) Match(e *logging.Entry) bool {
+	if f.ErrorsOnly && e.ErrorType() == "" {
+		return false
+	}
+
+	if len(f.Types) > 0 && !containsFold(f.Types, string(e.ErrorType())) {
+		return false
+	}
+
+	if len(f.Status) > 0 && !matchStatus(f.Status, e.StatusCode()) {
+		return false
+	}
+
+	if f.Location != "" && !strings.Contains(e.Location(), f.Location) {
+		return false
+	}
+
+	if f.RequestID != "" && e.RequestID() != f.RequestID {
+		return false
+	}
+
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+
+	if f.Message != nil && !f.Message.MatchString(e.Message) {
+		return false
+	}
+
+	return true
+}
+
+// Apply trả về các entries thỏa filter
+func (f *Filter) Apply(entries []*logging.Entry) []*logging.Entry {
+	var result []*logging.Entry
+	for _, e := range entries {
+		if f.Match(e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// ParseTime parse thời điểm dạng RFC3339, "2006-01-02 15:04", "2006-01-02"
+// hoặc khoảng thời gian tương đối so với now ("30m", "2h", "7d")
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339, YYYY-MM-DD[ HH:MM] or a duration like 2h, 7d", value)
+}
+
+// matchStatus kiểm tra status code theo danh sách "404", "4xx", ...
+func matchStatus(patterns []string, status int) bool {
+	code := strconv.Itoa(status)
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if len(p) == 3 && strings.HasSuffix(p, "xx") {
+			if status != 0 && code[0] == p[0] {
+				return true
+			}
+			continue
+		}
+		if p == code {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold kiểm tra value có trong list không (không phân biệt hoa thường)
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
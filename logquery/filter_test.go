@@ -0,0 +1,120 @@
+package logquery
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"fiber_log/logging"
+)
+
+var (
+	notFound = &logging.Entry{
+		Time:    time.Date(2025, 11, 11, 10, 0, 0, 0, time.UTC),
+		Level:   logging.ErrorLevel,
+		Message: "Không tìm thấy sản phẩm",
+		Fields: map[string]interface{}{
+			"error_type":  "BUSINESS",
+			"status_code": 404,
+			"location":    "services/product_service.go:GetProduct:42",
+			"request_id":  "req-1",
+		},
+	}
+	crash = &logging.Entry{
+		Time:    time.Date(2025, 11, 11, 11, 0, 0, 0, time.UTC),
+		Level:   logging.ErrorLevel,
+		Message: "runtime error: index out of range",
+		Fields: map[string]interface{}{
+			"error_type":  "PANIC",
+			"status_code": 500,
+			"location":    "panic_handlers.go:panicIndexHandler:30",
+			"request_id":  "req-2",
+		},
+	}
+	// Lỗi của background job: không có status code
+	jobFailed = &logging.Entry{
+		Time:    time.Date(2025, 11, 11, 12, 0, 0, 0, time.UTC),
+		Level:   logging.ErrorLevel,
+		Message: "Job thất bại",
+		Fields:  map[string]interface{}{"error_type": "SYSTEM"},
+	}
+	started = &logging.Entry{
+		Time:    time.Date(2025, 11, 11, 9, 0, 0, 0, time.UTC),
+		Level:   logging.InfoLevel,
+		Message: "Server started",
+	}
+)
+
+func TestFilterMatch(t *testing.T) {
+	entries := []*logging.Entry{started, notFound, crash, jobFailed}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []*logging.Entry
+	}{
+		{"no filter", Filter{}, entries},
+		{"errors only", Filter{ErrorsOnly: true}, []*logging.Entry{notFound, crash, jobFailed}},
+		{"types case-insensitive", Filter{Types: []string{"panic", " Business "}}, []*logging.Entry{notFound, crash}},
+		{"status exact", Filter{Status: []string{"404"}}, []*logging.Entry{notFound}},
+		{"status class", Filter{Status: []string{"5XX"}}, []*logging.Entry{crash}},
+		{"status list", Filter{Status: []string{"4xx", "500"}}, []*logging.Entry{notFound, crash}},
+		// Entry không có status code không thuộc nhóm nào, kể cả "0xx"
+		{"status missing", Filter{Status: []string{"0xx"}}, nil},
+		{"location file", Filter{Location: "product_service.go"}, []*logging.Entry{notFound}},
+		{"location function", Filter{Location: "panicIndexHandler"}, []*logging.Entry{crash}},
+		{"request id", Filter{RequestID: "req-2"}, []*logging.Entry{crash}},
+		{"request id exact", Filter{RequestID: "req"}, nil},
+		// [Since, Until): Since được tính, Until không
+		{"since", Filter{Since: crash.Time}, []*logging.Entry{crash, jobFailed}},
+		{"until", Filter{Until: crash.Time}, []*logging.Entry{started, notFound}},
+		{"since until", Filter{Since: notFound.Time, Until: jobFailed.Time}, []*logging.Entry{notFound, crash}},
+		{"message", Filter{Message: regexp.MustCompile(`(?i)^runtime error`)}, []*logging.Entry{crash}},
+		{"combined", Filter{Types: []string{"BUSINESS", "PANIC"}, Status: []string{"5xx"}, RequestID: "req-1"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Apply(entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply = %v, want %v", messages(got), messages(tt.want))
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 11, 11, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+		err   bool
+	}{
+		{"", time.Time{}, false},
+		{"30m", now.Add(-30 * time.Minute), false},
+		{" 2h ", now.Add(-2 * time.Hour), false},
+		{"7d", now.AddDate(0, 0, -7), false},
+		{"2025-11-11T08:00:00+07:00", time.Date(2025, 11, 11, 1, 0, 0, 0, time.UTC), false},
+		{"2025-11-10 08:15:30", time.Date(2025, 11, 10, 8, 15, 30, 0, time.Local), false},
+		{"2025-11-10 08:15", time.Date(2025, 11, 10, 8, 15, 0, 0, time.Local), false},
+		{"2025-11-10", time.Date(2025, 11, 10, 0, 0, 0, 0, time.Local), false},
+		{"yesterday", time.Time{}, true},
+		{"7days", time.Time{}, true},
+		{"2025-13-01", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if tt.err {
+				if err == nil || !strings.Contains(err.Error(), "invalid time") {
+					t.Errorf("ParseTime(%q) err = %v, want invalid time", tt.value, err)
+				}
+				return
+			}
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package logquery
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fiber_log/logging"
+)
+
+// backupTimeFormat là format timestamp trong tên file backup của lumberjack
+// Ví dụ: errors-2025-11-11T10-30-45.000.log.gz
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// logFile là một file log (file hiện tại hoặc backup) cùng thời điểm rotate
+type logFile struct {
+	path      string
+	rotatedAt time.Time
+}
+
+// Files trả về file log hiện tại và các file backup đã rotate (kể cả .gz),
+// sắp xếp từ cũ nhất đến mới nhất để đọc theo thứ tự thời gian
+func Files(path string) ([]string, error) {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read log directory: %w", err)
+	}
+
+	var backups []logFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimPrefix(name, prefix)
+		stamp = strings.TrimSuffix(stamp, ".gz")
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		rotatedAt, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(stamp, ext), time.Local)
+		if err != nil {
+			continue // Không phải backup của lumberjack
+		}
+		backups = append(backups, logFile{path: filepath.Join(dir, name), rotatedAt: rotatedAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotatedAt.Before(backups[j].rotatedAt)
+	})
+
+	files := make([]string, 0, len(backups)+1)
+	for _, b := range backups {
+		files = append(files, b.path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no log files found for %s", path)
+	}
+	return files, nil
+}
+
+// Skipped là một entry không decode được (JSON hỏng), bị bỏ qua khi đọc log
+type Skipped struct {
+	File   string // File log chứa entry, rỗng nếu đọc trực tiếp bằng Decode
+	Offset int64  // Vị trí byte đầu entry (trong nội dung đã giải nén với file .gz)
+	Err    error
+}
+
+// String trả về mô tả dạng "logs/errors.log: byte 19: invalid character ..."
+func (s Skipped) String() string {
+	if s.File == "" {
+		return fmt.Sprintf("byte %d: %v", s.Offset, s.Err)
+	}
+	return fmt.Sprintf("%s: byte %d: %v", s.File, s.Offset, s.Err)
+}
+
+// ReadAll đọc toàn bộ entries từ file log và các backup
+// Entry hỏng được bỏ qua và trả về trong skipped; chỉ lỗi I/O (mở, đọc, giải nén file) mới trả về error
+func ReadAll(path string) (entries []*logging.Entry, skipped []Skipped, err error) {
+	files, err := Files(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, file := range files {
+		err := ReadFile(file, func(e *logging.Entry) error {
+			entries = append(entries, e)
+			return nil
+		}, func(s Skipped) {
+			skipped = append(skipped, s)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return entries, skipped, nil
+}
+
+// ReadFile đọc từng entry trong một file log (tự giải nén nếu là .gz)
+// File có thể chứa JSON một dòng hoặc JSON pretty-print nhiều dòng
+// skip (có thể nil) được gọi với mỗi entry hỏng bị bỏ qua
+func ReadFile(path string, fn func(*logging.Entry) error, skip func(Skipped)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("open gzip log file %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	_, err = Decode(r, fn, func(s Skipped) {
+		if skip != nil {
+			s.File = path
+			skip(s)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	return nil
+}
+
+// Decode đọc các JSON entries liên tiếp từ r
+// Trả về số bytes đã xử lý xong để caller (tail -f) tiếp tục từ vị trí đó:
+// entry cuối chưa ghi xong (thiếu dấu đóng) không được tính, lần đọc sau sẽ decode lại
+//
+// Entry hỏng (JSON sai cú pháp, không phải object) được báo qua skip (có thể nil) rồi bỏ qua tới
+// dòng tiếp theo bắt đầu bằng "{" (entry mới, kể cả khi file dùng JSON pretty-print nhiều dòng).
+// Chỉ lỗi đọc r và lỗi từ fn mới dừng việc đọc
+func Decode(r io.Reader, fn func(*logging.Entry) error, skip func(Skipped)) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	for {
+		decoder := json.NewDecoder(bytes.NewReader(data[offset:]))
+		decoder.UseNumber()
+		start := offset
+
+		var err error
+		for {
+			var raw map[string]interface{}
+			if err = decoder.Decode(&raw); err != nil {
+				break
+			}
+			offset = start + decoder.InputOffset()
+
+			if err := fn(FromJSON(raw)); err != nil {
+				return offset, err
+			}
+		}
+
+		bad := offset + int64(len(data[offset:])-len(bytes.TrimLeft(data[offset:], " \t\r\n")))
+		next := bytes.Index(data[bad:], []byte("\n{"))
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) && next < 0 {
+			return offset, nil
+		}
+
+		// Entry hỏng (hoặc entry dở dang nhưng sau đó đã có entry mới): bỏ qua tới đầu entry tiếp theo
+		if skip != nil {
+			skip(Skipped{Offset: bad, Err: err})
+		}
+		if next < 0 {
+			return int64(len(data)), nil
+		}
+		offset = bad + int64(next) + 1
+	}
+}
+
+// FromJSON chuyển một JSON entry (format của logging.JSONEncoder / goerrorkit) thành logging.Entry
+func FromJSON(raw map[string]interface{}) *logging.Entry {
+	entry := &logging.Entry{Fields: make(map[string]interface{}, len(raw))}
+
+	for k, v := range raw {
+		switch k {
+		case "timestamp":
+			if s, ok := v.(string); ok {
+				entry.Time, _ = time.Parse(time.RFC3339, s)
+			}
+		case "level":
+			entry.Level = logging.ParseLevel(fmt.Sprint(v))
+		case "message":
+			entry.Message = fmt.Sprint(v)
+		default:
+			entry.Fields[k] = normalizeNumber(v)
+		}
+	}
+	return entry
+}
+
+// normalizeNumber chuyển json.Number thành int hoặc float64 (đệ quy với map/slice)
+func normalizeNumber(v interface{}) interface{} {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = normalizeNumber(item)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeNumber(item)
+		}
+		return value
+	}
+	return v
+}
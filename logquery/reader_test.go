@@ -0,0 +1,197 @@
+package logquery
+
+import (
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"fiber_log/logging"
+)
+
+// writeFile ghi content vào dir/name, nén gzip nếu name kết thúc bằng .gz
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(name, ".gz") {
+		gz := gzip.NewWriter(file)
+		defer gz.Close()
+		if _, err := gz.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// messages trả về message của từng entry
+func messages(entries []*logging.Entry) []string {
+	result := make([]string, len(entries))
+	for i, e := range entries {
+		result[i] = e.Message
+	}
+	return result
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	current := writeFile(t, dir, "errors.log", "")
+	newer := writeFile(t, dir, "errors-2025-11-11T10-30-45.000.log.gz", "")
+	older := writeFile(t, dir, "errors-2025-11-10T09-00-00.000.log", "")
+
+	// Không phải backup của errors.log
+	writeFile(t, dir, "errors-latest.log", "")
+	writeFile(t, dir, "errors-2025-11-12T00-00-00.000.json", "")
+	writeFile(t, dir, "validation-2025-11-11T10-30-45.000.log", "")
+	writeFile(t, dir, "errors.log.bak", "")
+	if err := os.Mkdir(filepath.Join(dir, "errors-2025-11-13T00-00-00.000.log"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Files(current)
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	if want := []string{older, newer, current}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Files = %v, want %v", files, want)
+	}
+
+	// File hiện tại đã bị xóa: vẫn đọc được các backup
+	os.Remove(current)
+	if files, err := Files(current); err != nil || !reflect.DeepEqual(files, []string{older, newer}) {
+		t.Errorf("Files (không có file hiện tại) = %v, %v", files, err)
+	}
+
+	if _, err := Files(filepath.Join(dir, "alerts.log")); err == nil {
+		t.Error("Files(alerts.log) = nil error, want no log files found")
+	}
+	if _, err := Files(filepath.Join(dir, "missing", "errors.log")); err == nil {
+		t.Error("Files(missing dir) = nil error")
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	dir := t.TempDir()
+	// Backup có một dòng hỏng: chỉ dòng đó bị bỏ qua
+	backupEntry := `{"timestamp":"2025-11-11T09:00:00Z","level":"error","message":"backup","error_type":"BUSINESS","status_code":404}` + "\n"
+	gzPath := writeFile(t, dir, "errors-2025-11-11T10-00-00.000.log.gz", backupEntry+"{\"message\": oops}\n")
+
+	// JSON pretty-print nhiều dòng (như logs/errors.log khi bật Pretty) xen với JSON một dòng
+	current := writeFile(t, dir, "errors.log", `{
+  "timestamp": "2025-11-11T10:30:45+07:00",
+  "level": "error",
+  "message": "pretty",
+  "error_type": "PANIC",
+  "status_code": 500,
+  "data": {"amount_minor": 2000000, "rate": 0.5, "items": [1, 2.5]}
+}
+{"timestamp":"2025-11-11T11:00:00Z","level":"warn","message":"compact"}
+`)
+
+	entries, skipped, err := ReadAll(current)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if got := messages(entries); !reflect.DeepEqual(got, []string{"backup", "pretty", "compact"}) {
+		t.Fatalf("messages = %v", got)
+	}
+	if len(skipped) != 1 || skipped[0].File != gzPath || skipped[0].Offset != int64(len(backupEntry)) {
+		t.Errorf("skipped = %v, want %s byte %d", skipped, gzPath, len(backupEntry))
+	}
+
+	backup, pretty, compact := entries[0], entries[1], entries[2]
+	if backup.StatusCode() != 404 || backup.ErrorType() != "BUSINESS" || backup.Level != logging.ErrorLevel {
+		t.Errorf("backup = %+v", backup)
+	}
+	if want := time.Date(2025, 11, 11, 3, 30, 45, 0, time.UTC); !pretty.Time.Equal(want) {
+		t.Errorf("pretty.Time = %v, want %v", pretty.Time, want)
+	}
+	// Số trong JSON được đổi về int / float64 giống entry ghi trực tiếp, kể cả trong map và slice
+	wantData := map[string]interface{}{"amount_minor": 2000000, "rate": 0.5, "items": []interface{}{1, 2.5}}
+	if !reflect.DeepEqual(pretty.Data(), wantData) {
+		t.Errorf("pretty.Data() = %#v, want %#v", pretty.Data(), wantData)
+	}
+	if compact.Level != logging.WarnLevel || compact.ErrorType() != "" {
+		t.Errorf("compact = %+v", compact)
+	}
+
+	// Backup .gz hỏng là lỗi I/O: dừng và ghi rõ tên file
+	if err := os.WriteFile(filepath.Join(dir, "errors-2025-11-11T10-15-00.000.log.gz"), []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadAll(current); err == nil || !strings.Contains(err.Error(), "errors-2025-11-11T10-15-00.000.log.gz") {
+		t.Errorf("ReadAll (gzip hỏng) = %v", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	first := `{"message":"first"}` + "\n"
+	second := `{"message":"second"}` + "\n"
+	pretty := "{\n  \"message\": \"pretty\"\n}\n"
+
+	tests := []struct {
+		name     string
+		input    string
+		messages []string
+		offset   int64
+		skipped  []int64
+	}{
+		{"empty", "", []string{}, 0, nil},
+		{"entries", first + second, []string{"first", "second"}, int64(len(first+second)) - 1, nil},
+		// Entry cuối đang được ghi dở (tail -f): dừng lại ở entry đầy đủ cuối cùng, đọc lại ở lần sau
+		{"partial trailing entry", first + `{"message":"sec`, []string{"first"}, int64(len(first)) - 1, nil},
+		// Dòng hỏng: bỏ qua dòng đó, vẫn đọc các entries sau
+		{"malformed line", first + "not json\n" + second, []string{"first", "second"}, int64(len(first+"not json\n"+second)) - 1, []int64{20}},
+		{"not an object", first + `["array"]` + "\n" + second, []string{"first", "second"}, int64(len(first+`["array"]`+"\n"+second)) - 1, []int64{20}},
+		// Entry bị cắt giữa chừng (crash khi đang ghi) rồi process mới ghi tiếp: không chặn các entries sau
+		{"truncated entry", `{"message":"cut` + "\n" + pretty, []string{"pretty"}, int64(len(`{"message":"cut`+"\n"+pretty)) - 1, []int64{0}},
+		{"unclosed entry", `{"data":[1,` + "\n" + second, []string{"second"}, int64(len(`{"data":[1,`+"\n"+second)) - 1, []int64{0}},
+		// Dòng hỏng cuối cùng đã ghi xong: bỏ qua tới hết dữ liệu để tail -f không báo lại
+		{"malformed last line", first + "not json\n", []string{"first"}, int64(len(first + "not json\n")), []int64{20}},
+		// Dòng hỏng giữa entry pretty-print: bỏ qua cả entry, tiếp tục từ dòng bắt đầu bằng "{"
+		{"malformed pretty entry", "{\n  \"message\": oops\n}\n" + pretty, []string{"pretty"}, int64(len("{\n  \"message\": oops\n}\n"+pretty)) - 1, []int64{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			var skipped []int64
+			offset, err := Decode(strings.NewReader(tt.input), func(e *logging.Entry) error {
+				got = append(got, e.Message)
+				return nil
+			}, func(s Skipped) {
+				if s.Err == nil {
+					t.Errorf("Skipped at byte %d without error", s.Offset)
+				}
+				skipped = append(skipped, s.Offset)
+			})
+			if err != nil {
+				t.Errorf("err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.messages) || offset != tt.offset {
+				t.Errorf("messages = %v, offset = %d, want %v, %d", got, offset, tt.messages, tt.offset)
+			}
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.skipped)
+			}
+		})
+	}
+
+	// Chỉ lỗi từ callback mới dừng việc đọc
+	stop := errors.New("stop")
+	if _, err := Decode(strings.NewReader(first+second), func(*logging.Entry) error { return stop }, nil); err != stop {
+		t.Errorf("Decode = %v, want lỗi từ callback", err)
+	}
+}
@@ -0,0 +1,90 @@
+package logquery
+
+import (
+	"sort"
+	"time"
+
+	"fiber_log/logging"
+)
+
+// LocationCount là số lần lỗi xảy ra tại một location
+type LocationCount struct {
+	Location string `json:"location"`
+	Count    int    `json:"count"`
+}
+
+// HourBucket là số lỗi theo từng loại trong một giờ
+type HourBucket struct {
+	Hour   time.Time      `json:"hour"`
+	Counts map[string]int `json:"counts"`
+	Total  int            `json:"total"`
+}
+
+// Stats là thống kê tổng hợp trên một tập entries lỗi
+type Stats struct {
+	Total        int             `json:"total"`
+	ByType       map[string]int  `json:"by_type"`
+	TopLocations []LocationCount `json:"top_locations"`
+	PerHour      []HourBucket    `json:"per_hour"`
+}
+
+// startOfHour trả về đầu giờ của t theo múi giờ của t
+// Không dùng t.Truncate(time.Hour): Truncate làm tròn theo UTC nên sai với múi giờ lệch
+// nửa giờ (+05:30, +09:30, ...)
+func startOfHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// ComputeStats thống kê entries lỗi: tổng số, theo loại, top locations và số lỗi mỗi giờ
+// Entries không phải lỗi (không có error_type) bị bỏ qua
+func ComputeStats(entries []*logging.Entry, top int) *Stats {
+	stats := &Stats{ByType: make(map[string]int)}
+	locations := make(map[string]int)
+	hours := make(map[time.Time]*HourBucket)
+
+	for _, e := range entries {
+		errorType := string(e.ErrorType())
+		if errorType == "" {
+			continue
+		}
+
+		stats.Total++
+		stats.ByType[errorType]++
+
+		if location := e.Location(); location != "" {
+			locations[location]++
+		}
+
+		hour := startOfHour(e.Time.Local())
+		bucket, ok := hours[hour]
+		if !ok {
+			bucket = &HourBucket{Hour: hour, Counts: make(map[string]int)}
+			hours[hour] = bucket
+		}
+		bucket.Counts[errorType]++
+		bucket.Total++
+	}
+
+	for location, count := range locations {
+		stats.TopLocations = append(stats.TopLocations, LocationCount{Location: location, Count: count})
+	}
+	sort.Slice(stats.TopLocations, func(i, j int) bool {
+		a, b := stats.TopLocations[i], stats.TopLocations[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Location < b.Location
+	})
+	if top > 0 && len(stats.TopLocations) > top {
+		stats.TopLocations = stats.TopLocations[:top]
+	}
+
+	for _, bucket := range hours {
+		stats.PerHour = append(stats.PerHour, *bucket)
+	}
+	sort.Slice(stats.PerHour, func(i, j int) bool {
+		return stats.PerHour[i].Hour.Before(stats.PerHour[j].Hour)
+	})
+
+	return stats
+}
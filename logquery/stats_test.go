@@ -0,0 +1,58 @@
+package logquery
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"fiber_log/logging"
+)
+
+func TestComputeStats(t *testing.T) {
+	stats := ComputeStats([]*logging.Entry{started, notFound, crash, notFound, jobFailed}, 1)
+
+	if stats.Total != 4 {
+		t.Errorf("Total = %d, want 4 (bỏ qua entry không phải lỗi)", stats.Total)
+	}
+	if want := map[string]int{"BUSINESS": 2, "PANIC": 1, "SYSTEM": 1}; !reflect.DeepEqual(stats.ByType, want) {
+		t.Errorf("ByType = %v, want %v", stats.ByType, want)
+	}
+	if want := []LocationCount{{Location: "services/product_service.go:GetProduct:42", Count: 2}}; !reflect.DeepEqual(stats.TopLocations, want) {
+		t.Errorf("TopLocations = %v, want %v", stats.TopLocations, want)
+	}
+	if len(stats.PerHour) != 3 || stats.PerHour[0].Total != 2 || !stats.PerHour[0].Hour.Before(stats.PerHour[1].Hour) {
+		t.Errorf("PerHour = %+v", stats.PerHour)
+	}
+}
+
+// Múi giờ lệch nửa giờ: bucket bắt đầu ở đầu giờ địa phương, không phải đầu giờ UTC
+func TestComputeStatsHalfHourZone(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("IST", 5*60*60+30*60)
+	defer func() { time.Local = local }()
+
+	at := func(hour, minute int) *logging.Entry {
+		return &logging.Entry{
+			Time:   time.Date(2025, 11, 11, hour, minute, 0, 0, time.UTC),
+			Fields: map[string]interface{}{"error_type": "BUSINESS"},
+		}
+	}
+	// 04:20 và 04:40 UTC là 09:50 và 10:10 IST: hai giờ khác nhau
+	stats := ComputeStats([]*logging.Entry{at(4, 20), at(4, 40), at(4, 45)}, 0)
+
+	want := []time.Time{
+		time.Date(2025, 11, 11, 9, 0, 0, 0, time.Local),
+		time.Date(2025, 11, 11, 10, 0, 0, 0, time.Local),
+	}
+	if len(stats.PerHour) != len(want) {
+		t.Fatalf("PerHour = %+v, want %d buckets", stats.PerHour, len(want))
+	}
+	for i, bucket := range stats.PerHour {
+		if !bucket.Hour.Equal(want[i]) {
+			t.Errorf("PerHour[%d].Hour = %v, want %v", i, bucket.Hour, want[i])
+		}
+	}
+	if stats.PerHour[0].Total != 1 || stats.PerHour[1].Total != 2 {
+		t.Errorf("totals = %d, %d, want 1, 2", stats.PerHour[0].Total, stats.PerHour[1].Total)
+	}
+}
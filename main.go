@@ -1,11 +1,28 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strconv"
-
+	"strings"
+
+	"fiber_log/audit"
+	"fiber_log/config"
+	"fiber_log/crash"
+	"fiber_log/errhandler"
+	"fiber_log/jobs"
+	"fiber_log/logging"
+	"fiber_log/safego"
 	"fiber_log/services"
+	"fiber_log/sourceview"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -17,26 +34,35 @@ import (
 // Global Variables
 // ============================================================================
 var (
-	homeTemplate   *template.Template
-	productService *services.ProductService
-	orderService   *services.OrderService
+	homeTemplate       *template.Template
+	devErrorsTemplate  *template.Template
+	adminJobsTemplate  *template.Template
+	productService     *services.ProductService
+	orderService       *services.OrderService
+	reservationService *services.ReservationService
+	jobRunner          *jobs.Runner
+	couponService      *services.CouponService
+	userService        *services.UserService
+	paymentWebhooks    *services.PaymentWebhooks
+	fulfillmentService *services.FulfillmentService
+	eventLog           *audit.Log
+	appConfig          config.Config
+	appLogger          *logging.Logger
+	crashStore         *crash.Store
+	sourceResolver     *sourceview.Resolver
 )
 
-// init khởi tạo logger và templates
-func init() {
-	// 1. Initialize logger với custom options
-	goerrorkit.InitLogger(goerrorkit.LoggerOptions{
-		ConsoleOutput: true,
-		FileOutput:    true,
-		FilePath:      "logs/errors.log",
-		JSONFormat:    true,
-		MaxFileSize:   10, // MB
-		MaxBackups:    5,
-		MaxAge:        30, // days
-		LogLevel:      "info",
-	})
+// errorLogPath trả về file log chính, được đọc lại bởi fiberlog và trang /dev/errors
+func errorLogPath() string {
+	return filepath.Join(appConfig.LogDir, "errors.log")
+}
 
-	// 2. Configure stack trace for this application
+// setup khởi tạo stack trace config, templates, services với cấu hình cfg
+// Không tạo logger và không mở port, nên tests có thể dựng app với logger riêng (xem newApp)
+func setup(cfg config.Config) {
+	appConfig = cfg
+
+	// Configure stack trace for this application
 	// 🎯 MỤC ĐÍCH: Lọc stack trace để CHỈ HIỂN THỊ code của BẠN, bỏ qua:
 	//    - Go runtime code (runtime.*, runtime/debug.*)
 	//    - Thư viện bên thứ 3 (fiber, goerrorkit, etc.)
@@ -71,12 +97,176 @@ func init() {
 
 	initTemplates()
 	initServices()
+	initCrashStore()
+	initSourceResolver()
+}
+
+// initLogger khởi tạo logger với nhiều sinks thay cho goerrorkit.InitLogger
+//
+// 📊 CÁC SINKS:
+//   - console:    Tất cả log (JSON) ra stdout
+//   - file:       Tất cả log vào <LOG_DIR>/errors.log (rotate theo kích thước)
+//   - validation: Chỉ VALIDATION errors vào <LOG_DIR>/validation.log (ưu tiên thấp)
+//   - alerts:     Chỉ SYSTEM + PANIC errors vào <LOG_DIR>/alerts/alerts-YYYY-MM-DD.log (rotate theo ngày)
+//   - http:       SYSTEM + PANIC errors gửi theo batch tới Loki (logfmt) hoặc Elasticsearch (ECS) nếu có LOG_HTTP_URL
+//   - syslog:     Tất cả errors (logfmt) gửi tới syslog daemon nếu có LOG_SYSLOG_SOCKET
+func initLogger() {
+	alertTypes := []goerrorkit.ErrorType{goerrorkit.SystemError, goerrorkit.PanicError}
+
+	sinks := []*logging.Sink{
+		{
+			Name:     "console",
+			Output:   logging.NewConsoleOutput(os.Stdout),
+			Encoder:  logging.JSONEncoder{Pretty: true},
+			MinLevel: logging.InfoLevel,
+		},
+		{
+			Name: "file",
+			Output: logging.NewFileOutput(logging.FileOptions{
+				Path:        errorLogPath(),
+				MaxFileSize: 10, // MB
+				MaxBackups:  5,
+				MaxAge:      30, // days
+			}),
+			Encoder:  logging.JSONEncoder{Pretty: true},
+			MinLevel: logging.InfoLevel,
+		},
+		{
+			Name: "validation",
+			Output: logging.NewFileOutput(logging.FileOptions{
+				Path:        filepath.Join(appConfig.LogDir, "validation.log"),
+				MaxFileSize: 5, // MB
+				MaxBackups:  2,
+				MaxAge:      7, // days
+			}),
+			Encoder:    logging.JSONEncoder{},
+			MinLevel:   logging.ErrorLevel,
+			ErrorTypes: []goerrorkit.ErrorType{goerrorkit.ValidationError},
+		},
+		{
+			Name:       "alerts",
+			Output:     logging.NewDailyFileOutput(filepath.Join(appConfig.LogDir, "alerts"), "alerts", 30),
+			Encoder:    logging.JSONEncoder{},
+			MinLevel:   logging.ErrorLevel,
+			ErrorTypes: alertTypes,
+		},
+	}
+
+	// HTTP batch sink: LOG_HTTP_URL=http://localhost:3100/loki/api/v1/push
+	// LOG_HTTP_FORMAT=elasticsearch để gửi theo Elasticsearch bulk API (encode theo ECS),
+	// mặc định Loki (encode theo logfmt)
+	if url := appConfig.LogHTTPURL; url != "" {
+		format := logging.HTTPFormat(appConfig.LogHTTPFormat)
+		var encoder logging.Encoder = logging.LogfmtEncoder{}
+		if format == logging.HTTPFormatElasticsearch {
+			encoder = logging.ECSEncoder{ServiceName: "fiber_log"}
+		}
+
+		sinks = append(sinks, &logging.Sink{
+			Name: "http",
+			Output: logging.NewHTTPBatchOutput(logging.HTTPBatchOptions{
+				URL:    url,
+				Format: format,
+				Labels: map[string]string{"app": "fiber_log"},
+			}),
+			Encoder:    encoder,
+			MinLevel:   logging.ErrorLevel,
+			ErrorTypes: alertTypes,
+		})
+	}
+
+	// Syslog sink: LOG_SYSLOG_SOCKET=/dev/log
+	if socket := appConfig.LogSyslogSocket; socket != "" {
+		sinks = append(sinks, &logging.Sink{
+			Name:     "syslog",
+			Output:   logging.NewSyslogOutput(logging.SyslogOptions{Socket: socket, Tag: "fiber_log"}),
+			Encoder:  logging.LogfmtEncoder{},
+			MinLevel: logging.ErrorLevel,
+		})
+	}
+
+	appLogger = logging.Init(sinks...)
+}
+
+// initCrashStore khởi tạo nơi lưu crash bundles (tắt bằng CRASH_BUNDLES=false)
+func initCrashStore() {
+	if !appConfig.CrashBundles {
+		return
+	}
+	crashStore = crash.NewStore(crash.Options{
+		Dir:          appConfig.CrashDir,
+		MaxBundles:   appConfig.CrashMaxBundles,
+		MaxBodyBytes: appConfig.CrashMaxBodyBytes,
+		AppConfig:    appConfig.Redacted(),
+	})
+}
+
+// initSourceResolver khởi tạo resolver source code cho trang /dev/errors (chỉ khi development)
+func initSourceResolver() {
+	if !appConfig.IsDevelopment() {
+		return
+	}
+	sourceResolver = sourceview.NewResolver(appConfig.SourceRoot)
 }
 
 // initServices khởi tạo business services
+// Sản phẩm được đọc từ PRODUCTS_FILE nếu file tồn tại, ngược lại dùng sản phẩm mẫu
+// File sản phẩm chỉ được đọc, không được ghi lại (chỉ cmd/products ghi file), xem config.Config.ProductsFile
+// User được đọc từ USERS_FILE (nếu file chưa tồn tại thì dùng user mẫu), mọi thay đổi được ghi lại vào file
+// Event log được ghi thêm vào EVENT_LOG_FILE nếu có
+// Shipping / notification là simulator chạy local, lỗi giả lập đọc từ SHIPPING_SIM_FAILURES, NOTIFICATION_SIM_FAILURES
 func initServices() {
 	productService = services.NewProductService()
-	orderService = services.NewOrderService(productService)
+	if appConfig.ProductsFile != "" {
+		products, err := services.LoadProducts(appConfig.ProductsFile)
+		switch {
+		case err == nil:
+			productService = services.NewProductServiceWith(products)
+		case !errors.Is(err, fs.ErrNotExist):
+			panic(fmt.Sprintf("Failed to load products: %v", err))
+		}
+	}
+	reservationService = services.NewReservationService(productService, appConfig.ReservationTTL)
+	orderService = services.NewOrderServiceWith(productService, reservationService, appConfig.RefundWindow)
+	couponService = services.NewCouponService(productService)
+	paymentWebhooks = services.NewPaymentWebhooks(orderService, appConfig.PaymentWebhookSecret, appConfig.PaymentWebhookTolerance)
+
+	userService = services.NewUserService()
+	if appConfig.UsersFile != "" {
+		users, err := services.LoadUsers(appConfig.UsersFile)
+		switch {
+		case err == nil:
+			userService = services.NewUserServiceWith(users, appConfig.UsersFile)
+		case errors.Is(err, fs.ErrNotExist):
+			userService = services.NewUserServiceWith(nil, appConfig.UsersFile)
+		default:
+			panic(fmt.Sprintf("Failed to load users: %v", err))
+		}
+	}
+
+	shippingFailures, err := services.ParseFailures(appConfig.ShippingSimFailures, services.ShippingOperations)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid SHIPPING_SIM_FAILURES: %v", err))
+	}
+	notificationFailures, err := services.ParseFailures(appConfig.NotificationSimFailures, services.NotificationOperations)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid NOTIFICATION_SIM_FAILURES: %v", err))
+	}
+	fulfillmentService = services.NewFulfillmentService(orderService, userService,
+		services.NewShippingSimulator(shippingFailures), services.NewNotificationSimulator(notificationFailures))
+
+	eventLog = audit.New()
+	if appConfig.EventLogFile != "" {
+		log, err := audit.Open(appConfig.EventLogFile)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to open event log: %v", err))
+		}
+		eventLog = log
+	}
+
+	jobRunner = jobs.NewRunner()
+	registerReservationJobs(jobRunner, appConfig.ReservationSweepInterval)
+	registerFulfillmentJobs(jobRunner)
 }
 
 // initTemplates khởi tạo HTML templates
@@ -86,20 +276,58 @@ func initTemplates() {
 	if err != nil {
 		panic(fmt.Sprintf("Failed to load templates: %v", err))
 	}
+	devErrorsTemplate, err = template.ParseFiles("templates/dev_errors.html")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load templates: %v", err))
+	}
+	// Trang /admin/jobs dùng lại layout "head" / "foot" của dev_errors.html
+	adminJobsTemplate, err = template.ParseFiles("templates/dev_errors.html", "templates/admin_jobs.html")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load templates: %v", err))
+	}
 }
 
 // ============================================================================
 // Main
 // ============================================================================
 func main() {
+	setup(config.Load())
+
+	// Initialize logger với nhiều sinks (mỗi sink có filter và format riêng)
+	initLogger()
+	defer appLogger.Close()
+
+	app := newApp()
+
+	// Background jobs (hoàn trả hàng giữ quá hạn, giao đơn hàng đã thanh toán): lỗi được log kèm job_context thay cho http_context
+	jobRunner.Start(context.Background())
+
+	printEndpoints()
+	if err := app.Listen(appConfig.Addr); err != nil {
+		panic(err)
+	}
+}
+
+// newApp tạo Fiber app với middleware và toàn bộ routes
+// Cần gọi setup trước; logger do caller khởi tạo (initLogger hoặc logger trong tests)
+func newApp() *fiber.App {
 	app := fiber.New(fiber.Config{
 		AppName: "FiberLog - GoErrorKit Demo",
+		// Params/Query trả về string copy thay vì trỏ vào buffer của request:
+		// services giữ lại product ID, order ID (orders, reservations) sau khi request kết thúc
+		Immutable: true,
 	})
 
 	// Middleware
 	app.Use(requestid.New())
-	app.Use(logger.New())
-	app.Use(goerrorkit.FiberErrorHandler()) // Middleware xử lý error
+	if appConfig.AccessLog {
+		app.Use(logger.New())
+	}
+	// Middleware xử lý error thay cho goerrorkit.FiberErrorHandler(): mọi error response có thêm request_id,
+	// log entry có thêm request_id, status_code, location, http_context (README: Bước 5)
+	app.Use(errhandler.New(errhandler.Config{
+		OnPanic: capturePanicBundle,
+	}))
 
 	// Routes - Home
 	app.Get("/", homeHandler)
@@ -109,6 +337,15 @@ func main() {
 	app.Get("/panic/division", panicDivisionHandler)
 	app.Get("/panic/index", panicIndexHandler)
 	app.Get("/panic/stack", panicStackHandler)
+	app.Get("/panic/goroutine", goroutinePanicHandler)
+	app.Get("/panic/nil-pointer", panicNilPointerHandler)
+	app.Get("/panic/nil-map", panicNilMapHandler)
+	app.Get("/panic/custom-error", panicErrorValueHandler)
+	app.Get("/panic/struct", panicStructHandler)
+	app.Get("/panic/defer", panicDeferHandler)
+	app.Get("/panic/repanic", panicRePanicHandler)
+	app.Get("/panic/closed-channel", panicClosedChannelHandler)
+	app.Get("/panic/template", panicTemplateHandler)
 
 	// Routes - Custom Errors
 	app.Get("/error/business", businessErrorHandler)
@@ -126,22 +363,72 @@ func main() {
 	app.Get("/error/wrap-callchain", wrapWithCallChainHandler)
 
 	// Routes - Service Layer Errors (Demo lỗi từ package khác)
+	app.Get("/products", listProductsHandler)
+	app.Post("/products", createProductHandler)
 	app.Get("/product/:id", getProductHandler)
+	app.Put("/product/:id", updateProductHandler)
+	app.Delete("/product/:id", deleteProductHandler)
 	app.Get("/product/:id/check-stock", checkStockHandler)
+	app.Get("/product/:id/availability", productAvailabilityHandler)
 	app.Post("/product/:id/reserve", reserveProductHandler)
+	app.Get("/reservations/:id", getReservationHandler)
+	app.Get("/product/:id/inventory", inventoryHandler)
+	app.Post("/product/:id/inventory/adjust", adjustStockHandler)
+	app.Get("/product/:id/inventory/movements", stockMovementsHandler)
 	app.Get("/product/:id/discount", calculateDiscountHandler)
 	app.Post("/order/create", createOrderHandler)
+	app.Post("/orders/validate", validateOrdersHandler)
+	app.Post("/orders/batch", createOrderBatchHandler)
+	app.Post("/products/import", importProductsHandler)
+	app.Post("/cart/apply-coupon", applyCouponHandler)
+	app.Post("/users", registerUserHandler)
+	app.Get("/users/:id", getUserHandler)
+	app.Put("/users/:id", updateUserHandler)
 	app.Delete("/order/:id/cancel", cancelOrderHandler)
 	app.Post("/order/:id/payment", processPaymentHandler)
+	app.Post("/order/:id/refund", refundOrderHandler)
+	app.Post("/webhooks/payment", paymentWebhookHandler)
+
+	// Routes - Admin
+	admin := app.Group("/admin", adminAuthMiddleware)
+	admin.Get("/crashes", listCrashBundlesHandler)
+	admin.Get("/crashes/:id", downloadCrashBundleHandler)
+	admin.Get("/jobs", listJobsHandler)
+	admin.Get("/events", listEventsHandler)
+	admin.Post("/jobs/dead-letters/:id/retry", retryDeadLetterHandler)
+	admin.Post("/jobs/:name/run", runJobHandler)
+
+	// Dev tools: chi tiết lỗi kèm source code (chỉ khi development)
+	if appConfig.IsDevelopment() {
+		dev := app.Group("/dev")
+		dev.Get("/errors", devErrorsHandler)
+		dev.Get("/errors/:request_id", devErrorDetailHandler)
+	}
 
-	// Start server
-	fmt.Println("🚀 Server starting on http://localhost:8081")
+	return app
+}
+
+// printEndpoints in danh sách endpoints khi server khởi động
+func printEndpoints() {
+	fmt.Printf("🚀 Server starting on http://localhost%s\n", appConfig.Addr)
+	if appConfig.ProductsFile != "" {
+		fmt.Printf("📦 Sản phẩm đọc từ %s (chỉ đọc: thay đổi qua API không được ghi lại, dùng cmd/products)\n", appConfig.ProductsFile)
+	}
 	fmt.Println("\n📝 Try these endpoints:")
 	fmt.Println("  GET  /                                    - Home page")
 	fmt.Println("\n  🔥 Panic Demos (auto-recovered):")
 	fmt.Println("  GET  /panic/division                      - Division by zero")
 	fmt.Println("  GET  /panic/index                         - Index out of range")
 	fmt.Println("  GET  /panic/stack                         - Deep call stack panic")
+	fmt.Println("  GET  /panic/goroutine                     - Panic trong goroutine (safego)")
+	fmt.Println("  GET  /panic/nil-pointer                   - Nil pointer dereference")
+	fmt.Println("  GET  /panic/nil-map                       - Ghi vào nil map")
+	fmt.Println("  GET  /panic/custom-error                  - panic(customError)")
+	fmt.Println("  GET  /panic/struct                        - panic(struct{...})")
+	fmt.Println("  GET  /panic/defer                         - Panic trong deferred function")
+	fmt.Println("  GET  /panic/repanic                       - Recover rồi panic lại")
+	fmt.Println("  GET  /panic/closed-channel                - Gửi vào channel đã đóng")
+	fmt.Println("  GET  /panic/template                      - Panic trong homeTemplate.Execute")
 	fmt.Println("\n  ⚠️  Custom Error Demos:")
 	fmt.Println("  GET  /error/business?product_id=123       - Business error (hết hàng)")
 	fmt.Println("  GET  /error/system                        - System error (database)")
@@ -158,21 +445,54 @@ func main() {
 	fmt.Println("\n  🛍️  Service Layer Demos:")
 	fmt.Println("  GET  /product/999                         - Product not found")
 	fmt.Println("  GET  /product/123/check-stock             - Stock check (hết hàng)")
+	fmt.Println("  GET  /product/123/availability            - errors.Is(err, ErrOutOfStock) → 200")
 	fmt.Println("  POST /product/456/reserve?quantity=10     - Reserve product")
+	fmt.Println("  POST /product/456/reserve?quantity=4&strategy=nearest - Không kho nào đủ hàng (409)")
+	fmt.Println("  GET  /reservations/RSV-0001               - Trạng thái giữ hàng (held, sold, expired)")
+	fmt.Println("  GET  /product/456/inventory               - Tồn kho từng kho")
+	fmt.Println("  POST /product/789/inventory/adjust        - Điều chỉnh tồn kho (lịch sử ở /inventory/movements)")
 	fmt.Println("  GET  /product/456/discount?percent=150    - Calculate discount")
+	fmt.Println("  GET  /product/456/discount?coupon=APPLE20 - Xem trước giá với mã giảm giá")
+	fmt.Println("  POST /cart/apply-coupon                   - Áp dụng mã giảm giá cho giỏ hàng")
+	fmt.Println("  POST /users                               - Đăng ký user (email trùng -> 409)")
+	fmt.Println("  GET  /users/USER001                       - Thông tin user")
+	fmt.Println("  PUT  /users/USER001                       - Cập nhật profile / đổi mật khẩu")
 	fmt.Println("  POST /order/create?product_id=123&quantity=1  - Create order")
+	fmt.Println("  POST /order/create?product_id=456&user_id=USER999 - User không tồn tại (404)")
+	fmt.Println("  POST /orders/validate                     - Validate nhiều đơn hàng (errors.Join)")
+	fmt.Println("  POST /orders/batch?mode=best_effort       - Tạo nhiều đơn hàng (207 Multi-Status)")
+	fmt.Println("  GET  /products?q=pro&min_price=abc&sort=rating - Tìm kiếm sản phẩm (lỗi validation gộp)")
+	fmt.Println("  PUT  /product/456 (If-Match: \"stale\")     - Cập nhật sản phẩm -> 412")
+	fmt.Println("  POST /products/import?strict=true         - Import sản phẩm từ CSV/JSON (report=csv để tải báo cáo)")
 	fmt.Println("  DELETE /order/ORD-shipped/cancel          - Cancel order")
 	fmt.Println("  POST /order/ORD-123/payment?amount=20000  - Process payment")
-	fmt.Println("\n📄 Check logs/errors.log for detailed error logs")
-
-	if err := app.Listen(":8081"); err != nil {
-		panic(err)
+	fmt.Println("  POST /order/ORD-123/payment?amount=10.005 - Số tiền sai số chữ số thập phân (Money)")
+	fmt.Println("  POST /order/ORD-USER001-456/refund?amount=20 - Hoàn tiền một phần (không có amount: hoàn toàn bộ)")
+	if appConfig.PaymentWebhookSecret != "" {
+		fmt.Println("  POST /webhooks/payment                    - Webhook payment gateway (gửi bằng go run ./cmd/paymentsim send)")
+	} else {
+		fmt.Println("  POST /webhooks/payment                    - Đã tắt (503): chưa set PAYMENT_WEBHOOK_SECRET")
+	}
+	fmt.Println("\n  🛠️  Admin:")
+	fmt.Println("  GET  /admin/crashes                       - Danh sách crash bundles")
+	fmt.Println("  GET  /admin/crashes/:id                   - Tải crash bundle (.zip)")
+	fmt.Println("  GET  /admin/jobs                          - Background jobs, lỗi gần nhất, dead letters (retry now)")
+	fmt.Println("  POST /admin/jobs/order_fulfillment/run?payload_id=ORD-USER001-456 - Giao lại đơn hàng (vận đơn, email / SMS)")
+	fmt.Println("  GET  /admin/events?entity=order&id=ORD-USER001-456 - Event log: ai thay đổi gì, khi nào (kể cả lần thử lỗi)")
+	if appConfig.IsDevelopment() {
+		fmt.Println("\n  🔍 Dev Tools:")
+		fmt.Println("  GET  /dev/errors                          - Lỗi gần nhất")
+		fmt.Println("  GET  /dev/errors/:request_id              - Chi tiết lỗi kèm source code")
 	}
+	fmt.Printf("\n📄 Check %s for detailed error logs\n", errorLogPath())
+	fmt.Println("   go run ./cmd/fiberlog tail | grep | stats | show <request_id>")
 }
 
 func homeHandler(c *fiber.Ctx) error {
 	c.Set("Content-Type", "text/html; charset=utf-8")
-	return homeTemplate.Execute(c.Response().BodyWriter(), nil)
+	return homeTemplate.Execute(c.Response().BodyWriter(), fiber.Map{
+		"DevMode": appConfig.IsDevelopment(),
+	})
 }
 
 func faviconHandler(c *fiber.Ctx) error {
@@ -224,6 +544,51 @@ func callW() int {
 	return GetElement() // Panic happens here, full call chain will be logged
 }
 
+// goroutinePanicHandler - Panic trong goroutine do handler tạo ra
+// Không dùng safego, panic này làm crash toàn bộ server (middleware chỉ recover goroutine của request)
+//
+// Test: GET /panic/goroutine               → safego.Group: panic được trả về response (500 PANIC)
+// Test: GET /panic/goroutine?mode=detached → safego.Go: trả về 202 ngay, panic được log ở background
+func goroutinePanicHandler(c *fiber.Ctx) error {
+	ctx := safego.FiberContext(c)
+	productIDs := []string{"123", "456", "789"}
+
+	if c.Query("mode") == "detached" {
+		safego.Go(ctx, func() {
+			for _, id := range productIDs {
+				stockSnapshot(id)
+			}
+		})
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "Đang đồng bộ tồn kho ở background",
+			"note":    "Panic trong goroutine được log vào logs/errors.log, server vẫn chạy",
+		})
+	}
+
+	g, _ := safego.WithContext(ctx)
+	snapshots := make([]int, len(productIDs))
+	for i, id := range productIDs {
+		g.Go(func() error {
+			snapshots[i] = stockSnapshot(id)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{"snapshots": snapshots})
+}
+
+// stockSnapshot tính tồn kho của sản phẩm theo từng kệ (chạy trong goroutine)
+func stockSnapshot(productID string) int {
+	shelves := map[string][]int{
+		"123": {0, 0},
+		"456": {3, 2},
+	}[productID]
+	return shelves[0] + shelves[1] // ← Panic trong goroutine với product 789 (không có kệ)
+}
+
 // ============================================================================
 // Demo Custom Error Handlers
 // ============================================================================
@@ -290,7 +655,7 @@ func validationErrorHandler(c *fiber.Ctx) error {
 	})
 }
 
-// User struct cho demo validation body
+// User struct cho demo validation body (không được lưu lại, đăng ký user thật qua POST /users)
 type User struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
@@ -369,6 +734,7 @@ func authErrorHandler(c *fiber.Ctx) error {
 }
 
 // externalErrorHandler - Demo lỗi từ external API/service
+// Lỗi thật của shipping / notification: xem job order_fulfillment (services.ShippingClient, services.Notifier)
 func externalErrorHandler(c *fiber.Ctx) error {
 	// Giả lập gọi external API thất bại
 	service := c.Query("service", "payment")
@@ -414,11 +780,95 @@ func getProductHandler(c *fiber.Ctx) error {
 		return err
 	}
 
+	c.Set(fiber.HeaderETag, product.ETag())
+	return c.JSON(fiber.Map{
+		"product": product,
+	})
+}
+
+// listProductsHandler - Tìm kiếm sản phẩm: q, min_price, max_price, sort (id, name, price, stock, "-" giảm dần),
+// limit, cursor (next_cursor của trang trước)
+// Tham số sai được trả về cùng lúc (lỗi gộp, mỗi tham số một ValidationError)
+// Test: GET /products?min_price=abc&sort=rating -> 400, errors: [min_price, sort]
+func listProductsHandler(c *fiber.Ctx) error {
+	params := make(map[string]string)
+	for _, key := range []string{"q", "min_price", "max_price", "sort", "limit", "cursor"} {
+		params[key] = c.Query(key)
+	}
+
+	query, err := services.ParseProductQuery(params)
+	if err != nil {
+		return err
+	}
+
+	page, err := productService.ListProducts(query)
+	if err != nil {
+		return err
+	}
+
+	response := fiber.Map{
+		"products": page.Products,
+		"count":    len(page.Products),
+	}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	return c.JSON(response)
+}
+
+// createProductHandler - Tạo sản phẩm, body {"id", "name", "price", "stock"}
+// Test: POST /products {"id": "456", "name": "MacBook Air", "price": 999, "stock": 1} -> BusinessError 409
+func createProductHandler(c *fiber.Ctx) error {
+	var input services.Product
+	if err := c.BodyParser(&input); err != nil {
+		return goerrorkit.NewValidationError("Request body không hợp lệ", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	product, err := productService.CreateProduct(input)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, product.ETag())
+	c.Set(fiber.HeaderLocation, "/product/"+product.ID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"product": product,
+	})
+}
+
+// updateProductHandler - Cập nhật name, price, stock của sản phẩm
+// Gửi If-Match là ETag từ GET /product/:id để không ghi đè thay đổi của request khác
+// Test: PUT /product/456 với If-Match: "stale" -> BusinessError 412
+func updateProductHandler(c *fiber.Ctx) error {
+	var input services.Product
+	if err := c.BodyParser(&input); err != nil {
+		return goerrorkit.NewValidationError("Request body không hợp lệ", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	product, err := productService.UpdateProduct(c.Params("id"), input, c.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, product.ETag())
 	return c.JSON(fiber.Map{
 		"product": product,
 	})
 }
 
+// deleteProductHandler - Xóa sản phẩm (If-Match tùy chọn, giống updateProductHandler)
+// Test: DELETE /product/999 -> BusinessError 404
+func deleteProductHandler(c *fiber.Ctx) error {
+	if err := productService.DeleteProduct(c.Params("id"), c.Get(fiber.HeaderIfMatch)); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // checkStockHandler - Kiểm tra tồn kho
 // Test: GET /product/123/check-stock (hết hàng) -> BusinessError từ CheckStock
 func checkStockHandler(c *fiber.Ctx) error {
@@ -435,29 +885,168 @@ func checkStockHandler(c *fiber.Ctx) error {
 	})
 }
 
+// productAvailabilityHandler - Phân nhánh theo sentinel error thay vì so sánh message
+// Hết hàng không phải lỗi với endpoint này (trả về available=false), các lỗi khác được propagate
+// Test: GET /product/123/availability -> 200 available=false
+// Test: GET /product/999/availability -> BusinessError 404 (ErrProductNotFound)
+func productAvailabilityHandler(c *fiber.Ctx) error {
+	productID := c.Params("id")
+
+	err := productService.CheckStock(productID)
+	if errors.Is(err, services.ErrOutOfStock) {
+		// errors.As lấy được typed error trong chuỗi cause của AppError
+		var productErr *services.ProductError
+		errors.As(err, &productErr)
+		return c.JSON(fiber.Map{
+			"product_id": productErr.ProductID,
+			"available":  false,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"product_id": productID,
+		"available":  true,
+	})
+}
+
 // reserveProductHandler - Đặt trước sản phẩm
+// Hàng được lấy từ các kho theo strategy (nearest, most_stock, split) và region (north, central, south)
+// và chỉ được giữ đến expires_at (RESERVATION_TTL), sau đó được hoàn trả về kho
 // Test: POST /product/456/reserve?quantity=10 -> ValidationError (không đủ hàng)
+// Test: POST /product/456/reserve?quantity=4&strategy=nearest -> BusinessError 409 (không kho nào đủ 4)
 func reserveProductHandler(c *fiber.Ctx) error {
 	productID := c.Params("id")
 	quantityStr := c.Query("quantity", "1")
 	quantity, _ := strconv.Atoi(quantityStr)
 
+	policy, err := services.ParseAllocationPolicy(c.Query("strategy"), c.Query("region"))
+	if err != nil {
+		return err
+	}
+
 	// Error sẽ được throw từ ProductService.ReserveProduct
-	err := productService.ReserveProduct(productID, quantity)
+	event := audit.Event{
+		Type: audit.StockReserved, Entity: audit.EntityProduct, EntityID: productID, Actor: eventActor(c, ""),
+		Before: inventorySnapshot(productID),
+	}
+	hold, err := reservationService.Hold(productID, quantity, policy)
+	if err != nil {
+		recordEvent(c, event, err)
+		return err
+	}
+	event.Refs = map[string]string{"reservation_id": hold.ID}
+	event.After = inventorySnapshot(productID)
+	recordEvent(c, event, nil)
+
+	return c.JSON(fiber.Map{
+		"message":        "Đặt hàng thành công",
+		"quantity":       quantity,
+		"allocations":    hold.Allocations,
+		"reservation_id": hold.ID,
+		"expires_at":     hold.ExpiresAt,
+	})
+}
+
+// getReservationHandler - Trạng thái giữ hàng: held, sold (đã thanh toán), released, expired
+// Test: GET /reservations/RSV-0001
+// Test: GET /reservations/RSV-404 -> BusinessError 404
+func getReservationHandler(c *fiber.Ctx) error {
+	reservation, err := reservationService.Get(c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(reservation)
+}
+
+// inventoryHandler - Tồn kho của sản phẩm tại từng kho
+// Test: GET /product/456/inventory -> WH-01: 2, WH-02: 0, WH-03: 3
+func inventoryHandler(c *fiber.Ctx) error {
+	productID := c.Params("id")
+
+	stock, err := productService.Inventory(productID)
 	if err != nil {
 		return err
 	}
 
+	total := 0
+	for _, w := range stock {
+		total += w.Available
+	}
 	return c.JSON(fiber.Map{
-		"message":  "Đặt hàng thành công",
-		"quantity": quantity,
+		"product_id": productID,
+		"total":      total,
+		"warehouses": stock,
+	})
+}
+
+// adjustStockHandler - Điều chỉnh tồn kho tại một kho, body {"warehouse_id", "delta", "reason"}
+// Mỗi lần điều chỉnh được ghi vào lịch sử xuất nhập kho (GET /product/:id/inventory/movements)
+// Test: POST /product/789/inventory/adjust {"warehouse_id": "WH-01", "delta": -5, "reason": "Hàng hỏng"}
+// -> BusinessError 409 (kho WH-01 chỉ còn 4)
+func adjustStockHandler(c *fiber.Ctx) error {
+	var body struct {
+		WarehouseID string `json:"warehouse_id"`
+		Delta       int    `json:"delta"`
+		Reason      string `json:"reason"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return goerrorkit.NewValidationError("Request body không hợp lệ", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	movement, err := productService.AdjustStock(c.Params("id"), body.WarehouseID, body.Delta, body.Reason)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Đã điều chỉnh tồn kho",
+		"movement": movement,
+	})
+}
+
+// stockMovementsHandler - Lịch sử xuất nhập kho của sản phẩm (reserve, hoàn trả, điều chỉnh, ...)
+// Test: GET /product/456/inventory/movements
+func stockMovementsHandler(c *fiber.Ctx) error {
+	productID := c.Params("id")
+
+	movements, err := productService.StockMovements(productID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"product_id": productID,
+		"movements":  movements,
+		"count":      len(movements),
 	})
 }
 
 // calculateDiscountHandler - Tính giá sau giảm giá
+// Với ?coupon=CODE: xem trước giá của 1 sản phẩm khi dùng mã giảm giá (không ghi nhận lượt dùng)
 // Test: GET /product/456/discount?percent=150 -> ValidationError (percent không hợp lệ)
+// Test: GET /product/456/discount?coupon=SUMMER2024 -> BusinessError 410 (COUPON_EXPIRED)
 func calculateDiscountHandler(c *fiber.Ctx) error {
 	productID := c.Params("id")
+	if code := c.Query("coupon"); code != "" {
+		quote, err := couponService.PreviewCoupons(services.CartRequest{
+			UserID:  c.Query("user_id", "USER001"),
+			Items:   []services.CartItem{{ProductID: productID, Quantity: 1}},
+			Coupons: []string{code},
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSON(fiber.Map{
+			"preview": true,
+			"quote":   quote,
+		})
+	}
+
 	percentStr := c.Query("percent", "10")
 	percent, _ := strconv.ParseFloat(percentStr, 64)
 
@@ -474,20 +1063,128 @@ func calculateDiscountHandler(c *fiber.Ctx) error {
 	})
 }
 
+// applyCouponHandler - Áp dụng mã giảm giá cho giỏ hàng và ghi nhận lượt dùng
+// Mỗi lý do từ chối là BusinessError có data.error_code riêng (COUPON_EXPIRED, COUPON_EXHAUSTED, ...);
+// nhiều mã bị từ chối thì trả về tất cả trong "errors"
+// Test: POST /cart/apply-coupon
+//
+//	{"user_id": "U1", "items": [{"product_id": "789", "quantity": 1}], "coupons": ["APPLE20"]}
+//	-> BusinessError 422 (COUPON_NOT_APPLICABLE)
+func applyCouponHandler(c *fiber.Ctx) error {
+	var req services.CartRequest
+	if err := c.BodyParser(&req); err != nil {
+		return goerrorkit.NewValidationError("Request body không hợp lệ", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	quote, err := couponService.ApplyCoupons(req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Đã áp dụng mã giảm giá",
+		"quote":   quote,
+	})
+}
+
+// registerUserHandler - Đăng ký user, body {"name", "email", "age", "password"}
+// Test: POST /users {"name": "An", "email": "AN@example.com", "age": 20, "password": "secret123"} -> BusinessError 409
+func registerUserHandler(c *fiber.Ctx) error {
+	var input services.UserInput
+	if err := c.BodyParser(&input); err != nil {
+		return goerrorkit.NewValidationError("Request body không hợp lệ", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	user, err := userService.Register(input)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderLocation, "/users/"+user.ID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"user": user,
+	})
+}
+
+// getUserHandler - Lấy thông tin user (không kèm password hash)
+// Test: GET /users/USER999 -> BusinessError 404
+func getUserHandler(c *fiber.Ctx) error {
+	user, err := userService.GetUser(c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"user": user,
+	})
+}
+
+// updateUserHandler - Cập nhật name, email, age; "password" khác rỗng thì đổi mật khẩu
+// Test: PUT /users/USER002 {"name": "Bình", "email": "an@example.com", "age": 25} -> BusinessError 409
+func updateUserHandler(c *fiber.Ctx) error {
+	var input services.UserInput
+	if err := c.BodyParser(&input); err != nil {
+		return goerrorkit.NewValidationError("Request body không hợp lệ", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	user, err := userService.UpdateProfile(c.Params("id"), input)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"user": user,
+	})
+}
+
 // createOrderHandler - Tạo đơn hàng mới
+// user_id (mặc định USER001) phải là user đã đăng ký
+// strategy và region (tùy chọn) quyết định lấy hàng từ kho nào, giống reserveProductHandler
+// Test: POST /order/create?product_id=456&user_id=USER999 -> BusinessError 404 (user không tồn tại)
 // Test: POST /order/create?product_id=123&quantity=1 -> BusinessError (hết hàng)
 // Test: POST /order/create?product_id=456&quantity=0 -> ValidationError (quantity <= 0)
+// Test: POST /order/create?product_id=456&quantity=3&strategy=nearest&region=north -> 200, lấy từ WH-03
 func createOrderHandler(c *fiber.Ctx) error {
 	productID := c.Query("product_id")
 	userID := c.Query("user_id", "USER001")
 	quantityStr := c.Query("quantity", "1")
 	quantity, _ := strconv.Atoi(quantityStr)
 
+	if _, err := userService.GetUser(userID); err != nil {
+		return err
+	}
+
+	policy, err := services.ParseAllocationPolicy(c.Query("strategy"), c.Query("region"))
+	if err != nil {
+		return err
+	}
+
 	// Error có thể được throw từ nhiều nơi trong OrderService
-	order, err := orderService.CreateOrder(productID, userID, quantity)
+	stockBefore := inventorySnapshot(productID)
+	order, err := orderService.CreateOrder(productID, userID, quantity, policy)
 	if err != nil {
+		recordEvent(c, audit.Event{
+			Type: audit.OrderCreated, Entity: audit.EntityOrder, Actor: eventActor(c, userID),
+			Refs: map[string]string{"product_id": productID},
+		}, err)
 		return err
 	}
+	recordEvent(c, audit.Event{
+		Type: audit.OrderCreated, Entity: audit.EntityOrder, EntityID: order.ID, Actor: eventActor(c, userID),
+		Refs:  map[string]string{"product_id": productID, "reservation_id": order.ReservationID},
+		After: audit.Snapshot(order),
+	}, nil)
+	recordEvent(c, audit.Event{
+		Type: audit.StockReserved, Entity: audit.EntityProduct, EntityID: productID, Actor: eventActor(c, userID),
+		Refs:   map[string]string{"order_id": order.ID, "reservation_id": order.ReservationID},
+		Before: stockBefore, After: inventorySnapshot(productID),
+	}, nil)
 
 	return c.JSON(fiber.Map{
 		"message": "Đơn hàng đã được tạo",
@@ -495,16 +1192,236 @@ func createOrderHandler(c *fiber.Ctx) error {
 	})
 }
 
+// validateOrdersHandler - Validate nhiều đơn hàng cùng lúc, trả về tất cả lỗi (errors.Join)
+// Status của response do lỗi con nghiêm trọng nhất quyết định (BUSINESS 404 > VALIDATION 400)
+// Test: POST /orders/validate
+//
+//	{"orders": [{"product_id": "456", "user_id": "U1", "quantity": 0},
+//	            {"product_id": "999", "user_id": "U1", "quantity": 1}]}
+//	-> 404, "errors": [order[0] VALIDATION 400, order[1] BUSINESS 404]
+func validateOrdersHandler(c *fiber.Ctx) error {
+	var body struct {
+		Orders []services.OrderRequest `json:"orders"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return goerrorkit.NewValidationError("Request body không hợp lệ", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	// Lỗi gộp được errhandler tách thành từng lỗi con trong response và log entry
+	if err := orderService.ValidateOrders(body.Orders); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Tất cả đơn hàng hợp lệ",
+		"count":   len(body.Orders),
+	})
+}
+
+// createOrderBatchHandler - Tạo nhiều đơn hàng, body là JSON array các dòng đơn hàng
+// Dòng lỗi trả về đầy đủ payload của goerrorkit (error, type, code, location, data)
+//   - mode=all_or_nothing (mặc định): dừng ở dòng lỗi đầu tiên, hoàn trả stock của các dòng đã tạo
+//   - mode=best_effort: tạo tất cả dòng hợp lệ
+//
+// Tất cả thành công -> 200, có dòng lỗi -> 207 Multi-Status và một log entry với children
+// Test: POST /orders/batch?mode=best_effort
+//
+//	[{"product_id": "456", "user_id": "U1", "quantity": 1},
+//	 {"product_id": "999", "user_id": "U1", "quantity": 1}]
+//	-> 207, results: [created, failed (BUSINESS 404)]
+func createOrderBatchHandler(c *fiber.Ctx) error {
+	var lines []services.OrderRequest
+	if err := c.BodyParser(&lines); err != nil {
+		return goerrorkit.NewValidationError("Request body không hợp lệ", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	mode := services.BatchMode(c.Query("mode", string(services.BatchAllOrNothing)))
+	results, err := orderService.CreateOrders(lines, mode)
+	if err != nil {
+		return err
+	}
+
+	items := make([]fiber.Map, 0, len(results))
+	var failures []error
+	created := 0
+	for _, result := range results {
+		item := fiber.Map{"index": result.Index, "status": result.Status}
+		if result.Order != nil {
+			item["order"] = result.Order
+		}
+		if result.Err != nil {
+			item["error"] = errhandler.ErrorPayload(result.Err)
+			failures = append(failures, fmt.Errorf("line[%d]: %w", result.Index, result.Err))
+		}
+		if result.Status == services.LineCreated {
+			created++
+		}
+		items = append(items, item)
+	}
+
+	response := fiber.Map{
+		"mode":      mode,
+		"succeeded": created,
+		"failed":    len(failures),
+		"results":   items,
+	}
+	if len(failures) == 0 {
+		return c.JSON(response)
+	}
+
+	response["request_id"] = logPartialFailure(c, failures, fiber.StatusMultiStatus)
+	return c.Status(fiber.StatusMultiStatus).JSON(response)
+}
+
+// logPartialFailure log các lỗi của request thành công một phần thành một entry với children
+// (response không phải error nên errhandler không log), trả về request_id để client tra cứu log
+func logPartialFailure(c *fiber.Ctx, failures []error, status int) string {
+	requestID, _ := c.Locals("requestid").(string)
+	if appErr, _ := errhandler.Aggregate(errors.Join(failures...), requestID); appErr != nil {
+		appErr.Code = status
+		errhandler.LogError(appErr, c)
+	}
+	return requestID
+}
+
+// importProductsHandler - Import sản phẩm từ CSV/JSON, báo cáo lỗi từng ô (row, column)
+// File gửi dạng multipart (field "file") hoặc raw body; format lấy từ ?format=, phần mở rộng của file hoặc Content-Type
+//   - Các dòng hợp lệ được thêm kể cả khi có dòng lỗi (207), trừ khi strict=true (400, không thêm dòng nào)
+//   - report=csv: trả về báo cáo lỗi dạng CSV để tải về thay vì JSON
+//
+// Test: POST /products/import (Content-Type: text/csv)
+//
+//	id,name,price,stock
+//	P1,Keyboard,49.9,10
+//	P2,Mouse,-5,abc
+//	-> 207, errors: [row 3 price, row 3 stock]
+func importProductsHandler(c *fiber.Ctx) error {
+	data, filename, err := uploadedFile(c)
+	if err != nil {
+		return err
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = importFormat(filename, c.Get(fiber.HeaderContentType))
+	}
+
+	report, err := productService.ImportProducts(bytes.NewReader(data), format, c.QueryBool("strict"))
+	if err != nil {
+		return err
+	}
+
+	status := fiber.StatusOK
+	if report.Failed > 0 {
+		status = fiber.StatusMultiStatus
+		if !report.Committed {
+			status = fiber.StatusBadRequest
+		}
+	}
+
+	var failures []error
+	rowErrors := make([]fiber.Map, 0, len(report.Errors))
+	for _, rowErr := range report.Errors {
+		item := fiber.Map(errhandler.ErrorPayload(rowErr.Err))
+		item["row"], item["column"] = rowErr.Row, rowErr.Column
+		rowErrors = append(rowErrors, item)
+		failures = append(failures, fmt.Errorf("row %d, column %s: %w", rowErr.Row, rowErr.Column, rowErr.Err))
+	}
+
+	var requestID string
+	if len(failures) > 0 {
+		requestID = logPartialFailure(c, failures, status)
+	}
+
+	if c.Query("report") == "csv" {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="import-report.csv"`)
+		return report.WriteCSV(c.Status(status).Response().BodyWriter())
+	}
+
+	response := fiber.Map{
+		"format":    report.Format,
+		"strict":    report.Strict,
+		"total":     report.Total,
+		"imported":  report.Imported,
+		"failed":    report.Failed,
+		"committed": report.Committed,
+		"errors":    rowErrors,
+	}
+	if requestID != "" {
+		response["request_id"] = requestID
+	}
+	return c.Status(status).JSON(response)
+}
+
+// uploadedFile đọc file upload: multipart field "file" hoặc toàn bộ request body
+func uploadedFile(c *fiber.Ctx) ([]byte, string, error) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		if len(c.Body()) == 0 {
+			return nil, "", goerrorkit.NewValidationError("Thiếu file import", map[string]interface{}{
+				"field": "file",
+				"hint":  "Gửi multipart field \"file\" hoặc nội dung file trong request body",
+			})
+		}
+		return c.Body(), "", nil
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, "", goerrorkit.WrapWithMessage(err, "Không thể đọc file upload").WithData(map[string]interface{}{
+			"filename": header.Filename,
+		})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", goerrorkit.WrapWithMessage(err, "Không thể đọc file upload").WithData(map[string]interface{}{
+			"filename": header.Filename,
+		})
+	}
+	return data, header.Filename, nil
+}
+
+// importFormat xác định format import từ phần mở rộng của file, sau đó tới Content-Type
+func importFormat(filename, contentType string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return services.ImportCSV
+	case ".json":
+		return services.ImportJSON
+	}
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return services.ImportCSV
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+		return services.ImportJSON
+	}
+	return ""
+}
+
 // cancelOrderHandler - Hủy đơn hàng
 // Test: DELETE /order/ORD-shipped/cancel -> BusinessError (đã ship)
 func cancelOrderHandler(c *fiber.Ctx) error {
 	orderID := c.Params("id")
 
 	// Error sẽ được throw từ OrderService.CancelOrder
+	event := audit.Event{
+		Type: audit.OrderCancelled, Entity: audit.EntityOrder, EntityID: orderID, Actor: eventActor(c, ""),
+		Before: orderSnapshot(orderID),
+	}
 	err := orderService.CancelOrder(orderID)
 	if err != nil {
+		recordEvent(c, event, err)
 		return err
 	}
+	event.After = orderSnapshot(orderID)
+	recordEvent(c, event, nil)
 
 	return c.JSON(fiber.Map{
 		"message":  "Đơn hàng đã được hủy",
@@ -512,24 +1429,143 @@ func cancelOrderHandler(c *fiber.Ctx) error {
 	})
 }
 
-// processPaymentHandler - Xử lý thanh toán
+// processPaymentHandler - Xử lý thanh toán, amount dạng decimal theo currency (mặc định USD)
+// Test: POST /order/ORD-123/payment?amount=10.005 -> ValidationError (USD có tối đa 2 chữ số thập phân)
 // Test: POST /order/ORD-invalid-card/payment?amount=100 -> ExternalError (payment gateway)
 // Test: POST /order/ORD-123/payment?amount=20000 -> ExternalError (timeout)
+// Đơn hàng tạo qua CreateOrder được đưa vào job order_fulfillment (vận đơn, email / SMS) sau khi thanh toán
 func processPaymentHandler(c *fiber.Ctx) error {
 	orderID := c.Params("id")
-	amountStr := c.Query("amount", "0")
-	amount, _ := strconv.ParseFloat(amountStr, 64)
+	amount, err := services.ParsePaymentAmount(c.Query("amount", "0"), c.Query("currency"))
+	if err != nil {
+		return err
+	}
 
 	// Error có thể được throw từ deep trong call stack (OrderService -> callPaymentGateway)
-	err := orderService.ProcessPayment(orderID, amount)
+	// Thanh toán lỗi được ghi thành payment.failed, thành công là payment.succeeded
+	event := audit.Event{
+		Type: audit.PaymentSucceeded, Entity: audit.EntityOrder, EntityID: orderID, Actor: eventActor(c, ""),
+		Refs:   map[string]string{"amount": amount.String()},
+		Before: orderSnapshot(orderID),
+	}
+	err = orderService.ProcessPayment(orderID, amount)
 	if err != nil {
+		event.Type = audit.PaymentFailed
+		recordEvent(c, event, err)
 		return err
 	}
+	event.After = orderSnapshot(orderID)
+	recordEvent(c, event, nil)
 
 	return c.JSON(fiber.Map{
-		"message":  "Thanh toán thành công",
-		"order_id": orderID,
-		"amount":   amount,
+		"message":            "Thanh toán thành công",
+		"order_id":           orderID,
+		"amount":             amount,
+		"fulfillment_queued": enqueueFulfillment(c, orderID),
+	})
+}
+
+// refundOrderHandler - Hoàn tiền đơn hàng đã thanh toán
+// amount (tùy chọn, theo tiền tệ đã thanh toán) rỗng thì hoàn toàn bộ số tiền còn lại;
+// restock (tùy chọn) là số sản phẩm khách trả lại, mặc định nhập lại toàn bộ khi hoàn hết tiền
+// Test: POST /order/ORD-123/refund -> BusinessError 404 (đơn hàng không tồn tại)
+// Test: POST /order/ORD-USER001-456/refund (chưa thanh toán) -> BusinessError 409
+// Test: POST /order/ORD-USER001-456/refund?amount=99999 -> BusinessError 422 (vượt quá số tiền đã thanh toán)
+func refundOrderHandler(c *fiber.Ctx) error {
+	orderID := c.Params("id")
+	order, err := orderService.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+
+	var req services.RefundRequest
+	if amount := c.Query("amount"); amount != "" {
+		currency := c.Query("currency")
+		if currency == "" && order.Paid != nil {
+			currency = string(order.Paid.Currency())
+		}
+		money, err := services.ParsePaymentAmount(amount, currency)
+		if err != nil {
+			return err
+		}
+		req.Amount = &money
+	}
+	if restock := c.Query("restock"); restock != "" {
+		n, err := strconv.Atoi(restock)
+		if err != nil {
+			return goerrorkit.NewValidationError("Tham số 'restock' phải là số nguyên", map[string]interface{}{
+				"field":    "restock",
+				"type":     "integer",
+				"received": restock,
+			})
+		}
+		req.Restock = &n
+	}
+	req.Reason = c.Query("reason")
+
+	event := audit.Event{
+		Type: audit.OrderRefunded, Entity: audit.EntityOrder, EntityID: orderID, Actor: eventActor(c, ""),
+		Before: audit.Snapshot(order),
+	}
+	refund, err := orderService.RefundOrder(orderID, req)
+	if refund != nil {
+		// Đã hoàn tiền (kể cả khi không nhập lại kho được): đơn hàng đã thay đổi
+		event.Refs = map[string]string{"refund_id": refund.ID}
+		event.After = orderSnapshot(orderID)
+	}
+	recordEvent(c, event, err)
+	if err != nil {
+		return err
+	}
+
+	order, err = orderService.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"message": "Hoàn tiền thành công",
+		"refund":  refund,
+		"order":   order,
+	})
+}
+
+// paymentWebhookHandler nhận event xác nhận thanh toán bất đồng bộ từ payment gateway
+// Chữ ký được tính trên raw body nên không parse body trước khi xác thực
+// Event trùng ID vẫn trả về 200 để gateway không gửi lại
+// Chưa set PAYMENT_WEBHOOK_SECRET (ngoài development) thì mọi event bị từ chối với 503
+// Event đã xác thực được ghi vào event log với actor payment_gateway (event trùng không được ghi lại)
+// payment.succeeded đưa đơn hàng vào job order_fulfillment giống thanh toán đồng bộ
+func paymentWebhookHandler(c *fiber.Ctx) error {
+	var target struct {
+		OrderID string `json:"order_id"`
+	}
+	json.Unmarshal(c.Body(), &target) // Chỉ để chụp đơn hàng trước khi xử lý, payload được kiểm tra trong Handle
+	before := orderSnapshot(target.OrderID)
+
+	event, duplicate, err := paymentWebhooks.Handle(c.Get(services.WebhookSignatureHeader), c.Body())
+	if event != nil && !duplicate {
+		change := audit.Event{
+			Type: event.Type, Entity: audit.EntityOrder, EntityID: event.OrderID, Actor: "payment_gateway",
+			Refs:   map[string]string{"webhook_event_id": event.ID},
+			Before: before,
+		}
+		if err == nil {
+			change.After = orderSnapshot(event.OrderID)
+		}
+		recordEvent(c, change, err)
+	}
+	if err != nil {
+		return err
+	}
+	if event.Type == services.WebhookPaymentSucceeded && !duplicate {
+		enqueueFulfillment(c, event.OrderID)
+	}
+	return c.JSON(fiber.Map{
+		"received":  true,
+		"event_id":  event.ID,
+		"type":      event.Type,
+		"order_id":  event.OrderID,
+		"duplicate": duplicate,
 	})
 }
 
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"fiber_log/config"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Panic Handlers (mở rộng) - Các loại panic khác nhau, location phải trỏ đúng dòng gây panic
+// Được kiểm tra tự động bởi panic_test.go
+// ============================================================================
+
+// discountRule là cấu hình giảm giá của sản phẩm
+type discountRule struct {
+	Percent int
+}
+
+// inventoryError là custom error type, dùng để demo panic(customError)
+type inventoryError struct {
+	ProductID string
+	Reason    string
+}
+
+func (e *inventoryError) Error() string {
+	return fmt.Sprintf("inventory %s: %s", e.ProductID, e.Reason)
+}
+
+// panicNilPointerHandler - Nil pointer dereference
+// Test: GET /panic/nil-pointer
+func panicNilPointerHandler(c *fiber.Ctx) error {
+	percent := discountPercent(nil)
+	return c.JSON(fiber.Map{"percent": percent})
+}
+
+func discountPercent(rule *discountRule) int {
+	return rule.Percent // ← Panic: nil pointer dereference
+}
+
+// panicNilMapHandler - Ghi vào nil map
+// Test: GET /panic/nil-map
+func panicNilMapHandler(c *fiber.Ctx) error {
+	var viewCounts map[string]int
+	viewCounts["product-123"]++ // ← Panic: assignment to entry in nil map
+	return c.JSON(fiber.Map{"views": viewCounts})
+}
+
+// panicErrorValueHandler - panic(customError): panic value là một error
+// Lưu ý: goerrorkit bỏ qua frame có tên chứa "ErrorHandler" khi tìm panic location,
+// nên tên handler không được đặt là panicCustomErrorHandler
+// Test: GET /panic/custom-error
+func panicErrorValueHandler(c *fiber.Ctx) error {
+	panic(&inventoryError{ProductID: "123", Reason: "stock ledger corrupted"}) // ← Panic: custom error
+}
+
+// panicStructHandler - panic(struct{...}): panic value không phải error hay string
+// Test: GET /panic/struct
+func panicStructHandler(c *fiber.Ctx) error {
+	state := struct {
+		Code   int
+		Reason string
+	}{Code: 42, Reason: "unexpected state"}
+	panic(state) // ← Panic: struct value
+}
+
+// panicDeferHandler - Panic trong deferred function (sau khi handler đã ghi response)
+// Test: GET /panic/defer
+func panicDeferHandler(c *fiber.Ctx) error {
+	// Gọi qua closure để call chain có dòng gọi flushAuditLog: khi defer trực tiếp, frame của
+	// handler là dòng return hay dấu } tùy compiler (khác nhau khi build với -race)
+	defer func() {
+		flushAuditLog(nil)
+	}()
+	return c.JSON(fiber.Map{"message": "Order updated"})
+}
+
+func flushAuditLog(entries *[]string) {
+	for _, entry := range *entries { // ← Panic: nil pointer trong defer
+		fmt.Println(entry)
+	}
+}
+
+// panicRePanicHandler - Defer recover panic rồi panic lại với message mới
+// Location là dòng re-panic, call_chain vẫn chứa dòng panic gốc
+// Test: GET /panic/repanic
+func panicRePanicHandler(c *fiber.Ctx) error {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(fmt.Sprintf("rollback thất bại: %v", r)) // ← Re-panic
+		}
+	}()
+
+	quantities := []int{1, 2}
+	return c.JSON(fiber.Map{"quantity": quantities[len(quantities)]}) // ← Panic gốc
+}
+
+// panicClosedChannelHandler - Gửi vào channel đã đóng
+// Test: GET /panic/closed-channel
+func panicClosedChannelHandler(c *fiber.Ctx) error {
+	results := make(chan int, 1)
+	close(results)
+	results <- 1 // ← Panic: send on closed channel
+	return c.JSON(fiber.Map{"result": <-results})
+}
+
+// brokenHomeData là data cho homeTemplate với method DevMode bị panic (config nil)
+type brokenHomeData struct {
+	cfg *config.Config
+}
+
+// DevMode được gọi từ {{.DevMode}} trong home.html
+func (d brokenHomeData) DevMode() bool {
+	return d.cfg.IsDevelopment()
+}
+
+// panicTemplateHandler - Panic trong lúc homeTemplate.Execute
+// text/template tự recover panic của method/function được gọi từ template và trả về error,
+// vì vậy panic không tới errhandler: lỗi được wrap tại handler (location là dòng wrap),
+// panic gốc nằm trong cause
+// Test: GET /panic/template
+func panicTemplateHandler(c *fiber.Ctx) error {
+	// Render vào buffer để không gửi nửa trang HTML khi lỗi
+	var buf bytes.Buffer
+	if err := homeTemplate.Execute(&buf, brokenHomeData{}); err != nil {
+		return goerrorkit.WrapWithMessage(err, "Không thể render trang chủ") // ← Template panic được wrap
+	}
+
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// panicCases bao phủ các loại panic mà README liệt kê
+// location và call_chain phải trỏ đúng dòng gây panic
+var panicCases = []routeCase{
+	{
+		name: "division by zero", route: "/panic/division", path: "/panic/division",
+		status: 500, errorType: goerrorkit.PanicError,
+		location: frame{"main.go", "panicDivisionHandler", "result := 100 / denominator"},
+	},
+	{
+		name: "index out of range", route: "/panic/index", path: "/panic/index",
+		status: 500, errorType: goerrorkit.PanicError,
+		location:  frame{"main.go", "GetElement", "return arr[10]"},
+		callChain: []frame{{"main.go", "panicIndexHandler", "element := GetElement()"}},
+	},
+	{
+		name: "deep call stack", route: "/panic/stack", path: "/panic/stack",
+		status: 500, errorType: goerrorkit.PanicError,
+		location: frame{"main.go", "GetElement", "return arr[10]"},
+		callChain: []frame{
+			{"main.go", "callW", "return GetElement()"},
+			{"main.go", "callZ", "return callW()"},
+			{"main.go", "callY", "return callZ()"},
+			{"main.go", "callX", "return callY()"},
+			{"main.go", "panicStackHandler", "result := callX()"},
+		},
+	},
+	{
+		name: "goroutine", route: "/panic/goroutine", path: "/panic/goroutine",
+		status: 500, errorType: goerrorkit.PanicError,
+		location:  frame{"main.go", "stockSnapshot", "return shelves[0] + shelves[1]"},
+		spawnedBy: []frame{{"main.go", "goroutinePanicHandler", "g.Go(func() error {"}},
+	},
+	{
+		name: "nil pointer dereference", route: "/panic/nil-pointer", path: "/panic/nil-pointer",
+		status: 500, errorType: goerrorkit.PanicError,
+		location:  frame{"panic_handlers.go", "discountPercent", "return rule.Percent"},
+		callChain: []frame{{"panic_handlers.go", "panicNilPointerHandler", "percent := discountPercent(nil)"}},
+	},
+	{
+		name: "nil map write", route: "/panic/nil-map", path: "/panic/nil-map",
+		status: 500, errorType: goerrorkit.PanicError,
+		location: frame{"panic_handlers.go", "panicNilMapHandler", `viewCounts["product-123"]++`},
+	},
+	{
+		name: "panic(customError)", route: "/panic/custom-error", path: "/panic/custom-error",
+		status: 500, errorType: goerrorkit.PanicError,
+		message:  "Panic recovered: inventory 123: stock ledger corrupted",
+		location: frame{"panic_handlers.go", "panicErrorValueHandler", "panic(&inventoryError{"},
+	},
+	{
+		name: "panic(struct)", route: "/panic/struct", path: "/panic/struct",
+		status: 500, errorType: goerrorkit.PanicError,
+		location: frame{"panic_handlers.go", "panicStructHandler", "panic(state)"},
+	},
+	{
+		name: "panic in deferred function", route: "/panic/defer", path: "/panic/defer",
+		status: 500, errorType: goerrorkit.PanicError,
+		location: frame{"panic_handlers.go", "flushAuditLog", "range *entries"},
+		// Frame của handler (dòng return hay dấu } tùy compiler) không được kiểm tra, chỉ frame của closure
+		callChain: []frame{{"panic_handlers.go", "panicDeferHandler.func1", "flushAuditLog(nil)"}},
+	},
+	{
+		name: "re-panic in deferred recover", route: "/panic/repanic", path: "/panic/repanic",
+		status: 500, errorType: goerrorkit.PanicError,
+		location:  frame{"panic_handlers.go", "panicRePanicHandler.func1", "// ← Re-panic"},
+		callChain: []frame{{"panic_handlers.go", "panicRePanicHandler", "quantities[len(quantities)]"}},
+	},
+	{
+		name: "send on closed channel", route: "/panic/closed-channel", path: "/panic/closed-channel",
+		status: 500, errorType: goerrorkit.PanicError,
+		location: frame{"panic_handlers.go", "panicClosedChannelHandler", "results <- 1"},
+	},
+	{
+		name: "panic during template execute", route: "/panic/template", path: "/panic/template",
+		status: 500, errorType: goerrorkit.SystemError,
+		message:  "Không thể render trang chủ",
+		location: frame{"panic_handlers.go", "panicTemplateHandler", `WrapWithMessage(err, "Không thể render trang chủ")`},
+		cause:    "error calling DevMode",
+	},
+}
+
+// TestPanicLocations kiểm tra location và call_chain được log trỏ đúng dòng gây lỗi
+// cho từng loại panic mà README liệt kê
+func TestPanicLocations(t *testing.T) {
+	for _, tc := range panicCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runRouteCase(t, tc)
+		})
+	}
+}
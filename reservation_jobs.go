@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"fiber_log/jobs"
+	"fiber_log/services"
+)
+
+// ============================================================================
+// Reservation Jobs - Hoàn trả hàng giữ quá hạn
+// ============================================================================
+
+// Tên các jobs của reservation (job_context.name trong log entry)
+const (
+	reservationSweepJob  = "reservation_sweep"  // Scheduled: tìm reservation quá hạn
+	reservationExpireJob = "reservation_expire" // Queued: hoàn trả một reservation, payload_id là reservation ID
+)
+
+// registerReservationJobs đăng ký jobs hoàn trả hàng giữ quá hạn
+// Mỗi reservation là một task riêng: lỗi của một reservation được retry / chuyển vào dead letters
+// mà không chặn các reservation khác
+func registerReservationJobs(runner *jobs.Runner, sweepInterval time.Duration) {
+	runner.Register(jobs.Job{
+		Name:  reservationSweepJob,
+		Every: sweepInterval,
+		Handler: func(ctx context.Context, _ string) error {
+			var errs []error
+			for _, id := range reservationService.DueHolds() {
+				if _, err := runner.Enqueue(reservationExpireJob, id); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return errors.Join(errs...)
+		},
+	})
+
+	runner.Register(jobs.Job{
+		Name: reservationExpireJob,
+		Handler: func(ctx context.Context, reservationID string) error {
+			return reservationService.ExpireHold(reservationID)
+		},
+		Retry: jobs.RetryPolicy{
+			MaxAttempts: 5,
+			Backoff:     time.Second,
+			MaxBackoff:  time.Minute,
+			// Sản phẩm đã bị xóa: thử lại cũng không hoàn trả được
+			Retryable: func(err error) bool { return !errors.Is(err, services.ErrProductNotFound) },
+		},
+	})
+}
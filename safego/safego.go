@@ -0,0 +1,149 @@
+package safego
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+
+	"fiber_log/errhandler"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// Middleware errhandler (cũng như goerrorkit.FiberErrorHandler) chỉ recover panic trên goroutine
+// của request. Panic trong goroutine do handler/service tạo ra sẽ làm crash toàn bộ server,
+// vì vậy mọi goroutine chạy nền phải được tạo qua Go hoặc Group.
+
+// requestIDKey là key của request ID trong context
+type requestIDKey struct{}
+
+// WithRequestID gắn request ID vào context để panic trong goroutine được log kèm request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID trả về request ID trong context ("unknown" nếu không có)
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	return "unknown"
+}
+
+// FiberContext trả về context của request kèm request ID (từ middleware requestid)
+func FiberContext(c *fiber.Ctx) context.Context {
+	ctx := c.UserContext()
+	if id, ok := c.Locals("requestid").(string); ok {
+		ctx = WithRequestID(ctx, id)
+	}
+	return ctx
+}
+
+// Go chạy fn trong goroutine mới (fire-and-forget)
+// Panic trong fn được recover, chuyển thành PANIC error và log, server tiếp tục chạy
+//
+// Example:
+//
+//	safego.Go(safego.FiberContext(c), func() {
+//	    rebuildSearchIndex()
+//	})
+func Go(ctx context.Context, fn func()) {
+	spawnedBy := spawnCallChain()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errhandler.LogError(panicError(ctx, r, spawnedBy), nil)
+			}
+		}()
+		fn()
+	}()
+}
+
+// Group chạy một nhóm goroutines và chờ tất cả hoàn thành (giống errgroup.Group)
+// Panic trong goroutine được recover và trả về từ Wait như một PANIC error,
+// nên khi handler return lỗi này, errhandler log và trả response như panic thông thường
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// WithContext tạo Group và context bị cancel khi goroutine đầu tiên lỗi hoặc panic
+//
+// Example:
+//
+//	g, ctx := safego.WithContext(safego.FiberContext(c))
+//	for _, id := range ids {
+//	    g.Go(func() error { return load(ctx, id) })
+//	}
+//	if err := g.Wait(); err != nil {
+//	    return err
+//	}
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go chạy fn trong goroutine mới của group
+func (g *Group) Go(fn func() error) {
+	spawnedBy := spawnCallChain()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				panicErr := panicError(g.ctx, r, spawnedBy)
+				if !g.fail(panicErr) {
+					// Wait chỉ trả về lỗi đầu tiên, panic không được trả về thì log để không bị mất
+					errhandler.LogError(panicErr, nil)
+				}
+			}
+		}()
+
+		if err := fn(); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+// Wait chờ tất cả goroutines hoàn thành và trả về lỗi đầu tiên (nếu có)
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+// fail ghi nhận lỗi, trả về true nếu đây là lỗi đầu tiên của group
+func (g *Group) fail(err error) bool {
+	first := false
+	g.errOnce.Do(func() {
+		g.err = err
+		g.cancel()
+		first = true
+	})
+	return first
+}
+
+// panicError chuyển panic trong goroutine thành AppError
+//
+// Ngoài location và call_chain của goroutine bị panic (từ goerrorkit.HandlePanic), Details có thêm:
+//   - spawned_by:      call chain tại nơi tạo goroutine (handler/service gọi Go)
+//   - goroutine_stack: stack đầy đủ của goroutine bị panic
+func panicError(ctx context.Context, r interface{}, spawnedBy []string) *goerrorkit.AppError {
+	panicErr := goerrorkit.HandlePanic(r, RequestID(ctx))
+	panicErr.Details["spawned_by"] = spawnedBy
+	panicErr.Details["goroutine_stack"] = string(debug.Stack())
+	return panicErr
+}
+
+// spawnCallChain lấy call chain tại nơi tạo goroutine (đã lọc theo cấu hình stack trace của goerrorkit)
+func spawnCallChain() []string {
+	chain, _ := (&goerrorkit.AppError{}).WithCallChain().Details["call_chain"].([]string)
+	return chain
+}
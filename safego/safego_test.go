@@ -0,0 +1,170 @@
+package safego
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fiber_log/logging"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// captureLogs log lỗi vào memory sink trong lúc test chạy
+// Call chain chỉ giữ frame của package safego (test binary không có package main)
+func captureLogs(t *testing.T) *logging.MemoryOutput {
+	t.Helper()
+	goerrorkit.ConfigureForApplication("fiber_log/safego")
+	memory := logging.NewMemoryOutput()
+	previous := goerrorkit.GetLogger()
+	goerrorkit.SetLogger(logging.New(&logging.Sink{Name: "memory", Output: memory}))
+	t.Cleanup(func() { goerrorkit.SetLogger(previous) })
+	return memory
+}
+
+// spawnWorker là nơi tạo goroutine, phải xuất hiện trong spawned_by
+func spawnWorker(ctx context.Context, fn func()) {
+	Go(ctx, fn)
+}
+
+// assertPanicFields kiểm tra các trường safego thêm vào panic error
+func assertPanicFields(t *testing.T, fields map[string]interface{}, spawner string) {
+	t.Helper()
+	spawnedBy, _ := fields["spawned_by"].([]string)
+	if !containsFrame(spawnedBy, spawner) {
+		t.Errorf("spawned_by = %v, want frame của %s", spawnedBy, spawner)
+	}
+	stack, _ := fields["goroutine_stack"].(string)
+	if !strings.HasPrefix(stack, "goroutine ") || !strings.Contains(stack, "panic(") {
+		t.Errorf("goroutine_stack = %q", stack)
+	}
+}
+
+// containsFrame cho biết call chain có frame chứa function
+func containsFrame(chain []string, function string) bool {
+	for _, frame := range chain {
+		if strings.Contains(frame, function) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGoRecoversPanic(t *testing.T) {
+	memory := captureLogs(t)
+
+	done := make(chan struct{})
+	spawnWorker(WithRequestID(context.Background(), "req-42"), func() {
+		defer close(done)
+		var m map[string]int
+		m["boom"] = 1
+	})
+	<-done
+
+	var errs []*logging.Entry
+	deadline := time.Now().Add(2 * time.Second)
+	for len(errs) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		errs = memory.Errors()
+	}
+	if len(errs) != 1 {
+		t.Fatalf("logged %d errors, want 1", len(errs))
+	}
+	entry := errs[0]
+	if entry.ErrorType() != "PANIC" || entry.RequestID() != "req-42" || !strings.Contains(entry.Message, "nil map") {
+		t.Errorf("entry = %+v", entry)
+	}
+	assertPanicFields(t, entry.Fields, "spawnWorker")
+}
+
+func TestRequestID(t *testing.T) {
+	if got := RequestID(context.Background()); got != "unknown" {
+		t.Errorf("RequestID = %q, want unknown", got)
+	}
+	if got := RequestID(WithRequestID(context.Background(), "")); got != "unknown" {
+		t.Errorf("RequestID(empty) = %q, want unknown", got)
+	}
+	if got := RequestID(WithRequestID(context.Background(), "req-1")); got != "req-1" {
+		t.Errorf("RequestID = %q, want req-1", got)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	errFirst := errors.New("first")
+
+	t.Run("success", func(t *testing.T) {
+		g, _ := WithContext(context.Background())
+		var mu sync.Mutex
+		sum := 0
+		for i := 1; i <= 3; i++ {
+			g.Go(func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				sum += i
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil || sum != 6 {
+			t.Errorf("Wait = %v, sum = %d", err, sum)
+		}
+	})
+
+	t.Run("first error cancels context", func(t *testing.T) {
+		g, ctx := WithContext(context.Background())
+		g.Go(func() error { return errFirst })
+		g.Go(func() error {
+			<-ctx.Done()
+			return errors.New("cancelled")
+		})
+		if err := g.Wait(); err != errFirst {
+			t.Errorf("Wait = %v, want %v", err, errFirst)
+		}
+	})
+
+	t.Run("panic returned from Wait", func(t *testing.T) {
+		memory := captureLogs(t)
+		g, ctx := WithContext(WithRequestID(context.Background(), "req-7"))
+		g.Go(func() error {
+			var items []int
+			_ = items[3]
+			return nil
+		})
+		g.Go(func() error {
+			<-ctx.Done() // Panic cancel context giống lỗi thường
+			return nil
+		})
+
+		err := g.Wait()
+		var appErr *goerrorkit.AppError
+		if !errors.As(err, &appErr) || appErr.Type != goerrorkit.PanicError || appErr.RequestID != "req-7" {
+			t.Fatalf("Wait = %#v, want PANIC error với request ID", err)
+		}
+		assertPanicFields(t, appErr.Details, "TestGroup")
+		// Panic được trả về từ Wait thì handler log, Group không log thêm
+		if errs := memory.Errors(); len(errs) != 0 {
+			t.Errorf("logged %d errors, want 0", len(errs))
+		}
+	})
+
+	t.Run("panic after first error is logged", func(t *testing.T) {
+		memory := captureLogs(t)
+		g, ctx := WithContext(context.Background())
+		g.Go(func() error { return errFirst })
+		g.Go(func() error {
+			<-ctx.Done()
+			panic("late panic")
+		})
+
+		if err := g.Wait(); err != errFirst {
+			t.Errorf("Wait = %v, want %v", err, errFirst)
+		}
+		errs := memory.Errors()
+		if len(errs) != 1 || errs[0].ErrorType() != "PANIC" || !strings.Contains(errs[0].Message, "late panic") {
+			t.Fatalf("logged = %+v, want 1 PANIC entry", errs)
+		}
+		assertPanicFields(t, errs[0].Fields, "TestGroup")
+	})
+}
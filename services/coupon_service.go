@@ -0,0 +1,401 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Coupon / Promotion Engine - Mã giảm giá áp dụng cho giỏ hàng
+// ============================================================================
+
+// DiscountKind là cách tính giảm giá của coupon
+type DiscountKind string
+
+const (
+	DiscountPercent DiscountKind = "percent" // Giảm Percent% trên tổng tiền sản phẩm được áp dụng
+	DiscountFixed   DiscountKind = "fixed"   // Giảm Amount (không vượt quá tổng tiền sản phẩm được áp dụng)
+)
+
+// Mã lỗi (data.error_code) của từng lý do coupon bị từ chối, client dùng để hiển thị thông báo phù hợp
+const (
+	CouponNotFound      = "COUPON_NOT_FOUND"          // 404
+	CouponExpired       = "COUPON_EXPIRED"            // 410
+	CouponExhausted     = "COUPON_EXHAUSTED"          // 409: hết tổng số lượt dùng
+	CouponUserLimit     = "COUPON_USER_LIMIT_REACHED" // 409: user đã dùng hết số lượt cho phép
+	CouponMinOrder      = "COUPON_MIN_ORDER_NOT_MET"  // 422
+	CouponNotApplicable = "COUPON_NOT_APPLICABLE"     // 422: giỏ hàng không có sản phẩm được áp dụng
+	CouponNotStackable  = "COUPON_NOT_STACKABLE"      // 422: coupon không dùng chung với coupon khác
+)
+
+// Coupon là một mã giảm giá
+type Coupon struct {
+	Code           string
+	Kind           DiscountKind
+	Percent        float64   // DiscountPercent: phần trăm giảm
+	Amount         Money     // DiscountFixed: số tiền giảm, cùng tiền tệ với giỏ hàng
+	MinOrder       Money     // Tổng giỏ hàng tối thiểu, 0 = không giới hạn
+	ProductIDs     []string  // Sản phẩm được áp dụng, rỗng = mọi sản phẩm
+	ExpiresAt      time.Time // Zero = không hết hạn
+	MaxUses        int       // Tổng số lượt dùng, 0 = không giới hạn
+	MaxUsesPerUser int       // Số lượt dùng của mỗi user, 0 = không giới hạn
+	Stackable      bool      // Cho phép dùng chung với coupon stackable khác
+}
+
+// CartItem là một dòng trong giỏ hàng
+type CartItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+// CartRequest là giỏ hàng cần áp dụng coupon
+type CartRequest struct {
+	UserID  string     `json:"user_id"`
+	Items   []CartItem `json:"items"`
+	Coupons []string   `json:"coupons"`
+}
+
+// AppliedCoupon là số tiền được giảm bởi một coupon
+type AppliedCoupon struct {
+	Code    string       `json:"code"`
+	Kind    DiscountKind `json:"kind"`
+	Percent float64      `json:"percent,omitempty"`
+	Amount  Money        `json:"amount"`
+}
+
+// CartQuote là kết quả áp dụng coupon cho giỏ hàng
+type CartQuote struct {
+	Subtotal Money           `json:"subtotal"`
+	Discount Money           `json:"discount"`
+	Total    Money           `json:"total"`
+	Applied  []AppliedCoupon `json:"applied"`
+}
+
+// cartTotals là thành tiền của giỏ hàng, mọi số tiền cùng tiền tệ
+type cartTotals struct {
+	subtotal  Money
+	byProduct map[string]Money // product_id -> thành tiền
+}
+
+// CouponService quản lý coupon và số lượt đã dùng
+type CouponService struct {
+	productService *ProductService
+
+	mu       sync.Mutex
+	coupons  map[string]*Coupon
+	uses     map[string]int            // code -> tổng số lượt đã dùng
+	userUses map[string]map[string]int // code -> user -> số lượt đã dùng
+	now      func() time.Time
+}
+
+// NewCouponService tạo CouponService với các coupon mẫu
+func NewCouponService(productService *ProductService) *CouponService {
+	s := &CouponService{
+		productService: productService,
+		coupons:        make(map[string]*Coupon),
+		uses:           make(map[string]int),
+		userUses:       make(map[string]map[string]int),
+		now:            time.Now,
+	}
+	for _, coupon := range []*Coupon{
+		{Code: "SAVE10", Kind: DiscountPercent, Percent: 10, Stackable: true},
+		{Code: "WELCOME50", Kind: DiscountFixed, Amount: MustParseMoney("50", USD), MinOrder: MustParseMoney("500", USD), MaxUsesPerUser: 1, Stackable: true},
+		{Code: "APPLE20", Kind: DiscountPercent, Percent: 20, ProductIDs: []string{"456"}},
+		{Code: "SUMMER2024", Kind: DiscountPercent, Percent: 15, ExpiresAt: time.Date(2024, 8, 31, 23, 59, 59, 0, time.UTC)},
+		{Code: "FLASH100", Kind: DiscountFixed, Amount: MustParseMoney("100", USD), MaxUses: 2},
+	} {
+		s.coupons[coupon.Code] = coupon
+	}
+	s.uses["FLASH100"] = 2 // Đã hết lượt
+	return s
+}
+
+// ApplyCoupons áp dụng coupon cho giỏ hàng và ghi nhận lượt dùng
+// Mọi coupon bị từ chối được trả về cùng lúc (errors.Join), mỗi lỗi là BusinessError
+// có data.error_code riêng và hỗ trợ errors.Is với sentinel tương ứng (ErrCouponExpired, ...)
+func (s *CouponService) ApplyCoupons(req CartRequest) (*CartQuote, error) {
+	return s.quote(req, true)
+}
+
+// PreviewCoupons tính giá sau giảm như ApplyCoupons nhưng không ghi nhận lượt dùng
+func (s *CouponService) PreviewCoupons(req CartRequest) (*CartQuote, error) {
+	return s.quote(req, false)
+}
+
+// quote kiểm tra giỏ hàng, từng coupon và quy tắc dùng chung rồi tính tiền giảm
+// Giữ s.mu trong suốt quá trình để kiểm tra và ghi nhận lượt dùng là một thao tác
+func (s *CouponService) quote(req CartRequest, redeem bool) (*CartQuote, error) {
+	if err := validateCart(req); err != nil {
+		return nil, err
+	}
+
+	cart, err := s.cartTotals(req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		codes   []string
+		coupons []*Coupon
+		errs    []error
+	)
+	for _, code := range req.Coupons {
+		code = normalizeCouponCode(code)
+		if containsString(codes, code) {
+			errs = append(errs, goerrorkit.NewValidationError(fmt.Sprintf("Mã giảm giá '%s' bị lặp", code), map[string]interface{}{
+				"field":       "coupons",
+				"coupon_code": code,
+			}))
+			continue
+		}
+		codes = append(codes, code)
+
+		coupon, err := s.checkCoupon(code, req.UserID, cart)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		coupons = append(coupons, coupon)
+	}
+	errs = append(errs, checkStacking(coupons, codes)...)
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	if len(errs) > 1 {
+		return nil, errors.Join(errs...)
+	}
+
+	// Coupon đã qua checkCoupon nên discount cùng tiền tệ với giỏ hàng
+	quote := &CartQuote{Subtotal: cart.subtotal, Discount: NewMoney(0, cart.subtotal.Currency())}
+	for _, coupon := range coupons {
+		amount, _ := coupon.discount(cart)
+		quote.Discount, _ = quote.Discount.Add(amount)
+		quote.Applied = append(quote.Applied, AppliedCoupon{
+			Code:    coupon.Code,
+			Kind:    coupon.Kind,
+			Percent: coupon.Percent,
+			Amount:  amount,
+		})
+	}
+	quote.Discount, _ = quote.Discount.Min(cart.subtotal)
+	quote.Total, _ = cart.subtotal.Sub(quote.Discount)
+
+	if redeem {
+		for _, coupon := range coupons {
+			s.uses[coupon.Code]++
+			if s.userUses[coupon.Code] == nil {
+				s.userUses[coupon.Code] = make(map[string]int)
+			}
+			s.userUses[coupon.Code][req.UserID]++
+		}
+	}
+	return quote, nil
+}
+
+// cartTotals tính thành tiền của giỏ hàng
+// Trả về ValidationError (ErrCurrencyMismatch) nếu giỏ hàng có sản phẩm khác tiền tệ
+func (s *CouponService) cartTotals(items []CartItem) (cartTotals, error) {
+	cart := cartTotals{byProduct: make(map[string]Money)}
+	for i, item := range items {
+		product, err := s.productService.GetProduct(item.ProductID)
+		if err != nil {
+			return cart, err
+		}
+		amount := product.Price.Mul(item.Quantity)
+		if i == 0 {
+			cart.subtotal = NewMoney(0, amount.Currency())
+		}
+		if cart.subtotal, err = cart.subtotal.Add(amount); err != nil {
+			return cart, err
+		}
+		previous, ok := cart.byProduct[item.ProductID]
+		if !ok {
+			previous = NewMoney(0, amount.Currency())
+		}
+		cart.byProduct[item.ProductID], _ = previous.Add(amount)
+	}
+	return cart, nil
+}
+
+// validateCart kiểm tra dữ liệu đầu vào của giỏ hàng
+func validateCart(req CartRequest) error {
+	if strings.TrimSpace(req.UserID) == "" {
+		return goerrorkit.NewValidationError("User ID không được để trống", map[string]interface{}{
+			"field":    "user_id",
+			"required": true,
+		})
+	}
+	if len(req.Items) == 0 {
+		return goerrorkit.NewValidationError("Giỏ hàng trống", map[string]interface{}{
+			"field": "items",
+			"min":   1,
+		})
+	}
+	for i, item := range req.Items {
+		if item.Quantity <= 0 {
+			return goerrorkit.NewValidationError("Số lượng phải lớn hơn 0", map[string]interface{}{
+				"field":    fmt.Sprintf("items[%d].quantity", i),
+				"min":      1,
+				"received": item.Quantity,
+			})
+		}
+	}
+	if len(req.Coupons) == 0 {
+		return goerrorkit.NewValidationError("Thiếu mã giảm giá", map[string]interface{}{
+			"field": "coupons",
+			"min":   1,
+		})
+	}
+	return nil
+}
+
+// checkCoupon kiểm tra coupon còn dùng được cho user và giỏ hàng hay không, s.mu phải đang được giữ
+func (s *CouponService) checkCoupon(code, userID string, cart cartTotals) (*Coupon, error) {
+	coupon, exists := s.coupons[code]
+	if !exists {
+		appErr := goerrorkit.NewBusinessError(404, fmt.Sprintf("Mã giảm giá '%s' không tồn tại", code)).WithData(map[string]interface{}{
+			"error_code":  CouponNotFound,
+			"coupon_code": code,
+		})
+		appErr.Cause = &CouponError{Code: code, Err: ErrCouponNotFound}
+		return nil, appErr
+	}
+
+	if !coupon.ExpiresAt.IsZero() && s.now().After(coupon.ExpiresAt) {
+		appErr := goerrorkit.NewBusinessError(410, fmt.Sprintf("Mã giảm giá '%s' đã hết hạn", code)).WithData(map[string]interface{}{
+			"error_code":  CouponExpired,
+			"coupon_code": code,
+			"expired_at":  coupon.ExpiresAt.Format(time.RFC3339),
+		})
+		appErr.Cause = &CouponError{Code: code, Err: ErrCouponExpired}
+		return nil, appErr
+	}
+
+	if coupon.MaxUses > 0 && s.uses[code] >= coupon.MaxUses {
+		appErr := goerrorkit.NewBusinessError(409, fmt.Sprintf("Mã giảm giá '%s' đã hết lượt sử dụng", code)).WithData(map[string]interface{}{
+			"error_code":  CouponExhausted,
+			"coupon_code": code,
+			"max_uses":    coupon.MaxUses,
+			"used":        s.uses[code],
+		})
+		appErr.Cause = &CouponError{Code: code, Err: ErrCouponExhausted}
+		return nil, appErr
+	}
+
+	if used := s.userUses[code][userID]; coupon.MaxUsesPerUser > 0 && used >= coupon.MaxUsesPerUser {
+		appErr := goerrorkit.NewBusinessError(409, fmt.Sprintf("Bạn đã dùng mã giảm giá '%s' %d lần", code, used)).WithData(map[string]interface{}{
+			"error_code":        CouponUserLimit,
+			"coupon_code":       code,
+			"user_id":           userID,
+			"max_uses_per_user": coupon.MaxUsesPerUser,
+			"used":              used,
+		})
+		appErr.Cause = &CouponError{Code: code, Err: ErrCouponExhausted}
+		return nil, appErr
+	}
+
+	if coupon.MinOrder.IsPositive() {
+		c, err := cart.subtotal.Cmp(coupon.MinOrder)
+		if err != nil {
+			return nil, err
+		}
+		if c < 0 {
+			missing, _ := coupon.MinOrder.Sub(cart.subtotal)
+			appErr := goerrorkit.NewBusinessError(422, fmt.Sprintf("Đơn hàng tối thiểu %s để dùng mã '%s'", coupon.MinOrder, code)).WithData(map[string]interface{}{
+				"error_code":  CouponMinOrder,
+				"coupon_code": code,
+				"min_order":   coupon.MinOrder,
+				"subtotal":    cart.subtotal,
+				"missing":     missing,
+			})
+			appErr.Cause = &CouponError{Code: code, Err: ErrCouponNotApplicable}
+			return nil, appErr
+		}
+	}
+
+	discount, err := coupon.discount(cart)
+	if err != nil {
+		return nil, err
+	}
+	if discount.IsZero() {
+		appErr := goerrorkit.NewBusinessError(422, fmt.Sprintf("Mã giảm giá '%s' không áp dụng cho sản phẩm trong giỏ hàng", code)).WithData(map[string]interface{}{
+			"error_code":  CouponNotApplicable,
+			"coupon_code": code,
+			"product_ids": coupon.ProductIDs,
+		})
+		appErr.Cause = &CouponError{Code: code, Err: ErrCouponNotApplicable}
+		return nil, appErr
+	}
+
+	return coupon, nil
+}
+
+// checkStacking kiểm tra quy tắc dùng chung: coupon không stackable chỉ được dùng một mình
+// codes là các mã (đã chuẩn hóa, không lặp) trong request, kể cả mã bị từ chối
+func checkStacking(coupons []*Coupon, codes []string) []error {
+	var errs []error
+	for _, coupon := range coupons {
+		if coupon.Stackable || len(codes) == 1 {
+			continue
+		}
+
+		var others []string
+		for _, code := range codes {
+			if code != coupon.Code {
+				others = append(others, code)
+			}
+		}
+		sort.Strings(others)
+		appErr := goerrorkit.NewBusinessError(422, fmt.Sprintf("Mã giảm giá '%s' không dùng chung với mã khác", coupon.Code)).WithData(map[string]interface{}{
+			"error_code":     CouponNotStackable,
+			"coupon_code":    coupon.Code,
+			"conflicts_with": others,
+		})
+		appErr.Cause = &CouponError{Code: coupon.Code, Err: ErrCouponNotStackable}
+		errs = append(errs, appErr)
+	}
+	return errs
+}
+
+// discount tính tiền giảm của coupon trên các sản phẩm được áp dụng
+// Giảm theo phần trăm được làm tròn half-up đến minor unit (xem Money.Percent)
+func (c *Coupon) discount(cart cartTotals) (Money, error) {
+	eligible := cart.subtotal
+	if len(c.ProductIDs) > 0 {
+		eligible = NewMoney(0, cart.subtotal.Currency())
+		for _, productID := range c.ProductIDs {
+			if amount, ok := cart.byProduct[productID]; ok {
+				eligible, _ = eligible.Add(amount)
+			}
+		}
+	}
+
+	if c.Kind == DiscountFixed {
+		return c.Amount.Min(eligible)
+	}
+	return eligible.Percent(c.Percent)
+}
+
+// normalizeCouponCode chuẩn hóa mã do user nhập (bỏ khoảng trắng, viết hoa)
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// containsString cho biết values có chứa value hay không
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"fiber_log/testkit"
+)
+
+func cart(userID string, coupons ...string) CartRequest {
+	return CartRequest{
+		UserID:  userID,
+		Items:   []CartItem{{ProductID: "456", Quantity: 1}, {ProductID: "789", Quantity: 2}},
+		Coupons: coupons,
+	}
+}
+
+func TestApplyCoupons(t *testing.T) {
+	s := NewCouponService(NewProductService())
+
+	quote, err := s.ApplyCoupons(cart("U1", " save10 ", "WELCOME50"))
+	testkit.AssertNoError(t, err)
+	if quote.Subtotal.String() != "2999.97 USD" || quote.Discount.String() != "350.00 USD" || quote.Total.String() != "2649.97 USD" || len(quote.Applied) != 2 {
+		t.Fatalf("quote = %+v", quote)
+	}
+
+	// Coupon theo sản phẩm chỉ giảm trên sản phẩm được áp dụng
+	quote, err = s.ApplyCoupons(cart("U1", "APPLE20"))
+	testkit.AssertNoError(t, err)
+	if quote.Discount.String() != "500.00 USD" || quote.Total.String() != "2499.97 USD" {
+		t.Errorf("APPLE20 quote = %+v", quote)
+	}
+
+	// Giảm cố định không vượt quá tổng tiền
+	s.coupons["BIG"] = &Coupon{Code: "BIG", Kind: DiscountFixed, Amount: MustParseMoney("5000", USD)}
+	quote, err = s.ApplyCoupons(cart("U1", "BIG"))
+	testkit.AssertNoError(t, err)
+	if quote.Discount.String() != "2999.97 USD" || !quote.Total.IsZero() {
+		t.Errorf("BIG quote = %+v", quote)
+	}
+}
+
+func TestApplyCouponsRejections(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       CartRequest
+		status    int
+		errorCode string
+		sentinel  error
+	}{
+		{"not found", cart("U1", "NOPE"), 404, CouponNotFound, ErrCouponNotFound},
+		{"expired", cart("U1", "SUMMER2024"), 410, CouponExpired, ErrCouponExpired},
+		{"exhausted", cart("U1", "FLASH100"), 409, CouponExhausted, ErrCouponExhausted},
+		{"min order", CartRequest{UserID: "U1", Items: []CartItem{{ProductID: "789", Quantity: 1}}, Coupons: []string{"WELCOME50"}},
+			422, CouponMinOrder, ErrCouponNotApplicable},
+		{"not applicable", CartRequest{UserID: "U1", Items: []CartItem{{ProductID: "789", Quantity: 3}}, Coupons: []string{"APPLE20"}},
+			422, CouponNotApplicable, ErrCouponNotApplicable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCouponService(NewProductService()).ApplyCoupons(tt.req)
+			testkit.AssertErrorType(t, err, testkit.Business)
+			testkit.AssertStatus(t, err, tt.status)
+			testkit.AssertData(t, err, "error_code", tt.errorCode)
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("errors.Is(err, %v) = false", tt.sentinel)
+			}
+			var couponErr *CouponError
+			if !errors.As(err, &couponErr) || couponErr.Code != tt.req.Coupons[0] {
+				t.Errorf("errors.As(err, *CouponError) = %+v", couponErr)
+			}
+		})
+	}
+}
+
+func TestApplyCouponsUsageLimit(t *testing.T) {
+	s := NewCouponService(NewProductService())
+
+	// Preview không ghi nhận lượt dùng
+	_, err := s.PreviewCoupons(cart("U1", "WELCOME50"))
+	testkit.AssertNoError(t, err)
+	_, err = s.ApplyCoupons(cart("U1", "WELCOME50"))
+	testkit.AssertNoError(t, err)
+
+	_, err = s.ApplyCoupons(cart("U1", "WELCOME50"))
+	testkit.AssertStatus(t, err, 409)
+	testkit.AssertLocation(t, err, "checkCoupon")
+	testkit.AssertData(t, err, "error_code", CouponUserLimit)
+	testkit.AssertData(t, err, "used", 1)
+
+	_, err = s.ApplyCoupons(cart("U2", "WELCOME50"))
+	testkit.AssertNoError(t, err)
+
+	// Coupon bị từ chối không ghi nhận lượt dùng của coupon khác trong cùng request
+	_, err = s.ApplyCoupons(cart("U3", "WELCOME50", "NOPE"))
+	testkit.AssertStatus(t, err, 404)
+	_, err = s.ApplyCoupons(cart("U3", "WELCOME50"))
+	testkit.AssertNoError(t, err)
+}
+
+func TestApplyCouponsExpiry(t *testing.T) {
+	s := NewCouponService(NewProductService())
+	s.now = func() time.Time { return time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC) }
+
+	quote, err := s.ApplyCoupons(cart("U1", "SUMMER2024"))
+	testkit.AssertNoError(t, err)
+	if quote.Discount.String() != "450.00 USD" { // 2999.97 * 15% = 449.9955
+		t.Errorf("quote = %+v", quote)
+	}
+}
+
+func TestApplyCouponsStacking(t *testing.T) {
+	s := NewCouponService(NewProductService())
+
+	_, err := s.ApplyCoupons(cart("U1", "APPLE20", "SAVE10"))
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 422)
+	testkit.AssertLocation(t, err, "checkStacking")
+	testkit.AssertData(t, err, "error_code", CouponNotStackable)
+	testkit.AssertData(t, err, "conflicts_with", []string{"SAVE10"})
+	if !errors.Is(err, ErrCouponNotStackable) {
+		t.Error("errors.Is(err, ErrCouponNotStackable) = false")
+	}
+
+	// Nhiều lỗi: trả về tất cả (lặp mã + không dùng chung)
+	_, err = s.ApplyCoupons(cart("U1", "APPLE20", "save10", "SAVE10"))
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("err = %T %v, want lỗi gộp", err, err)
+	}
+	children := joined.Unwrap()
+	if len(children) != 2 {
+		t.Fatalf("got %d lỗi, want 2: %v", len(children), err)
+	}
+	testkit.AssertErrorType(t, children[0], testkit.Validation)
+	testkit.AssertMessage(t, children[0], "Mã giảm giá 'SAVE10' bị lặp")
+	testkit.AssertData(t, children[1], "error_code", CouponNotStackable)
+}
+
+func TestApplyCouponsInvalidCart(t *testing.T) {
+	s := NewCouponService(NewProductService())
+
+	tests := []struct {
+		name  string
+		req   CartRequest
+		field string
+	}{
+		{"missing user", CartRequest{Items: []CartItem{{ProductID: "456", Quantity: 1}}, Coupons: []string{"SAVE10"}}, "user_id"},
+		{"empty cart", CartRequest{UserID: "U1", Coupons: []string{"SAVE10"}}, "items"},
+		{"bad quantity", CartRequest{UserID: "U1", Items: []CartItem{{ProductID: "456", Quantity: 0}}, Coupons: []string{"SAVE10"}}, "items[0].quantity"},
+		{"no coupon", CartRequest{UserID: "U1", Items: []CartItem{{ProductID: "456", Quantity: 1}}}, "coupons"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ApplyCoupons(tt.req)
+			testkit.AssertErrorType(t, err, testkit.Validation)
+			testkit.AssertLocation(t, err, "validateCart")
+			testkit.AssertData(t, err, "field", tt.field)
+		})
+	}
+
+	_, err := s.ApplyCoupons(CartRequest{UserID: "U1", Items: []CartItem{{ProductID: "999", Quantity: 1}}, Coupons: []string{"SAVE10"}})
+	if !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("err = %v, want ErrProductNotFound", err)
+	}
+}
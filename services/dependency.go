@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// External Dependencies - Lỗi của dependency bên ngoài và simulator chạy local
+// ============================================================================
+
+// Mọi lỗi khi gọi dependency (shipping, notification) là ExternalError có data dependency, operation,
+// upstream_status và Cause là *DependencyError bọc sentinel theo loại lỗi, nên caller quyết định retry
+// bằng DependencyError.Temporary thay vì so sánh status code
+//
+// Simulator đọc FailureMode của từng operation (SHIPPING_SIM_FAILURES, NOTIFICATION_SIM_FAILURES):
+//
+//	failures, _ := services.ParseFailures("create_label=timeout:2,quote=unavailable", services.ShippingOperations)
+//	shipping := services.NewShippingSimulator(failures)
+//	// 2 lần CreateLabel đầu: ExternalError 504, từ lần thứ 3 thành công; Quote luôn lỗi 503
+
+// Tên dependency (data.dependency của ExternalError)
+const (
+	DependencyShipping     = "shipping"
+	DependencyNotification = "notification"
+)
+
+// FailureMode là cách simulator giả lập dependency bị lỗi
+type FailureMode string
+
+const (
+	FailureTimeout     FailureMode = "timeout"      // Upstream không phản hồi kịp: ExternalError 504
+	FailureUnavailable FailureMode = "unavailable"  // Upstream trả 503: ExternalError 503
+	FailureRateLimited FailureMode = "rate_limited" // Upstream trả 429: ExternalError 503
+	FailureRejected    FailureMode = "rejected"     // Upstream trả 422: ExternalError 502, retry cũng không thành công
+)
+
+// failureModes mô tả từng FailureMode: status trả cho client, status của upstream, sentinel và lý do
+var failureModes = map[FailureMode]struct {
+	status   int
+	upstream int
+	sentinel error
+	reason   string
+}{
+	FailureTimeout:     {504, 504, ErrDependencyTimeout, "không phản hồi sau 10s"},
+	FailureUnavailable: {503, 503, ErrDependencyUnavailable, "upstream trả về 503 Service Unavailable"},
+	FailureRateLimited: {503, 429, ErrDependencyRateLimited, "upstream trả về 429 Too Many Requests"},
+	FailureRejected:    {502, 422, ErrDependencyRejected, "upstream từ chối request (422)"},
+}
+
+// Failure là lỗi được cấu hình cho một operation của simulator
+type Failure struct {
+	Mode  FailureMode `json:"mode"`
+	Times int         `json:"times,omitempty"` // Số lần lỗi trước khi thành công (0: luôn lỗi)
+}
+
+// ParseFailures đọc cấu hình lỗi dạng "operation=mode[:times],..." (ví dụ "create_label=timeout:2")
+// operations là các operation hợp lệ của dependency, chuỗi rỗng nghĩa là không có lỗi nào
+func ParseFailures(spec string, operations []string) (map[string]Failure, error) {
+	failures := make(map[string]Failure)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		operation, value, ok := strings.Cut(item, "=")
+		if !ok || !slices.Contains(operations, operation) {
+			return nil, fmt.Errorf("failure %q: operation must be one of %v", item, operations)
+		}
+
+		mode, times, _ := strings.Cut(value, ":")
+		failure := Failure{Mode: FailureMode(mode)}
+		if _, known := failureModes[failure.Mode]; !known {
+			return nil, fmt.Errorf("failure %q: unknown mode %q", item, mode)
+		}
+		if times != "" {
+			n, err := strconv.Atoi(times)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("failure %q: times must be a positive integer", item)
+			}
+			failure.Times = n
+		}
+		failures[operation] = failure
+	}
+	return failures, nil
+}
+
+// simulator là phần chung của các simulator: đếm số lần gọi từng operation
+// và trả về lỗi theo Failure đã cấu hình
+type simulator struct {
+	dependency string
+
+	mu       sync.Mutex
+	failures map[string]Failure
+	calls    map[string]int
+}
+
+// newSimulator tạo simulator của dependency với các lỗi ban đầu (bản sao của failures)
+func newSimulator(dependency string, failures map[string]Failure) *simulator {
+	s := &simulator{dependency: dependency, failures: make(map[string]Failure), calls: make(map[string]int)}
+	for operation, failure := range failures {
+		s.failures[operation] = failure
+	}
+	return s
+}
+
+// SetFailure đổi lỗi của operation, Mode rỗng nghĩa là operation không còn lỗi
+func (s *simulator) SetFailure(operation string, failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if failure.Mode == "" {
+		delete(s.failures, operation)
+		return
+	}
+	s.failures[operation] = failure
+}
+
+// Calls trả về số lần operation đã được gọi (kể cả các lần lỗi)
+func (s *simulator) Calls(operation string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[operation]
+}
+
+// call ghi nhận một lần gọi operation, trả về ExternalError nếu operation đang được cấu hình lỗi
+// data (ví dụ order_id) được thêm vào data của lỗi
+func (s *simulator) call(operation string, data map[string]interface{}) error {
+	s.mu.Lock()
+	s.calls[operation]++
+	failure, failing := s.failures[operation]
+	if failing && failure.Times > 0 {
+		if failure.Times--; failure.Times == 0 {
+			delete(s.failures, operation)
+		} else {
+			s.failures[operation] = failure
+		}
+	}
+	s.mu.Unlock()
+
+	if !failing {
+		return nil
+	}
+	return dependencyFailure(s.dependency, operation, failure.Mode, data)
+}
+
+// dependencyFailure tạo ExternalError cho lần gọi operation của dependency bị lỗi theo mode
+func dependencyFailure(dependency, operation string, mode FailureMode, data map[string]interface{}) error {
+	spec := failureModes[mode]
+	fields := map[string]interface{}{
+		"dependency":      dependency,
+		"operation":       operation,
+		"upstream_status": spec.upstream,
+	}
+	for k, v := range data {
+		fields[k] = v
+	}
+	return goerrorkit.NewExternalError(
+		spec.status,
+		fmt.Sprintf("Dependency %s lỗi khi %s: %s", dependency, operation, spec.reason),
+		&DependencyError{Dependency: dependency, Operation: operation, UpstreamStatus: spec.upstream, Err: spec.sentinel},
+	).WithData(fields)
+}
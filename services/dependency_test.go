@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"fiber_log/testkit"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+func TestParseFailures(t *testing.T) {
+	failures, err := ParseFailures(" create_label=timeout:2, quote=unavailable ", ShippingOperations)
+	testkit.AssertNoError(t, err)
+	want := map[string]Failure{
+		OperationCreateLabel: {Mode: FailureTimeout, Times: 2},
+		OperationQuote:       {Mode: FailureUnavailable},
+	}
+	if !reflect.DeepEqual(failures, want) {
+		t.Errorf("failures = %v, want %v", failures, want)
+	}
+
+	if failures, err := ParseFailures("", ShippingOperations); err != nil || len(failures) != 0 {
+		t.Errorf("ParseFailures(\"\") = %v, %v", failures, err)
+	}
+	for _, spec := range []string{"send_sms=timeout", "quote", "quote=flaky", "quote=timeout:0", "quote=timeout:x"} {
+		if _, err := ParseFailures(spec, ShippingOperations); err == nil {
+			t.Errorf("ParseFailures(%q) không trả về lỗi", spec)
+		}
+	}
+}
+
+// TestDependencyFailures kiểm tra ExternalError của từng FailureMode: status, upstream_status, sentinel, Temporary
+func TestDependencyFailures(t *testing.T) {
+	tests := []struct {
+		mode      FailureMode
+		status    int
+		upstream  int
+		sentinel  error
+		temporary bool
+	}{
+		{FailureTimeout, 504, 504, ErrDependencyTimeout, true},
+		{FailureUnavailable, 503, 503, ErrDependencyUnavailable, true},
+		{FailureRateLimited, 503, 429, ErrDependencyRateLimited, true},
+		{FailureRejected, 502, 422, ErrDependencyRejected, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			shipping := NewShippingSimulator(map[string]Failure{OperationCreateLabel: {Mode: tt.mode}})
+			_, err := shipping.CreateLabel(ShipmentRequest{OrderID: "ORD-1"}, Rate{ID: "ORD-1-standard"})
+
+			testkit.AssertErrorType(t, err, testkit.External)
+			testkit.AssertStatus(t, err, tt.status)
+			testkit.AssertLocation(t, err, "services/dependency.go:dependencyFailure")
+			testkit.AssertData(t, err, "dependency", DependencyShipping)
+			testkit.AssertData(t, err, "operation", OperationCreateLabel)
+			testkit.AssertData(t, err, "upstream_status", tt.upstream)
+			testkit.AssertData(t, err, "order_id", "ORD-1")
+
+			var depErr *DependencyError
+			if !errors.As(goerrorkit.Wrap(err), &depErr) || !errors.Is(err, tt.sentinel) {
+				t.Fatalf("err = %v, want *DependencyError bọc %v", err, tt.sentinel)
+			}
+			if depErr.UpstreamStatus != tt.upstream || depErr.Temporary() != tt.temporary {
+				t.Errorf("DependencyError = %+v, temporary = %v", depErr, depErr.Temporary())
+			}
+		})
+	}
+}
+
+func TestSimulatorFailureTimes(t *testing.T) {
+	notifier := NewNotificationSimulator(map[string]Failure{OperationSendSMS: {Mode: FailureUnavailable, Times: 2}})
+
+	for i := 1; i <= 2; i++ {
+		if _, err := notifier.SendSMS(SMS{To: "+84901234567"}); err == nil {
+			t.Fatalf("lần gửi %d không lỗi", i)
+		}
+	}
+	_, err := notifier.SendSMS(SMS{To: "+84901234567"})
+	testkit.AssertNoError(t, err)
+	if calls := notifier.Calls(OperationSendSMS); calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+
+	// SetFailure đổi lỗi lúc đang chạy, Mode rỗng là hết lỗi
+	notifier.SetFailure(OperationSendEmail, Failure{Mode: FailureRejected})
+	_, err = notifier.SendEmail(Email{To: "an@example.com"})
+	testkit.AssertStatus(t, err, 502)
+	notifier.SetFailure(OperationSendEmail, Failure{})
+	_, err = notifier.SendEmail(Email{To: "an@example.com"})
+	testkit.AssertNoError(t, err)
+}
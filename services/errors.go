@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ============================================================================
+// Sentinel Errors - Cho phép caller phân nhánh bằng errors.Is thay vì so sánh message
+// ============================================================================
+
+// Các lỗi goerrorkit do services trả về có Cause là typed error bọc sentinel tương ứng,
+// nên errors.Is / errors.As vẫn hoạt động sau khi bị Wrap / WrapWithMessage ở tầng trên
+//
+// Example:
+//
+//	err := productService.CheckStock(id)
+//	if errors.Is(err, services.ErrOutOfStock) {
+//	    // Hết hàng: gợi ý sản phẩm khác thay vì trả lỗi
+//	}
+//
+//	var productErr *services.ProductError
+//	if errors.As(err, &productErr) {
+//	    log.Println("product:", productErr.ProductID)
+//	}
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrProductExists   = errors.New("product already exists")
+	ErrProductModified = errors.New("product modified since last read")
+	ErrOutOfStock      = errors.New("product out of stock")
+	ErrOrderShipped    = errors.New("order already shipped")
+	ErrPaymentDeclined = errors.New("payment declined")
+
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderNotPaid        = errors.New("order not paid")
+	ErrOrderPaid           = errors.New("order already paid")
+	ErrRefundExceeded      = errors.New("refund exceeds refundable amount")
+	ErrRefundWindowExpired = errors.New("refund window expired")
+	ErrRefundDeclined      = errors.New("refund declined")
+
+	ErrInsufficientStock = errors.New("insufficient stock in warehouse")
+	ErrWarehouseNotFound = errors.New("warehouse not found")
+
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationExpired  = errors.New("reservation expired")
+	ErrReservationClosed   = errors.New("reservation no longer held")
+
+	ErrInvalidAmount       = errors.New("invalid money amount")
+	ErrAmountPrecision     = errors.New("too many decimal places for currency")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponExpired       = errors.New("coupon expired")
+	ErrCouponExhausted     = errors.New("coupon usage limit reached")
+	ErrCouponNotApplicable = errors.New("coupon not applicable")
+	ErrCouponNotStackable  = errors.New("coupon cannot be combined")
+
+	ErrUserNotFound = errors.New("user not found")
+	ErrEmailTaken   = errors.New("email already registered")
+
+	ErrWebhookSignature = errors.New("invalid webhook signature")
+	ErrWebhookExpired   = errors.New("webhook timestamp outside tolerance")
+	ErrWebhookDisabled  = errors.New("webhook secret not configured")
+	ErrPaymentMismatch  = errors.New("payment amount does not match order total")
+
+	ErrDependencyTimeout     = errors.New("dependency timeout")
+	ErrDependencyUnavailable = errors.New("dependency unavailable")
+	ErrDependencyRateLimited = errors.New("dependency rate limited")
+	ErrDependencyRejected    = errors.New("dependency rejected request")
+)
+
+// ProductError gắn product ID vào sentinel error của sản phẩm
+type ProductError struct {
+	ProductID string
+	Err       error
+}
+
+func (e *ProductError) Error() string {
+	return fmt.Sprintf("product %s: %v", e.ProductID, e.Err)
+}
+
+func (e *ProductError) Unwrap() error {
+	return e.Err
+}
+
+// OrderError gắn order ID vào sentinel error của đơn hàng
+type OrderError struct {
+	OrderID string
+	Err     error
+}
+
+func (e *OrderError) Error() string {
+	return fmt.Sprintf("order %s: %v", e.OrderID, e.Err)
+}
+
+func (e *OrderError) Unwrap() error {
+	return e.Err
+}
+
+// PaymentError là lỗi thanh toán / hoàn tiền do payment gateway trả về
+type PaymentError struct {
+	OrderID string
+	Reason  string // Lý do từ gateway (ví dụ "card declined by bank")
+	Err     error
+}
+
+func (e *PaymentError) Error() string {
+	return fmt.Sprintf("payment for order %s: %s", e.OrderID, e.Reason)
+}
+
+func (e *PaymentError) Unwrap() error {
+	return e.Err
+}
+
+// WarehouseError gắn warehouse ID vào sentinel error của kho hàng
+type WarehouseError struct {
+	WarehouseID string
+	Err         error
+}
+
+func (e *WarehouseError) Error() string {
+	return fmt.Sprintf("warehouse %s: %v", e.WarehouseID, e.Err)
+}
+
+func (e *WarehouseError) Unwrap() error {
+	return e.Err
+}
+
+// ReservationError gắn reservation ID vào sentinel error của giữ hàng
+type ReservationError struct {
+	ReservationID string
+	Err           error
+}
+
+func (e *ReservationError) Error() string {
+	return fmt.Sprintf("reservation %s: %v", e.ReservationID, e.Err)
+}
+
+func (e *ReservationError) Unwrap() error {
+	return e.Err
+}
+
+// UserError gắn user ID (hoặc email khi đăng ký trùng) vào sentinel error của user
+type UserError struct {
+	UserID string
+	Email  string
+	Err    error
+}
+
+func (e *UserError) Error() string {
+	if e.UserID == "" {
+		return fmt.Sprintf("user %s: %v", e.Email, e.Err)
+	}
+	return fmt.Sprintf("user %s: %v", e.UserID, e.Err)
+}
+
+func (e *UserError) Unwrap() error {
+	return e.Err
+}
+
+// WebhookError gắn event ID (nếu đã đọc được) và lý do vào sentinel error của webhook
+type WebhookError struct {
+	EventID string
+	Reason  string
+	Err     error
+}
+
+func (e *WebhookError) Error() string {
+	if e.EventID == "" {
+		return fmt.Sprintf("webhook: %s: %v", e.Reason, e.Err)
+	}
+	return fmt.Sprintf("webhook %s: %s: %v", e.EventID, e.Reason, e.Err)
+}
+
+func (e *WebhookError) Unwrap() error {
+	return e.Err
+}
+
+// DependencyError là lỗi khi gọi dependency bên ngoài (shipping, notification)
+type DependencyError struct {
+	Dependency     string
+	Operation      string // Ví dụ "create_label", "send_sms"
+	UpstreamStatus int    // HTTP status upstream trả về
+	Err            error
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("%s %s: upstream %d: %v", e.Dependency, e.Operation, e.UpstreamStatus, e.Err)
+}
+
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary cho biết gọi lại sau có thể thành công (mọi lỗi trừ ErrDependencyRejected)
+func (e *DependencyError) Temporary() bool {
+	return !errors.Is(e.Err, ErrDependencyRejected)
+}
+
+// CouponError gắn mã coupon vào sentinel error của coupon
+type CouponError struct {
+	Code string
+	Err  error
+}
+
+func (e *CouponError) Error() string {
+	return fmt.Sprintf("coupon %s: %v", e.Code, e.Err)
+}
+
+func (e *CouponError) Unwrap() error {
+	return e.Err
+}
+
+// CurrencyError là lỗi khi tính toán hai số tiền khác tiền tệ
+type CurrencyError struct {
+	Expected Currency
+	Received Currency
+	Err      error
+}
+
+func (e *CurrencyError) Error() string {
+	return fmt.Sprintf("currency %s, received %s: %v", e.Expected, e.Received, e.Err)
+}
+
+func (e *CurrencyError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// TestSentinelErrors kiểm tra errors.Is / errors.As trên lỗi trả về từ services,
+// kể cả sau khi bị Wrap / WrapWithMessage ở tầng trên
+func TestSentinelErrors(t *testing.T) {
+	products := NewProductService()
+	orders := NewOrderService(products)
+
+	_, notFound := products.GetProduct("999")
+	outOfStock := products.CheckStock("123")
+	shipped := orders.CancelOrder("ORD-shipped")
+	declined := orders.ProcessPayment("ORD-invalid-card", MustParseMoney("100", USD))
+
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+	}{
+		{"product not found", notFound, ErrProductNotFound},
+		{"out of stock", outOfStock, ErrOutOfStock},
+		{"order shipped", shipped, ErrOrderShipped},
+		{"payment declined", declined, ErrPaymentDeclined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chains := map[string]error{
+				"direct":          tt.err,
+				"Wrap":            goerrorkit.Wrap(tt.err),
+				"WrapWithMessage": goerrorkit.WrapWithMessage(tt.err, "handler context"),
+			}
+			for name, err := range chains {
+				if !errors.Is(err, tt.sentinel) {
+					t.Errorf("%s: errors.Is(err, %v) = false", name, tt.sentinel)
+				}
+			}
+		})
+	}
+
+	// Sentinel không bị nhầm lẫn giữa các lỗi
+	if errors.Is(outOfStock, ErrProductNotFound) || errors.Is(notFound, ErrOutOfStock) {
+		t.Error("sentinel errors không được khớp chéo")
+	}
+
+	var productErr *ProductError
+	if !errors.As(goerrorkit.Wrap(outOfStock), &productErr) || productErr.ProductID != "123" {
+		t.Errorf("errors.As(*ProductError) = %+v", productErr)
+	}
+	var orderErr *OrderError
+	if !errors.As(shipped, &orderErr) || orderErr.OrderID != "ORD-shipped" {
+		t.Errorf("errors.As(*OrderError) = %+v", orderErr)
+	}
+	var paymentErr *PaymentError
+	if !errors.As(declined, &paymentErr) || paymentErr.Reason != "card declined by bank" {
+		t.Errorf("errors.As(*PaymentError) = %+v", paymentErr)
+	}
+}
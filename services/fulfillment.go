@@ -0,0 +1,157 @@
+package services
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Fulfillment - Giao đơn hàng đã thanh toán: tạo vận đơn và báo cho khách
+// ============================================================================
+
+// Fulfill chạy sau khi thanh toán (job order_fulfillment): chọn mức phí rẻ nhất, tạo vận đơn
+// rồi gửi email (và SMS nếu user có số điện thoại) kèm mã vận đơn. Mỗi bước thành công được lưu
+// vào đơn hàng nên gọi lại Fulfill sau lỗi chỉ chạy các bước còn lại, không tạo vận đơn hay gửi email hai lần
+//
+// Example:
+//
+//	order, err := fulfillmentService.Fulfill("ORD-USER001-456")
+//	var depErr *services.DependencyError
+//	if errors.As(err, &depErr) && depErr.Temporary() {
+//	    // shipping / notification lỗi tạm thời: thử lại sau
+//	}
+
+// FulfillmentService giao đơn hàng qua ShippingClient và báo cho khách qua Notifier
+type FulfillmentService struct {
+	orders   *OrderService
+	users    *UserService
+	shipping ShippingClient
+	notifier Notifier
+}
+
+// NewFulfillmentService tạo FulfillmentService, địa chỉ nhận thông báo lấy từ users theo UserID của đơn hàng
+func NewFulfillmentService(orders *OrderService, users *UserService, shipping ShippingClient, notifier Notifier) *FulfillmentService {
+	return &FulfillmentService{orders: orders, users: users, shipping: shipping, notifier: notifier}
+}
+
+// Fulfill tạo vận đơn và gửi thông báo còn thiếu của đơn hàng, trả về đơn hàng sau khi xử lý
+// Đơn hàng đã hoàn toàn bộ tiền trước khi có vận đơn không được giao
+// Lỗi:
+//   - BusinessError 404 nếu đơn hàng không tồn tại (ErrOrderNotFound)
+//   - BusinessError 409 nếu đơn hàng chưa thanh toán (ErrOrderNotPaid)
+//   - ExternalError nếu shipping / notification lỗi (*DependencyError), các bước đã xong vẫn được giữ
+func (f *FulfillmentService) Fulfill(orderID string) (*Order, error) {
+	order, err := f.orders.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Paid == nil {
+		appErr := goerrorkit.NewBusinessError(409, fmt.Sprintf("Đơn hàng %s chưa được thanh toán", orderID)).WithData(map[string]interface{}{
+			"order_id": orderID,
+			"status":   order.Status,
+		})
+		appErr.Cause = &OrderError{OrderID: orderID, Err: ErrOrderNotPaid} // errors.Is(err, ErrOrderNotPaid)
+		return nil, appErr
+	}
+
+	if order.Shipment == nil {
+		if order.Status == OrderRefunded {
+			return order, nil
+		}
+		if err := f.ship(order); err != nil {
+			return nil, err
+		}
+		if order, err = f.orders.GetOrder(orderID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.notify(order); err != nil {
+		return nil, err
+	}
+	return f.orders.GetOrder(orderID)
+}
+
+// ship báo giá, chọn mức phí rẻ nhất và tạo vận đơn cho đơn hàng
+func (f *FulfillmentService) ship(order *Order) error {
+	req := ShipmentRequest{OrderID: order.ID, Parcels: order.Allocations}
+	rates, err := f.shipping.Quote(req)
+	if err != nil {
+		return err
+	}
+	if len(rates) == 0 {
+		return goerrorkit.NewBusinessError(422, fmt.Sprintf("Không có dịch vụ vận chuyển cho đơn hàng %s", order.ID)).WithData(map[string]interface{}{
+			"order_id": order.ID,
+			"parcels":  len(req.Parcels),
+		})
+	}
+	rate := slices.MinFunc(rates, func(a, b Rate) int { return cmp.Compare(a.Cost.Minor(), b.Cost.Minor()) })
+
+	label, err := f.shipping.CreateLabel(req, rate)
+	if err != nil {
+		return err
+	}
+	f.orders.recordShipment(order.ID, *label)
+	return nil
+}
+
+// notify gửi các thông báo chưa gửi về vận đơn của đơn hàng
+// User không tồn tại (đơn hàng tạo với user_id tùy ý) thì không có địa chỉ để gửi
+func (f *FulfillmentService) notify(order *Order) error {
+	if order.Shipment == nil {
+		return nil
+	}
+	user, err := f.users.GetUser(order.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Đơn hàng %s đã được giao cho %s, mã vận đơn %s", order.ID, order.Shipment.Carrier, order.Shipment.TrackingNumber)
+	if !order.notified(ChannelEmail) {
+		delivery, err := f.notifier.SendEmail(Email{To: user.Email, Subject: fmt.Sprintf("Đơn hàng %s đang được giao", order.ID), Body: body})
+		if err != nil {
+			return err
+		}
+		f.orders.recordDelivery(order.ID, *delivery)
+	}
+	if user.Phone != "" && !order.notified(ChannelSMS) {
+		delivery, err := f.notifier.SendSMS(SMS{To: user.Phone, Body: body})
+		if err != nil {
+			return err
+		}
+		f.orders.recordDelivery(order.ID, *delivery)
+	}
+	return nil
+}
+
+// notified cho biết đã gửi thông báo qua channel cho đơn hàng chưa
+func (o *Order) notified(channel string) bool {
+	return slices.ContainsFunc(o.Notifications, func(d Delivery) bool { return d.Channel == channel })
+}
+
+// recordShipment lưu vận đơn của đơn hàng, đơn đang paid chuyển sang shipped
+// (đơn đã hoàn một phần giữ nguyên trạng thái)
+func (s *OrderService) recordShipment(orderID string, label Label) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order := s.orders[orderID]
+	order.Shipment = &label
+	if order.Status == OrderPaid {
+		order.Status = OrderShipped
+	}
+}
+
+// recordDelivery lưu thông báo đã gửi cho khách về đơn hàng
+func (s *OrderService) recordDelivery(orderID string, delivery Delivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order := s.orders[orderID]
+	order.Notifications = append(order.Notifications, delivery)
+}
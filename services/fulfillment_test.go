@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"fiber_log/testkit"
+)
+
+// newFulfillment tạo FulfillmentService với simulators cho đơn hàng 2 x 456 của USER001 đã thanh toán
+func newFulfillment(t *testing.T) (*FulfillmentService, *ShippingSimulator, *NotificationSimulator, *Order) {
+	t.Helper()
+	orders, order, _ := newPaidOrder(t, NewProductService(), "456")
+	shipping, notifier := NewShippingSimulator(nil), NewNotificationSimulator(nil)
+	return NewFulfillmentService(orders, NewUserService(), shipping, notifier), shipping, notifier, order
+}
+
+func TestFulfill(t *testing.T) {
+	f, shipping, notifier, order := newFulfillment(t)
+
+	fulfilled, err := f.Fulfill(order.ID)
+	testkit.AssertNoError(t, err)
+	if fulfilled.Status != OrderShipped || fulfilled.Shipment == nil || fulfilled.Shipment.Service != "standard" {
+		t.Fatalf("order = %+v, want shipped với mức phí rẻ nhất", fulfilled)
+	}
+	sent := notifier.Sent()
+	if len(sent) != 2 || sent[0].To != "an@example.com" || sent[1].To != "+84901234567" || len(fulfilled.Notifications) != 2 {
+		t.Errorf("sent = %+v, notifications = %+v", sent, fulfilled.Notifications)
+	}
+
+	// Gọi lại không tạo vận đơn hay gửi thông báo lần nữa
+	_, err = f.Fulfill(order.ID)
+	testkit.AssertNoError(t, err)
+	if shipping.Calls(OperationCreateLabel) != 1 || len(notifier.Sent()) != 2 {
+		t.Errorf("create_label calls = %d, sent = %d", shipping.Calls(OperationCreateLabel), len(notifier.Sent()))
+	}
+
+	// Đơn đã có vận đơn không hủy được
+	err = f.orders.CancelOrder(order.ID)
+	testkit.AssertStatus(t, err, 400)
+	if !errors.Is(err, ErrOrderShipped) {
+		t.Errorf("CancelOrder = %v, want ErrOrderShipped", err)
+	}
+}
+
+// TestFulfillRetry kiểm tra lỗi ở từng bước: lần gọi sau chỉ chạy lại bước lỗi
+func TestFulfillRetry(t *testing.T) {
+	f, shipping, notifier, order := newFulfillment(t)
+	shipping.SetFailure(OperationCreateLabel, Failure{Mode: FailureTimeout, Times: 1})
+	notifier.SetFailure(OperationSendSMS, Failure{Mode: FailureRejected, Times: 1})
+
+	_, err := f.Fulfill(order.ID)
+	testkit.AssertStatus(t, err, 504)
+	testkit.AssertData(t, err, "operation", OperationCreateLabel)
+	if current, _ := f.orders.GetOrder(order.ID); current.Status != OrderPaid || current.Shipment != nil {
+		t.Errorf("order = %+v, tạo vận đơn lỗi không được ghi", current)
+	}
+
+	// Vận đơn được tạo, email gửi được, SMS lỗi
+	_, err = f.Fulfill(order.ID)
+	testkit.AssertStatus(t, err, 502)
+	var depErr *DependencyError
+	if !errors.As(err, &depErr) || depErr.Dependency != DependencyNotification || depErr.Operation != OperationSendSMS || depErr.Temporary() {
+		t.Errorf("err = %v, want *DependencyError notification send_sms", err)
+	}
+	current, _ := f.orders.GetOrder(order.ID)
+	if current.Shipment == nil || len(current.Notifications) != 1 {
+		t.Errorf("order = %+v, want vận đơn và email đã gửi", current)
+	}
+
+	fulfilled, err := f.Fulfill(order.ID)
+	testkit.AssertNoError(t, err)
+	if len(fulfilled.Notifications) != 2 || shipping.Calls(OperationCreateLabel) != 2 || notifier.Calls(OperationSendEmail) != 1 {
+		t.Errorf("notifications = %+v, create_label calls = %d, send_email calls = %d",
+			fulfilled.Notifications, shipping.Calls(OperationCreateLabel), notifier.Calls(OperationSendEmail))
+	}
+}
+
+func TestFulfillErrors(t *testing.T) {
+	f, shipping, _, order := newFulfillment(t)
+
+	_, err := f.Fulfill("ORD-999")
+	testkit.AssertStatus(t, err, 404)
+
+	unpaid, err := f.orders.CreateOrder("456", "USER002", 1, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+	_, err = f.Fulfill(unpaid.ID)
+	testkit.AssertStatus(t, err, 409)
+	testkit.AssertLocation(t, err, "services/fulfillment.go:Fulfill")
+	if !errors.Is(err, ErrOrderNotPaid) {
+		t.Errorf("errors.Is(err, ErrOrderNotPaid) = false")
+	}
+
+	// Hoàn toàn bộ tiền trước khi giao: không tạo vận đơn
+	_, err = f.orders.RefundOrder(order.ID, RefundRequest{})
+	testkit.AssertNoError(t, err)
+	refunded, err := f.Fulfill(order.ID)
+	testkit.AssertNoError(t, err)
+	if refunded.Shipment != nil || shipping.Calls(OperationQuote) != 0 {
+		t.Errorf("order = %+v, quote calls = %d", refunded, shipping.Calls(OperationQuote))
+	}
+}
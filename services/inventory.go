@@ -0,0 +1,383 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Inventory - Tồn kho theo từng kho hàng, chiến lược phân bổ và lịch sử xuất nhập kho
+// ============================================================================
+
+// Product.Stock là tổng tồn kho của sản phẩm ở tất cả kho, luôn được cập nhật cùng lúc với
+// tồn kho từng kho (dưới s.mu). Mọi thay đổi tồn kho được ghi lại thành StockMovement
+//
+// Example:
+//
+//	allocations, err := productService.ReserveProduct("456", 4, services.AllocationPolicy{
+//	    Strategy: services.StrategyNearest,
+//	    Region:   "south",
+//	})
+//	// Không kho nào đủ 4 -> BusinessError 409, data.warehouses là tồn kho từng kho
+//	// StrategySplit -> [{WH-03 3} {WH-01 1}]: lấy từ kho gần trước, giao thành nhiều kiện
+
+// Warehouse là kho hàng, Region dùng để chọn kho gần địa chỉ giao hàng nhất
+type Warehouse struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Region string `json:"region"`
+}
+
+// DefaultWarehouse nhận stock của sản phẩm tạo mới, import hoặc được cập nhật tăng stock
+const DefaultWarehouse = "WH-01"
+
+// Regions là các vùng giao hàng theo thứ tự địa lý: khoảng cách giữa hai vùng là độ lệch vị trí trong danh sách
+var Regions = []string{"north", "central", "south"}
+
+// defaultWarehouses là các kho của hệ thống, theo thứ tự ưu tiên khi không chỉ định vùng giao hàng
+func defaultWarehouses() []Warehouse {
+	return []Warehouse{
+		{ID: "WH-01", Name: "Kho Hà Nội", Region: "north"},
+		{ID: "WH-02", Name: "Kho Đà Nẵng", Region: "central"},
+		{ID: "WH-03", Name: "Kho TP.HCM", Region: "south"},
+	}
+}
+
+// AllocationStrategy quyết định lấy hàng từ kho nào khi reserve
+type AllocationStrategy string
+
+const (
+	// StrategyNearest lấy toàn bộ từ kho gần vùng giao hàng nhất còn đủ hàng
+	StrategyNearest AllocationStrategy = "nearest"
+	// StrategyMostStock lấy toàn bộ từ kho còn nhiều hàng nhất
+	StrategyMostStock AllocationStrategy = "most_stock"
+	// StrategySplit chia đơn cho nhiều kho (giao thành nhiều kiện), kho gần trước
+	StrategySplit AllocationStrategy = "split"
+)
+
+// AllocationPolicy là chiến lược phân bổ và vùng giao hàng
+// Zero value là StrategySplit theo thứ tự kho (giống hành vi khi chỉ có tổng stock)
+type AllocationPolicy struct {
+	Strategy AllocationStrategy
+	Region   string // Vùng giao hàng (Regions), rỗng thì xếp kho theo thứ tự mặc định
+}
+
+// strategy trả về chiến lược, StrategySplit nếu không chỉ định
+func (p AllocationPolicy) strategy() AllocationStrategy {
+	if p.Strategy == "" {
+		return StrategySplit
+	}
+	return p.Strategy
+}
+
+// ParseAllocationPolicy đọc strategy và region từ query của request
+// Tham số sai được trả về cùng lúc (errors.Join các ValidationError)
+func ParseAllocationPolicy(strategy, region string) (AllocationPolicy, error) {
+	policy := AllocationPolicy{
+		Strategy: AllocationStrategy(strings.ToLower(strings.TrimSpace(strategy))),
+		Region:   strings.ToLower(strings.TrimSpace(region)),
+	}
+
+	var errs []error
+	strategies := []string{string(StrategyNearest), string(StrategyMostStock), string(StrategySplit)}
+	if policy.Strategy != "" && !slices.Contains(strategies, string(policy.Strategy)) {
+		errs = append(errs, goerrorkit.NewValidationError(fmt.Sprintf("Strategy '%s' không hợp lệ", strategy), map[string]interface{}{
+			"field":    "strategy",
+			"allowed":  strategies,
+			"received": strategy,
+		}))
+	}
+	if policy.Region != "" && !slices.Contains(Regions, policy.Region) {
+		errs = append(errs, goerrorkit.NewValidationError(fmt.Sprintf("Vùng giao hàng '%s' không hợp lệ", region), map[string]interface{}{
+			"field":    "region",
+			"allowed":  Regions,
+			"received": region,
+		}))
+	}
+	return policy, errors.Join(errs...)
+}
+
+// Allocation là số lượng lấy từ một kho
+type Allocation struct {
+	WarehouseID string `json:"warehouse_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+// WarehouseStock là tồn kho của sản phẩm tại một kho
+type WarehouseStock struct {
+	Warehouse
+	Available int `json:"available"`
+}
+
+// Loại movement trong lịch sử xuất nhập kho
+const (
+	MovementInitial    = "initial"    // Tồn kho ban đầu (sản phẩm mẫu, tạo mới, import)
+	MovementUpdate     = "update"     // Cập nhật stock qua PUT /product/:id
+	MovementAdjustment = "adjustment" // Điều chỉnh thủ công (kiểm kê, nhập hàng, hàng hỏng)
+	MovementReserve    = "reserve"    // Xuất kho cho đơn hàng
+	MovementRelease    = "release"    // Hoàn trả hàng đã reserve (rollback đơn hàng)
+	MovementRefund     = "refund"     // Nhập lại hàng khách trả khi hoàn tiền đơn hàng
+)
+
+// StockMovement là một dòng trong lịch sử xuất nhập kho (append-only)
+type StockMovement struct {
+	ID          int       `json:"id"`
+	ProductID   string    `json:"product_id"`
+	WarehouseID string    `json:"warehouse_id"`
+	Type        string    `json:"type"`
+	Delta       int       `json:"delta"`
+	Balance     int       `json:"balance"` // Tồn kho của kho sau movement
+	Reason      string    `json:"reason,omitempty"`
+	At          time.Time `json:"at"`
+}
+
+// Warehouses trả về danh sách kho
+func (s *ProductService) Warehouses() []Warehouse {
+	return slices.Clone(s.warehouses)
+}
+
+// Inventory trả về tồn kho của sản phẩm tại từng kho
+func (s *ProductService) Inventory(productID string) ([]WarehouseStock, error) {
+	if _, err := s.GetProduct(productID); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stockLevels(productID), nil
+}
+
+// StockMovements trả về lịch sử xuất nhập kho của sản phẩm, cũ nhất trước
+func (s *ProductService) StockMovements(productID string) ([]StockMovement, error) {
+	if _, err := s.GetProduct(productID); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	movements := []StockMovement{}
+	for _, movement := range s.movements {
+		if movement.ProductID == productID {
+			movements = append(movements, movement)
+		}
+	}
+	return movements, nil
+}
+
+// AdjustStock điều chỉnh tồn kho của sản phẩm tại một kho (delta > 0 nhập hàng, < 0 xuất hàng)
+// reason là lý do điều chỉnh, bắt buộc để lịch sử xuất nhập kho có thể kiểm tra lại
+func (s *ProductService) AdjustStock(productID, warehouseID string, delta int, reason string) (StockMovement, error) {
+	if _, err := s.GetProduct(productID); err != nil {
+		return StockMovement{}, err
+	}
+
+	var errs []error
+	if delta == 0 {
+		errs = append(errs, goerrorkit.NewValidationError("Delta phải khác 0", map[string]interface{}{
+			"field":    "delta",
+			"received": delta,
+		}))
+	}
+	if strings.TrimSpace(reason) == "" {
+		errs = append(errs, goerrorkit.NewValidationError("Lý do điều chỉnh không được để trống", map[string]interface{}{
+			"field":    "reason",
+			"required": true,
+		}))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return StockMovement{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.lockedProduct(productID)
+	if err != nil {
+		return StockMovement{}, err
+	}
+	if !slices.ContainsFunc(s.warehouses, func(w Warehouse) bool { return w.ID == warehouseID }) {
+		appErr := goerrorkit.NewBusinessError(404, fmt.Sprintf("Kho %s không tồn tại", warehouseID)).WithData(map[string]interface{}{
+			"warehouse_id": warehouseID,
+		})
+		appErr.Cause = &WarehouseError{WarehouseID: warehouseID, Err: ErrWarehouseNotFound} // errors.Is(err, ErrWarehouseNotFound)
+		return StockMovement{}, appErr
+	}
+
+	available := s.levels[productID][warehouseID]
+	if available+delta < 0 {
+		appErr := goerrorkit.NewBusinessError(
+			409,
+			fmt.Sprintf("Không thể xuất %d sản phẩm '%s' khỏi kho %s: còn lại %d", -delta, product.Name, warehouseID, available),
+		).WithData(map[string]interface{}{
+			"product_id":   productID,
+			"warehouse_id": warehouseID,
+			"delta":        delta,
+			"available":    available,
+		})
+		appErr.Cause = &ProductError{ProductID: productID, Err: ErrInsufficientStock} // errors.Is(err, ErrInsufficientStock)
+		return StockMovement{}, appErr
+	}
+
+	return s.move(product, warehouseID, delta, MovementAdjustment, strings.TrimSpace(reason)), nil
+}
+
+// allocate chọn kho theo policy và xuất kho, s.mu phải đang được giữ và tổng stock phải đủ
+// Trả về BusinessError 409 kèm tồn kho từng kho nếu chiến lược không phân bổ được
+// (ví dụ nearest nhưng không kho nào đủ hàng cho cả đơn)
+func (s *ProductService) allocate(product *Product, quantity int, policy AllocationPolicy) ([]Allocation, error) {
+	stock := s.stockLevels(product.ID)
+	allocations, ok := plan(stock, quantity, policy)
+	if !ok {
+		data := map[string]interface{}{
+			"product_id":      product.ID,
+			"requested":       quantity,
+			"strategy":        policy.strategy(),
+			"total_available": product.Stock,
+			"warehouses":      stock,
+			"suggestion":      StrategySplit, // Tổng tồn kho đủ: giao thành nhiều kiện từ nhiều kho
+		}
+		if policy.Region != "" {
+			data["region"] = policy.Region
+		}
+		appErr := goerrorkit.NewBusinessError(
+			409,
+			fmt.Sprintf("Không kho nào đủ %d sản phẩm '%s' (strategy %s)", quantity, product.Name, policy.strategy()),
+		).WithData(data)
+		appErr.Cause = &ProductError{ProductID: product.ID, Err: ErrInsufficientStock} // errors.Is(err, ErrInsufficientStock)
+		return nil, appErr
+	}
+
+	for _, allocation := range allocations {
+		s.move(product, allocation.WarehouseID, -allocation.Quantity, MovementReserve, "")
+	}
+	return allocations, nil
+}
+
+// plan chọn kho cho quantity sản phẩm theo policy, false nếu chiến lược không phân bổ được
+func plan(stock []WarehouseStock, quantity int, policy AllocationPolicy) ([]Allocation, bool) {
+	if quantity <= 0 {
+		return []Allocation{}, true
+	}
+	candidates := byDistance(stock, policy.Region)
+
+	switch policy.strategy() {
+	case StrategyNearest:
+		for _, w := range candidates {
+			if w.Available >= quantity {
+				return []Allocation{{WarehouseID: w.ID, Quantity: quantity}}, true
+			}
+		}
+		return nil, false
+
+	case StrategyMostStock:
+		// Nhiều kho cùng số lượng thì chọn kho gần hơn (candidates đã sắp xếp theo khoảng cách)
+		var best *WarehouseStock
+		for i := range candidates {
+			if best == nil || candidates[i].Available > best.Available {
+				best = &candidates[i]
+			}
+		}
+		if best == nil || best.Available < quantity {
+			return nil, false
+		}
+		return []Allocation{{WarehouseID: best.ID, Quantity: quantity}}, true
+	}
+
+	var allocations []Allocation
+	remaining := quantity
+	for _, w := range candidates {
+		take := min(remaining, w.Available)
+		if take <= 0 {
+			continue
+		}
+		allocations = append(allocations, Allocation{WarehouseID: w.ID, Quantity: take})
+		remaining -= take
+		if remaining == 0 {
+			return allocations, true
+		}
+	}
+	return nil, false
+}
+
+// byDistance sắp xếp kho theo khoảng cách tới vùng giao hàng, cùng khoảng cách giữ thứ tự kho
+func byDistance(stock []WarehouseStock, region string) []WarehouseStock {
+	candidates := slices.Clone(stock)
+	if region == "" {
+		return candidates
+	}
+	target := slices.Index(Regions, region)
+	distance := func(w WarehouseStock) int {
+		d := slices.Index(Regions, w.Region) - target
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return distance(candidates[i]) < distance(candidates[j]) })
+	return candidates
+}
+
+// stockLevels trả về tồn kho của sản phẩm tại tất cả kho, s.mu phải đang được giữ
+func (s *ProductService) stockLevels(productID string) []WarehouseStock {
+	stock := make([]WarehouseStock, 0, len(s.warehouses))
+	for _, warehouse := range s.warehouses {
+		stock = append(stock, WarehouseStock{Warehouse: warehouse, Available: s.levels[productID][warehouse.ID]})
+	}
+	return stock
+}
+
+// putProduct thêm sản phẩm với tồn kho từng kho, Product.Stock được tính lại từ levels
+// s.mu phải đang được giữ (hoặc service đang được khởi tạo)
+func (s *ProductService) putProduct(product *Product, levels map[string]int, reason string) {
+	product.Stock = 0
+	s.products[product.ID] = product
+	s.levels[product.ID] = make(map[string]int)
+	for _, warehouse := range s.warehouses {
+		if quantity := levels[warehouse.ID]; quantity != 0 {
+			s.move(product, warehouse.ID, quantity, MovementInitial, reason)
+		}
+	}
+}
+
+// setStock đưa tổng tồn kho của sản phẩm về total: phần tăng nhập vào DefaultWarehouse,
+// phần giảm xuất từ các kho theo thứ tự mặc định. s.mu phải đang được giữ
+func (s *ProductService) setStock(product *Product, total int) {
+	delta := total - product.Stock
+	if delta > 0 {
+		s.move(product, DefaultWarehouse, delta, MovementUpdate, "")
+		return
+	}
+	allocations, _ := plan(s.stockLevels(product.ID), -delta, AllocationPolicy{})
+	for _, allocation := range allocations {
+		s.move(product, allocation.WarehouseID, -allocation.Quantity, MovementUpdate, "")
+	}
+}
+
+// move thay đổi tồn kho của sản phẩm tại một kho và ghi lại movement, s.mu phải đang được giữ
+func (s *ProductService) move(product *Product, warehouseID string, delta int, movementType, reason string) StockMovement {
+	if s.levels[product.ID] == nil {
+		s.levels[product.ID] = make(map[string]int)
+	}
+	s.levels[product.ID][warehouseID] += delta
+	product.Stock += delta
+
+	movement := StockMovement{
+		ID:          len(s.movements) + 1,
+		ProductID:   product.ID,
+		WarehouseID: warehouseID,
+		Type:        movementType,
+		Delta:       delta,
+		Balance:     s.levels[product.ID][warehouseID],
+		Reason:      reason,
+		At:          s.now(),
+	}
+	s.movements = append(s.movements, movement)
+	return movement
+}
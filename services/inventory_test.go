@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"fiber_log/testkit"
+)
+
+// levels trả về tồn kho của sản phẩm theo warehouse ID
+func levels(t *testing.T, s *ProductService, productID string) map[string]int {
+	t.Helper()
+	stock, err := s.Inventory(productID)
+	testkit.AssertNoError(t, err)
+	got := make(map[string]int)
+	for _, w := range stock {
+		got[w.ID] = w.Available
+	}
+	return got
+}
+
+func TestReserveProductStrategies(t *testing.T) {
+	// Sản phẩm 456: WH-01 (north) 2, WH-02 (central) 0, WH-03 (south) 3
+	tests := []struct {
+		name     string
+		quantity int
+		policy   AllocationPolicy
+		want     []Allocation
+	}{
+		{"split mặc định theo thứ tự kho", 4, AllocationPolicy{}, []Allocation{{"WH-01", 2}, {"WH-03", 2}}},
+		{"split từ kho gần nhất", 4, AllocationPolicy{Strategy: StrategySplit, Region: "south"}, []Allocation{{"WH-03", 3}, {"WH-01", 1}}},
+		{"nearest", 2, AllocationPolicy{Strategy: StrategyNearest, Region: "south"}, []Allocation{{"WH-03", 2}}},
+		{"nearest bỏ qua kho gần không đủ", 3, AllocationPolicy{Strategy: StrategyNearest, Region: "north"}, []Allocation{{"WH-03", 3}}},
+		{"most stock", 1, AllocationPolicy{Strategy: StrategyMostStock, Region: "north"}, []Allocation{{"WH-03", 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewProductService()
+			allocations, err := s.ReserveProduct("456", tt.quantity, tt.policy)
+			testkit.AssertNoError(t, err)
+			if !reflect.DeepEqual(allocations, tt.want) {
+				t.Errorf("allocations = %+v, want %+v", allocations, tt.want)
+			}
+			if product, _ := s.GetProduct("456"); product.Stock != 5-tt.quantity {
+				t.Errorf("stock = %d, want %d", product.Stock, 5-tt.quantity)
+			}
+		})
+	}
+}
+
+func TestReserveProductUnallocatable(t *testing.T) {
+	s := NewProductService()
+
+	// Tổng đủ (5) nhưng không kho nào có 4
+	for _, strategy := range []AllocationStrategy{StrategyNearest, StrategyMostStock} {
+		_, err := s.ReserveProduct("456", 4, AllocationPolicy{Strategy: strategy, Region: "central"})
+		testkit.AssertErrorType(t, err, testkit.Business)
+		testkit.AssertStatus(t, err, 409)
+		testkit.AssertLocation(t, err, "services/inventory.go:allocate")
+		testkit.AssertData(t, err, "strategy", strategy)
+		testkit.AssertData(t, err, "region", "central")
+		testkit.AssertData(t, err, "total_available", 5)
+		testkit.AssertData(t, err, "suggestion", StrategySplit)
+		testkit.AssertData(t, err, "warehouses", []WarehouseStock{
+			{Warehouse{"WH-01", "Kho Hà Nội", "north"}, 2},
+			{Warehouse{"WH-02", "Kho Đà Nẵng", "central"}, 0},
+			{Warehouse{"WH-03", "Kho TP.HCM", "south"}, 3},
+		})
+		if !errors.Is(err, ErrInsufficientStock) {
+			t.Errorf("%s: errors.Is(err, ErrInsufficientStock) = false", strategy)
+		}
+	}
+
+	// Không phân bổ được thì không kho nào bị trừ
+	if got := levels(t, s, "456"); !reflect.DeepEqual(got, map[string]int{"WH-01": 2, "WH-02": 0, "WH-03": 3}) {
+		t.Errorf("levels = %v", got)
+	}
+}
+
+func TestParseAllocationPolicy(t *testing.T) {
+	policy, err := ParseAllocationPolicy(" Nearest ", "SOUTH")
+	testkit.AssertNoError(t, err)
+	if policy != (AllocationPolicy{Strategy: StrategyNearest, Region: "south"}) {
+		t.Errorf("policy = %+v", policy)
+	}
+
+	_, err = ParseAllocationPolicy("cheapest", "mars")
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatalf("err = %v, want 2 lỗi", err)
+	}
+	testkit.AssertData(t, joined.Unwrap()[0], "field", "strategy")
+	testkit.AssertData(t, joined.Unwrap()[1], "field", "region")
+}
+
+func TestAdjustStock(t *testing.T) {
+	s := NewProductService()
+
+	movement, err := s.AdjustStock("789", "WH-03", 5, "Nhập hàng PO-42")
+	testkit.AssertNoError(t, err)
+	if movement.Type != MovementAdjustment || movement.Delta != 5 || movement.Balance != 5 || movement.Reason != "Nhập hàng PO-42" {
+		t.Errorf("movement = %+v", movement)
+	}
+	if product, _ := s.GetProduct("789"); product.Stock != 15 {
+		t.Errorf("stock = %d, want 15", product.Stock)
+	}
+
+	_, err = s.AdjustStock("789", "WH-01", -5, "Hàng hỏng")
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 409)
+	testkit.AssertMessage(t, err, "Không thể xuất 5 sản phẩm 'AirPods Pro' khỏi kho WH-01: còn lại 4")
+	testkit.AssertData(t, err, "available", 4)
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Error("errors.Is(err, ErrInsufficientStock) = false")
+	}
+
+	_, err = s.AdjustStock("789", "WH-99", 1, "Nhập hàng")
+	testkit.AssertStatus(t, err, 404)
+	var warehouseErr *WarehouseError
+	if !errors.As(err, &warehouseErr) || warehouseErr.WarehouseID != "WH-99" || !errors.Is(err, ErrWarehouseNotFound) {
+		t.Errorf("err = %v, want *WarehouseError WH-99", err)
+	}
+
+	_, err = s.AdjustStock("789", "WH-01", 0, " ")
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatalf("err = %v, want 2 lỗi (delta, reason)", err)
+	}
+
+	_, err = s.AdjustStock("999", "WH-01", 1, "Nhập hàng")
+	testkit.AssertStatus(t, err, 404)
+	testkit.AssertLocation(t, err, "GetProduct")
+}
+
+func TestStockMovements(t *testing.T) {
+	s := NewProductService()
+	orders := NewOrderService(s)
+
+	// Batch all_or_nothing lỗi ở dòng 2: hàng được hoàn trả về đúng kho đã lấy
+	_, err := orders.CreateOrders([]OrderRequest{
+		{ProductID: "456", UserID: "U1", Quantity: 3},
+		{ProductID: "123", UserID: "U1", Quantity: 1},
+	}, BatchAllOrNothing)
+	testkit.AssertNoError(t, err)
+	if got := levels(t, s, "456"); !reflect.DeepEqual(got, map[string]int{"WH-01": 2, "WH-02": 0, "WH-03": 3}) {
+		t.Errorf("levels sau rollback = %v", got)
+	}
+
+	_, err = s.UpdateProduct("456", Product{Name: "MacBook Pro", Price: MustParseMoney("2499.99", USD), Stock: 1}, "")
+	testkit.AssertNoError(t, err)
+	if got := levels(t, s, "456"); !reflect.DeepEqual(got, map[string]int{"WH-01": 0, "WH-02": 0, "WH-03": 1}) {
+		t.Errorf("levels sau update = %v", got)
+	}
+
+	movements, err := s.StockMovements("456")
+	testkit.AssertNoError(t, err)
+	var got []string
+	balances := make(map[string]int)
+	for _, m := range movements {
+		got = append(got, m.Type+":"+m.WarehouseID)
+		balances[m.WarehouseID] = m.Balance
+	}
+	want := []string{
+		"initial:WH-01", "initial:WH-03",
+		"reserve:WH-01", "reserve:WH-03",
+		"release:WH-01", "release:WH-03",
+		"update:WH-01", "update:WH-03",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("movements = %v, want %v", got, want)
+	}
+	if balances["WH-01"] != 0 || balances["WH-03"] != 1 {
+		t.Errorf("balance cuối = %v", balances)
+	}
+
+	// Sản phẩm tạo mới nhập vào DefaultWarehouse
+	_, err = s.CreateProduct(Product{ID: "P1", Name: "Keyboard", Price: MustParseMoney("49.9", USD), Stock: 7})
+	testkit.AssertNoError(t, err)
+	if got := levels(t, s, "P1"); got[DefaultWarehouse] != 7 {
+		t.Errorf("levels P1 = %v", got)
+	}
+}
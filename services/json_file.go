@@ -0,0 +1,93 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// JSON Files - Lưu sản phẩm (PRODUCTS_FILE) và user (USERS_FILE) ra file JSON
+// ============================================================================
+
+// LoadProducts đọc danh sách sản phẩm từ file JSON do SaveProducts ghi ra
+func LoadProducts(path string) ([]*Product, error) {
+	var products []*Product
+	if err := readJSONFile(path, "sản phẩm", &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// SaveProducts ghi danh sách sản phẩm ra file JSON
+func SaveProducts(path string, products []*Product) error {
+	return writeJSONFile(path, "sản phẩm", products, 0o644)
+}
+
+// LoadUsers đọc danh sách user (kèm password hash) từ file JSON do UserService ghi ra
+func LoadUsers(path string) ([]*UserRecord, error) {
+	var users []*UserRecord
+	if err := readJSONFile(path, "user", &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// SaveUsers ghi danh sách user (kèm password hash) ra file JSON
+// File chỉ owner đọc/ghi được (0600) vì chứa password hash
+func SaveUsers(path string, users []*UserRecord) error {
+	return writeJSONFile(path, "user", users, 0o600)
+}
+
+// readJSONFile decode file JSON vào v, kind là tên loại dữ liệu trong message lỗi ("sản phẩm", "user")
+func readJSONFile(path, kind string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return goerrorkit.WrapWithMessage(err, fmt.Sprintf("Không thể đọc file %s", kind)).WithData(map[string]interface{}{
+			"path": path,
+		})
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return goerrorkit.WrapWithMessage(err, fmt.Sprintf("File %s không hợp lệ", kind)).WithData(map[string]interface{}{
+			"path": path,
+		})
+	}
+	return nil
+}
+
+// writeJSONFile ghi v ra file JSON với quyền perm
+// Ghi vào file tạm rồi rename để file cũ không bị hỏng nếu ghi lỗi giữa chừng
+func writeJSONFile(path, kind string, v interface{}, perm os.FileMode) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return goerrorkit.WrapWithMessage(err, fmt.Sprintf("Không thể encode danh sách %s", kind))
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return goerrorkit.WrapWithMessage(err, fmt.Sprintf("Không thể tạo thư mục chứa file %s", kind)).WithData(map[string]interface{}{
+				"path": path,
+			})
+		}
+	}
+
+	// Xóa file tạm còn sót lại: os.WriteFile chỉ đặt perm khi tạo file mới
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := os.WriteFile(tmp, append(data, '\n'), perm); err != nil {
+		return goerrorkit.WrapWithMessage(err, fmt.Sprintf("Không thể ghi file %s", kind)).WithData(map[string]interface{}{
+			"path": path,
+		})
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return goerrorkit.WrapWithMessage(err, fmt.Sprintf("Không thể ghi file %s", kind)).WithData(map[string]interface{}{
+			"path": path,
+		})
+	}
+	return nil
+}
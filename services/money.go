@@ -0,0 +1,304 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Money - Số tiền decimal (lưu bằng minor units) kèm mã tiền tệ ISO 4217
+// ============================================================================
+
+// Currency là mã tiền tệ ISO 4217
+type Currency string
+
+const (
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+	VND Currency = "VND"
+	JPY Currency = "JPY"
+)
+
+// DefaultCurrency là tiền tệ của số tiền không ghi rõ currency (giá trong JSON dạng số, query min_price, ...)
+const DefaultCurrency = USD
+
+// currencyExponents là số chữ số thập phân (minor units) của từng tiền tệ được hỗ trợ
+var currencyExponents = map[Currency]int{
+	USD: 2,
+	EUR: 2,
+	VND: 0,
+	JPY: 0,
+}
+
+// Exponent trả về số chữ số thập phân của tiền tệ, false nếu tiền tệ không được hỗ trợ
+func (c Currency) Exponent() (int, bool) {
+	exponent, ok := currencyExponents[c]
+	return exponent, ok
+}
+
+// supportedCurrencies trả về các tiền tệ được hỗ trợ, sắp xếp theo mã
+func supportedCurrencies() []string {
+	currencies := make([]string, 0, len(currencyExponents))
+	for currency := range currencyExponents {
+		currencies = append(currencies, string(currency))
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// ParseCurrency chuẩn hóa và kiểm tra mã tiền tệ ("usd" -> USD)
+func ParseCurrency(code string) (Currency, error) {
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if _, ok := currency.Exponent(); !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedCurrency, code)
+	}
+	return currency, nil
+}
+
+// Money là số tiền chính xác tuyệt đối: số nguyên minor units (cent, đồng, ...) và tiền tệ
+// Zero value là 0 DefaultCurrency
+//
+// Example:
+//
+//	price := services.MustParseMoney("2499.99", services.USD)
+//	price.Percent(10)        // 250.00 USD, nil (làm tròn half-up, không còn 249.999)
+//	price.Mul(3).String()    // "7499.97 USD"
+//	price.Add(services.NewMoney(5000, services.VND)) // ValidationError: khác tiền tệ
+type Money struct {
+	minor    int64
+	currency Currency
+}
+
+// NewMoney tạo Money từ số minor units (ví dụ 249999 cent = 2499.99 USD)
+func NewMoney(minor int64, currency Currency) Money {
+	return Money{minor: minor, currency: currency}
+}
+
+// ParseMoney đọc số tiền dạng decimal ("2499.99", "-5", "1e3" không hợp lệ)
+// Trả về lỗi bọc ErrInvalidAmount nếu không phải số, bọc thêm ErrAmountPrecision nếu có nhiều
+// chữ số thập phân hơn tiền tệ cho phép (không tự làm tròn số tiền do client gửi)
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exponent, ok := currency.Exponent()
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q không phải số", ErrInvalidAmount, amount)
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > exponent {
+		return Money{}, fmt.Errorf("%w: %w: %q có nhiều hơn %d chữ số thập phân (%s)", ErrInvalidAmount, ErrAmountPrecision, amount, exponent, currency)
+	}
+
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q vượt quá giới hạn", ErrInvalidAmount, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{minor: minor, currency: currency}, nil
+}
+
+// MustParseMoney giống ParseMoney nhưng panic nếu lỗi, dùng cho hằng số trong code
+func MustParseMoney(amount string, currency Currency) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// isDigits cho biết s chỉ gồm chữ số (chuỗi rỗng cũng hợp lệ)
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor trả về số tiền theo minor units
+func (m Money) Minor() int64 { return m.minor }
+
+// Currency trả về tiền tệ, DefaultCurrency nếu là zero value
+func (m Money) Currency() Currency {
+	if m.currency == "" {
+		return DefaultCurrency
+	}
+	return m.currency
+}
+
+// IsZero, IsNegative, IsPositive so sánh số tiền với 0
+func (m Money) IsZero() bool     { return m.minor == 0 }
+func (m Money) IsNegative() bool { return m.minor < 0 }
+func (m Money) IsPositive() bool { return m.minor > 0 }
+
+// Equal cho biết hai số tiền cùng tiền tệ và cùng số minor units
+func (m Money) Equal(other Money) bool {
+	return m.Currency() == other.Currency() && m.minor == other.minor
+}
+
+// Add cộng hai số tiền cùng tiền tệ, trả về ValidationError (ErrCurrencyMismatch) nếu khác tiền tệ
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{minor: m.minor + other.minor, currency: m.Currency()}, nil
+}
+
+// Sub trừ hai số tiền cùng tiền tệ, trả về ValidationError (ErrCurrencyMismatch) nếu khác tiền tệ
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{minor: m.minor - other.minor, currency: m.Currency()}, nil
+}
+
+// Cmp so sánh hai số tiền cùng tiền tệ (-1, 0, 1), trả về ValidationError nếu khác tiền tệ
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.minor < other.minor:
+		return -1, nil
+	case m.minor > other.minor:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Mul nhân số tiền với số lượng
+func (m Money) Mul(quantity int) Money {
+	return Money{minor: m.minor * int64(quantity), currency: m.Currency()}
+}
+
+// Percent trả về percent% của số tiền, làm tròn half-up (xa số 0) đến minor unit
+// Tính bằng số hữu tỉ nên 2499.99 * 10% = 249.999 -> 250.00, không có sai số float64
+// Trả về lỗi bọc ErrInvalidAmount nếu percent là NaN hoặc ±Inf
+func (m Money) Percent(percent float64) (Money, error) {
+	rate, ok := new(big.Rat).SetString(strconv.FormatFloat(percent, 'f', -1, 64))
+	if !ok {
+		return Money{}, fmt.Errorf("%w: phần trăm %v không phải số hữu hạn", ErrInvalidAmount, percent)
+	}
+	amount := new(big.Rat).Mul(new(big.Rat).SetInt64(m.minor), rate)
+	amount.Quo(amount, big.NewRat(100, 1))
+	return Money{minor: roundHalfUp(amount), currency: m.Currency()}, nil
+}
+
+// Min trả về số tiền nhỏ hơn (cùng tiền tệ)
+func (m Money) Min(other Money) (Money, error) {
+	c, err := m.Cmp(other)
+	if err != nil {
+		return Money{}, err
+	}
+	if c > 0 {
+		return other, nil
+	}
+	return m, nil
+}
+
+// roundHalfUp làm tròn số hữu tỉ đến số nguyên gần nhất, .5 làm tròn xa số 0
+func roundHalfUp(r *big.Rat) int64 {
+	num, den := new(big.Int).Abs(r.Num()), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
+
+// Decimal trả về số tiền dạng decimal với đúng số chữ số thập phân của tiền tệ ("2499.99", "5000")
+func (m Money) Decimal() string {
+	exponent, _ := m.Currency().Exponent()
+	minor := m.minor
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	digits := fmt.Sprintf("%0*d", exponent+1, minor)
+	if exponent == 0 {
+		return sign + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String trả về "2499.99 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + string(m.Currency())
+}
+
+// sameCurrency trả về ValidationError nếu hai số tiền khác tiền tệ
+func (m Money) sameCurrency(other Money) error {
+	if m.Currency() == other.Currency() {
+		return nil
+	}
+	appErr := goerrorkit.NewValidationError(
+		fmt.Sprintf("Không thể tính %s với %s: khác tiền tệ", m, other),
+		map[string]interface{}{
+			"currency":       m.Currency(),
+			"other_currency": other.Currency(),
+		},
+	).WithCallChain()
+	appErr.Cause = &CurrencyError{Expected: m.Currency(), Received: other.Currency(), Err: ErrCurrencyMismatch}
+	return appErr
+}
+
+// moneyJSON là dạng JSON của Money: amount là chuỗi decimal để client không mất độ chính xác
+type moneyJSON struct {
+	Amount   json.Number `json:"amount"`
+	Currency Currency    `json:"currency"`
+}
+
+// MarshalJSON ghi {"amount": "2499.99", "currency": "USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"amount":   m.Decimal(),
+		"currency": string(m.Currency()),
+	})
+}
+
+// UnmarshalJSON đọc {"amount": "2499.99" hoặc 2499.99, "currency": "USD"}
+// Số hoặc chuỗi không kèm currency (ví dụ "price": 49.9) được hiểu là DefaultCurrency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	value := moneyJSON{Currency: DefaultCurrency}
+	if bytes.HasPrefix(data, []byte("{")) {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(data, &value.Amount); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidAmount, data)
+	}
+
+	currency, err := ParseCurrency(string(value.Currency))
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(value.Amount.String(), currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
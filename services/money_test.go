@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"fiber_log/testkit"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency Currency
+		minor    int64
+		decimal  string
+	}{
+		{"2499.99", USD, 249999, "2499.99"},
+		{"49.9", USD, 4990, "49.90"},
+		{"0.05", USD, 5, "0.05"},
+		{".5", EUR, 50, "0.50"},
+		{"-5", USD, -500, "-5.00"},
+		{"10.500", USD, 1050, "10.50"}, // Số 0 thừa không tính là chữ số thập phân
+		{"150000", VND, 150000, "150000"},
+		{"150000.0", VND, 150000, "150000"},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.amount, tt.currency)
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %s): %v", tt.amount, tt.currency, err)
+			continue
+		}
+		if m.Minor() != tt.minor || m.Decimal() != tt.decimal || m.Currency() != tt.currency {
+			t.Errorf("ParseMoney(%q, %s) = %d %s %s", tt.amount, tt.currency, m.Minor(), m.Decimal(), m.Currency())
+		}
+	}
+
+	invalid := []struct {
+		amount    string
+		currency  Currency
+		precision bool
+	}{
+		{"", USD, false},
+		{"abc", USD, false},
+		{"1e3", USD, false},
+		{"NaN", USD, false},
+		{"1.2.3", USD, false},
+		{"99999999999999999999", USD, false},
+		{"10.005", USD, true},
+		{"1000.5", VND, true},
+	}
+	for _, tt := range invalid {
+		_, err := ParseMoney(tt.amount, tt.currency)
+		if !errors.Is(err, ErrInvalidAmount) || errors.Is(err, ErrAmountPrecision) != tt.precision {
+			t.Errorf("ParseMoney(%q, %s) = %v", tt.amount, tt.currency, err)
+		}
+	}
+
+	if _, err := ParseMoney("10", "BTC"); !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Errorf("ParseMoney(BTC) = %v, want ErrUnsupportedCurrency", err)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	price := MustParseMoney("2499.99", USD)
+
+	if got := price.Mul(3).String(); got != "7499.97 USD" {
+		t.Errorf("Mul(3) = %s", got)
+	}
+	percents := []struct {
+		money   Money
+		percent float64
+		want    string
+	}{
+		{price, 10, "250.00 USD"},                         // 249.999
+		{MustParseMoney("249.99", USD), 50, "125.00 USD"}, // 124.995: .5 làm tròn lên
+		{MustParseMoney("0.01", USD), 50, "0.01 USD"},
+		{MustParseMoney("-0.01", USD), 50, "-0.01 USD"}, // Xa số 0
+		{MustParseMoney("0.10", USD), 33.3, "0.03 USD"},
+		{MustParseMoney("999", VND), 15, "150 VND"}, // 149.85
+	}
+	for _, tt := range percents {
+		got, err := tt.money.Percent(tt.percent)
+		testkit.AssertNoError(t, err)
+		if got.String() != tt.want {
+			t.Errorf("%s.Percent(%v) = %s, want %s", tt.money, tt.percent, got, tt.want)
+		}
+	}
+	for _, percent := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := price.Percent(percent); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Percent(%v) = %v, want ErrInvalidAmount", percent, err)
+		}
+	}
+
+	sum, err := price.Add(MustParseMoney("0.01", USD))
+	testkit.AssertNoError(t, err)
+	if sum.String() != "2500.00 USD" {
+		t.Errorf("Add = %s", sum)
+	}
+
+	// Zero value là 0 DefaultCurrency
+	if sum, err := (Money{}).Add(price); err != nil || sum != price {
+		t.Errorf("Money{}.Add = %v, %v", sum, err)
+	}
+}
+
+func TestMoneyCurrencyMismatch(t *testing.T) {
+	usd, vnd := MustParseMoney("10", USD), MustParseMoney("5000", VND)
+
+	_, err := usd.Add(vnd)
+	testkit.AssertErrorType(t, err, testkit.Validation)
+	testkit.AssertMessage(t, err, "Không thể tính 10.00 USD với 5000 VND: khác tiền tệ")
+	testkit.AssertData(t, err, "currency", USD)
+	testkit.AssertData(t, err, "other_currency", VND)
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Error("errors.Is(err, ErrCurrencyMismatch) = false")
+	}
+	var currencyErr *CurrencyError
+	if !errors.As(err, &currencyErr) || currencyErr.Expected != USD || currencyErr.Received != VND {
+		t.Errorf("errors.As(*CurrencyError) = %+v", currencyErr)
+	}
+
+	for name, op := range map[string]func() error{
+		"Sub": func() error { _, err := usd.Sub(vnd); return err },
+		"Cmp": func() error { _, err := usd.Cmp(vnd); return err },
+		"Min": func() error { _, err := usd.Min(vnd); return err },
+	} {
+		if err := op(); !errors.Is(err, ErrCurrencyMismatch) {
+			t.Errorf("%s: err = %v, want ErrCurrencyMismatch", name, err)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(MustParseMoney("2499.9", USD))
+	testkit.AssertNoError(t, err)
+	if string(data) != `{"amount":"2499.90","currency":"USD"}` {
+		t.Errorf("MarshalJSON = %s", data)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"amount":"2499.90","currency":"USD"}`, "2499.90 USD"},
+		{`{"amount":150000,"currency":"vnd"}`, "150000 VND"},
+		{`{"amount":"12.5"}`, "12.50 USD"},
+		{`49.9`, "49.90 USD"},
+		{`"49.9"`, "49.90 USD"},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := json.Unmarshal([]byte(tt.input), &m); err != nil || m.String() != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v; want %s", tt.input, m, err, tt.want)
+		}
+	}
+
+	for input, sentinel := range map[string]error{
+		`49.999`:                          ErrAmountPrecision,
+		`{"amount":"1","currency":"BTC"}`: ErrUnsupportedCurrency,
+		`true`:                            ErrInvalidAmount,
+	} {
+		var m Money
+		if err := json.Unmarshal([]byte(input), &m); !errors.Is(err, sentinel) {
+			t.Errorf("Unmarshal(%s) = %v, want %v", input, err, sentinel)
+		}
+	}
+
+	// Product không có json tag: client gửi "price": 49.9 như trước
+	var product Product
+	testkit.AssertNoError(t, json.Unmarshal([]byte(`{"id":"P1","price":49.9}`), &product))
+	if product.Price.String() != "49.90 USD" {
+		t.Errorf("product.Price = %s", product.Price)
+	}
+}
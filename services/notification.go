@@ -0,0 +1,103 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// Notification - Gửi email / SMS cho khách hàng
+// ============================================================================
+
+// Operations của notification (data.operation của ExternalError, key của NOTIFICATION_SIM_FAILURES)
+const (
+	OperationSendEmail = "send_email"
+	OperationSendSMS   = "send_sms"
+)
+
+// NotificationOperations là các operation của Notifier
+var NotificationOperations = []string{OperationSendEmail, OperationSendSMS}
+
+// Kênh gửi thông báo
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Notifier gửi thông báo cho khách hàng
+// Lỗi của upstream là ExternalError có Cause là *DependencyError
+type Notifier interface {
+	SendEmail(msg Email) (*Delivery, error)
+	SendSMS(msg SMS) (*Delivery, error)
+}
+
+// Email là email cần gửi
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// SMS là tin nhắn cần gửi, To là số điện thoại dạng E.164
+type SMS struct {
+	To   string
+	Body string
+}
+
+// Delivery là thông báo đã được provider nhận
+type Delivery struct {
+	ID      string    `json:"id"`
+	Channel string    `json:"channel"`
+	To      string    `json:"to"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// NotificationSimulator là Notifier chạy local, chỉ lưu lại các thông báo đã gửi
+// Operation được cấu hình Failure trả về ExternalError thay vì gửi
+type NotificationSimulator struct {
+	*simulator
+	sent []Delivery
+	now  func() time.Time
+}
+
+// NewNotificationSimulator tạo NotificationSimulator với lỗi ban đầu của từng operation (nil: không lỗi)
+func NewNotificationSimulator(failures map[string]Failure) *NotificationSimulator {
+	return &NotificationSimulator{simulator: newSimulator(DependencyNotification, failures), now: time.Now}
+}
+
+// SendEmail gửi email
+func (s *NotificationSimulator) SendEmail(msg Email) (*Delivery, error) {
+	if err := s.call(OperationSendEmail, nil); err != nil {
+		return nil, err
+	}
+	return s.deliver(ChannelEmail, msg.To), nil
+}
+
+// SendSMS gửi SMS
+func (s *NotificationSimulator) SendSMS(msg SMS) (*Delivery, error) {
+	if err := s.call(OperationSendSMS, nil); err != nil {
+		return nil, err
+	}
+	return s.deliver(ChannelSMS, msg.To), nil
+}
+
+// Sent trả về các thông báo đã gửi, cũ nhất trước
+func (s *NotificationSimulator) Sent() []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Delivery(nil), s.sent...)
+}
+
+// deliver lưu thông báo đã gửi với ID "MSG-000001"
+func (s *NotificationSimulator) deliver(channel, to string) *Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivery := Delivery{
+		ID:      fmt.Sprintf("MSG-%06d", len(s.sent)+1),
+		Channel: channel,
+		To:      to,
+		SentAt:  s.now(),
+	}
+	s.sent = append(s.sent, delivery)
+	return &delivery
+}
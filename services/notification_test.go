@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+
+	"fiber_log/testkit"
+)
+
+func TestNotificationSimulator(t *testing.T) {
+	notifier := NewNotificationSimulator(map[string]Failure{OperationSendSMS: {Mode: FailureTimeout}})
+
+	delivery, err := notifier.SendEmail(Email{To: "an@example.com", Subject: "Đơn hàng", Body: "..."})
+	testkit.AssertNoError(t, err)
+	if delivery.ID != "MSG-000001" || delivery.Channel != ChannelEmail || delivery.To != "an@example.com" {
+		t.Errorf("delivery = %+v", delivery)
+	}
+
+	// SMS lỗi không được lưu vào Sent
+	_, err = notifier.SendSMS(SMS{To: "+84901234567", Body: "..."})
+	testkit.AssertErrorType(t, err, testkit.External)
+	testkit.AssertStatus(t, err, 504)
+	testkit.AssertData(t, err, "dependency", DependencyNotification)
+	testkit.AssertData(t, err, "operation", OperationSendSMS)
+	if sent := notifier.Sent(); len(sent) != 1 || sent[0] != *delivery {
+		t.Errorf("sent = %+v", sent)
+	}
+}
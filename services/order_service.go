@@ -1,37 +1,90 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/techmaster-vietnam/goerrorkit"
 )
 
 // Order đại diện cho đơn hàng
 type Order struct {
-	ID        string
-	ProductID string
-	Quantity  int
-	UserID    string
-	Status    string
+	ID            string
+	ProductID     string
+	Quantity      int
+	UserID        string
+	Status        string
+	Allocations   []Allocation // Số lượng lấy từ từng kho, nhiều kho nghĩa là giao thành nhiều kiện
+	ReservationID string       // Hàng được giữ đến khi thanh toán, xem ReservationService
+	Total         Money        // Giá x số lượng lúc tạo đơn, webhook payment.succeeded phải khớp số tiền này
+	Paid          *Money       `json:",omitempty"` // Số tiền đã thanh toán qua ProcessPayment
+	PaidAt        *time.Time   `json:",omitempty"`
+	Refunds       []Refund     `json:",omitempty"` // Các lần hoàn tiền, xem RefundOrder
+	Shipment      *Label       `json:",omitempty"` // Vận đơn tạo sau khi thanh toán, xem FulfillmentService
+	Notifications []Delivery   `json:",omitempty"` // Thông báo đã gửi cho khách về vận đơn
+}
+
+// Trạng thái của đơn hàng
+const (
+	OrderConfirmed         = "confirmed" // Đã tạo, hàng đang được giữ
+	OrderPaid              = "paid"
+	OrderShipped           = "shipped"        // Đã tạo vận đơn, hàng được giao cho đơn vị vận chuyển
+	OrderPaymentFailed     = "payment_failed" // Gateway báo thanh toán thất bại qua webhook, hàng vẫn được giữ
+	OrderPartiallyRefunded = "partially_refunded"
+	OrderRefunded          = "refunded"  // Đã hoàn toàn bộ số tiền
+	OrderCancelled         = "cancelled" // Đã hủy, hàng giữ được hoàn trả về kho
+)
+
+// OrderRequest là dữ liệu đầu vào để tạo một đơn hàng
+type OrderRequest struct {
+	ProductID string `json:"product_id"`
+	UserID    string `json:"user_id"`
+	Quantity  int    `json:"quantity"`
 }
 
 // OrderService xử lý business logic liên quan đến đơn hàng
 type OrderService struct {
 	productService *ProductService
+	reservations   *ReservationService
+
+	refundWindow time.Duration
+
+	mu        sync.Mutex
+	orders    map[string]*Order
+	refundSeq int
+	now       func() time.Time
+	charge    func(gatewayCharge) error // callPaymentGateway, tests thay để chen sự kiện vào giữa lúc charge
 }
 
-// NewOrderService tạo OrderService mới
+// NewOrderService tạo OrderService mới, hàng của đơn hàng được giữ trong DefaultHoldTTL
 func NewOrderService(productService *ProductService) *OrderService {
-	return &OrderService{
+	return NewOrderServiceWith(productService, NewReservationService(productService, DefaultHoldTTL), DefaultRefundWindow)
+}
+
+// NewOrderServiceWith tạo OrderService dùng ReservationService cho trước (ví dụ TTL đọc từ RESERVATION_TTL)
+// refundWindow là thời hạn hoàn tiền tính từ lúc thanh toán, <= 0 dùng DefaultRefundWindow
+func NewOrderServiceWith(productService *ProductService, reservations *ReservationService, refundWindow time.Duration) *OrderService {
+	if refundWindow <= 0 {
+		refundWindow = DefaultRefundWindow
+	}
+	s := &OrderService{
 		productService: productService,
+		reservations:   reservations,
+		refundWindow:   refundWindow,
+		orders:         make(map[string]*Order),
+		now:            time.Now,
 	}
+	s.charge = s.callPaymentGateway
+	return s
 }
 
 // CreateOrder tạo đơn hàng mới
-// Sẽ kiểm tra stock và thực hiện reserve
-func (s *OrderService) CreateOrder(productID, userID string, quantity int) (*Order, error) {
+// Sẽ kiểm tra stock và giữ hàng (hold) đến khi thanh toán, hàng được lấy từ các kho theo policy
+func (s *OrderService) CreateOrder(productID, userID string, quantity int, policy AllocationPolicy) (*Order, error) {
 	// Kiểm tra sản phẩm có tồn tại không
-	_, err := s.productService.GetProduct(productID)
+	product, err := s.productService.GetProduct(productID)
 	if err != nil {
 		// Error được propagate từ ProductService
 		return nil, err
@@ -50,25 +103,139 @@ func (s *OrderService) CreateOrder(productID, userID string, quantity int) (*Ord
 		).WithCallChain()
 	}
 
-	// Kiểm tra và reserve stock
-	if err := s.productService.ReserveProduct(productID, quantity); err != nil {
+	// Kiểm tra stock và giữ hàng
+	hold, err := s.reservations.Hold(productID, quantity, policy)
+	if err != nil {
 		// Error được propagate từ ProductService.ReserveProduct
 		return nil, err
 	}
 
 	// Tạo order
 	order := &Order{
-		ID:        fmt.Sprintf("ORD-%s-%s", userID, productID),
-		ProductID: productID,
-		Quantity:  quantity,
-		UserID:    userID,
-		Status:    "confirmed",
+		ProductID:     productID,
+		Quantity:      quantity,
+		UserID:        userID,
+		Status:        OrderConfirmed,
+		Allocations:   hold.Allocations,
+		ReservationID: hold.ID,
+		Total:         product.Price.Mul(quantity),
+	}
+	s.save(order)
+
+	return order, nil
+}
+
+// save lưu order với ID "ORD-<user>-<product>", thêm hậu tố -2, -3... nếu user đặt cùng sản phẩm nhiều lần
+func (s *OrderService) save(order *Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	base := fmt.Sprintf("ORD-%s-%s", order.UserID, order.ProductID)
+	order.ID = base
+	for n := 2; s.orders[order.ID] != nil; n++ {
+		order.ID = fmt.Sprintf("%s-%d", base, n)
 	}
+	s.orders[order.ID] = order
+}
+
+// order trả về order đã tạo qua CreateOrder, nil nếu không có (order ID giả lập như "ORD-123")
+func (s *OrderService) order(orderID string) *Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.orders[orderID]
+}
+
+// GetOrder trả về bản sao order đã tạo qua CreateOrder, BusinessError 404 nếu không tồn tại
+func (s *OrderService) GetOrder(orderID string) (*Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, err := s.find(orderID)
+	if err != nil {
+		return nil, err
+	}
+	copied := *order
+	copied.Refunds = append([]Refund(nil), order.Refunds...)
+	copied.Notifications = append([]Delivery(nil), order.Notifications...)
+	return &copied, nil
+}
 
+// find tìm order theo ID, s.mu phải đang được giữ
+func (s *OrderService) find(orderID string) (*Order, error) {
+	order, exists := s.orders[orderID]
+	if !exists {
+		appErr := goerrorkit.NewBusinessError(404, fmt.Sprintf("Đơn hàng %s không tồn tại", orderID)).WithData(map[string]interface{}{
+			"order_id": orderID,
+		})
+		appErr.Cause = &OrderError{OrderID: orderID, Err: ErrOrderNotFound} // errors.Is(err, ErrOrderNotFound)
+		return nil, appErr
+	}
 	return order, nil
 }
 
+// ValidateOrder kiểm tra một đơn hàng có thể tạo được hay không (không reserve stock)
+func (s *OrderService) ValidateOrder(req OrderRequest) error {
+	if req.UserID == "" {
+		return goerrorkit.NewValidationError("User ID không được để trống", map[string]interface{}{
+			"field":    "user_id",
+			"required": true,
+		})
+	}
+
+	product, err := s.productService.GetProduct(req.ProductID)
+	if err != nil {
+		return err
+	}
+
+	if req.Quantity <= 0 {
+		return goerrorkit.NewValidationError(
+			"Số lượng phải lớn hơn 0",
+			map[string]interface{}{
+				"field":    "quantity",
+				"min":      1,
+				"received": req.Quantity,
+			},
+		)
+	}
+
+	if product.Stock < req.Quantity {
+		return goerrorkit.NewValidationError(
+			fmt.Sprintf("Không đủ hàng: yêu cầu %d, còn lại %d", req.Quantity, product.Stock),
+			map[string]interface{}{
+				"product_id":      req.ProductID,
+				"requested":       req.Quantity,
+				"available_stock": product.Stock,
+			},
+		)
+	}
+
+	return nil
+}
+
+// ValidateOrders kiểm tra nhiều đơn hàng cùng lúc và trả về tất cả lỗi thay vì dừng ở lỗi đầu tiên
+// Lỗi trả về là errors.Join của các lỗi con dạng "order[i]: <message>", mỗi lỗi con vẫn
+// giữ AppError gốc (type, status, location) và hỗ trợ errors.Is với sentinel errors
+func (s *OrderService) ValidateOrders(orders []OrderRequest) error {
+	if len(orders) == 0 {
+		return goerrorkit.NewValidationError("Danh sách đơn hàng trống", map[string]interface{}{
+			"field": "orders",
+			"min":   1,
+		})
+	}
+
+	var errs []error
+	for i, order := range orders {
+		if err := s.ValidateOrder(order); err != nil {
+			errs = append(errs, fmt.Errorf("order[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // CancelOrder hủy đơn hàng
+// Đơn hàng tạo qua CreateOrder được chuyển sang cancelled và hàng đang giữ được hoàn trả về kho;
+// đơn đã có vận đơn trả về BusinessError 400 (ErrOrderShipped),
+// đơn đã thanh toán trả về BusinessError 409 (ErrOrderPaid), cần dùng RefundOrder
 func (s *OrderService) CancelOrder(orderID string) error {
 	// Giả lập kiểm tra order không tồn tại
 	if orderID == "" {
@@ -81,54 +248,210 @@ func (s *OrderService) CancelOrder(orderID string) error {
 	// Giả lập order đã được ship
 	if orderID == "ORD-shipped" {
 		// Error với message cụ thể
-		return goerrorkit.NewBusinessError(
+		appErr := goerrorkit.NewBusinessError(
 			400,
 			"Không thể hủy đơn hàng đã được giao cho đơn vị vận chuyển",
 		).WithData(map[string]interface{}{
 			"order_id": orderID,
 			"status":   "shipped",
 		})
+		appErr.Cause = &OrderError{OrderID: orderID, Err: ErrOrderShipped} // errors.Is(err, ErrOrderShipped)
+		return appErr
+	}
+
+	// Đơn hàng tạo qua CreateOrder: hoàn trả hàng đang giữ và chuyển sang cancelled
+	order := s.order(orderID)
+	if order == nil {
+		return nil
+	}
+	s.mu.Lock()
+	status, paid, shipped := order.Status, order.Paid != nil, order.Shipment != nil
+	s.mu.Unlock()
+	if status == OrderCancelled {
+		return nil
+	}
+	if shipped {
+		appErr := goerrorkit.NewBusinessError(400, "Không thể hủy đơn hàng đã được giao cho đơn vị vận chuyển").WithData(map[string]interface{}{
+			"order_id": orderID,
+			"status":   status,
+		})
+		appErr.Cause = &OrderError{OrderID: orderID, Err: ErrOrderShipped} // errors.Is(err, ErrOrderShipped)
+		return appErr
+	}
+	if paid {
+		appErr := goerrorkit.NewBusinessError(409, fmt.Sprintf("Đơn hàng %s đã thanh toán, hãy hoàn tiền thay vì hủy", orderID)).WithData(map[string]interface{}{
+			"order_id": orderID,
+			"status":   status,
+		})
+		appErr.Cause = &OrderError{OrderID: orderID, Err: ErrOrderPaid} // errors.Is(err, ErrOrderPaid)
+		return appErr
+	}
+	if order.ReservationID != "" {
+		// Hàng giữ đã hết hạn thì đã được hoàn trả về kho, vẫn hủy được đơn hàng
+		if err := s.reservations.Release(order.ReservationID); err != nil && !errors.Is(err, ErrReservationExpired) {
+			return err
+		}
 	}
 
+	s.mu.Lock()
+	order.Status = OrderCancelled
+	s.mu.Unlock()
 	return nil
 }
 
+// ParsePaymentAmount đọc số tiền thanh toán do client gửi (amount dạng decimal, currency ISO 4217)
+// Số tiền không bị làm tròn: nhiều chữ số thập phân hơn tiền tệ cho phép (ví dụ 10.005 USD) là ValidationError
+func ParsePaymentAmount(amount, currency string) (Money, error) {
+	if currency == "" {
+		currency = string(DefaultCurrency)
+	}
+	cur, err := ParseCurrency(currency)
+	if err != nil {
+		return Money{}, goerrorkit.NewValidationError(fmt.Sprintf("Tiền tệ '%s' không hỗ trợ", currency), map[string]interface{}{
+			"field":    "currency",
+			"allowed":  supportedCurrencies(),
+			"received": currency,
+		})
+	}
+
+	money, err := ParseMoney(amount, cur)
+	if errors.Is(err, ErrAmountPrecision) {
+		exponent, _ := cur.Exponent()
+		return Money{}, goerrorkit.NewValidationError(
+			fmt.Sprintf("Số tiền %s có tối đa %d chữ số thập phân", cur, exponent),
+			map[string]interface{}{
+				"field":        "amount",
+				"currency":     cur,
+				"max_decimals": exponent,
+				"received":     amount,
+			},
+		)
+	}
+	if err != nil {
+		return Money{}, goerrorkit.NewValidationError("Số tiền thanh toán phải là số", map[string]interface{}{
+			"field":    "amount",
+			"received": amount,
+		})
+	}
+	return money, nil
+}
+
 // ProcessPayment xử lý thanh toán đơn hàng
-func (s *OrderService) ProcessPayment(orderID string, amount float64) error {
-	if amount <= 0 {
+// Số tiền được kiểm tra theo minor units và gửi tới gateway dưới dạng minor units kèm tiền tệ
+// Với đơn hàng tạo qua CreateOrder, hàng đang giữ phải còn hạn trước khi charge (BusinessError 410 nếu hết hạn)
+// và bị khóa trong lúc charge (ReservationService.BeginCharge): hết hạn, hủy đơn hay thanh toán song song
+// không lấy lại được hàng đã thu tiền. Hàng được chuyển thành bán (sold) sau khi gateway chấp nhận
+// Số tiền hoặc tiền tệ khác Order.Total trả về BusinessError 422 (ErrPaymentMismatch) trước khi charge,
+// giống webhook payment.succeeded (ApplyPaymentEvent)
+func (s *OrderService) ProcessPayment(orderID string, amount Money) error {
+	if !amount.IsPositive() {
 		// Validation error từ deep trong call stack
 		return goerrorkit.NewValidationError(
 			"Số tiền thanh toán phải lớn hơn 0",
 			map[string]interface{}{
 				"field":    "amount",
-				"min":      0.01,
+				"min":      NewMoney(1, amount.Currency()),
 				"received": amount,
 			},
 		)
 	}
 
+	order := s.order(orderID)
+	if order != nil && !amount.Equal(order.Total) {
+		appErr := goerrorkit.NewBusinessError(422, fmt.Sprintf("Số tiền thanh toán %s không khớp tổng đơn hàng %s", amount, order.Total)).WithData(map[string]interface{}{
+			"order_id": order.ID,
+			"expected": order.Total,
+			"received": amount,
+		})
+		appErr.Cause = &OrderError{OrderID: order.ID, Err: ErrPaymentMismatch} // errors.Is(err, ErrPaymentMismatch)
+		return appErr
+	}
+
+	// Không charge khi hàng đã được hoàn trả về kho, khóa hàng đang giữ đến khi có kết quả charge
+	held := order != nil && order.ReservationID != ""
+	if held {
+		if err := s.reservations.BeginCharge(order.ReservationID); err != nil {
+			return err
+		}
+	}
+
 	// Giả lập gọi payment gateway (external service)
-	err := s.callPaymentGateway(orderID, amount)
+	err := s.charge(gatewayCharge{
+		OrderID:     orderID,
+		AmountMinor: amount.Minor(),
+		Currency:    amount.Currency(),
+	})
 	if err != nil {
+		if held {
+			s.reservations.CancelCharge(order.ReservationID)
+		}
 		return err
 	}
 
+	if held {
+		return s.markPaid(order, amount)
+	}
+
+	return nil
+}
+
+// markPaid chuyển hàng đang giữ thành bán và ghi nhận số tiền đã thanh toán (ProcessPayment)
+// Đơn đã được webhook đánh dấu thanh toán trong lúc charge thì bỏ qua
+func (s *OrderService) markPaid(order *Order, amount Money) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if order.Paid != nil {
+		return nil
+	}
+	return s.markPaidLocked(order, amount)
+}
+
+// markPaidLocked dùng chung cho ProcessPayment và ApplyPaymentEvent, s.mu phải đang được giữ
+// từ lúc kiểm tra order.Paid để gateway đồng bộ và webhook không đánh dấu một đơn hai lần
+func (s *OrderService) markPaidLocked(order *Order, amount Money) error {
+	if order.ReservationID != "" {
+		if _, err := s.reservations.Confirm(order.ReservationID, order.ID); err != nil {
+			return err
+		}
+	}
+	paidAt := s.now()
+	order.Status, order.Paid, order.PaidAt = OrderPaid, &amount, &paidAt
 	return nil
 }
 
+// gatewayCharge là request gửi payment gateway: số tiền luôn là số nguyên minor units (cent, đồng)
+// để gateway không phải parse decimal
+type gatewayCharge struct {
+	OrderID     string
+	AmountMinor int64
+	Currency    Currency
+}
+
+// paymentConfirmLimits là số tiền tối đa (minor units) gateway xử lý ngay, lớn hơn thì cần xác nhận thêm
+var paymentConfirmLimits = map[Currency]int64{
+	USD: 10000_00,
+	EUR: 10000_00,
+	VND: 250_000_000,
+	JPY: 1_500_000,
+}
+
 // callPaymentGateway giả lập gọi external payment service
-func (s *OrderService) callPaymentGateway(orderID string, amount float64) error {
+func (s *OrderService) callPaymentGateway(charge gatewayCharge) error {
+	orderID := charge.OrderID
+
 	// Giả lập payment gateway timeout
-	if amount > 10000 {
+	if charge.AmountMinor > paymentConfirmLimits[charge.Currency] {
 		// External error được throw từ deep function
 		return goerrorkit.NewExternalError(
 			504,
 			"Payment gateway timeout: Giao dịch quá lớn cần xác nhận thêm",
 			fmt.Errorf("timeout after 30s waiting for payment confirmation"),
 		).WithData(map[string]interface{}{
-			"order_id": orderID,
-			"amount":   amount,
-			"timeout":  "30s",
+			"order_id":     orderID,
+			"amount_minor": charge.AmountMinor,
+			"currency":     charge.Currency,
+			"timeout":      "30s",
 		})
 	}
 
@@ -137,7 +460,7 @@ func (s *OrderService) callPaymentGateway(orderID string, amount float64) error
 		return goerrorkit.NewExternalError(
 			502,
 			"Payment failed: Thẻ thanh toán không hợp lệ",
-			fmt.Errorf("card declined by bank"),
+			&PaymentError{OrderID: orderID, Reason: "card declined by bank", Err: ErrPaymentDeclined},
 		).WithData(map[string]interface{}{
 			"order_id": orderID,
 			"service":  "payment_gateway",
@@ -146,3 +469,105 @@ func (s *OrderService) callPaymentGateway(orderID string, amount float64) error
 
 	return nil
 }
+
+// ============================================================================
+// Batch Orders - Tạo nhiều đơn hàng trong một request
+// ============================================================================
+
+// BatchMode quyết định cách xử lý khi một dòng trong batch bị lỗi
+type BatchMode string
+
+const (
+	// BatchAllOrNothing dừng ở dòng lỗi đầu tiên và hoàn trả stock của các dòng đã tạo
+	BatchAllOrNothing BatchMode = "all_or_nothing"
+	// BatchBestEffort xử lý tất cả các dòng, dòng lỗi không ảnh hưởng các dòng khác
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// Trạng thái của từng dòng trong batch
+const (
+	LineCreated    = "created"
+	LineFailed     = "failed"
+	LineRolledBack = "rolled_back" // Đã tạo nhưng bị hoàn trả do dòng khác lỗi (all_or_nothing)
+	LineSkipped    = "skipped"     // Không xử lý vì dòng trước đã lỗi (all_or_nothing)
+)
+
+// OrderLineResult là kết quả xử lý một dòng trong batch
+type OrderLineResult struct {
+	Index  int
+	Status string
+	Order  *Order // Khác nil nếu dòng đã được tạo, đơn bị rollback có Status cancelled và đã bị xóa
+	Err    error  // Lỗi từ CreateOrder nếu Status là LineFailed
+}
+
+// CreateOrders tạo nhiều đơn hàng, mỗi dòng đi qua CreateOrder
+// Lỗi của từng dòng nằm trong kết quả; error trả về chỉ dành cho lỗi của cả batch
+// (danh sách trống, mode không hợp lệ)
+//
+// Với BatchAllOrNothing, khi một dòng lỗi thì stock đã reserve của các dòng trước được hoàn trả
+// và các dòng sau được đánh dấu LineSkipped
+func (s *OrderService) CreateOrders(lines []OrderRequest, mode BatchMode) ([]OrderLineResult, error) {
+	if mode != BatchAllOrNothing && mode != BatchBestEffort {
+		return nil, goerrorkit.NewValidationError("Batch mode không hợp lệ", map[string]interface{}{
+			"field":    "mode",
+			"allowed":  []string{string(BatchAllOrNothing), string(BatchBestEffort)},
+			"received": string(mode),
+		})
+	}
+	if len(lines) == 0 {
+		return nil, goerrorkit.NewValidationError("Danh sách đơn hàng trống", map[string]interface{}{
+			"field": "orders",
+			"min":   1,
+		})
+	}
+
+	results := make([]OrderLineResult, len(lines))
+	failed := false
+	for i, line := range lines {
+		results[i].Index = i
+		if failed && mode == BatchAllOrNothing {
+			results[i].Status = LineSkipped
+			continue
+		}
+
+		order, err := s.CreateOrder(line.ProductID, line.UserID, line.Quantity, AllocationPolicy{})
+		if err != nil {
+			results[i].Status, results[i].Err = LineFailed, err
+			failed = true
+			continue
+		}
+		results[i].Status, results[i].Order = LineCreated, order
+	}
+
+	if failed && mode == BatchAllOrNothing {
+		if err := s.rollback(results); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// rollback hoàn trả stock của các dòng đã tạo trong batch và xóa đơn hàng của chúng
+// Đơn hàng bị rollback có Status cancelled, không còn tìm được qua GetOrder (ErrOrderNotFound)
+// nên không thể bị thanh toán, hoàn tiền hay nhận webhook
+func (s *OrderService) rollback(results []OrderLineResult) error {
+	for i := range results {
+		if results[i].Status != LineCreated {
+			continue
+		}
+
+		order := results[i].Order
+		if err := s.reservations.Release(order.ReservationID); err != nil {
+			return goerrorkit.WrapWithMessage(err, "Không thể hoàn trả stock khi rollback batch").WithData(map[string]interface{}{
+				"order_id": order.ID,
+				"line":     i,
+			})
+		}
+		s.mu.Lock()
+		delete(s.orders, order.ID)
+		order.Status = OrderCancelled
+		s.mu.Unlock()
+		results[i].Status = LineRolledBack
+	}
+	return nil
+}
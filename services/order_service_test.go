@@ -0,0 +1,387 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"fiber_log/testkit"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+func TestCreateOrder(t *testing.T) {
+	// Giống main.go: call chain chỉ giữ frames của application code
+	goerrorkit.ConfigureForApplication("services")
+	s := NewOrderService(NewProductService())
+
+	order, err := s.CreateOrder("456", "USER001", 2, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+	want := Order{ID: "ORD-USER001-456", ProductID: "456", Quantity: 2, UserID: "USER001", Status: "confirmed",
+		Allocations: []Allocation{{WarehouseID: "WH-01", Quantity: 2}}, ReservationID: "RSV-0001", Total: MustParseMoney("4999.98", USD)}
+	if !reflect.DeepEqual(*order, want) {
+		t.Errorf("order = %+v, want %+v", *order, want)
+	}
+
+	_, err = s.CreateOrder("999", "USER001", 1, AllocationPolicy{})
+	testkit.AssertStatus(t, err, 404)
+	testkit.AssertLocation(t, err, "GetProduct")
+
+	for _, quantity := range []int{0, -1} {
+		_, err = s.CreateOrder("456", "USER001", quantity, AllocationPolicy{})
+		testkit.AssertErrorType(t, err, testkit.Validation)
+		testkit.AssertMessage(t, err, "Số lượng phải lớn hơn 0")
+		testkit.AssertData(t, err, "received", quantity)
+		testkit.AssertLocation(t, err, "services/order_service.go:CreateOrder")
+		testkit.AssertCallChain(t, err, "order_service.go", "TestCreateOrder")
+	}
+
+	// Hết hàng: lỗi validation được propagate từ ReserveProduct
+	_, err = s.CreateOrder("123", "USER001", 1, AllocationPolicy{})
+	testkit.AssertErrorType(t, err, testkit.Validation)
+	testkit.AssertLocation(t, err, "services/product_service.go:ReserveProduct")
+}
+
+func TestValidateOrders(t *testing.T) {
+	s := NewOrderService(NewProductService())
+
+	testkit.AssertNoError(t, s.ValidateOrders([]OrderRequest{
+		{ProductID: "456", UserID: "U1", Quantity: 5},
+		{ProductID: "789", UserID: "U2", Quantity: 1},
+	}))
+
+	err := s.ValidateOrders(nil)
+	testkit.AssertErrorType(t, err, testkit.Validation)
+	testkit.AssertMessage(t, err, "Danh sách đơn hàng trống")
+
+	err = s.ValidateOrders([]OrderRequest{
+		{ProductID: "456", UserID: "U1", Quantity: 1},
+		{ProductID: "999", UserID: "U1", Quantity: 1},
+		{ProductID: "123", UserID: "U1", Quantity: 1},
+		{ProductID: "456", UserID: "", Quantity: 1},
+	})
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("ValidateOrders trả về %T, want lỗi gộp (errors.Join)", err)
+	}
+
+	children := joined.Unwrap()
+	wantMessages := []string{
+		"order[1]: Sản phẩm ID=999 không tồn tại",
+		"order[2]: Không đủ hàng: yêu cầu 1, còn lại 0",
+		"order[3]: User ID không được để trống",
+	}
+	if len(children) != len(wantMessages) {
+		t.Fatalf("got %d lỗi con, want %d: %v", len(children), len(wantMessages), err)
+	}
+	for i, want := range wantMessages {
+		if children[i].Error() != want {
+			t.Errorf("children[%d] = %q, want %q", i, children[i].Error(), want)
+		}
+	}
+
+	// Lỗi con vẫn giữ AppError gốc và sentinel error qua fmt.Errorf("%w") và errors.Join
+	testkit.AssertStatus(t, children[0], 404)
+	testkit.AssertLocation(t, children[0], "GetProduct")
+	testkit.AssertLocation(t, children[1], "services/order_service.go:ValidateOrder")
+	testkit.AssertData(t, children[2], "field", "user_id")
+	if !errors.Is(err, ErrProductNotFound) {
+		t.Error("errors.Is(err, ErrProductNotFound) = false")
+	}
+}
+
+func TestCreateOrders(t *testing.T) {
+	lines := []OrderRequest{
+		{ProductID: "456", UserID: "U1", Quantity: 2},
+		{ProductID: "789", UserID: "U1", Quantity: 4},
+		{ProductID: "123", UserID: "U1", Quantity: 1},
+		{ProductID: "456", UserID: "U1", Quantity: 1},
+	}
+
+	tests := []struct {
+		mode     BatchMode
+		statuses []string
+		stock    map[string]int
+	}{
+		{BatchBestEffort, []string{LineCreated, LineCreated, LineFailed, LineCreated}, map[string]int{"456": 2, "789": 6}},
+		{BatchAllOrNothing, []string{LineRolledBack, LineRolledBack, LineFailed, LineSkipped}, map[string]int{"456": 5, "789": 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			products := NewProductService()
+			s := NewOrderService(products)
+
+			results, err := s.CreateOrders(lines, tt.mode)
+			testkit.AssertNoError(t, err)
+			for i, want := range tt.statuses {
+				if results[i].Index != i || results[i].Status != want {
+					t.Errorf("results[%d] = %d %s, want %s", i, results[i].Index, results[i].Status, want)
+				}
+			}
+
+			// Dòng 2 (hết hàng) giữ nguyên lỗi của ReserveProduct
+			testkit.AssertErrorType(t, results[2].Err, testkit.Validation)
+			testkit.AssertLocation(t, results[2].Err, "ReserveProduct")
+
+			for id, want := range tt.stock {
+				product, _ := products.GetProduct(id)
+				if product.Stock != want {
+					t.Errorf("stock %s = %d, want %d", id, product.Stock, want)
+				}
+			}
+
+			// Đơn hàng bị rollback không còn tồn tại
+			for _, result := range results {
+				if result.Status != LineRolledBack {
+					continue
+				}
+				if result.Order.Status != OrderCancelled {
+					t.Errorf("rolled back order status = %s, want %s", result.Order.Status, OrderCancelled)
+				}
+				_, err := s.GetOrder(result.Order.ID)
+				testkit.AssertStatus(t, err, 404)
+				if !errors.Is(err, ErrOrderNotFound) {
+					t.Errorf("GetOrder(%s) = %v, want ErrOrderNotFound", result.Order.ID, err)
+				}
+			}
+		})
+	}
+
+	_, err := NewOrderService(NewProductService()).CreateOrders(lines, "sometimes")
+	testkit.AssertErrorType(t, err, testkit.Validation)
+	testkit.AssertData(t, err, "received", "sometimes")
+
+	_, err = NewOrderService(NewProductService()).CreateOrders(nil, BatchBestEffort)
+	testkit.AssertMessage(t, err, "Danh sách đơn hàng trống")
+}
+
+func TestCancelOrder(t *testing.T) {
+	s := NewOrderService(NewProductService())
+
+	testkit.AssertNoError(t, s.CancelOrder("ORD-123"))
+
+	err := s.CancelOrder("")
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 400)
+	testkit.AssertMessage(t, err, "Order ID không được để trống")
+	testkit.AssertData(t, err, "field", "order_id")
+	testkit.AssertLocation(t, err, "CancelOrder")
+
+	err = s.CancelOrder("ORD-shipped")
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertMessage(t, err, "Không thể hủy đơn hàng đã được giao cho đơn vị vận chuyển")
+	testkit.AssertData(t, err, "status", "shipped")
+	testkit.AssertLocation(t, err, "CancelOrder")
+
+	// Đơn hàng thật: hoàn trả hàng giữ, hủy lần hai không lỗi
+	order, err := s.CreateOrder("456", "USER001", 2, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+	testkit.AssertNoError(t, s.CancelOrder(order.ID))
+	testkit.AssertNoError(t, s.CancelOrder(order.ID))
+	if got, _ := s.GetOrder(order.ID); got.Status != OrderCancelled {
+		t.Errorf("status = %s, want %s", got.Status, OrderCancelled)
+	}
+	if product, _ := s.productService.GetProduct("456"); product.Stock != 5 {
+		t.Errorf("stock 456 = %d, want 5 (hàng giữ được hoàn trả)", product.Stock)
+	}
+
+	paid, err := s.CreateOrder("456", "USER001", 1, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+	testkit.AssertNoError(t, s.ProcessPayment(paid.ID, paid.Total))
+	err = s.CancelOrder(paid.ID)
+	testkit.AssertStatus(t, err, 409)
+	testkit.AssertLocation(t, err, "services/order_service.go:CancelOrder")
+	if !errors.Is(err, ErrOrderPaid) {
+		t.Error("errors.Is(err, ErrOrderPaid) = false")
+	}
+}
+
+func TestProcessPayment(t *testing.T) {
+	s := NewOrderService(NewProductService())
+
+	testkit.AssertNoError(t, s.ProcessPayment("ORD-123", MustParseMoney("100", USD)))
+	testkit.AssertNoError(t, s.ProcessPayment("ORD-123", MustParseMoney("10000", USD)))
+	testkit.AssertNoError(t, s.ProcessPayment("ORD-123", MustParseMoney("250000000", VND)))
+
+	tests := []struct {
+		name     string
+		orderID  string
+		amount   Money
+		errType  goerrorkit.ErrorType
+		status   int
+		location string
+		cause    string
+	}{
+		{"zero amount", "ORD-123", Money{}, testkit.Validation, 400, "ProcessPayment", ""},
+		{"negative amount", "ORD-123", MustParseMoney("-5", USD), testkit.Validation, 400, "ProcessPayment", ""},
+		{"gateway timeout", "ORD-123", MustParseMoney("10000.01", USD), testkit.External, 504, "callPaymentGateway", "timeout after 30s"},
+		{"gateway timeout VND", "ORD-123", MustParseMoney("250000001", VND), testkit.External, 504, "callPaymentGateway", "timeout after 30s"},
+		{"card declined", "ORD-invalid-card", MustParseMoney("100", USD), testkit.External, 502, "callPaymentGateway", "card declined by bank"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.ProcessPayment(tt.orderID, tt.amount)
+			testkit.AssertErrorType(t, err, tt.errType)
+			testkit.AssertStatus(t, err, tt.status)
+			testkit.AssertLocation(t, err, tt.location)
+			if tt.cause != "" {
+				testkit.AssertCause(t, err, tt.cause)
+			}
+		})
+	}
+}
+
+// TestProcessPaymentMismatch kiểm tra số tiền hoặc tiền tệ khác tổng đơn hàng bị từ chối trước khi charge
+func TestProcessPaymentMismatch(t *testing.T) {
+	s := NewOrderService(NewProductService())
+	order, err := s.CreateOrder("456", "USER001", 2, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+	s.charge = func(charge gatewayCharge) error {
+		t.Errorf("gateway được gọi với %+v", charge)
+		return nil
+	}
+
+	tests := []struct {
+		name   string
+		amount Money
+	}{
+		{"underpaid", MustParseMoney("4999.97", USD)},
+		{"overpaid", MustParseMoney("5000", USD)},
+		{"wrong currency", MustParseMoney("4999.98", EUR)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.ProcessPayment(order.ID, tt.amount)
+			testkit.AssertErrorType(t, err, testkit.Business)
+			testkit.AssertStatus(t, err, 422)
+			testkit.AssertLocation(t, err, "services/order_service.go:ProcessPayment")
+			testkit.AssertData(t, err, "expected", order.Total)
+			testkit.AssertData(t, err, "received", tt.amount)
+			if !errors.Is(err, ErrPaymentMismatch) {
+				t.Error("errors.Is(err, ErrPaymentMismatch) = false")
+			}
+		})
+	}
+
+	got, _ := s.GetOrder(order.ID)
+	if got.Status != OrderConfirmed || got.Paid != nil {
+		t.Errorf("order = %+v, want chưa thanh toán", got)
+	}
+	if hold, _ := s.reservations.Get(order.ReservationID); hold.Status != HoldActive {
+		t.Errorf("hold status = %s, want %s", hold.Status, HoldActive)
+	}
+}
+
+// TestProcessPaymentHoldLocked kiểm tra hàng đang giữ không bị lấy lại trong lúc gateway đang charge
+func TestProcessPaymentHoldLocked(t *testing.T) {
+	products := NewProductService()
+	reservations, advance := newClockedReservations(products, time.Minute)
+	s := NewOrderServiceWith(products, reservations, 0)
+
+	order, err := s.CreateOrder("456", "USER001", 2, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+
+	// Hold hết hạn giữa lúc charge và confirm: job hết hạn, hủy đơn và thanh toán song song đều không lấy được hàng
+	s.charge = func(charge gatewayCharge) error {
+		advance(2 * time.Minute)
+		testkit.AssertNoError(t, reservations.ExpireHold(order.ReservationID))
+		if due := reservations.DueHolds(); len(due) != 0 {
+			t.Errorf("DueHolds = %v, hold đang charge không được hết hạn", due)
+		}
+		testkit.AssertStatus(t, s.CancelOrder(order.ID), 409)
+		err := s.ProcessPayment(order.ID, order.Total)
+		testkit.AssertStatus(t, err, 409)
+		if !errors.Is(err, ErrReservationClosed) {
+			t.Errorf("ProcessPayment song song = %v, want ErrReservationClosed", err)
+		}
+		return s.callPaymentGateway(charge)
+	}
+	testkit.AssertNoError(t, s.ProcessPayment(order.ID, order.Total))
+	if paid, _ := s.GetOrder(order.ID); paid.Status != OrderPaid || paid.Paid == nil {
+		t.Errorf("order = %+v, want paid", paid)
+	}
+	if hold, _ := reservations.Get(order.ReservationID); hold.Status != HoldSold {
+		t.Errorf("hold status = %s, want %s", hold.Status, HoldSold)
+	}
+	if product, _ := products.GetProduct("456"); product.Stock != 3 {
+		t.Errorf("stock 456 = %d, want 3 (hàng đã bán không được hoàn trả)", product.Stock)
+	}
+
+	// Charge lỗi: hold được mở khóa, đã quá hạn thì job hết hạn hoàn trả hàng
+	second, err := s.CreateOrder("456", "USER002", 1, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+	s.charge = func(charge gatewayCharge) error {
+		advance(2 * time.Minute)
+		return goerrorkit.NewExternalError(504, "Payment gateway timeout", errors.New("timeout after 30s"))
+	}
+	testkit.AssertStatus(t, s.ProcessPayment(second.ID, second.Total), 504)
+	if due := reservations.DueHolds(); len(due) != 1 || due[0] != second.ReservationID {
+		t.Errorf("DueHolds = %v, want [%s]", due, second.ReservationID)
+	}
+	testkit.AssertNoError(t, reservations.ExpireHold(second.ReservationID))
+	if product, _ := products.GetProduct("456"); product.Stock != 3 {
+		t.Errorf("stock 456 = %d, want 3 (hàng giữ đã hết hạn được hoàn trả)", product.Stock)
+	}
+}
+
+func TestParsePaymentAmount(t *testing.T) {
+	amount, err := ParsePaymentAmount("10.5", "")
+	testkit.AssertNoError(t, err)
+	if amount.Minor() != 1050 || amount.Currency() != USD {
+		t.Errorf("amount = %v (minor %d)", amount, amount.Minor())
+	}
+	amount, err = ParsePaymentAmount("150000", "vnd")
+	testkit.AssertNoError(t, err)
+	if amount.Minor() != 150000 || amount.Currency() != VND {
+		t.Errorf("amount = %v (minor %d)", amount, amount.Minor())
+	}
+
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		field    string
+		message  string
+	}{
+		{"too many decimals", "10.005", "USD", "amount", "Số tiền USD có tối đa 2 chữ số thập phân"},
+		{"decimals for VND", "1000.5", "VND", "amount", "Số tiền VND có tối đa 0 chữ số thập phân"},
+		{"not a number", "1e3", "USD", "amount", "Số tiền thanh toán phải là số"},
+		{"unsupported currency", "10", "BTC", "currency", "Tiền tệ 'BTC' không hỗ trợ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePaymentAmount(tt.amount, tt.currency)
+			testkit.AssertErrorType(t, err, testkit.Validation)
+			testkit.AssertLocation(t, err, "ParsePaymentAmount")
+			testkit.AssertMessage(t, err, tt.message)
+			testkit.AssertData(t, err, "field", tt.field)
+		})
+	}
+}
+
+// TestPaymentErrorLogged kiểm tra entry được log của external error (cause, data, location)
+func TestPaymentErrorLogged(t *testing.T) {
+	logs := testkit.CaptureLogs(t)
+	s := NewOrderService(NewProductService())
+
+	err := s.ProcessPayment("ORD-123", MustParseMoney("20000", USD))
+	goerrorkit.LogError(testkit.AppError(t, err), "POST /order/ORD-123/payment")
+
+	errs := logs.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("logged %d errors, want 1", len(errs))
+	}
+	entry := errs[0]
+	if entry.ErrorType() != testkit.External || entry.Cause() != "timeout after 30s waiting for payment confirmation" {
+		t.Errorf("entry = %s cause=%q", entry.ErrorType(), entry.Cause())
+	}
+	if got := entry.Location(); got != testkit.Location(testkit.AppError(t, err)) {
+		t.Errorf("entry.location = %q", got)
+	}
+	if got := entry.Data()["order_id"]; got != "ORD-123" {
+		t.Errorf("entry.data.order_id = %v", got)
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Payment Webhooks - Payment gateway xác nhận thanh toán bất đồng bộ
+// ============================================================================
+
+// Gateway gửi event kèm header X-Payment-Signature: "t=<unix>,v1=<hex>", với
+// v1 = HMAC-SHA256(secret, "<unix>.<body>"). Event được xử lý một lần theo ID: gateway gửi lại
+// cùng event (retry, replay) thì được trả về thành công mà không cập nhật đơn hàng lần nữa
+//
+// Example:
+//
+//	payload, _ := json.Marshal(services.WebhookEvent{ID: "evt_1", Type: services.WebhookPaymentSucceeded,
+//	    OrderID: "ORD-USER001-456", Amount: services.MustParseMoney("100", services.USD)})
+//	signature := services.SignWebhook(secret, time.Now(), payload)
+//	event, duplicate, err := paymentWebhooks.Handle(signature, payload)
+//	// Sai chữ ký hoặc quá tolerance: AuthError 401; đơn hàng không tồn tại: BusinessError 404
+
+// WebhookSignatureHeader là header chứa chữ ký của webhook
+const WebhookSignatureHeader = "X-Payment-Signature"
+
+// DefaultWebhookTolerance là độ lệch tối đa giữa timestamp trong chữ ký và thời điểm nhận
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// Loại event webhook
+const (
+	WebhookPaymentSucceeded = "payment.succeeded"
+	WebhookPaymentFailed    = "payment.failed"
+)
+
+// WebhookEvent là event payment gateway gửi tới POST /webhooks/payment
+type WebhookEvent struct {
+	ID      string    `json:"id"`
+	Type    string    `json:"type"`
+	OrderID string    `json:"order_id"`
+	Amount  Money     `json:"amount"`
+	Reason  string    `json:"reason,omitempty"` // Lý do thất bại (payment.failed)
+	Created time.Time `json:"created"`
+}
+
+// PaymentWebhooks xác thực và xử lý webhook của payment gateway
+type PaymentWebhooks struct {
+	orders    *OrderService
+	secret    string
+	tolerance time.Duration
+
+	mu        sync.Mutex
+	processed map[string]time.Time // event ID -> thời điểm xử lý, xóa sau 2 x tolerance (xem prune)
+	now       func() time.Time
+}
+
+// NewPaymentWebhooks tạo PaymentWebhooks với secret dùng chung với gateway, tolerance <= 0 dùng DefaultWebhookTolerance
+func NewPaymentWebhooks(orders *OrderService, secret string, tolerance time.Duration) *PaymentWebhooks {
+	if tolerance <= 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+	return &PaymentWebhooks{
+		orders:    orders,
+		secret:    secret,
+		tolerance: tolerance,
+		processed: make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// SignWebhook trả về giá trị header X-Payment-Signature cho payload (dùng bởi gateway giả lập và tests)
+func SignWebhook(secret string, timestamp time.Time, payload []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", timestamp.Unix(), webhookMAC(secret, timestamp.Unix(), payload))
+}
+
+// Handle xác thực chữ ký, parse event và cập nhật đơn hàng
+// duplicate = true nếu event ID đã được xử lý trước đó (đơn hàng không bị cập nhật lại)
+// Event lỗi không được đánh dấu đã xử lý nên gateway có thể gửi lại sau
+// secret rỗng trả về BusinessError 503 (ErrWebhookDisabled): HMAC với key rỗng thì ai cũng ký được
+func (w *PaymentWebhooks) Handle(signature string, payload []byte) (*WebhookEvent, bool, error) {
+	if w.secret == "" {
+		appErr := goerrorkit.NewBusinessError(503, "Webhook thanh toán chưa được cấu hình").WithData(map[string]interface{}{
+			"setting": "PAYMENT_WEBHOOK_SECRET",
+		})
+		appErr.Cause = &WebhookError{Reason: "no webhook secret configured", Err: ErrWebhookDisabled} // errors.Is(err, ErrWebhookDisabled)
+		return nil, false, appErr
+	}
+	if err := w.verify(signature, payload); err != nil {
+		return nil, false, err
+	}
+	event, err := parseWebhookEvent(payload)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Giữ lock khi xử lý để hai lần gửi cùng event không cùng cập nhật đơn hàng
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	w.prune(now)
+	if _, seen := w.processed[event.ID]; seen {
+		return event, true, nil
+	}
+	if err := w.orders.ApplyPaymentEvent(*event); err != nil {
+		return event, false, err
+	}
+	w.processed[event.ID] = now
+	return event, false, nil
+}
+
+// prune xóa event ID đã xử lý quá 2 x tolerance để processed không tăng mãi, w.mu phải đang được giữ
+// Chữ ký của request đã xử lý lệch tối đa tolerance so với lúc xử lý và bị verify từ chối sau thêm
+// tolerance nữa, nên request đó không thể được gửi lại sau khi bị xóa khỏi processed
+func (w *PaymentWebhooks) prune(now time.Time) {
+	for id, processedAt := range w.processed {
+		if now.Sub(processedAt) > 2*w.tolerance {
+			delete(w.processed, id)
+		}
+	}
+}
+
+// verify kiểm tra chữ ký và timestamp trong header X-Payment-Signature
+func (w *PaymentWebhooks) verify(signature string, payload []byte) error {
+	if strings.TrimSpace(signature) == "" {
+		appErr := goerrorkit.NewAuthError(401, "Thiếu chữ ký webhook").WithData(map[string]interface{}{
+			"header": WebhookSignatureHeader,
+		})
+		appErr.Cause = &WebhookError{Reason: "missing signature", Err: ErrWebhookSignature} // errors.Is(err, ErrWebhookSignature)
+		return appErr
+	}
+
+	timestamp, signatures, ok := parseSignatureHeader(signature)
+	if !ok {
+		appErr := goerrorkit.NewAuthError(401, "Chữ ký webhook không đúng định dạng").WithData(map[string]interface{}{
+			"header":   WebhookSignatureHeader,
+			"expected": "t=<unix>,v1=<hex>",
+		})
+		appErr.Cause = &WebhookError{Reason: "malformed signature header", Err: ErrWebhookSignature}
+		return appErr
+	}
+
+	// Kiểm tra chữ ký trước timestamp để không tiết lộ thông tin thời gian cho request giả mạo
+	expected, _ := hex.DecodeString(webhookMAC(w.secret, timestamp, payload))
+	matched := false
+	for _, candidate := range signatures {
+		if got, err := hex.DecodeString(candidate); err == nil && hmac.Equal(got, expected) {
+			matched = true
+		}
+	}
+	if !matched {
+		appErr := goerrorkit.NewAuthError(401, "Chữ ký webhook không hợp lệ").WithData(map[string]interface{}{
+			"header":    WebhookSignatureHeader,
+			"timestamp": timestamp,
+		})
+		appErr.Cause = &WebhookError{Reason: "signature mismatch", Err: ErrWebhookSignature}
+		return appErr
+	}
+
+	now := w.now()
+	signedAt := time.Unix(timestamp, 0)
+	if skew := now.Sub(signedAt); skew > w.tolerance || skew < -w.tolerance {
+		appErr := goerrorkit.NewAuthError(401, "Webhook nằm ngoài khoảng thời gian cho phép").WithData(map[string]interface{}{
+			"signed_at": signedAt.UTC(),
+			"skew":      skew.Round(time.Second).String(),
+			"tolerance": w.tolerance.String(),
+		})
+		appErr.Cause = &WebhookError{Reason: "timestamp outside tolerance", Err: ErrWebhookExpired} // errors.Is(err, ErrWebhookExpired)
+		return appErr
+	}
+	return nil
+}
+
+// parseSignatureHeader đọc "t=<unix>,v1=<hex>[,v1=<hex>]" (nhiều v1 khi gateway đang đổi secret)
+func parseSignatureHeader(header string) (int64, []string, bool) {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			return 0, nil, false
+		}
+		switch key {
+		case "t":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, nil, false
+			}
+			timestamp = t
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	return timestamp, signatures, timestamp > 0 && len(signatures) > 0
+}
+
+// webhookMAC trả về HMAC-SHA256(secret, "<timestamp>.<payload>") dạng hex
+func webhookMAC(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseWebhookEvent decode và kiểm tra event, trả về errors.Join của ValidationError từng trường
+func parseWebhookEvent(payload []byte) (*WebhookEvent, error) {
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, goerrorkit.NewValidationError("Webhook payload không hợp lệ", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	var errs []error
+	required := func(field, value string) {
+		if strings.TrimSpace(value) == "" {
+			errs = append(errs, goerrorkit.NewValidationError(fmt.Sprintf("Webhook thiếu trường '%s'", field), map[string]interface{}{
+				"field":    field,
+				"required": true,
+			}))
+		}
+	}
+	required("id", event.ID)
+	required("order_id", event.OrderID)
+	switch event.Type {
+	case WebhookPaymentSucceeded:
+		if !event.Amount.IsPositive() {
+			errs = append(errs, goerrorkit.NewValidationError("Số tiền thanh toán phải lớn hơn 0", map[string]interface{}{
+				"field":    "amount",
+				"received": event.Amount,
+			}))
+		}
+	case WebhookPaymentFailed:
+	default:
+		errs = append(errs, goerrorkit.NewValidationError(fmt.Sprintf("Loại event '%s' không hỗ trợ", event.Type), map[string]interface{}{
+			"field":    "type",
+			"allowed":  []string{WebhookPaymentSucceeded, WebhookPaymentFailed},
+			"received": event.Type,
+		}))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ApplyPaymentEvent cập nhật đơn hàng theo event của payment gateway
+//   - payment.succeeded: giống ProcessPayment sau khi gateway chấp nhận (hàng giữ chuyển thành sold, Status paid);
+//     đơn đã thanh toán thì bỏ qua (gateway xác nhận lại giao dịch đã xử lý đồng bộ)
+//   - payment.failed: Status payment_failed, hàng vẫn được giữ đến khi hết hạn để khách thanh toán lại
+//
+// Đơn hàng không tồn tại trả về BusinessError 404 (ErrOrderNotFound), số tiền hoặc tiền tệ
+// khác Order.Total trả về BusinessError 422 (ErrPaymentMismatch) và đơn hàng không đổi
+func (s *OrderService) ApplyPaymentEvent(event WebhookEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, err := s.find(event.OrderID)
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case WebhookPaymentSucceeded:
+		if order.Paid != nil {
+			return nil
+		}
+		if !event.Amount.Equal(order.Total) {
+			appErr := goerrorkit.NewBusinessError(422, fmt.Sprintf("Số tiền thanh toán %s không khớp tổng đơn hàng %s", event.Amount, order.Total)).WithData(map[string]interface{}{
+				"order_id": order.ID,
+				"event_id": event.ID,
+				"expected": order.Total,
+				"received": event.Amount,
+			})
+			appErr.Cause = &OrderError{OrderID: order.ID, Err: ErrPaymentMismatch} // errors.Is(err, ErrPaymentMismatch)
+			return appErr
+		}
+		return s.markPaidLocked(order, event.Amount)
+	case WebhookPaymentFailed:
+		if order.Paid == nil {
+			order.Status = OrderPaymentFailed
+		}
+	}
+	return nil
+}
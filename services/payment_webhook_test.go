@@ -0,0 +1,215 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"fiber_log/testkit"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// newWebhooks tạo PaymentWebhooks với đồng hồ giả lập và một đơn hàng 2 x 456 chưa thanh toán
+func newWebhooks(t *testing.T) (*PaymentWebhooks, *Order, time.Time) {
+	t.Helper()
+	orders := NewOrderService(NewProductService())
+	order, err := orders.CreateOrder("456", "USER001", 2, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+
+	w := NewPaymentWebhooks(orders, testWebhookSecret, 0)
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+	return w, order, now
+}
+
+func webhookPayload(t *testing.T, event WebhookEvent) []byte {
+	t.Helper()
+	payload, err := json.Marshal(event)
+	testkit.AssertNoError(t, err)
+	return payload
+}
+
+func TestPaymentWebhookSucceeded(t *testing.T) {
+	w, order, now := newWebhooks(t)
+	amount := MustParseMoney("4999.98", USD) // 2 x 2499.99
+	payload := webhookPayload(t, WebhookEvent{ID: "evt_1", Type: WebhookPaymentSucceeded, OrderID: order.ID, Amount: amount})
+
+	event, duplicate, err := w.Handle(SignWebhook(testWebhookSecret, now, payload), payload)
+	testkit.AssertNoError(t, err)
+	if event.ID != "evt_1" || duplicate {
+		t.Errorf("event = %+v, duplicate = %v", event, duplicate)
+	}
+	got, _ := w.orders.GetOrder(order.ID)
+	if got.Status != OrderPaid || got.Paid == nil || *got.Paid != amount {
+		t.Errorf("order = %+v", got)
+	}
+	if reservation, _ := w.orders.reservations.Get(order.ReservationID); reservation.Status != HoldSold {
+		t.Errorf("reservation status = %s, want %s", reservation.Status, HoldSold)
+	}
+
+	// Gateway gửi lại cùng event với chữ ký mới: không cập nhật lại đơn hàng
+	_, duplicate, err = w.Handle(SignWebhook(testWebhookSecret, now.Add(time.Minute), payload), payload)
+	testkit.AssertNoError(t, err)
+	if !duplicate {
+		t.Error("duplicate = false, want true")
+	}
+	if again, _ := w.orders.GetOrder(order.ID); !again.PaidAt.Equal(*got.PaidAt) {
+		t.Errorf("PaidAt = %v, want %v", again.PaidAt, got.PaidAt)
+	}
+
+	// Event khác xác nhận đơn đã thanh toán: bỏ qua
+	other := webhookPayload(t, WebhookEvent{ID: "evt_2", Type: WebhookPaymentSucceeded, OrderID: order.ID, Amount: amount})
+	_, duplicate, err = w.Handle(SignWebhook(testWebhookSecret, now, other), other)
+	testkit.AssertNoError(t, err)
+	if duplicate {
+		t.Error("duplicate = true, want false")
+	}
+}
+
+// TestPaymentWebhookPrune kiểm tra event ID đã xử lý được xóa khi request gốc không còn gửi lại được
+func TestPaymentWebhookPrune(t *testing.T) {
+	w, order, now := newWebhooks(t)
+	payload := webhookPayload(t, WebhookEvent{ID: "evt_1", Type: WebhookPaymentSucceeded, OrderID: order.ID, Amount: order.Total})
+	signature := SignWebhook(testWebhookSecret, now.Add(DefaultWebhookTolerance), payload)
+	_, _, err := w.Handle(signature, payload)
+	testkit.AssertNoError(t, err)
+
+	// Chữ ký lệch về tương lai vẫn còn hiệu lực đến 2 x tolerance sau lúc xử lý: chưa được xóa
+	w.now = func() time.Time { return now.Add(2 * DefaultWebhookTolerance) }
+	_, duplicate, err := w.Handle(signature, payload)
+	testkit.AssertNoError(t, err)
+	if !duplicate || len(w.processed) != 1 {
+		t.Errorf("duplicate = %v, processed = %v", duplicate, w.processed)
+	}
+
+	w.now = func() time.Time { return now.Add(2*DefaultWebhookTolerance + time.Second) }
+	other := webhookPayload(t, WebhookEvent{ID: "evt_2", Type: WebhookPaymentFailed, OrderID: order.ID})
+	_, _, err = w.Handle(SignWebhook(testWebhookSecret, w.now(), other), other)
+	testkit.AssertNoError(t, err)
+	if _, seen := w.processed["evt_1"]; seen || len(w.processed) != 1 {
+		t.Errorf("processed = %v, want chỉ evt_2", w.processed)
+	}
+	_, _, err = w.Handle(signature, payload)
+	testkit.AssertStatus(t, err, 401)
+	if !errors.Is(err, ErrWebhookExpired) {
+		t.Errorf("gửi lại sau khi xóa = %v, want ErrWebhookExpired", err)
+	}
+}
+
+func TestPaymentWebhookFailed(t *testing.T) {
+	w, order, now := newWebhooks(t)
+	payload := webhookPayload(t, WebhookEvent{ID: "evt_1", Type: WebhookPaymentFailed, OrderID: order.ID, Reason: "card_declined"})
+
+	_, _, err := w.Handle(SignWebhook(testWebhookSecret, now, payload), payload)
+	testkit.AssertNoError(t, err)
+	if got, _ := w.orders.GetOrder(order.ID); got.Status != OrderPaymentFailed || got.Paid != nil {
+		t.Errorf("order = %+v", got)
+	}
+
+	// Hàng vẫn được giữ nên khách có thể thanh toán lại
+	testkit.AssertNoError(t, w.orders.ProcessPayment(order.ID, order.Total))
+	if got, _ := w.orders.GetOrder(order.ID); got.Status != OrderPaid {
+		t.Errorf("status = %s, want %s", got.Status, OrderPaid)
+	}
+}
+
+func TestPaymentWebhookSignature(t *testing.T) {
+	w, order, now := newWebhooks(t)
+	payload := webhookPayload(t, WebhookEvent{ID: "evt_1", Type: WebhookPaymentSucceeded, OrderID: order.ID, Amount: order.Total})
+
+	tests := []struct {
+		name      string
+		signature string
+		payload   []byte
+		sentinel  error
+	}{
+		{"missing", "", payload, ErrWebhookSignature},
+		{"malformed", "v1=abc", payload, ErrWebhookSignature},
+		{"wrong secret", SignWebhook("whsec_other", now, payload), payload, ErrWebhookSignature},
+		{"tampered body", SignWebhook(testWebhookSecret, now, payload), append([]byte(" "), payload...), ErrWebhookSignature},
+		{"too old", SignWebhook(testWebhookSecret, now.Add(-DefaultWebhookTolerance-time.Second), payload), payload, ErrWebhookExpired},
+		{"too new", SignWebhook(testWebhookSecret, now.Add(DefaultWebhookTolerance+time.Second), payload), payload, ErrWebhookExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := w.Handle(tt.signature, tt.payload)
+			testkit.AssertErrorType(t, err, testkit.Auth)
+			testkit.AssertStatus(t, err, 401)
+			testkit.AssertLocation(t, err, "services/payment_webhook.go:verify")
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("errors.Is(err, %v) = false", tt.sentinel)
+			}
+		})
+	}
+
+	// Nhiều v1 khi gateway đang đổi secret: chỉ cần một chữ ký đúng
+	rotated := SignWebhook("whsec_old", now, payload) + ",v1=" + webhookMAC(testWebhookSecret, now.Unix(), payload)
+	_, _, err := w.Handle(rotated, payload)
+	testkit.AssertNoError(t, err)
+}
+
+func TestPaymentWebhookErrors(t *testing.T) {
+	w, order, now := newWebhooks(t)
+	send := func(event WebhookEvent) error {
+		payload := webhookPayload(t, event)
+		_, _, err := w.Handle(SignWebhook(testWebhookSecret, now, payload), payload)
+		return err
+	}
+
+	err := send(WebhookEvent{ID: "evt_1", Type: WebhookPaymentSucceeded, OrderID: "ORD-123", Amount: MustParseMoney("100", USD)})
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 404)
+	if !errors.Is(err, ErrOrderNotFound) {
+		t.Error("errors.Is(err, ErrOrderNotFound) = false")
+	}
+
+	// Số tiền hoặc tiền tệ khác tổng đơn hàng: đơn hàng không đổi
+	for _, amount := range []Money{MustParseMoney("0.01", USD), MustParseMoney("5000", USD), MustParseMoney("4999.98", EUR)} {
+		err = send(WebhookEvent{ID: "evt_1", Type: WebhookPaymentSucceeded, OrderID: order.ID, Amount: amount})
+		testkit.AssertErrorType(t, err, testkit.Business)
+		testkit.AssertStatus(t, err, 422)
+		testkit.AssertLocation(t, err, "services/payment_webhook.go:ApplyPaymentEvent")
+		testkit.AssertData(t, err, "expected", order.Total)
+		testkit.AssertData(t, err, "received", amount)
+		if !errors.Is(err, ErrPaymentMismatch) {
+			t.Errorf("errors.Is(err, ErrPaymentMismatch) = false for %s", amount)
+		}
+	}
+	if got, _ := w.orders.GetOrder(order.ID); got.Status != OrderConfirmed || got.Paid != nil {
+		t.Errorf("order = %+v, want chưa thanh toán", got)
+	}
+
+	// Event lỗi không được đánh dấu đã xử lý
+	testkit.AssertNoError(t, send(WebhookEvent{ID: "evt_1", Type: WebhookPaymentSucceeded, OrderID: order.ID, Amount: order.Total}))
+	if got, _ := w.orders.GetOrder(order.ID); got.Status != OrderPaid {
+		t.Errorf("status = %s, want %s", got.Status, OrderPaid)
+	}
+
+	err = send(WebhookEvent{Type: "payment.refunded", Amount: Money{}})
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Handle trả về %T, want lỗi gộp", err)
+	}
+	for i, field := range []string{"id", "order_id", "type"} {
+		child := joined.Unwrap()[i]
+		testkit.AssertErrorType(t, child, testkit.Validation)
+		testkit.AssertData(t, child, "field", field)
+	}
+
+	err = send(WebhookEvent{ID: "evt_2", Type: WebhookPaymentSucceeded, OrderID: order.ID})
+	testkit.AssertErrorType(t, err, testkit.Validation)
+	testkit.AssertData(t, err, "field", "amount")
+
+	disabled := NewPaymentWebhooks(w.orders, "", 0)
+	_, _, err = disabled.Handle(SignWebhook("", now, []byte("{}")), []byte("{}"))
+	testkit.AssertStatus(t, err, 503)
+	if !errors.Is(err, ErrWebhookDisabled) {
+		t.Error("errors.Is(err, ErrWebhookDisabled) = false")
+	}
+
+	payload := []byte("{not json")
+	_, _, err = w.Handle(SignWebhook(testWebhookSecret, now, payload), payload)
+	testkit.AssertErrorType(t, err, testkit.Validation)
+}
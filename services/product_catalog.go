@@ -0,0 +1,412 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Product CRUD - Tạo, cập nhật, xóa sản phẩm với optimistic concurrency (ETag / If-Match)
+// ============================================================================
+
+// ETag trả về entity tag của sản phẩm, thay đổi mỗi khi name, price hoặc stock thay đổi
+//
+// Example:
+//
+//	etag := product.ETag() // "\"3f1c2a9b7d4e5f60\""
+func (p *Product) ETag() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%v|%d", p.ID, p.Name, p.Price, p.Stock)))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// CreateProduct thêm sản phẩm mới
+// Trả về lỗi gộp (errors.Join) các ValidationError nếu dữ liệu không hợp lệ, BusinessError 409 nếu ID đã tồn tại
+func (s *ProductService) CreateProduct(input Product) (*Product, error) {
+	if err := validateProduct(input, true); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.products[input.ID]; exists {
+		appErr := goerrorkit.NewBusinessError(409, fmt.Sprintf("Sản phẩm ID=%s đã tồn tại", input.ID)).WithData(map[string]interface{}{
+			"product_id": input.ID,
+		})
+		appErr.Cause = &ProductError{ProductID: input.ID, Err: ErrProductExists} // errors.Is(err, ErrProductExists)
+		return nil, appErr
+	}
+
+	product := input
+	s.putProduct(&product, map[string]int{DefaultWarehouse: input.Stock}, "create")
+	created := product
+	return &created, nil
+}
+
+// UpdateProduct thay name, price, stock của sản phẩm (ID giữ nguyên)
+// Stock là tổng tồn kho: phần tăng nhập vào DefaultWarehouse, phần giảm xuất từ các kho theo thứ tự
+// ifMatch là header If-Match của request: rỗng thì cập nhật không điều kiện, "*" khớp mọi ETag,
+// khác ETag hiện tại thì trả về BusinessError 412 (sản phẩm đã bị thay đổi sau khi client đọc)
+func (s *ProductService) UpdateProduct(productID string, input Product, ifMatch string) (*Product, error) {
+	input.ID = productID
+	if err := validateProduct(input, false); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.GetProduct(productID); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.lockedProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPrecondition(product, ifMatch); err != nil {
+		return nil, err
+	}
+
+	product.Name, product.Price = input.Name, input.Price
+	s.setStock(product, input.Stock)
+	updated := *product
+	return &updated, nil
+}
+
+// DeleteProduct xóa sản phẩm, ifMatch có ý nghĩa giống UpdateProduct
+func (s *ProductService) DeleteProduct(productID, ifMatch string) error {
+	if _, err := s.GetProduct(productID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.lockedProduct(productID)
+	if err != nil {
+		return err
+	}
+	if err := checkPrecondition(product, ifMatch); err != nil {
+		return err
+	}
+
+	delete(s.products, productID)
+	delete(s.levels, productID)
+	return nil
+}
+
+// checkPrecondition so sánh If-Match với ETag hiện tại, s.mu phải đang được giữ
+func checkPrecondition(product *Product, ifMatch string) error {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" {
+		return nil
+	}
+
+	etag := product.ETag()
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return nil
+		}
+	}
+
+	appErr := goerrorkit.NewBusinessError(412, "Sản phẩm đã bị thay đổi, hãy tải lại trước khi cập nhật").WithData(map[string]interface{}{
+		"product_id":   product.ID,
+		"if_match":     ifMatch,
+		"current_etag": etag,
+	})
+	appErr.Cause = &ProductError{ProductID: product.ID, Err: ErrProductModified} // errors.Is(err, ErrProductModified)
+	return appErr
+}
+
+// validateProduct kiểm tra dữ liệu sản phẩm, trả về errors.Join của ValidationError từng trường
+func validateProduct(p Product, requireID bool) error {
+	var errs []error
+	invalid := func(field, message string, received interface{}) {
+		errs = append(errs, goerrorkit.NewValidationError(message, map[string]interface{}{
+			"field":    field,
+			"received": received,
+		}))
+	}
+
+	if requireID && strings.TrimSpace(p.ID) == "" {
+		invalid("id", "ID không được để trống", p.ID)
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		invalid("name", "Tên sản phẩm không được để trống", p.Name)
+	}
+	if p.Price.IsNegative() {
+		invalid("price", "Giá phải lớn hơn hoặc bằng 0", p.Price)
+	}
+	if p.Stock < 0 {
+		invalid("stock", "Stock phải lớn hơn hoặc bằng 0", p.Stock)
+	}
+	return errors.Join(errs...)
+}
+
+// ============================================================================
+// Product Search - Tìm kiếm, lọc, sắp xếp và phân trang bằng cursor
+// ============================================================================
+
+// Giới hạn số sản phẩm mỗi trang
+const (
+	DefaultProductLimit = 20
+	MaxProductLimit     = 100
+)
+
+// productSorts là các giá trị hợp lệ của sort ("-" là giảm dần)
+var productSorts = []string{"id", "-id", "name", "-name", "price", "-price", "stock", "-stock"}
+
+// ProductQuery là điều kiện tìm kiếm sản phẩm
+type ProductQuery struct {
+	Search   string // Chuỗi con của tên sản phẩm, không phân biệt hoa thường
+	MinPrice *Money // Giá tối thiểu (bao gồm), chỉ khớp sản phẩm cùng tiền tệ
+	MaxPrice *Money // Giá tối đa (bao gồm), chỉ khớp sản phẩm cùng tiền tệ
+	Sort     string // Một trong productSorts, mặc định "id"
+	Limit    int    // 1..MaxProductLimit, mặc định DefaultProductLimit
+	Cursor   string // NextCursor của trang trước
+}
+
+// ProductPage là một trang kết quả tìm kiếm
+type ProductPage struct {
+	Products   []*Product
+	NextCursor string // Rỗng nếu là trang cuối
+}
+
+// productCursor là vị trí sau phần tử cuối của trang trước (keyset pagination)
+// Lưu giá trị sort của phần tử đó nên trang sau vẫn đúng khi phần tử bị xóa hoặc có sản phẩm mới
+type productCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// ParseProductQuery đọc điều kiện tìm kiếm từ query params (q, min_price, max_price, currency, sort, limit, cursor)
+// min_price, max_price tính theo currency (mặc định DefaultCurrency)
+// Trả về errors.Join của ValidationError từng tham số sai để client sửa tất cả trong một lần
+//
+// Example:
+//
+//	query, err := services.ParseProductQuery(map[string]string{"min_price": "abc", "sort": "rating"})
+//	// err: 2 ValidationErrors (min_price, sort) → errhandler trả về 400 với "errors": [...]
+func ParseProductQuery(params map[string]string) (ProductQuery, error) {
+	query := ProductQuery{
+		Search: strings.TrimSpace(params["q"]),
+		Sort:   params["sort"],
+		Limit:  DefaultProductLimit,
+		Cursor: params["cursor"],
+	}
+	var errs []error
+	invalid := func(field, message string, data map[string]interface{}) {
+		data["field"], data["received"] = field, params[field]
+		errs = append(errs, goerrorkit.NewValidationError(message, data))
+	}
+
+	currency := DefaultCurrency
+	if raw := params["currency"]; raw != "" {
+		parsed, err := ParseCurrency(raw)
+		if err != nil {
+			invalid("currency", fmt.Sprintf("currency '%s' không hỗ trợ", raw), map[string]interface{}{"allowed": supportedCurrencies()})
+		}
+		currency = parsed
+	}
+
+	for _, field := range []string{"min_price", "max_price"} {
+		raw := params[field]
+		if raw == "" || currency == "" {
+			continue
+		}
+		value, err := ParseMoney(raw, currency)
+		if err != nil || value.IsNegative() {
+			invalid(field, fmt.Sprintf("%s phải là số >= 0", field), map[string]interface{}{"min": 0})
+			continue
+		}
+		if field == "min_price" {
+			query.MinPrice = &value
+		} else {
+			query.MaxPrice = &value
+		}
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && query.MinPrice.Minor() > query.MaxPrice.Minor() {
+		invalid("max_price", "max_price phải lớn hơn hoặc bằng min_price", map[string]interface{}{"min_price": *query.MinPrice})
+	}
+
+	if query.Sort == "" {
+		query.Sort = "id"
+	} else if !slices.Contains(productSorts, query.Sort) {
+		invalid("sort", fmt.Sprintf("sort '%s' không hỗ trợ", query.Sort), map[string]interface{}{"allowed": productSorts})
+	}
+
+	if raw := params["limit"]; raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxProductLimit {
+			invalid("limit", fmt.Sprintf("limit phải là số nguyên từ 1 đến %d", MaxProductLimit), map[string]interface{}{
+				"min": 1,
+				"max": MaxProductLimit,
+			})
+		} else {
+			query.Limit = limit
+		}
+	}
+
+	if query.Cursor != "" {
+		if cursor, err := decodeCursor(query.Cursor); err != nil {
+			invalid("cursor", "cursor không hợp lệ", map[string]interface{}{})
+		} else if cursor.Sort != query.Sort {
+			invalid("cursor", "cursor thuộc về sort khác, hãy bắt đầu lại từ trang đầu", map[string]interface{}{
+				"cursor_sort": cursor.Sort,
+				"sort":        query.Sort,
+			})
+		}
+	}
+
+	return query, errors.Join(errs...)
+}
+
+// ListProducts tìm kiếm sản phẩm theo query, trả về một trang kết quả
+// Các sản phẩm trong kết quả là bản sao (không bị thay đổi bởi request khác trong lúc encode)
+func (s *ProductService) ListProducts(query ProductQuery) (*ProductPage, error) {
+	if query.Sort == "" {
+		query.Sort = "id"
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultProductLimit
+	}
+
+	var after *productCursor
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil || cursor.Sort != query.Sort {
+			return nil, goerrorkit.NewValidationError("cursor không hợp lệ", map[string]interface{}{
+				"field":    "cursor",
+				"received": query.Cursor,
+			})
+		}
+		after = cursor
+	}
+
+	search := strings.ToLower(query.Search)
+	var matched []*Product
+	s.mu.RLock()
+	for _, product := range s.products {
+		if search != "" && !strings.Contains(strings.ToLower(product.Name), search) {
+			continue
+		}
+		if query.MinPrice != nil {
+			if c, err := product.Price.Cmp(*query.MinPrice); err != nil || c < 0 {
+				continue
+			}
+		}
+		if query.MaxPrice != nil {
+			if c, err := product.Price.Cmp(*query.MaxPrice); err != nil || c > 0 {
+				continue
+			}
+		}
+		copied := *product
+		matched = append(matched, &copied)
+	}
+	s.mu.RUnlock()
+
+	field, desc := strings.TrimPrefix(query.Sort, "-"), strings.HasPrefix(query.Sort, "-")
+	less := func(a, b *Product) bool {
+		if c := compareSortValue(sortValue(a, field), sortValue(b, field)); c != 0 {
+			return (c < 0) != desc
+		}
+		return a.ID < b.ID // ID phân biệt các sản phẩm cùng giá trị sort, luôn tăng dần
+	}
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			c := compareSortValue(sortValue(matched[i], field), after.Value)
+			if c == 0 {
+				return matched[i].ID > after.ID
+			}
+			return (c > 0) != desc
+		})
+	}
+
+	page := &ProductPage{Products: matched[start:]}
+	if len(page.Products) > query.Limit {
+		page.Products = page.Products[:query.Limit]
+		last := page.Products[len(page.Products)-1]
+		page.NextCursor = encodeCursor(productCursor{Sort: query.Sort, Value: sortValue(last, field), ID: last.ID})
+	}
+	return page, nil
+}
+
+// sortValue trả về giá trị của trường sort (string hoặc float64)
+// Giá được so theo minor units nên chỉ có ý nghĩa giữa các sản phẩm cùng tiền tệ
+func sortValue(p *Product, field string) interface{} {
+	switch field {
+	case "name":
+		return strings.ToLower(p.Name)
+	case "price":
+		return float64(p.Price.Minor())
+	case "stock":
+		return float64(p.Stock)
+	}
+	return p.ID
+}
+
+// compareSortValue so sánh hai giá trị sort cùng kiểu (cursor đọc từ JSON nên số luôn là float64)
+func compareSortValue(a, b interface{}) int {
+	switch x := a.(type) {
+	case float64:
+		y, _ := b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case string:
+		y, _ := b.(string)
+		return strings.Compare(x, y)
+	}
+	return 0
+}
+
+// encodeCursor mã hóa cursor thành chuỗi opaque cho client
+func encodeCursor(cursor productCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor giải mã cursor, kiểm tra kiểu giá trị khớp với trường sort
+func decodeCursor(raw string) (*productCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor productCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	switch strings.TrimPrefix(cursor.Sort, "-") {
+	case "price", "stock":
+		_, ok = cursor.Value.(float64)
+	default:
+		_, ok = cursor.Value.(string)
+	}
+	if !ok || !slices.Contains(productSorts, cursor.Sort) {
+		return nil, fmt.Errorf("cursor value %v does not match sort %q", cursor.Value, cursor.Sort)
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"fiber_log/testkit"
+)
+
+func TestCreateProduct(t *testing.T) {
+	s := NewProductService()
+
+	product, err := s.CreateProduct(Product{ID: "P1", Name: "Keyboard", Price: MustParseMoney("49.9", USD), Stock: 10})
+	testkit.AssertNoError(t, err)
+	if stored, _ := s.GetProduct("P1"); *stored != *product {
+		t.Errorf("stored = %+v, want %+v", stored, product)
+	}
+
+	_, err = s.CreateProduct(Product{ID: "456", Name: "MacBook Air", Price: MustParseMoney("999", USD), Stock: 1})
+	testkit.AssertStatus(t, err, 409)
+	testkit.AssertLocation(t, err, "CreateProduct")
+	if !errors.Is(err, ErrProductExists) {
+		t.Error("errors.Is(err, ErrProductExists) = false")
+	}
+
+	// Mỗi trường sai là một ValidationError trong lỗi gộp
+	_, err = s.CreateProduct(Product{ID: "", Name: " ", Price: MustParseMoney("-1", USD), Stock: -2})
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("CreateProduct trả về %T, want lỗi gộp", err)
+	}
+	for i, field := range []string{"id", "name", "price", "stock"} {
+		child := joined.Unwrap()[i]
+		testkit.AssertErrorType(t, child, testkit.Validation)
+		testkit.AssertData(t, child, "field", field)
+	}
+}
+
+func TestUpdateProductPrecondition(t *testing.T) {
+	s := NewProductService()
+	current, _ := s.GetProduct("456")
+	etag := current.ETag()
+
+	updated, err := s.UpdateProduct("456", Product{Name: "MacBook Pro M4", Price: MustParseMoney("2599", USD), Stock: 4}, etag)
+	testkit.AssertNoError(t, err)
+	if updated.ID != "456" || updated.Name != "MacBook Pro M4" || updated.ETag() == etag {
+		t.Fatalf("updated = %+v (etag %s)", updated, updated.ETag())
+	}
+
+	// ETag cũ: sản phẩm đã bị thay đổi sau khi client đọc
+	_, err = s.UpdateProduct("456", Product{Name: "Stale write", Price: MustParseMoney("1", USD), Stock: 1}, etag)
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 412)
+	testkit.AssertLocation(t, err, "checkPrecondition")
+	testkit.AssertData(t, err, "current_etag", updated.ETag())
+	if !errors.Is(err, ErrProductModified) {
+		t.Error("errors.Is(err, ErrProductModified) = false")
+	}
+	if product, _ := s.GetProduct("456"); product.Name != "MacBook Pro M4" {
+		t.Errorf("update bị ghi đè: %+v", product)
+	}
+
+	// Danh sách ETag và "*" đều hợp lệ; không có If-Match thì cập nhật không điều kiện
+	_, err = s.UpdateProduct("456", Product{Name: "A", Price: MustParseMoney("1", USD), Stock: 1}, `"other", `+updated.ETag())
+	testkit.AssertNoError(t, err)
+	_, err = s.UpdateProduct("456", Product{Name: "B", Price: MustParseMoney("1", USD), Stock: 1}, "*")
+	testkit.AssertNoError(t, err)
+	_, err = s.UpdateProduct("456", Product{Name: "C", Price: MustParseMoney("1", USD), Stock: 1}, "")
+	testkit.AssertNoError(t, err)
+
+	_, err = s.UpdateProduct("999", Product{Name: "X", Price: MustParseMoney("1", USD), Stock: 1}, "")
+	testkit.AssertStatus(t, err, 404)
+	_, err = s.UpdateProduct("456", Product{Name: "", Price: MustParseMoney("1", USD), Stock: 1}, "")
+	testkit.AssertErrorType(t, err, testkit.Validation)
+}
+
+func TestDeleteProduct(t *testing.T) {
+	s := NewProductService()
+
+	err := s.DeleteProduct("789", `"stale"`)
+	testkit.AssertStatus(t, err, 412)
+
+	testkit.AssertNoError(t, s.DeleteProduct("789", ""))
+	_, err = s.GetProduct("789")
+	testkit.AssertStatus(t, err, 404)
+
+	err = s.DeleteProduct("789", "")
+	testkit.AssertStatus(t, err, 404)
+}
+
+func TestParseProductQuery(t *testing.T) {
+	query, err := ParseProductQuery(map[string]string{"q": " pro ", "min_price": "100", "max_price": "2500", "sort": "-price", "limit": "2"})
+	testkit.AssertNoError(t, err)
+	if query.Search != "pro" || query.MinPrice.String() != "100.00 USD" || query.MaxPrice.String() != "2500.00 USD" || query.Sort != "-price" || query.Limit != 2 {
+		t.Errorf("query = %+v", query)
+	}
+
+	query, err = ParseProductQuery(map[string]string{})
+	testkit.AssertNoError(t, err)
+	if query.Sort != "id" || query.Limit != DefaultProductLimit || query.MinPrice != nil {
+		t.Errorf("default query = %+v", query)
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		fields []string
+	}{
+		{"bad numbers", map[string]string{"min_price": "abc", "max_price": "-1", "limit": "0"}, []string{"min_price", "max_price", "limit"}},
+		{"NaN price", map[string]string{"min_price": "NaN"}, []string{"min_price"}},
+		{"min greater than max", map[string]string{"min_price": "10", "max_price": "5"}, []string{"max_price"}},
+		{"unknown sort and limit too large", map[string]string{"sort": "rating", "limit": "1000"}, []string{"sort", "limit"}},
+		{"garbage cursor", map[string]string{"cursor": "not-a-cursor"}, []string{"cursor"}},
+		{"cursor from other sort", map[string]string{"sort": "price", "cursor": encodeCursor(productCursor{Sort: "id", Value: "123", ID: "123"})}, []string{"cursor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseProductQuery(tt.params)
+			joined, ok := err.(interface{ Unwrap() []error })
+			if !ok {
+				t.Fatalf("err = %T %v, want lỗi gộp", err, err)
+			}
+			children := joined.Unwrap()
+			if len(children) != len(tt.fields) {
+				t.Fatalf("got %d lỗi, want %d: %v", len(children), len(tt.fields), err)
+			}
+			for i, field := range tt.fields {
+				testkit.AssertErrorType(t, children[i], testkit.Validation)
+				testkit.AssertData(t, children[i], "field", field)
+			}
+		})
+	}
+}
+
+func TestListProducts(t *testing.T) {
+	s := NewProductService()
+	for _, p := range []Product{
+		{ID: "A1", Name: "Apple Pencil", Price: MustParseMoney("129", USD), Stock: 20},
+		{ID: "B2", Name: "iPad Pro", Price: MustParseMoney("999", USD), Stock: 3},
+		{ID: "C3", Name: "Pro Display", Price: MustParseMoney("4999", USD), Stock: 1},
+	} {
+		if _, err := s.CreateProduct(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(page *ProductPage) []string {
+		var result []string
+		for _, p := range page.Products {
+			result = append(result, p.ID)
+		}
+		return result
+	}
+	assertIDs := func(t *testing.T, got, want []string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("ids = %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("ids = %v, want %v", got, want)
+			}
+		}
+	}
+
+	minPrice, maxPrice := MustParseMoney("200", USD), MustParseMoney("3000", USD)
+	page, err := s.ListProducts(ProductQuery{Search: "PRO", MinPrice: &minPrice, MaxPrice: &maxPrice, Sort: "-price"})
+	testkit.AssertNoError(t, err)
+	assertIDs(t, ids(page), []string{"456", "B2", "789"}) // MacBook Pro 2499.99, iPad Pro 999, AirPods Pro 249.99
+	if page.NextCursor != "" {
+		t.Errorf("next_cursor = %q, want rỗng ở trang cuối", page.NextCursor)
+	}
+
+	// Duyệt toàn bộ theo stock giảm dần, mỗi trang 2 sản phẩm
+	var all []string
+	query := ProductQuery{Sort: "-stock", Limit: 2}
+	for pages := 0; pages < 10; pages++ {
+		page, err := s.ListProducts(query)
+		testkit.AssertNoError(t, err)
+		all = append(all, ids(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assertIDs(t, all, []string{"A1", "789", "456", "B2", "C3", "123"})
+
+	// Cursor vẫn đúng khi sản phẩm cuối của trang trước bị xóa
+	first, _ := s.ListProducts(ProductQuery{Sort: "name", Limit: 2})
+	assertIDs(t, ids(first), []string{"789", "A1"})
+	testkit.AssertNoError(t, s.DeleteProduct("A1", ""))
+	next, err := s.ListProducts(ProductQuery{Sort: "name", Limit: 2, Cursor: first.NextCursor})
+	testkit.AssertNoError(t, err)
+	assertIDs(t, ids(next), []string{"B2", "123"})
+
+	_, err = s.ListProducts(ProductQuery{Sort: "price", Cursor: first.NextCursor})
+	testkit.AssertErrorType(t, err, testkit.Validation)
+}
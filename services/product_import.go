@@ -0,0 +1,294 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Product Import - Import sản phẩm từ CSV/JSON với báo cáo lỗi từng dòng
+// ============================================================================
+
+// Định dạng file import
+const (
+	ImportCSV  = "csv"
+	ImportJSON = "json"
+)
+
+// importColumns là các cột của file import (CSV header hoặc key của JSON object, không phân biệt hoa thường)
+var importColumns = []string{"id", "name", "price", "stock"}
+
+// importOptionalColumns là các cột không bắt buộc, currency mặc định là DefaultCurrency
+var importOptionalColumns = []string{"currency"}
+
+// ImportRowError là lỗi validation của một ô trong file import
+type ImportRowError struct {
+	Row    int    // CSV: số dòng trong file (header là dòng 1); JSON: thứ tự phần tử trong mảng, bắt đầu từ 1
+	Column string // id, name, price, stock, currency
+	Value  string
+	Err    *goerrorkit.AppError // ValidationError với data {row, column, value}
+}
+
+// ImportReport là kết quả import
+type ImportReport struct {
+	Format    string
+	Strict    bool
+	Total     int  // Số dòng dữ liệu
+	Imported  int  // Số sản phẩm đã được thêm
+	Failed    int  // Số dòng có lỗi
+	Committed bool // false nếu Strict và có dòng lỗi (không sản phẩm nào được thêm)
+	Errors    []ImportRowError
+}
+
+// ImportProducts import sản phẩm từ CSV hoặc JSON
+//
+// Mỗi dòng được validate đầy đủ (một dòng có thể có nhiều lỗi):
+//   - id: bắt buộc, không trùng với sản phẩm đã có và các dòng khác trong file
+//   - name: bắt buộc
+//   - price: số, >= 0, không nhiều chữ số thập phân hơn tiền tệ cho phép (USD: 2, VND: 0)
+//   - currency: không bắt buộc, mã ISO 4217 được hỗ trợ (mặc định DefaultCurrency)
+//   - stock: số nguyên, >= 0
+//
+// Các dòng hợp lệ được thêm kể cả khi có dòng khác lỗi, trừ khi strict = true
+// Error trả về chỉ dành cho lỗi của cả file (format không hỗ trợ, không đọc được, thiếu cột)
+//
+// Example:
+//
+//	report, err := productService.ImportProducts(file, services.ImportCSV, false)
+//	// report.Errors[0].Err: ValidationError "Giá phải lớn hơn hoặc bằng 0", data {row: 3, column: "price", value: "-5"}
+func (s *ProductService) ImportProducts(r io.Reader, format string, strict bool) (*ImportReport, error) {
+	var records []importRecord
+	var err error
+	switch format {
+	case ImportCSV:
+		records, err = readCSVRecords(r)
+	case ImportJSON:
+		records, err = readJSONRecords(r)
+	default:
+		return nil, goerrorkit.NewValidationError("Định dạng import không hỗ trợ", map[string]interface{}{
+			"field":    "format",
+			"allowed":  []string{ImportCSV, ImportJSON},
+			"received": format,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Format: format, Strict: strict, Total: len(records)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]int) // id → dòng đầu tiên có id đó
+	var valid []*Product
+	for _, record := range records {
+		product, rowErrs := s.validateImportRecord(record, seen)
+		if len(rowErrs) > 0 {
+			report.Failed++
+			report.Errors = append(report.Errors, rowErrs...)
+			continue
+		}
+		valid = append(valid, product)
+	}
+
+	if strict && report.Failed > 0 {
+		return report, nil
+	}
+	for _, product := range valid {
+		s.putProduct(product, map[string]int{DefaultWarehouse: product.Stock}, "import")
+	}
+	report.Imported = len(valid)
+	report.Committed = true
+	return report, nil
+}
+
+// importRecord là một dòng dữ liệu đã đọc từ file, giá trị theo tên cột
+type importRecord struct {
+	row    int
+	values map[string]string
+}
+
+// validateImportRecord validate một dòng, s.mu phải đang được giữ
+func (s *ProductService) validateImportRecord(record importRecord, seen map[string]int) (*Product, []ImportRowError) {
+	var errs []ImportRowError
+	fail := func(column, message string) {
+		errs = append(errs, newImportRowError(record.row, column, record.values[column], message))
+	}
+
+	id := record.values["id"]
+	if id == "" {
+		fail("id", "ID không được để trống")
+	} else if _, exists := s.products[id]; exists {
+		fail("id", fmt.Sprintf("ID '%s' đã tồn tại", id))
+	} else if row, duplicated := seen[id]; duplicated {
+		fail("id", fmt.Sprintf("ID '%s' bị trùng với dòng %d", id, row))
+	} else {
+		seen[id] = record.row
+	}
+
+	name := record.values["name"]
+	if name == "" {
+		fail("name", "Tên sản phẩm không được để trống")
+	}
+
+	currency := DefaultCurrency
+	if raw := record.values["currency"]; raw != "" {
+		parsed, err := ParseCurrency(raw)
+		if err != nil {
+			fail("currency", fmt.Sprintf("Tiền tệ '%s' không hỗ trợ", raw))
+		}
+		currency = parsed
+	}
+
+	price, err := ParseMoney(record.values["price"], currency)
+	switch {
+	case currency == "":
+		// Tiền tệ sai: không kiểm tra được số chữ số thập phân của giá
+	case errors.Is(err, ErrAmountPrecision):
+		exponent, _ := currency.Exponent()
+		fail("price", fmt.Sprintf("Giá có tối đa %d chữ số thập phân (%s)", exponent, currency))
+	case err != nil:
+		fail("price", "Giá phải là số")
+	case price.IsNegative():
+		fail("price", "Giá phải lớn hơn hoặc bằng 0")
+	}
+
+	stock, err := strconv.Atoi(record.values["stock"])
+	if err != nil {
+		fail("stock", "Stock phải là số nguyên")
+	} else if stock < 0 {
+		fail("stock", "Stock phải lớn hơn hoặc bằng 0")
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &Product{ID: id, Name: name, Stock: stock, Price: price}, nil
+}
+
+// newImportRowError tạo ValidationError cho một ô của file import
+func newImportRowError(row int, column, value, message string) ImportRowError {
+	return ImportRowError{
+		Row:    row,
+		Column: column,
+		Value:  value,
+		Err: goerrorkit.NewValidationError(message, map[string]interface{}{
+			"row":    row,
+			"column": column,
+			"value":  value,
+		}),
+	}
+}
+
+// readCSVRecords đọc CSV có header, các cột thừa bị bỏ qua
+func readCSVRecords(r io.Reader) ([]importRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Dòng thiếu cột được báo lỗi theo từng ô thay vì dừng cả file
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, goerrorkit.NewValidationError("File import trống", map[string]interface{}{
+			"format": ImportCSV,
+		})
+	}
+	if err != nil {
+		return nil, goerrorkit.WrapWithMessage(err, "Không thể đọc file CSV").WithData(map[string]interface{}{
+			"format": ImportCSV,
+		})
+	}
+
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, column := range importColumns {
+		if _, ok := index[column]; !ok {
+			return nil, goerrorkit.NewValidationError(fmt.Sprintf("File CSV thiếu cột '%s'", column), map[string]interface{}{
+				"required": importColumns,
+				"header":   header,
+			})
+		}
+	}
+
+	var records []importRecord
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, goerrorkit.WrapWithMessage(err, "Không thể đọc file CSV").WithData(map[string]interface{}{
+				"format": ImportCSV,
+			})
+		}
+
+		line, _ := reader.FieldPos(0)
+		record := importRecord{row: line, values: make(map[string]string)}
+		for _, column := range append(importColumns, importOptionalColumns...) {
+			if i, ok := index[column]; ok && i < len(fields) {
+				record.values[column] = strings.TrimSpace(fields[i])
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readJSONRecords đọc mảng JSON các object {"id", "name", "price", "stock"}
+func readJSONRecords(r io.Reader) ([]importRecord, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber() // Giữ nguyên "5.5" để báo lỗi stock không phải số nguyên
+
+	var items []map[string]interface{}
+	if err := decoder.Decode(&items); err != nil {
+		return nil, goerrorkit.NewValidationError("File JSON không hợp lệ", map[string]interface{}{
+			"format":   ImportJSON,
+			"error":    err.Error(),
+			"expected": `[{"id": "...", "name": "...", "price": 0, "stock": 0}]`,
+		})
+	}
+
+	records := make([]importRecord, 0, len(items))
+	for i, item := range items {
+		record := importRecord{row: i + 1, values: make(map[string]string)}
+		for key, value := range item {
+			column := strings.ToLower(key)
+			if value != nil {
+				record.values[column] = strings.TrimSpace(fmt.Sprint(value))
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ============================================================================
+// Import Report
+// ============================================================================
+
+// WriteCSV ghi danh sách lỗi của report dạng CSV (row, column, value, type, code, error) để tải về
+func (r *ImportReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"row", "column", "value", "type", "code", "error"})
+	for _, rowErr := range r.Errors {
+		writer.Write([]string{
+			strconv.Itoa(rowErr.Row),
+			rowErr.Column,
+			rowErr.Value,
+			string(rowErr.Err.Type),
+			strconv.Itoa(rowErr.Err.Code),
+			rowErr.Err.Message,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
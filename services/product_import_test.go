@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fiber_log/testkit"
+)
+
+const importCSV = `id,name,price,stock
+P1,Keyboard,49.9,10
+P2,Mouse,-5,abc
+123,Duplicate of seed,1,1
+P1,Duplicate in file,2,2
+P3,Monitor,199,0
+`
+
+func TestImportProductsCSV(t *testing.T) {
+	s := NewProductService()
+
+	report, err := s.ImportProducts(strings.NewReader(importCSV), ImportCSV, false)
+	testkit.AssertNoError(t, err)
+	if report.Total != 5 || report.Imported != 2 || report.Failed != 3 || !report.Committed {
+		t.Fatalf("report = %+v", report)
+	}
+
+	want := []struct {
+		row     int
+		column  string
+		value   string
+		message string
+	}{
+		{3, "price", "-5", "Giá phải lớn hơn hoặc bằng 0"},
+		{3, "stock", "abc", "Stock phải là số nguyên"},
+		{4, "id", "123", "ID '123' đã tồn tại"},
+		{5, "id", "P1", "ID 'P1' bị trùng với dòng 2"},
+	}
+	if len(report.Errors) != len(want) {
+		t.Fatalf("got %d errors, want %d: %+v", len(report.Errors), len(want), report.Errors)
+	}
+	for i, w := range want {
+		rowErr := report.Errors[i]
+		if rowErr.Row != w.row || rowErr.Column != w.column || rowErr.Value != w.value {
+			t.Errorf("errors[%d] = row %d %s %q, want row %d %s %q", i, rowErr.Row, rowErr.Column, rowErr.Value, w.row, w.column, w.value)
+		}
+		testkit.AssertErrorType(t, rowErr.Err, testkit.Validation)
+		testkit.AssertMessage(t, rowErr.Err, w.message)
+		testkit.AssertData(t, rowErr.Err, "row", w.row)
+		testkit.AssertData(t, rowErr.Err, "column", w.column)
+	}
+
+	product, err := s.GetProduct("P1")
+	testkit.AssertNoError(t, err)
+	if product.Name != "Keyboard" || product.Price.String() != "49.90 USD" || product.Stock != 10 {
+		t.Errorf("P1 = %+v", product)
+	}
+	_, err = s.GetProduct("P2")
+	testkit.AssertStatus(t, err, 404)
+	if product, _ := s.GetProduct("123"); product.Name != "iPhone 15" {
+		t.Errorf("sản phẩm có sẵn bị ghi đè: %+v", product)
+	}
+}
+
+func TestImportProductsStrict(t *testing.T) {
+	s := NewProductService()
+
+	report, err := s.ImportProducts(strings.NewReader(importCSV), ImportCSV, true)
+	testkit.AssertNoError(t, err)
+	if report.Committed || report.Imported != 0 || report.Failed != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	_, err = s.GetProduct("P1")
+	testkit.AssertStatus(t, err, 404)
+
+	// Không có dòng lỗi: strict vẫn import
+	report, err = s.ImportProducts(strings.NewReader("ID,Name,Price,Stock\nP9,Cable,5,100\n"), ImportCSV, true)
+	testkit.AssertNoError(t, err)
+	if !report.Committed || report.Imported != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestImportProductsJSON(t *testing.T) {
+	s := NewProductService()
+
+	input := `[
+		{"id": "J1", "name": "Tablet", "price": 300, "stock": 4},
+		{"id": "J2", "name": "", "price": "cheap", "stock": 1.5},
+		{"ID": "J3", "Name": "Charger", "Price": 19.5, "Stock": 7}
+	]`
+	report, err := s.ImportProducts(strings.NewReader(input), ImportJSON, false)
+	testkit.AssertNoError(t, err)
+	if report.Imported != 2 || report.Failed != 1 || len(report.Errors) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	for i, column := range []string{"name", "price", "stock"} {
+		if rowErr := report.Errors[i]; rowErr.Row != 2 || rowErr.Column != column {
+			t.Errorf("errors[%d] = row %d %s, want row 2 %s", i, rowErr.Row, rowErr.Column, column)
+		}
+	}
+}
+
+func TestImportProductsFileErrors(t *testing.T) {
+	s := NewProductService()
+
+	tests := []struct {
+		name    string
+		input   string
+		format  string
+		message string
+	}{
+		{"unsupported format", "id,name", "xml", "Định dạng import không hỗ trợ"},
+		{"empty csv", "", ImportCSV, "File import trống"},
+		{"missing column", "id,name,price\nP1,A,1\n", ImportCSV, "File CSV thiếu cột 'stock'"},
+		{"malformed json", `{"id": "P1"}`, ImportJSON, "File JSON không hợp lệ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ImportProducts(strings.NewReader(tt.input), tt.format, false)
+			testkit.AssertErrorType(t, err, testkit.Validation)
+			testkit.AssertMessage(t, err, tt.message)
+		})
+	}
+}
+
+func TestImportReportCSV(t *testing.T) {
+	report, err := NewProductService().ImportProducts(strings.NewReader(importCSV), ImportCSV, false)
+	testkit.AssertNoError(t, err)
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != "row,column,value,type,code,error" || len(lines) != 5 {
+		t.Fatalf("report:\n%s", buf.String())
+	}
+	if lines[1] != "3,price,-5,VALIDATION,400,Giá phải lớn hơn hoặc bằng 0" {
+		t.Errorf("line 1 = %q", lines[1])
+	}
+}
+
+func TestProductsFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "products.json")
+
+	_, err := LoadProducts(path)
+	testkit.AssertErrorType(t, err, testkit.System)
+
+	s := NewProductService()
+	testkit.AssertNoError(t, SaveProducts(path, s.Products()))
+
+	products, err := LoadProducts(path)
+	testkit.AssertNoError(t, err)
+	loaded := NewProductServiceWith(products)
+	if got := len(loaded.Products()); got != 3 {
+		t.Fatalf("loaded %d products, want 3", got)
+	}
+	if product, _ := loaded.GetProduct("456"); product.Name != "MacBook Pro" || product.Stock != 5 {
+		t.Errorf("456 = %+v", product)
+	}
+}
@@ -2,6 +2,10 @@ package services
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/techmaster-vietnam/goerrorkit"
 )
@@ -10,36 +14,97 @@ import (
 type Product struct {
 	ID    string
 	Name  string
-	Stock int
-	Price float64
+	Stock int // Tổng tồn kho ở tất cả kho (xem Inventory)
+	Price Money
 }
 
 // ProductService xử lý business logic liên quan đến sản phẩm
 type ProductService struct {
 	// Giả lập database
-	products map[string]*Product
+	mu         sync.RWMutex
+	products   map[string]*Product
+	warehouses []Warehouse
+	levels     map[string]map[string]int // product ID -> warehouse ID -> tồn kho
+	movements  []StockMovement
+	now        func() time.Time
+}
+
+// newProductService tạo ProductService rỗng với các kho mặc định
+func newProductService() *ProductService {
+	return &ProductService{
+		products:   make(map[string]*Product),
+		warehouses: defaultWarehouses(),
+		levels:     make(map[string]map[string]int),
+		now:        time.Now,
+	}
 }
 
 // NewProductService tạo ProductService mới
 func NewProductService() *ProductService {
-	return &ProductService{
-		products: map[string]*Product{
-			"123": {ID: "123", Name: "iPhone 15", Stock: 0, Price: 999.99},
-			"456": {ID: "456", Name: "MacBook Pro", Stock: 5, Price: 2499.99},
-			"789": {ID: "789", Name: "AirPods Pro", Stock: 10, Price: 249.99},
-		},
+	s := newProductService()
+	s.putProduct(&Product{ID: "123", Name: "iPhone 15", Price: MustParseMoney("999.99", USD)}, nil, "seed")
+	s.putProduct(&Product{ID: "456", Name: "MacBook Pro", Price: MustParseMoney("2499.99", USD)}, map[string]int{"WH-01": 2, "WH-03": 3}, "seed")
+	s.putProduct(&Product{ID: "789", Name: "AirPods Pro", Price: MustParseMoney("249.99", USD)}, map[string]int{"WH-01": 4, "WH-02": 6}, "seed")
+	return s
+}
+
+// NewProductServiceWith tạo ProductService với danh sách sản phẩm cho trước (ví dụ đọc từ PRODUCTS_FILE)
+// Stock của mỗi sản phẩm được nhập vào DefaultWarehouse
+func NewProductServiceWith(products []*Product) *ProductService {
+	s := newProductService()
+	for _, product := range products {
+		s.putProduct(product, map[string]int{DefaultWarehouse: product.Stock}, "load")
 	}
+	return s
+}
+
+// Products trả về bản sao của tất cả sản phẩm, sắp xếp theo ID
+func (s *ProductService) Products() []*Product {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	products := make([]*Product, 0, len(s.products))
+	for _, product := range s.products {
+		copied := *product
+		products = append(products, &copied)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	return products
 }
 
 // GetProduct lấy thông tin sản phẩm theo ID
-// Trả về error nếu sản phẩm không tồn tại
+// Trả về bản sao (không bị request khác thay đổi trong lúc dùng), error nếu sản phẩm không tồn tại
 func (s *ProductService) GetProduct(productID string) (*Product, error) {
+	s.mu.RLock()
 	product, exists := s.products[productID]
+	var copied Product
+	if exists {
+		copied = *product
+	}
+	s.mu.RUnlock()
 	if !exists {
 		// Error được throw từ đây - trong package services
-		return nil, goerrorkit.NewBusinessError(404, fmt.Sprintf("Sản phẩm ID=%s không tồn tại", productID)).WithData(map[string]interface{}{
+		appErr := goerrorkit.NewBusinessError(404, fmt.Sprintf("Sản phẩm ID=%s không tồn tại", productID)).WithData(map[string]interface{}{
 			"product_id": productID,
 		})
+		appErr.Cause = &ProductError{ProductID: productID, Err: ErrProductNotFound} // errors.Is(err, ErrProductNotFound)
+		return nil, appErr
+	}
+	return &copied, nil
+}
+
+// lockedProduct trả về sản phẩm đang lưu để thay đổi, s.mu phải đang được giữ (write lock)
+// Các thao tác ghi kiểm tra bằng GetProduct rồi mới lấy lock: sản phẩm có thể đã bị xóa
+// (hoặc xóa rồi tạo lại) trong khoảng đó nên phải tìm lại, không dùng kết quả của GetProduct
+func (s *ProductService) lockedProduct(productID string) (*Product, error) {
+	product, exists := s.products[productID]
+	if !exists {
+		// Sản phẩm bị xóa bởi request khác sau khi GetProduct trả về
+		appErr := goerrorkit.NewBusinessError(404, fmt.Sprintf("Sản phẩm ID=%s không tồn tại", productID)).WithData(map[string]interface{}{
+			"product_id": productID,
+		})
+		appErr.Cause = &ProductError{ProductID: productID, Err: ErrProductNotFound} // errors.Is(err, ErrProductNotFound)
+		return nil, appErr
 	}
 	return product, nil
 }
@@ -54,60 +119,119 @@ func (s *ProductService) CheckStock(productID string) error {
 
 	if product.Stock == 0 {
 		// Error được throw từ đây - trong package services, function CheckStock
-		return goerrorkit.NewBusinessError(400, fmt.Sprintf("Sản phẩm '%s' đã hết hàng", product.Name)).WithData(map[string]interface{}{
+		appErr := goerrorkit.NewBusinessError(400, fmt.Sprintf("Sản phẩm '%s' đã hết hàng", product.Name)).WithData(map[string]interface{}{
 			"product_id":   productID,
 			"product_name": product.Name,
 		})
+		appErr.Cause = &ProductError{ProductID: productID, Err: ErrOutOfStock} // errors.Is(err, ErrOutOfStock)
+		return appErr
 	}
 
 	return nil
 }
 
-// ReserveProduct đặt trước sản phẩm (giảm stock)
-func (s *ProductService) ReserveProduct(productID string, quantity int) error {
-	product, err := s.GetProduct(productID)
+// ReserveProduct đặt trước sản phẩm (giảm stock), lấy hàng từ các kho theo policy
+// Trả về số lượng lấy từ từng kho để có thể hoàn trả đúng kho bằng ReleaseProduct
+func (s *ProductService) ReserveProduct(productID string, quantity int, policy AllocationPolicy) ([]Allocation, error) {
+	if _, err := s.GetProduct(productID); err != nil {
+		return nil, err
+	}
+
+	// Kiểm tra và giảm stock trong cùng một lock để hai request không cùng reserve phần cuối
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.lockedProduct(productID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if product.Stock < quantity {
 		// Error với thông tin chi tiết
-		return goerrorkit.NewValidationError(
+		return nil, goerrorkit.NewValidationError(
 			fmt.Sprintf("Không đủ hàng: yêu cầu %d, còn lại %d", quantity, product.Stock),
 			map[string]interface{}{
 				"product_id":      productID,
 				"product_name":    product.Name,
 				"requested":       quantity,
 				"available_stock": product.Stock,
+				"warehouses":      s.stockLevels(productID),
 			},
 		)
 	}
 
-	// Giảm stock
-	product.Stock -= quantity
-	return nil
+	// Giảm stock của từng kho
+	return s.allocate(product, quantity, policy)
 }
 
 // CalculateDiscount tính giá sau khi giảm giá
-func (s *ProductService) CalculateDiscount(productID string, discountPercent float64) (float64, error) {
+// Tiền giảm được làm tròn half-up đến minor unit rồi trừ vào giá (2499.99 giảm 10% -> 2249.99)
+func (s *ProductService) CalculateDiscount(productID string, discountPercent float64) (Money, error) {
 	product, err := s.GetProduct(productID)
 	if err != nil {
-		return 0, err
+		return Money{}, err
 	}
 
-	if discountPercent < 0 || discountPercent > 100 {
+	// NaN không thỏa mãn phép so sánh nào nên phải kiểm tra riêng
+	finite := !math.IsNaN(discountPercent) && !math.IsInf(discountPercent, 0)
+	if !finite || discountPercent < 0 || discountPercent > 100 {
+		var received interface{} = discountPercent
+		if !finite {
+			received = fmt.Sprint(discountPercent) // JSON không biểu diễn được NaN/Inf
+		}
 		// Validation error từ service layer
-		return 0, goerrorkit.NewValidationError(
+		return Money{}, goerrorkit.NewValidationError(
 			"Phần trăm giảm giá không hợp lệ",
 			map[string]interface{}{
 				"field":    "discount_percent",
 				"min":      0,
 				"max":      100,
-				"received": discountPercent,
+				"received": received,
 			},
 		)
 	}
 
-	finalPrice := product.Price * (1 - discountPercent/100)
-	return finalPrice, nil
+	discount, err := product.Price.Percent(discountPercent)
+	if err != nil {
+		return Money{}, err
+	}
+	return product.Price.Sub(discount)
+}
+
+// ReleaseProduct hoàn trả stock đã reserve về đúng kho đã lấy (tăng stock), dùng khi rollback đơn hàng
+func (s *ProductService) ReleaseProduct(productID string, allocations []Allocation) error {
+	if _, err := s.GetProduct(productID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.lockedProduct(productID)
+	if err != nil {
+		return err
+	}
+	for _, allocation := range allocations {
+		s.move(product, allocation.WarehouseID, allocation.Quantity, MovementRelease, "")
+	}
+	return nil
+}
+
+// RestockProduct nhập lại hàng đã bán về đúng kho đã xuất (hoàn tiền kèm trả hàng), reason ghi vào lịch sử xuất nhập kho
+func (s *ProductService) RestockProduct(productID string, allocations []Allocation, reason string) error {
+	if _, err := s.GetProduct(productID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, err := s.lockedProduct(productID)
+	if err != nil {
+		return err
+	}
+	for _, allocation := range allocations {
+		s.move(product, allocation.WarehouseID, allocation.Quantity, MovementRefund, reason)
+	}
+	return nil
 }
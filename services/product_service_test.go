@@ -0,0 +1,177 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"fiber_log/testkit"
+)
+
+func TestGetProduct(t *testing.T) {
+	s := NewProductService()
+
+	product, err := s.GetProduct("456")
+	testkit.AssertNoError(t, err)
+	if product.Name != "MacBook Pro" || product.Stock != 5 {
+		t.Errorf("product = %+v", product)
+	}
+
+	_, err = s.GetProduct("999")
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 404)
+	testkit.AssertMessage(t, err, "Sản phẩm ID=999 không tồn tại")
+	testkit.AssertData(t, err, "product_id", "999")
+	testkit.AssertLocation(t, err, "services/product_service.go:GetProduct")
+
+	// Kết quả là bản sao: sửa trên bản sao không đổi dữ liệu của service
+	product.Stock, product.Name = 0, "changed"
+	if again, _ := s.GetProduct("456"); again.Stock != 5 || again.Name != "MacBook Pro" {
+		t.Errorf("GetProduct trả về sản phẩm đang lưu: %+v", again)
+	}
+	for _, p := range s.Products() {
+		p.Stock = 0
+	}
+	if again, _ := s.GetProduct("456"); again.Stock != 5 {
+		t.Errorf("Products trả về sản phẩm đang lưu: stock = %d", again.Stock)
+	}
+}
+
+// Sản phẩm bị xóa giữa lúc GetProduct trả về và lúc lấy write lock: thao tác ghi trả về 404
+// và không tạo lại tồn kho cho sản phẩm đã xóa (chạy với -race)
+func TestWritesRacingDelete(t *testing.T) {
+	writes := map[string]func(s *ProductService) error{
+		"reserve": func(s *ProductService) error {
+			_, err := s.ReserveProduct("789", 1, AllocationPolicy{})
+			return err
+		},
+		"release": func(s *ProductService) error {
+			return s.ReleaseProduct("789", []Allocation{{WarehouseID: "WH-01", Quantity: 1}})
+		},
+		"restock": func(s *ProductService) error {
+			return s.RestockProduct("789", []Allocation{{WarehouseID: "WH-01", Quantity: 1}}, "refund")
+		},
+		"adjust": func(s *ProductService) error {
+			_, err := s.AdjustStock("789", "WH-01", 1, "kiểm kê")
+			return err
+		},
+		"update": func(s *ProductService) error {
+			_, err := s.UpdateProduct("789", Product{Name: "AirPods", Price: MustParseMoney("1", USD), Stock: 20}, "")
+			return err
+		},
+	}
+	for name, write := range writes {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				s := NewProductService()
+				done := make(chan error)
+				go func() { done <- write(s) }()
+				testkit.AssertNoError(t, s.DeleteProduct("789", ""))
+				if err := <-done; err != nil {
+					testkit.AssertStatus(t, err, 404)
+				}
+
+				s.mu.RLock()
+				levels := s.levels["789"]
+				s.mu.RUnlock()
+				if levels != nil {
+					t.Fatalf("tồn kho của sản phẩm đã xóa được tạo lại: %v", levels)
+				}
+			}
+		})
+	}
+
+	s := NewProductService()
+	s.mu.Lock()
+	delete(s.products, "789")
+	_, err := s.lockedProduct("789")
+	s.mu.Unlock()
+	testkit.AssertStatus(t, err, 404)
+	testkit.AssertData(t, err, "product_id", "789")
+}
+
+func TestCheckStock(t *testing.T) {
+	s := NewProductService()
+
+	testkit.AssertNoError(t, s.CheckStock("789"))
+
+	err := s.CheckStock("123")
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 400)
+	testkit.AssertMessage(t, err, "Sản phẩm 'iPhone 15' đã hết hàng")
+	testkit.AssertData(t, err, "product_id", "123")
+	testkit.AssertData(t, err, "product_name", "iPhone 15")
+	testkit.AssertLocation(t, err, "CheckStock")
+
+	// Lỗi không tồn tại được propagate nguyên vẹn từ GetProduct
+	err = s.CheckStock("999")
+	testkit.AssertStatus(t, err, 404)
+	testkit.AssertLocation(t, err, "GetProduct")
+}
+
+func TestReserveProduct(t *testing.T) {
+	s := NewProductService()
+
+	allocations, err := s.ReserveProduct("456", 2, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+	if product, _ := s.GetProduct("456"); product.Stock != 3 {
+		t.Errorf("stock sau khi reserve = %d, want 3", product.Stock)
+	}
+	if len(allocations) != 1 || allocations[0] != (Allocation{WarehouseID: "WH-01", Quantity: 2}) {
+		t.Errorf("allocations = %+v", allocations)
+	}
+
+	// Reserve đúng bằng số còn lại vẫn hợp lệ
+	_, err = s.ReserveProduct("456", 3, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+
+	_, err = s.ReserveProduct("456", 1, AllocationPolicy{})
+	testkit.AssertErrorType(t, err, testkit.Validation)
+	testkit.AssertStatus(t, err, 400)
+	testkit.AssertMessage(t, err, "Không đủ hàng: yêu cầu 1, còn lại 0")
+	testkit.AssertData(t, err, "requested", 1)
+	testkit.AssertData(t, err, "available_stock", 0)
+	testkit.AssertLocation(t, err, "ReserveProduct")
+
+	_, err = s.ReserveProduct("999", 1, AllocationPolicy{})
+	testkit.AssertStatus(t, err, 404)
+	testkit.AssertLocation(t, err, "GetProduct")
+}
+
+func TestCalculateDiscount(t *testing.T) {
+	s := NewProductService()
+
+	tests := []struct {
+		productID string
+		percent   float64
+		want      string
+	}{
+		{"789", 0, "249.99 USD"},
+		{"789", 50, "124.99 USD"}, // Giảm 124.995 -> 125.00 (half-up)
+		{"789", 100, "0.00 USD"},
+		{"456", 10, "2249.99 USD"}, // float64: 2249.991
+		{"456", 12.5, "2187.49 USD"},
+	}
+	for _, tt := range tests {
+		price, err := s.CalculateDiscount(tt.productID, tt.percent)
+		testkit.AssertNoError(t, err)
+		if price.String() != tt.want {
+			t.Errorf("CalculateDiscount(%s, %v) = %v, want %s", tt.productID, tt.percent, price, tt.want)
+		}
+	}
+
+	for _, percent := range []float64{-1, 101, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		_, err := s.CalculateDiscount("789", percent)
+		testkit.AssertErrorType(t, err, testkit.Validation)
+		testkit.AssertMessage(t, err, "Phần trăm giảm giá không hợp lệ")
+		testkit.AssertLocation(t, err, "CalculateDiscount")
+		if math.IsNaN(percent) || math.IsInf(percent, 0) {
+			testkit.AssertData(t, err, "received", fmt.Sprint(percent))
+		} else {
+			testkit.AssertData(t, err, "received", percent)
+		}
+	}
+
+	_, err := s.CalculateDiscount("999", 10)
+	testkit.AssertStatus(t, err, 404)
+}
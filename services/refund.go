@@ -0,0 +1,259 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Refunds - Hoàn tiền toàn bộ hoặc một phần cho đơn hàng đã thanh toán
+// ============================================================================
+
+// Tiền được hoàn qua payment gateway giống ProcessPayment; hàng khách trả lại được nhập về đúng kho đã xuất
+//
+// Example:
+//
+//	partial := services.MustParseMoney("20", services.USD)
+//	refund, err := orderService.RefundOrder("ORD-USER001-456", services.RefundRequest{Amount: &partial})
+//	// refund.ID = "RFD-0001", order.Status = "partially_refunded", không nhập lại kho
+//	refund, err = orderService.RefundOrder("ORD-USER001-456", services.RefundRequest{})
+//	// Hoàn phần còn lại, order.Status = "refunded", nhập lại toàn bộ hàng
+//	// Hoàn thêm: BusinessError 422 (ErrRefundExceeded)
+
+// DefaultRefundWindow là thời hạn hoàn tiền mặc định tính từ lúc thanh toán
+const DefaultRefundWindow = 30 * 24 * time.Hour
+
+// Refund là một lần hoàn tiền của đơn hàng
+type Refund struct {
+	ID        string    `json:"id"`
+	Amount    Money     `json:"amount"`
+	Restocked int       `json:"restocked"` // Số sản phẩm được nhập lại kho
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RefundRequest là yêu cầu hoàn tiền
+type RefundRequest struct {
+	Amount  *Money // nil: hoàn toàn bộ số tiền còn lại
+	Restock *int   // Số sản phẩm khách trả lại; nil: hoàn hết tiền thì nhập lại toàn bộ, hoàn một phần thì không nhập
+	Reason  string
+}
+
+// RefundOrder hoàn tiền cho đơn hàng đã thanh toán qua ProcessPayment
+// Lỗi trả về:
+//   - BusinessError 404 (ErrOrderNotFound), 409 chưa thanh toán (ErrOrderNotPaid)
+//   - BusinessError 410 quá RefundWindow kể từ lúc thanh toán (ErrRefundWindowExpired)
+//   - BusinessError 422 vượt quá số tiền còn có thể hoàn (ErrRefundExceeded)
+//   - ValidationError nếu số tiền <= 0, khác tiền tệ thanh toán, hoặc restock ngoài khoảng cho phép
+//   - ExternalError nếu payment gateway từ chối (ErrRefundDeclined), đơn hàng không thay đổi
+func (s *OrderService) RefundOrder(orderID string, req RefundRequest) (*Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, err := s.find(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRefundable(order); err != nil {
+		return nil, err
+	}
+
+	refunded := order.refunded()
+	refundable, _ := order.Paid.Sub(refunded)
+	amount := refundable
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if !amount.IsPositive() {
+		return nil, goerrorkit.NewValidationError("Số tiền hoàn phải lớn hơn 0", map[string]interface{}{
+			"field":    "amount",
+			"min":      NewMoney(1, amount.Currency()),
+			"received": amount,
+		})
+	}
+	c, err := amount.Cmp(refundable)
+	if err != nil {
+		// Khác tiền tệ thanh toán
+		return nil, err
+	}
+	if c > 0 {
+		appErr := goerrorkit.NewBusinessError(422, fmt.Sprintf("Số tiền hoàn %s vượt quá số tiền còn có thể hoàn %s", amount, refundable)).WithData(map[string]interface{}{
+			"order_id":   orderID,
+			"requested":  amount,
+			"refundable": refundable,
+			"refunded":   refunded,
+		})
+		appErr.Cause = &OrderError{OrderID: orderID, Err: ErrRefundExceeded} // errors.Is(err, ErrRefundExceeded)
+		return nil, appErr
+	}
+
+	returnable := order.Quantity - order.restocked()
+	restock := 0
+	if req.Restock != nil {
+		restock = *req.Restock
+	} else if c == 0 {
+		restock = returnable
+	}
+	if restock < 0 || restock > returnable {
+		return nil, goerrorkit.NewValidationError(fmt.Sprintf("Số sản phẩm nhập lại kho phải từ 0 đến %d", returnable), map[string]interface{}{
+			"field":    "restock",
+			"min":      0,
+			"max":      returnable,
+			"received": restock,
+		})
+	}
+	if restock > 0 {
+		// Kiểm tra trước khi hoàn tiền: sản phẩm đã bị xóa thì không nhập lại kho được
+		if _, err := s.productService.GetProduct(order.ProductID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Giả lập gọi payment gateway (external service)
+	if err := s.callRefundGateway(gatewayRefund{
+		OrderID:     orderID,
+		AmountMinor: amount.Minor(),
+		Currency:    amount.Currency(),
+	}); err != nil {
+		return nil, err
+	}
+
+	s.refundSeq++
+	refund := Refund{
+		ID:        fmt.Sprintf("RFD-%04d", s.refundSeq),
+		Amount:    amount,
+		Restocked: restock,
+		Reason:    strings.TrimSpace(req.Reason),
+		CreatedAt: s.now(),
+	}
+	var restockErr error
+	if restock > 0 {
+		allocations := returnAllocations(order.Allocations, order.restocked(), restock)
+		if restockErr = s.productService.RestockProduct(order.ProductID, allocations, refund.ID+" "+orderID); restockErr != nil {
+			refund.Restocked = 0
+		}
+	}
+
+	// Tiền đã được hoàn nên refund luôn được ghi nhận, kể cả khi không nhập lại kho được
+	order.Refunds = append(order.Refunds, refund)
+	order.Status = OrderPartiallyRefunded
+	if c == 0 {
+		order.Status = OrderRefunded
+	}
+	if restockErr != nil {
+		return &refund, goerrorkit.WrapWithMessage(restockErr, "Đã hoàn tiền nhưng không thể nhập lại kho").WithData(map[string]interface{}{
+			"order_id":  orderID,
+			"refund_id": refund.ID,
+			"restock":   restock,
+		})
+	}
+	return &refund, nil
+}
+
+// checkRefundable kiểm tra đơn hàng đã thanh toán, chưa hoàn hết tiền và còn trong thời hạn hoàn tiền
+// s.mu phải đang được giữ
+func (s *OrderService) checkRefundable(order *Order) error {
+	if order.Paid == nil {
+		appErr := goerrorkit.NewBusinessError(409, fmt.Sprintf("Đơn hàng %s chưa được thanh toán", order.ID)).WithData(map[string]interface{}{
+			"order_id": order.ID,
+			"status":   order.Status,
+		})
+		appErr.Cause = &OrderError{OrderID: order.ID, Err: ErrOrderNotPaid} // errors.Is(err, ErrOrderNotPaid)
+		return appErr
+	}
+
+	if order.Status == OrderRefunded {
+		appErr := goerrorkit.NewBusinessError(422, fmt.Sprintf("Đơn hàng %s đã được hoàn toàn bộ số tiền", order.ID)).WithData(map[string]interface{}{
+			"order_id": order.ID,
+			"refunded": order.refunded(),
+		})
+		appErr.Cause = &OrderError{OrderID: order.ID, Err: ErrRefundExceeded} // errors.Is(err, ErrRefundExceeded)
+		return appErr
+	}
+
+	if deadline := order.PaidAt.Add(s.refundWindow); s.now().After(deadline) {
+		appErr := goerrorkit.NewBusinessError(410, fmt.Sprintf("Đơn hàng %s đã quá thời hạn hoàn tiền", order.ID)).WithData(map[string]interface{}{
+			"order_id":        order.ID,
+			"paid_at":         *order.PaidAt,
+			"refund_deadline": deadline,
+			"refund_window":   s.refundWindow.String(),
+		})
+		appErr.Cause = &OrderError{OrderID: order.ID, Err: ErrRefundWindowExpired} // errors.Is(err, ErrRefundWindowExpired)
+		return appErr
+	}
+	return nil
+}
+
+// refunded trả về tổng số tiền đã hoàn, Paid phải khác nil
+func (o *Order) refunded() Money {
+	total := NewMoney(0, o.Paid.Currency())
+	for _, refund := range o.Refunds {
+		total, _ = total.Add(refund.Amount)
+	}
+	return total
+}
+
+// restocked trả về tổng số sản phẩm đã được nhập lại kho qua các lần hoàn tiền
+func (o *Order) restocked() int {
+	total := 0
+	for _, refund := range o.Refunds {
+		total += refund.Restocked
+	}
+	return total
+}
+
+// returnAllocations chọn n sản phẩm để nhập lại kho theo thứ tự các kho đã xuất, bỏ qua skip sản phẩm đã nhập lại trước đó
+func returnAllocations(allocations []Allocation, skip, n int) []Allocation {
+	var result []Allocation
+	for _, allocation := range allocations {
+		quantity := allocation.Quantity
+		if skip >= quantity {
+			skip -= quantity
+			continue
+		}
+		quantity -= skip
+		skip = 0
+		if quantity > n {
+			quantity = n
+		}
+		result = append(result, Allocation{WarehouseID: allocation.WarehouseID, Quantity: quantity})
+		if n -= quantity; n == 0 {
+			break
+		}
+	}
+	return result
+}
+
+// gatewayRefund là request hoàn tiền gửi payment gateway, số tiền tính bằng minor units giống gatewayCharge
+type gatewayRefund struct {
+	OrderID     string
+	AmountMinor int64
+	Currency    Currency
+}
+
+// refundUnsupported là các tiền tệ payment gateway giả lập không hoàn tiền tự động
+var refundUnsupported = map[Currency]bool{
+	JPY: true,
+}
+
+// callRefundGateway giả lập gọi external payment service để hoàn tiền
+func (s *OrderService) callRefundGateway(refund gatewayRefund) error {
+	// Giả lập payment gateway từ chối hoàn tiền
+	if refundUnsupported[refund.Currency] {
+		return goerrorkit.NewExternalError(
+			502,
+			fmt.Sprintf("Refund failed: Payment gateway không hỗ trợ hoàn tiền %s", refund.Currency),
+			&PaymentError{OrderID: refund.OrderID, Reason: "refunds not supported for " + string(refund.Currency), Err: ErrRefundDeclined},
+		).WithData(map[string]interface{}{
+			"order_id":     refund.OrderID,
+			"amount_minor": refund.AmountMinor,
+			"currency":     refund.Currency,
+			"service":      "payment_gateway",
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"fiber_log/testkit"
+)
+
+// newPaidOrder tạo OrderService với đồng hồ giả lập và một đơn hàng 2 x productID đã thanh toán đủ Total
+func newPaidOrder(t *testing.T, products *ProductService, productID string) (*OrderService, *Order, func(time.Duration)) {
+	t.Helper()
+	s := NewOrderService(products)
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	order, err := s.CreateOrder(productID, "USER001", 2, AllocationPolicy{Strategy: StrategySplit, Region: "south"})
+	testkit.AssertNoError(t, err)
+	testkit.AssertNoError(t, s.ProcessPayment(order.ID, order.Total))
+	return s, order, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestRefundOrder(t *testing.T) {
+	s, order, _ := newPaidOrder(t, NewProductService(), "456")
+	before := levels(t, s.productService, "456")
+
+	// Hoàn một phần: không nhập lại kho nếu không có restock
+	partial := MustParseMoney("30", USD)
+	refund, err := s.RefundOrder(order.ID, RefundRequest{Amount: &partial, Reason: " giao trễ "})
+	testkit.AssertNoError(t, err)
+	if refund.ID != "RFD-0001" || refund.Restocked != 0 || refund.Reason != "giao trễ" {
+		t.Errorf("refund = %+v", refund)
+	}
+	if got := levels(t, s.productService, "456"); !reflect.DeepEqual(got, before) {
+		t.Errorf("levels sau hoàn một phần = %v, want %v", got, before)
+	}
+
+	// Vượt quá số tiền còn lại (4969.98)
+	over := MustParseMoney("4969.99", USD)
+	_, err = s.RefundOrder(order.ID, RefundRequest{Amount: &over})
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 422)
+	testkit.AssertLocation(t, err, "services/refund.go:RefundOrder")
+	testkit.AssertData(t, err, "refundable", MustParseMoney("4969.98", USD))
+	if !errors.Is(err, ErrRefundExceeded) {
+		t.Error("errors.Is(err, ErrRefundExceeded) = false")
+	}
+
+	// Hoàn một phần kèm trả 1 sản phẩm: nhập lại kho đầu tiên đã xuất
+	one, second := 1, MustParseMoney("20", USD)
+	_, err = s.RefundOrder(order.ID, RefundRequest{Amount: &second, Restock: &one})
+	testkit.AssertNoError(t, err)
+	after := levels(t, s.productService, "456")
+	if after["WH-03"] != before["WH-03"]+1 || after["WH-01"] != before["WH-01"] {
+		t.Errorf("levels sau restock 1 = %v, trước %v (allocations %v)", after, before, order.Allocations)
+	}
+
+	// Không có amount: hoàn phần còn lại và nhập lại các sản phẩm còn lại
+	refund, err = s.RefundOrder(order.ID, RefundRequest{})
+	testkit.AssertNoError(t, err)
+	if refund.Amount != MustParseMoney("4949.98", USD) || refund.Restocked != 1 {
+		t.Errorf("refund cuối = %+v", refund)
+	}
+	if got := levels(t, s.productService, "456"); got["WH-01"]+got["WH-03"] != before["WH-01"]+before["WH-03"]+2 {
+		t.Errorf("levels sau hoàn toàn bộ = %v, trước %v", got, before)
+	}
+
+	got, err := s.GetOrder(order.ID)
+	testkit.AssertNoError(t, err)
+	if got.Status != OrderRefunded || len(got.Refunds) != 3 {
+		t.Errorf("order = %+v", got)
+	}
+
+	_, err = s.RefundOrder(order.ID, RefundRequest{})
+	testkit.AssertStatus(t, err, 422)
+	testkit.AssertLocation(t, err, "services/refund.go:checkRefundable")
+}
+
+func TestRefundOrderErrors(t *testing.T) {
+	s, order, advance := newPaidOrder(t, NewProductService(), "456")
+
+	_, err := s.RefundOrder("ORD-123", RefundRequest{})
+	testkit.AssertStatus(t, err, 404)
+	if !errors.Is(err, ErrOrderNotFound) {
+		t.Error("errors.Is(err, ErrOrderNotFound) = false")
+	}
+
+	unpaid, err := s.CreateOrder("789", "USER001", 1, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+	_, err = s.RefundOrder(unpaid.ID, RefundRequest{})
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 409)
+	testkit.AssertData(t, err, "status", OrderConfirmed)
+	if !errors.Is(err, ErrOrderNotPaid) {
+		t.Error("errors.Is(err, ErrOrderNotPaid) = false")
+	}
+
+	tests := []struct {
+		name  string
+		req   RefundRequest
+		field string
+	}{
+		{"zero amount", RefundRequest{Amount: &Money{}}, "amount"},
+		{"restock too many", RefundRequest{Restock: intPtr(3)}, "restock"},
+		{"negative restock", RefundRequest{Restock: intPtr(-1)}, "restock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.RefundOrder(order.ID, tt.req)
+			testkit.AssertErrorType(t, err, testkit.Validation)
+			testkit.AssertData(t, err, "field", tt.field)
+		})
+	}
+	eur := MustParseMoney("10", EUR)
+	_, err = s.RefundOrder(order.ID, RefundRequest{Amount: &eur})
+	testkit.AssertErrorType(t, err, testkit.Validation)
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Error("errors.Is(err, ErrCurrencyMismatch) = false")
+	}
+
+	// Quá thời hạn hoàn tiền
+	advance(DefaultRefundWindow + time.Second)
+	_, err = s.RefundOrder(order.ID, RefundRequest{})
+	testkit.AssertStatus(t, err, 410)
+	testkit.AssertData(t, err, "refund_window", "720h0m0s")
+	if !errors.Is(err, ErrRefundWindowExpired) {
+		t.Error("errors.Is(err, ErrRefundWindowExpired) = false")
+	}
+	if got, _ := s.GetOrder(order.ID); got.Status != OrderPaid || len(got.Refunds) != 0 {
+		t.Errorf("order = %+v, lỗi không được thay đổi đơn hàng", got)
+	}
+}
+
+func TestRefundGatewayDeclined(t *testing.T) {
+	products := NewProductService()
+	_, err := products.CreateProduct(Product{ID: "JP1", Name: "Nintendo Switch", Price: MustParseMoney("7500", JPY), Stock: 5})
+	testkit.AssertNoError(t, err)
+	s, order, _ := newPaidOrder(t, products, "JP1") // 15000 JPY
+	before := levels(t, s.productService, "JP1")
+
+	_, err = s.RefundOrder(order.ID, RefundRequest{})
+	testkit.AssertErrorType(t, err, testkit.External)
+	testkit.AssertStatus(t, err, 502)
+	testkit.AssertLocation(t, err, "callRefundGateway")
+	testkit.AssertCause(t, err, "refunds not supported for JPY")
+	var paymentErr *PaymentError
+	if !errors.As(err, &paymentErr) || paymentErr.OrderID != order.ID || !errors.Is(err, ErrRefundDeclined) {
+		t.Errorf("err = %v, want *PaymentError %s", err, order.ID)
+	}
+
+	// Gateway lỗi: không ghi nhận refund, không nhập lại kho
+	if got, _ := s.GetOrder(order.ID); got.Status != OrderPaid || len(got.Refunds) != 0 {
+		t.Errorf("order = %+v", got)
+	}
+	if got := levels(t, s.productService, "JP1"); !reflect.DeepEqual(got, before) {
+		t.Errorf("levels = %v, want %v", got, before)
+	}
+}
+
+func intPtr(n int) *int { return &n }
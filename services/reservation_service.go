@@ -0,0 +1,301 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Reservations - Giữ hàng có thời hạn thay vì trừ stock vĩnh viễn
+// ============================================================================
+
+// Hàng được giữ (hold) khi reserve hoặc tạo đơn hàng. Hold chuyển thành bán (sold) khi thanh toán
+// thành công; hold quá ExpiresAt được ExpireHold (job chạy nền) hoàn trả về đúng kho đã lấy.
+// Trong lúc payment gateway đang charge, hold bị khóa (charging): không hết hạn, không hủy được
+// và không charge song song được, nên tiền đã thu luôn có hàng để Confirm
+//
+// Example:
+//
+//	hold, err := reservationService.Hold("456", 2, services.AllocationPolicy{})
+//	// hold.ID = "RSV-0001", hold.Status = "held", stock 456 giảm 2
+//	_, err = reservationService.Confirm(hold.ID, "ORD-USER001-456") // held -> sold
+//	// Sau hold.ExpiresAt: Confirm trả về BusinessError 410 (ErrReservationExpired)
+
+// DefaultHoldTTL là thời gian giữ hàng mặc định
+const DefaultHoldTTL = 15 * time.Minute
+
+// Trạng thái của reservation
+const (
+	HoldActive   = "held"
+	HoldCharging = "charging" // Payment gateway đang charge (BeginCharge), chờ Confirm hoặc CancelCharge
+	HoldSold     = "sold"     // Đã thanh toán, stock không được hoàn trả
+	HoldReleased = "released" // Hủy (rollback đơn hàng), stock đã hoàn trả
+	HoldExpired  = "expired"  // Quá hạn, stock đã hoàn trả bởi ExpireHold
+)
+
+// Reservation là một lần giữ hàng
+type Reservation struct {
+	ID          string       `json:"id"`
+	ProductID   string       `json:"product_id"`
+	Quantity    int          `json:"quantity"`
+	Allocations []Allocation `json:"allocations"`
+	Status      string       `json:"status"`
+	OrderID     string       `json:"order_id,omitempty"` // Đơn hàng đã thanh toán (Status sold)
+	CreatedAt   time.Time    `json:"created_at"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	ClosedAt    *time.Time   `json:"closed_at,omitempty"` // Thời điểm chuyển sang sold / released / expired
+}
+
+// ReservationService quản lý giữ hàng có thời hạn
+type ReservationService struct {
+	productService *ProductService
+	ttl            time.Duration
+
+	mu    sync.Mutex
+	holds map[string]*Reservation
+	seq   int
+	now   func() time.Time
+}
+
+// NewReservationService tạo ReservationService, ttl <= 0 dùng DefaultHoldTTL
+func NewReservationService(productService *ProductService, ttl time.Duration) *ReservationService {
+	if ttl <= 0 {
+		ttl = DefaultHoldTTL
+	}
+	return &ReservationService{
+		productService: productService,
+		ttl:            ttl,
+		holds:          make(map[string]*Reservation),
+		now:            time.Now,
+	}
+}
+
+// Hold giữ quantity sản phẩm trong ttl, hàng được lấy từ các kho theo policy
+// Lỗi tồn kho được propagate từ ProductService.ReserveProduct
+func (s *ReservationService) Hold(productID string, quantity int, policy AllocationPolicy) (*Reservation, error) {
+	if quantity <= 0 {
+		return nil, goerrorkit.NewValidationError("Số lượng phải lớn hơn 0", map[string]interface{}{
+			"field":    "quantity",
+			"min":      1,
+			"received": quantity,
+		})
+	}
+
+	allocations, err := s.productService.ReserveProduct(productID, quantity, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	now := s.now()
+	hold := &Reservation{
+		ID:          fmt.Sprintf("RSV-%04d", s.seq),
+		ProductID:   productID,
+		Quantity:    quantity,
+		Allocations: allocations,
+		Status:      HoldActive,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	s.holds[hold.ID] = hold
+	return hold.snapshot(), nil
+}
+
+// Get trả về reservation theo ID, BusinessError 404 nếu không tồn tại
+func (s *ReservationService) Get(id string) (*Reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return hold.snapshot(), nil
+}
+
+// CheckHeld kiểm tra reservation vẫn đang giữ hàng (chưa bán, chưa hủy, chưa hết hạn, không đang charge)
+func (s *ReservationService) CheckHeld(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	return s.checkActive(hold)
+}
+
+// BeginCharge khóa reservation đang giữ hàng (held -> charging) trước khi charge thanh toán
+// Hold đang charge không bị ExpireHold hay Release lấy lại hàng kể cả khi quá ExpiresAt, và lần
+// BeginCharge thứ hai trả về BusinessError 409 (ErrReservationClosed) nên đơn hàng không bị charge hai lần
+// Sau khi charge: Confirm nếu gateway chấp nhận, CancelCharge nếu gateway báo lỗi
+func (s *ReservationService) BeginCharge(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkActive(hold); err != nil {
+		return err
+	}
+	hold.Status = HoldCharging
+	return nil
+}
+
+// CancelCharge mở khóa reservation khi charge thất bại (charging -> held)
+// Hàng tiếp tục được giữ đến ExpiresAt, đã quá hạn thì lần chạy sau của job hết hạn sẽ hoàn trả
+func (s *ReservationService) CancelCharge(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hold, exists := s.holds[id]; exists && hold.Status == HoldCharging {
+		hold.Status = HoldActive
+	}
+}
+
+// Confirm chuyển reservation thành bán (sold) cho orderID, stock không còn được hoàn trả
+// Reservation phải đang giữ hàng hoặc đang được charge (BeginCharge)
+func (s *ReservationService) Confirm(id, orderID string) (*Reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != HoldCharging {
+		if err := s.checkActive(hold); err != nil {
+			return nil, err
+		}
+	}
+
+	hold.OrderID = orderID
+	hold.close(HoldSold, s.now())
+	return hold.snapshot(), nil
+}
+
+// Release hủy reservation đang giữ và hoàn trả stock về đúng kho đã lấy (rollback đơn hàng)
+func (s *ReservationService) Release(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	if hold.Status != HoldActive {
+		return s.checkActive(hold)
+	}
+
+	if err := s.productService.ReleaseProduct(hold.ProductID, hold.Allocations); err != nil {
+		return err
+	}
+	hold.close(HoldReleased, s.now())
+	return nil
+}
+
+// DueHolds trả về ID các reservation đang giữ hàng nhưng đã quá hạn, sắp xếp theo ID
+func (s *ReservationService) DueHolds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	due := []string{}
+	for id, hold := range s.holds {
+		if hold.Status == HoldActive && !now.Before(hold.ExpiresAt) {
+			due = append(due, id)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+// ExpireHold hoàn trả stock của reservation đã quá hạn về đúng kho đã lấy (job chạy nền gọi cho từng reservation)
+// Reservation không còn giữ hàng hoặc chưa đến hạn được bỏ qua, nên gọi lại nhiều lần vẫn an toàn
+//
+// Không hoàn trả được thì reservation vẫn ở trạng thái held để retry, trừ khi sản phẩm đã bị xóa:
+// không còn stock để hoàn trả nên reservation vẫn kết thúc, lỗi được trả về để job ghi nhận
+func (s *ReservationService) ExpireHold(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	if hold.Status != HoldActive || now.Before(hold.ExpiresAt) {
+		return nil
+	}
+
+	if err := s.productService.ReleaseProduct(hold.ProductID, hold.Allocations); err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			hold.close(HoldExpired, now)
+		}
+		return goerrorkit.WrapWithMessage(err, fmt.Sprintf("Không thể hoàn trả stock của reservation %s", hold.ID)).WithData(map[string]interface{}{
+			"reservation_id": hold.ID,
+			"product_id":     hold.ProductID,
+			"quantity":       hold.Quantity,
+			"expires_at":     hold.ExpiresAt,
+		})
+	}
+	hold.close(HoldExpired, now)
+	return nil
+}
+
+// find tìm reservation theo ID, s.mu phải đang được giữ
+func (s *ReservationService) find(id string) (*Reservation, error) {
+	hold, exists := s.holds[id]
+	if !exists {
+		appErr := goerrorkit.NewBusinessError(404, fmt.Sprintf("Reservation %s không tồn tại", id)).WithData(map[string]interface{}{
+			"reservation_id": id,
+		})
+		appErr.Cause = &ReservationError{ReservationID: id, Err: ErrReservationNotFound} // errors.Is(err, ErrReservationNotFound)
+		return nil, appErr
+	}
+	return hold, nil
+}
+
+// checkActive trả về lỗi nếu reservation không còn giữ hàng, s.mu phải đang được giữ
+// Reservation quá hạn nhưng worker chưa chạy cũng được coi là hết hạn
+func (s *ReservationService) checkActive(hold *Reservation) error {
+	if hold.Status == HoldExpired || hold.Status == HoldActive && !s.now().Before(hold.ExpiresAt) {
+		appErr := goerrorkit.NewBusinessError(410, fmt.Sprintf("Reservation %s đã hết hạn", hold.ID)).WithData(map[string]interface{}{
+			"reservation_id": hold.ID,
+			"expires_at":     hold.ExpiresAt,
+		})
+		appErr.Cause = &ReservationError{ReservationID: hold.ID, Err: ErrReservationExpired} // errors.Is(err, ErrReservationExpired)
+		return appErr
+	}
+	if hold.Status != HoldActive {
+		appErr := goerrorkit.NewBusinessError(409, fmt.Sprintf("Reservation %s không còn giữ hàng (%s)", hold.ID, hold.Status)).WithData(map[string]interface{}{
+			"reservation_id": hold.ID,
+			"status":         hold.Status,
+		})
+		appErr.Cause = &ReservationError{ReservationID: hold.ID, Err: ErrReservationClosed} // errors.Is(err, ErrReservationClosed)
+		return appErr
+	}
+	return nil
+}
+
+// close chuyển reservation sang trạng thái kết thúc
+func (r *Reservation) close(status string, at time.Time) {
+	r.Status = status
+	r.ClosedAt = &at
+}
+
+// snapshot trả về bản sao để caller không đọc/ghi reservation ngoài s.mu
+func (r *Reservation) snapshot() *Reservation {
+	copied := *r
+	return &copied
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"fiber_log/testkit"
+)
+
+// newClockedReservations tạo ReservationService với đồng hồ giả lập, trả về hàm tua đồng hồ
+func newClockedReservations(products *ProductService, ttl time.Duration) (*ReservationService, func(time.Duration)) {
+	s := NewReservationService(products, ttl)
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestReservationLifecycle(t *testing.T) {
+	products := NewProductService()
+	s, advance := newClockedReservations(products, time.Minute)
+
+	hold, err := s.Hold("456", 3, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+	if hold.ID != "RSV-0001" || hold.Status != HoldActive || !hold.ExpiresAt.Equal(hold.CreatedAt.Add(time.Minute)) {
+		t.Errorf("hold = %+v", hold)
+	}
+	if got := levels(t, products, "456"); !reflect.DeepEqual(got, map[string]int{"WH-01": 0, "WH-02": 0, "WH-03": 2}) {
+		t.Errorf("levels sau hold = %v", got)
+	}
+
+	advance(30 * time.Second)
+	sold, err := s.Confirm(hold.ID, "ORD-USER001-456")
+	testkit.AssertNoError(t, err)
+	if sold.Status != HoldSold || sold.OrderID != "ORD-USER001-456" || sold.ClosedAt == nil {
+		t.Errorf("sold = %+v", sold)
+	}
+
+	// Hàng đã bán không được hoàn trả kể cả khi quá hạn
+	advance(time.Hour)
+	if due := s.DueHolds(); len(due) != 0 {
+		t.Errorf("due = %v, want []", due)
+	}
+	testkit.AssertNoError(t, s.ExpireHold(hold.ID))
+	if product, _ := products.GetProduct("456"); product.Stock != 2 {
+		t.Errorf("stock = %d, want 2", product.Stock)
+	}
+
+	err = s.Release(hold.ID)
+	testkit.AssertStatus(t, err, 409)
+	testkit.AssertData(t, err, "status", HoldSold)
+	if !errors.Is(err, ErrReservationClosed) {
+		t.Error("errors.Is(err, ErrReservationClosed) = false")
+	}
+
+	_, err = s.Get("RSV-9999")
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 404)
+	var reservationErr *ReservationError
+	if !errors.As(err, &reservationErr) || reservationErr.ReservationID != "RSV-9999" || !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("err = %v, want *ReservationError RSV-9999", err)
+	}
+
+	_, err = s.Hold("456", 0, AllocationPolicy{})
+	testkit.AssertErrorType(t, err, testkit.Validation)
+	testkit.AssertLocation(t, err, "services/reservation_service.go:Hold")
+}
+
+func TestExpireHold(t *testing.T) {
+	products := NewProductService()
+	s, advance := newClockedReservations(products, time.Minute)
+
+	first, err := s.Hold("456", 4, AllocationPolicy{Strategy: StrategySplit, Region: "south"})
+	testkit.AssertNoError(t, err)
+	advance(30 * time.Second)
+	second, err := s.Hold("789", 1, AllocationPolicy{})
+	testkit.AssertNoError(t, err)
+
+	// Chưa đến hạn: bỏ qua
+	testkit.AssertNoError(t, s.ExpireHold(first.ID))
+	testkit.AssertNoError(t, s.CheckHeld(first.ID))
+
+	// Quá hạn nhưng job chưa chạy: không được xác nhận thanh toán
+	advance(45 * time.Second)
+	_, err = s.Confirm(first.ID, "ORD-U1-456")
+	testkit.AssertStatus(t, err, 410)
+	testkit.AssertLocation(t, err, "services/reservation_service.go:checkActive")
+	if !errors.Is(err, ErrReservationExpired) {
+		t.Error("errors.Is(err, ErrReservationExpired) = false")
+	}
+
+	if due := s.DueHolds(); !reflect.DeepEqual(due, []string{first.ID}) {
+		t.Errorf("due = %v, want [%s]", due, first.ID)
+	}
+	testkit.AssertNoError(t, s.ExpireHold(first.ID))
+	testkit.AssertNoError(t, s.ExpireHold(first.ID)) // Gọi lại không hoàn trả hai lần
+	// Hoàn trả về đúng kho đã lấy
+	if got := levels(t, products, "456"); !reflect.DeepEqual(got, map[string]int{"WH-01": 2, "WH-02": 0, "WH-03": 3}) {
+		t.Errorf("levels sau expire = %v", got)
+	}
+	if hold, _ := s.Get(first.ID); hold.Status != HoldExpired {
+		t.Errorf("status = %s, want %s", hold.Status, HoldExpired)
+	}
+	testkit.AssertStatus(t, s.CheckHeld(first.ID), 410)
+	testkit.AssertNoError(t, s.CheckHeld(second.ID))
+
+	// Sản phẩm bị xóa trước khi hold hết hạn: lỗi được trả về kèm reservation_id, hold vẫn kết thúc
+	testkit.AssertNoError(t, products.DeleteProduct("789", ""))
+	advance(time.Minute)
+	err = s.ExpireHold(second.ID)
+	testkit.AssertErrorType(t, err, testkit.System)
+	testkit.AssertLocation(t, err, "services/reservation_service.go:ExpireHold")
+	testkit.AssertData(t, err, "reservation_id", second.ID)
+	if !errors.Is(err, ErrProductNotFound) {
+		t.Error("errors.Is(err, ErrProductNotFound) = false")
+	}
+	if due := s.DueHolds(); len(due) != 0 {
+		t.Errorf("due = %v, want []", due)
+	}
+
+	testkit.AssertStatus(t, s.ExpireHold("RSV-9999"), 404)
+}
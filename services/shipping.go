@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// Shipping - Báo giá và tạo vận đơn qua đơn vị vận chuyển
+// ============================================================================
+
+// Operations của shipping (data.operation của ExternalError, key của SHIPPING_SIM_FAILURES)
+const (
+	OperationQuote       = "quote"
+	OperationCreateLabel = "create_label"
+)
+
+// ShippingOperations là các operation của ShippingClient
+var ShippingOperations = []string{OperationQuote, OperationCreateLabel}
+
+// ShippingClient là client của đơn vị vận chuyển
+// Lỗi của upstream là ExternalError có Cause là *DependencyError
+type ShippingClient interface {
+	// Quote trả về các mức phí giao hàng cho đơn hàng
+	Quote(req ShipmentRequest) ([]Rate, error)
+
+	// CreateLabel tạo vận đơn theo mức phí đã chọn
+	CreateLabel(req ShipmentRequest, rate Rate) (*Label, error)
+}
+
+// ShipmentRequest là hàng cần giao của đơn hàng, hàng từ mỗi kho là một kiện
+type ShipmentRequest struct {
+	OrderID string
+	Parcels []Allocation
+}
+
+// Rate là một mức phí giao hàng
+type Rate struct {
+	ID      string `json:"id"`
+	Carrier string `json:"carrier"`
+	Service string `json:"service"`
+	Cost    Money  `json:"cost"`
+	Days    int    `json:"days"` // Số ngày giao dự kiến
+}
+
+// Label là vận đơn đã tạo
+type Label struct {
+	TrackingNumber string    `json:"tracking_number"`
+	Carrier        string    `json:"carrier"`
+	Service        string    `json:"service"`
+	Cost           Money     `json:"cost"`
+	Parcels        int       `json:"parcels"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// simCarrier là tên đơn vị vận chuyển của ShippingSimulator
+const simCarrier = "SimShip"
+
+// simShippingServices là bảng giá của ShippingSimulator (minor units của DefaultCurrency)
+var simShippingServices = []struct {
+	service   string
+	perParcel int64
+	perItem   int64
+	days      int
+}{
+	{"standard", 300, 50, 3},
+	{"express", 800, 100, 1},
+}
+
+// ShippingSimulator là ShippingClient chạy local, báo giá theo số kiện và số sản phẩm
+// Operation được cấu hình Failure trả về ExternalError thay vì kết quả
+type ShippingSimulator struct {
+	*simulator
+	labelSeq int
+	now      func() time.Time
+}
+
+// NewShippingSimulator tạo ShippingSimulator với lỗi ban đầu của từng operation (nil: không lỗi)
+func NewShippingSimulator(failures map[string]Failure) *ShippingSimulator {
+	return &ShippingSimulator{simulator: newSimulator(DependencyShipping, failures), now: time.Now}
+}
+
+// Quote trả về giá của từng dịch vụ, phí tính bằng DefaultCurrency
+func (s *ShippingSimulator) Quote(req ShipmentRequest) ([]Rate, error) {
+	if err := s.call(OperationQuote, map[string]interface{}{"order_id": req.OrderID}); err != nil {
+		return nil, err
+	}
+
+	items := 0
+	for _, parcel := range req.Parcels {
+		items += parcel.Quantity
+	}
+	rates := make([]Rate, 0, len(simShippingServices))
+	for _, svc := range simShippingServices {
+		rates = append(rates, Rate{
+			ID:      fmt.Sprintf("%s-%s", req.OrderID, svc.service),
+			Carrier: simCarrier,
+			Service: svc.service,
+			Cost:    NewMoney(svc.perParcel*int64(len(req.Parcels))+svc.perItem*int64(items), DefaultCurrency),
+			Days:    svc.days,
+		})
+	}
+	return rates, nil
+}
+
+// CreateLabel tạo vận đơn với tracking number "SIM00000001"
+func (s *ShippingSimulator) CreateLabel(req ShipmentRequest, rate Rate) (*Label, error) {
+	err := s.call(OperationCreateLabel, map[string]interface{}{"order_id": req.OrderID, "rate_id": rate.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.labelSeq++
+	return &Label{
+		TrackingNumber: fmt.Sprintf("SIM%08d", s.labelSeq),
+		Carrier:        rate.Carrier,
+		Service:        rate.Service,
+		Cost:           rate.Cost,
+		Parcels:        len(req.Parcels),
+		CreatedAt:      s.now(),
+	}, nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"fiber_log/testkit"
+)
+
+func TestShippingSimulator(t *testing.T) {
+	shipping := NewShippingSimulator(nil)
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	shipping.now = func() time.Time { return now }
+
+	// 2 kiện, 3 sản phẩm: standard 2 x 3.00 + 3 x 0.50, express 2 x 8.00 + 3 x 1.00
+	req := ShipmentRequest{OrderID: "ORD-1", Parcels: []Allocation{{"WH-HCM", 2}, {"WH-HN", 1}}}
+	rates, err := shipping.Quote(req)
+	testkit.AssertNoError(t, err)
+	if len(rates) != 2 || rates[0].Cost.String() != "7.50 USD" || rates[1].Cost.String() != "19.00 USD" || rates[1].Days != 1 {
+		t.Fatalf("rates = %+v", rates)
+	}
+
+	label, err := shipping.CreateLabel(req, rates[0])
+	testkit.AssertNoError(t, err)
+	want := Label{TrackingNumber: "SIM00000001", Carrier: "SimShip", Service: "standard", Cost: rates[0].Cost, Parcels: 2, CreatedAt: now}
+	if *label != want {
+		t.Errorf("label = %+v, want %+v", label, want)
+	}
+
+	shipping.SetFailure(OperationQuote, Failure{Mode: FailureRateLimited})
+	_, err = shipping.Quote(req)
+	testkit.AssertStatus(t, err, 503)
+	testkit.AssertData(t, err, "operation", OperationQuote)
+	testkit.AssertData(t, err, "upstream_status", 429)
+}
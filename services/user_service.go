@@ -0,0 +1,362 @@
+package services
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// ============================================================================
+// Users - Đăng ký, tra cứu, cập nhật profile (lưu ra USERS_FILE giống sản phẩm)
+// ============================================================================
+
+// User là user đã đăng ký, không chứa password hash nên có thể trả thẳng về client
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone,omitempty"` // Số điện thoại dạng E.164 (+84...), nhận SMS khi đơn hàng được giao
+	Age       int       `json:"age"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserRecord là User kèm password hash, dạng được lưu trong USERS_FILE
+type UserRecord struct {
+	User
+	PasswordHash string `json:"password_hash,omitempty"` // Rỗng: user mẫu chưa đặt mật khẩu
+}
+
+// UserInput là dữ liệu đăng ký / cập nhật profile
+// Khi cập nhật, Password rỗng nghĩa là giữ mật khẩu cũ
+type UserInput struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Age      int    `json:"age"`
+	Password string `json:"password"`
+}
+
+// Ràng buộc của user
+const (
+	MinUserAge        = 18
+	MinPasswordLength = 8
+)
+
+// passwordIterations là số vòng PBKDF2-SHA256 khi hash mật khẩu
+const passwordIterations = 600_000
+
+// UserService quản lý user, email là duy nhất (không phân biệt hoa thường)
+// Nếu có path, mọi thay đổi được ghi ra file bằng SaveUsers trước khi trả về
+type UserService struct {
+	mu     sync.RWMutex
+	users  map[string]*UserRecord
+	emails map[string]string // email đã chuẩn hóa -> user ID
+	seq    int
+	path   string
+	now    func() time.Time
+}
+
+// NewUserService tạo UserService chỉ lưu trong bộ nhớ với các user mẫu (USER001 là user mặc định khi tạo đơn hàng)
+func NewUserService() *UserService {
+	return NewUserServiceWith(nil, "")
+}
+
+// NewUserServiceWith tạo UserService với danh sách user cho trước (ví dụ đọc từ USERS_FILE), nil dùng user mẫu
+// path rỗng thì không ghi ra file
+func NewUserServiceWith(users []*UserRecord, path string) *UserService {
+	s := &UserService{
+		users:  make(map[string]*UserRecord),
+		emails: make(map[string]string),
+		path:   path,
+		now:    time.Now,
+	}
+	if users == nil {
+		users = seedUsers()
+	}
+	for _, user := range users {
+		s.put(user)
+	}
+	return s
+}
+
+// seedUsers trả về các user mẫu, chưa đặt mật khẩu nên Authenticate luôn thất bại
+func seedUsers() []*UserRecord {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []*UserRecord{
+		{User: User{ID: "USER001", Name: "Nguyễn Văn An", Email: "an@example.com", Phone: "+84901234567", Age: 30, CreatedAt: created, UpdatedAt: created}},
+		{User: User{ID: "USER002", Name: "Trần Thị Bình", Email: "binh@example.com", Age: 25, CreatedAt: created, UpdatedAt: created}},
+	}
+}
+
+// Register đăng ký user mới, mật khẩu được hash bằng PBKDF2-SHA256 với salt ngẫu nhiên
+// Trả về lỗi gộp (errors.Join) các ValidationError nếu dữ liệu không hợp lệ, BusinessError 409 nếu email đã được dùng
+//
+// Example:
+//
+//	user, err := userService.Register(services.UserInput{Name: "An", Email: "an@example.com", Age: 20, Password: "secret123"})
+//	if errors.Is(err, services.ErrEmailTaken) {
+//	    // 409: gợi ý đăng nhập thay vì đăng ký
+//	}
+func (s *UserService) Register(input UserInput) (*User, error) {
+	input.Email = normalizeEmail(input.Email)
+	input.Phone = strings.TrimSpace(input.Phone)
+	if err := validateUser(input, true); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkEmail(input.Email, ""); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	record := &UserRecord{
+		User: User{
+			ID:        s.nextID(),
+			Name:      strings.TrimSpace(input.Name),
+			Email:     input.Email,
+			Phone:     input.Phone,
+			Age:       input.Age,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		PasswordHash: hash,
+	}
+	s.put(record)
+	if err := s.persist(); err != nil {
+		delete(s.users, record.ID)
+		delete(s.emails, record.Email)
+		return nil, err
+	}
+
+	user := record.User
+	return &user, nil
+}
+
+// GetUser lấy user theo ID, BusinessError 404 nếu không tồn tại
+func (s *UserService) GetUser(userID string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	user := record.User
+	return &user, nil
+}
+
+// UpdateProfile thay name, email, phone, age của user, Password khác rỗng thì đổi mật khẩu
+// Lỗi giống Register, thêm BusinessError 404 nếu user không tồn tại
+func (s *UserService) UpdateProfile(userID string, input UserInput) (*User, error) {
+	input.Email = normalizeEmail(input.Email)
+	input.Phone = strings.TrimSpace(input.Phone)
+	if err := validateUser(input, false); err != nil {
+		return nil, err
+	}
+	var hash string
+	if input.Password != "" {
+		var err error
+		if hash, err = hashPassword(input.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkEmail(input.Email, userID); err != nil {
+		return nil, err
+	}
+
+	previous := *record
+	record.Name, record.Email, record.Phone, record.Age = strings.TrimSpace(input.Name), input.Email, input.Phone, input.Age
+	if hash != "" {
+		record.PasswordHash = hash
+	}
+	record.UpdatedAt = s.now()
+	delete(s.emails, previous.Email)
+	s.emails[record.Email] = userID
+	if err := s.persist(); err != nil {
+		delete(s.emails, record.Email)
+		*record = previous
+		s.emails[previous.Email] = userID
+		return nil, err
+	}
+
+	user := record.User
+	return &user, nil
+}
+
+// Authenticate kiểm tra email và mật khẩu, AuthError 401 nếu sai (không cho biết email có tồn tại hay không)
+func (s *UserService) Authenticate(email, password string) (*User, error) {
+	email = normalizeEmail(email)
+
+	s.mu.RLock()
+	var record *UserRecord
+	if userID, exists := s.emails[email]; exists {
+		record = s.users[userID]
+	}
+	s.mu.RUnlock()
+
+	if record == nil || !checkPassword(record.PasswordHash, password) {
+		return nil, goerrorkit.NewAuthError(401, "Email hoặc mật khẩu không đúng").WithData(map[string]interface{}{
+			"email": email,
+		})
+	}
+	user := record.User
+	return &user, nil
+}
+
+// find tìm user theo ID, s.mu phải đang được giữ
+func (s *UserService) find(userID string) (*UserRecord, error) {
+	record, exists := s.users[userID]
+	if !exists {
+		appErr := goerrorkit.NewBusinessError(404, fmt.Sprintf("User %s không tồn tại", userID)).WithData(map[string]interface{}{
+			"user_id": userID,
+		})
+		appErr.Cause = &UserError{UserID: userID, Err: ErrUserNotFound} // errors.Is(err, ErrUserNotFound)
+		return nil, appErr
+	}
+	return record, nil
+}
+
+// checkEmail trả về BusinessError 409 nếu email đã thuộc về user khác exceptID, s.mu phải đang được giữ
+func (s *UserService) checkEmail(email, exceptID string) error {
+	if owner, taken := s.emails[email]; taken && owner != exceptID {
+		appErr := goerrorkit.NewBusinessError(409, fmt.Sprintf("Email %s đã được đăng ký", email)).WithData(map[string]interface{}{
+			"field": "email",
+			"email": email,
+		})
+		appErr.Cause = &UserError{Email: email, Err: ErrEmailTaken} // errors.Is(err, ErrEmailTaken)
+		return appErr
+	}
+	return nil
+}
+
+// put thêm user vào map và index email, cập nhật seq để ID mới không trùng
+func (s *UserService) put(record *UserRecord) {
+	s.users[record.ID] = record
+	s.emails[normalizeEmail(record.Email)] = record.ID
+	if n, err := strconv.Atoi(strings.TrimPrefix(record.ID, "USER")); err == nil && n > s.seq {
+		s.seq = n
+	}
+}
+
+// nextID trả về ID cho user mới dạng USER003, s.mu phải đang được giữ
+func (s *UserService) nextID() string {
+	s.seq++
+	return fmt.Sprintf("USER%03d", s.seq)
+}
+
+// persist ghi tất cả user ra s.path (nếu có), s.mu phải đang được giữ
+func (s *UserService) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	records := make([]*UserRecord, 0, len(s.users))
+	for _, record := range s.users {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID }) // File ổn định giữa các lần ghi
+	return SaveUsers(s.path, records)
+}
+
+// normalizeEmail bỏ khoảng trắng và chuyển về chữ thường để so sánh email
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateUser kiểm tra dữ liệu user, trả về errors.Join của ValidationError từng trường
+// Mật khẩu không bao giờ được đưa vào data của lỗi
+func validateUser(input UserInput, requirePassword bool) error {
+	var errs []error
+	invalid := func(message string, data map[string]interface{}) {
+		errs = append(errs, goerrorkit.NewValidationError(message, data))
+	}
+
+	if strings.TrimSpace(input.Name) == "" {
+		invalid("Tên không được để trống", map[string]interface{}{"field": "name", "required": true})
+	}
+	if input.Email == "" {
+		invalid("Email không được để trống", map[string]interface{}{"field": "email", "required": true})
+	} else if address, err := mail.ParseAddress(input.Email); err != nil || address.Address != input.Email {
+		invalid("Email không hợp lệ", map[string]interface{}{"field": "email", "received": input.Email})
+	}
+	if input.Phone != "" && !isE164(input.Phone) {
+		invalid("Số điện thoại phải có dạng E.164 (ví dụ +84901234567)", map[string]interface{}{"field": "phone", "received": input.Phone})
+	}
+	if input.Age < MinUserAge {
+		invalid(fmt.Sprintf("Tuổi phải >= %d", MinUserAge), map[string]interface{}{"field": "age", "min": MinUserAge, "received": input.Age})
+	}
+	if (requirePassword || input.Password != "") && len(input.Password) < MinPasswordLength {
+		invalid(fmt.Sprintf("Mật khẩu phải có ít nhất %d ký tự", MinPasswordLength), map[string]interface{}{"field": "password", "min_length": MinPasswordLength})
+	}
+	return errors.Join(errs...)
+}
+
+// isE164 kiểm tra số điện thoại dạng E.164: "+" và 8 đến 15 chữ số, không bắt đầu bằng 0
+func isE164(phone string) bool {
+	digits, ok := strings.CutPrefix(phone, "+")
+	if !ok || len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return false
+	}
+	return strings.Trim(digits, "0123456789") == ""
+}
+
+// hashPassword trả về "pbkdf2-sha256$<iterations>$<salt>$<key>" (salt, key mã hóa base64)
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, 32)
+	if err != nil {
+		return "", goerrorkit.WrapWithMessage(err, "Không thể hash mật khẩu")
+	}
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// checkPassword so sánh mật khẩu với hash do hashPassword tạo ra (constant time)
+func checkPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	return err == nil && subtle.ConstantTimeCompare(key, want) == 1
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fiber_log/testkit"
+)
+
+func TestRegisterUser(t *testing.T) {
+	s := NewUserService()
+
+	user, err := s.Register(UserInput{Name: " Lan ", Email: "Lan@Example.com", Age: 22, Password: "secret123"})
+	testkit.AssertNoError(t, err)
+	if user.ID != "USER003" || user.Name != "Lan" || user.Email != "lan@example.com" || user.CreatedAt.IsZero() {
+		t.Errorf("user = %+v", user)
+	}
+	if stored, _ := s.GetUser(user.ID); *stored != *user {
+		t.Errorf("stored = %+v, want %+v", stored, user)
+	}
+
+	// Email không phân biệt hoa thường
+	_, err = s.Register(UserInput{Name: "An", Email: " AN@example.com", Age: 20, Password: "secret123"})
+	testkit.AssertErrorType(t, err, testkit.Business)
+	testkit.AssertStatus(t, err, 409)
+	testkit.AssertLocation(t, err, "services/user_service.go:checkEmail")
+	testkit.AssertData(t, err, "email", "an@example.com")
+	var userErr *UserError
+	if !errors.As(err, &userErr) || userErr.Email != "an@example.com" || !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("err = %v, want *UserError an@example.com", err)
+	}
+
+	// Mỗi trường sai là một ValidationError trong lỗi gộp, mật khẩu không xuất hiện trong data
+	_, err = s.Register(UserInput{Name: "", Email: "not-an-email", Age: 16, Password: "short"})
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Register trả về %T, want lỗi gộp", err)
+	}
+	for i, field := range []string{"name", "email", "age", "password"} {
+		child := joined.Unwrap()[i]
+		testkit.AssertErrorType(t, child, testkit.Validation)
+		testkit.AssertData(t, child, "field", field)
+	}
+	if data := testkit.AppError(t, joined.Unwrap()[3]).Data; data["received"] != nil {
+		t.Errorf("password data = %v, không được chứa mật khẩu", data)
+	}
+
+	// Số điện thoại không bắt buộc, có thì phải là E.164
+	_, err = s.Register(UserInput{Name: "Minh", Email: "minh@example.com", Phone: "0901234567", Age: 20, Password: "secret123"})
+	testkit.AssertErrorType(t, err, testkit.Validation)
+	testkit.AssertData(t, err, "field", "phone")
+	user, err = s.Register(UserInput{Name: "Minh", Email: "minh@example.com", Phone: " +84901234567 ", Age: 20, Password: "secret123"})
+	testkit.AssertNoError(t, err)
+	if user.Phone != "+84901234567" {
+		t.Errorf("phone = %q", user.Phone)
+	}
+
+	_, err = s.GetUser("USER999")
+	testkit.AssertStatus(t, err, 404)
+	testkit.AssertData(t, err, "user_id", "USER999")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Error("errors.Is(err, ErrUserNotFound) = false")
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	s := NewUserService()
+	user, err := s.Register(UserInput{Name: "Lan", Email: "lan@example.com", Age: 22, Password: "secret123"})
+	testkit.AssertNoError(t, err)
+
+	// Password rỗng: giữ mật khẩu cũ
+	updated, err := s.UpdateProfile(user.ID, UserInput{Name: "Lan Nguyễn", Email: "lan.nguyen@example.com", Age: 23})
+	testkit.AssertNoError(t, err)
+	if updated.Name != "Lan Nguyễn" || updated.Email != "lan.nguyen@example.com" || !updated.CreatedAt.Equal(user.CreatedAt) {
+		t.Errorf("updated = %+v", updated)
+	}
+	_, err = s.Authenticate("lan.nguyen@example.com", "secret123")
+	testkit.AssertNoError(t, err)
+
+	// Email cũ được giải phóng, email của user khác thì không
+	_, err = s.Register(UserInput{Name: "Lan 2", Email: "lan@example.com", Age: 30, Password: "password2"})
+	testkit.AssertNoError(t, err)
+	_, err = s.UpdateProfile(user.ID, UserInput{Name: "Lan", Email: "binh@example.com", Age: 23})
+	testkit.AssertStatus(t, err, 409)
+	if current, _ := s.GetUser(user.ID); current.Email != "lan.nguyen@example.com" {
+		t.Errorf("email = %s, update lỗi không được ghi", current.Email)
+	}
+
+	_, err = s.UpdateProfile(user.ID, UserInput{Name: "Lan", Email: "lan.nguyen@example.com", Age: 23, Password: "new-secret"})
+	testkit.AssertNoError(t, err)
+	_, err = s.Authenticate("lan.nguyen@example.com", "secret123")
+	testkit.AssertErrorType(t, err, testkit.Auth)
+	testkit.AssertStatus(t, err, 401)
+	_, err = s.Authenticate("LAN.NGUYEN@example.com", "new-secret")
+	testkit.AssertNoError(t, err)
+
+	// User mẫu chưa đặt mật khẩu
+	_, err = s.Authenticate("an@example.com", "")
+	testkit.AssertStatus(t, err, 401)
+
+	_, err = s.UpdateProfile("USER999", UserInput{Name: "X", Email: "x@example.com", Age: 20})
+	testkit.AssertStatus(t, err, 404)
+	_, err = s.UpdateProfile(user.ID, UserInput{Name: "Lan", Email: "lan.nguyen@example.com", Age: 23, Password: "short"})
+	testkit.AssertErrorType(t, err, testkit.Validation)
+}
+
+func TestUsersFilePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "users.json")
+
+	s := NewUserServiceWith(nil, path)
+	user, err := s.Register(UserInput{Name: "Lan", Email: "lan@example.com", Age: 22, Password: "secret123"})
+	testkit.AssertNoError(t, err)
+
+	data, err := os.ReadFile(path)
+	testkit.AssertNoError(t, err)
+	if strings.Contains(string(data), "secret123") || !strings.Contains(string(data), `"password_hash": "pbkdf2-sha256$`) {
+		t.Errorf("users file:\n%s", data)
+	}
+	assertMode := func(want os.FileMode) {
+		t.Helper()
+		info, err := os.Stat(path)
+		testkit.AssertNoError(t, err)
+		if info.Mode().Perm() != want {
+			t.Errorf("users file mode = %v, want %v", info.Mode().Perm(), want)
+		}
+	}
+	assertMode(0o600)
+
+	users, err := LoadUsers(path)
+	testkit.AssertNoError(t, err)
+	loaded := NewUserServiceWith(users, path)
+	if got, _ := loaded.GetUser(user.ID); got == nil || got.Email != "lan@example.com" {
+		t.Fatalf("loaded %s = %+v", user.ID, got)
+	}
+	_, err = loaded.Authenticate("lan@example.com", "secret123")
+	testkit.AssertNoError(t, err)
+	next, err := loaded.Register(UserInput{Name: "Minh", Email: "minh@example.com", Age: 40, Password: "secret456"})
+	testkit.AssertNoError(t, err)
+	if next.ID != "USER004" {
+		t.Errorf("next ID = %s, want USER004", next.ID)
+	}
+
+	// File cũ ghi với quyền rộng hơn được thu hẹp ở lần ghi tiếp theo
+	testkit.AssertNoError(t, os.Chmod(path, 0o644))
+	testkit.AssertNoError(t, os.WriteFile(path+".tmp", nil, 0o644))
+	_, err = loaded.Register(UserInput{Name: "Hoa", Email: "hoa.le@example.com", Age: 30, Password: "secret789"})
+	testkit.AssertNoError(t, err)
+	assertMode(0o600)
+
+	// Không ghi được file: user không được thêm vào bộ nhớ
+	broken := NewUserServiceWith(nil, filepath.Join(path, "users.json"))
+	_, err = broken.Register(UserInput{Name: "Hoa", Email: "hoa@example.com", Age: 30, Password: "secret789"})
+	testkit.AssertErrorType(t, err, testkit.System)
+	_, err = broken.Register(UserInput{Name: "Hoa", Email: "hoa@example.com", Age: 30, Password: "secret789"})
+	testkit.AssertErrorType(t, err, testkit.System) // Không phải 409
+}
@@ -0,0 +1,270 @@
+package sourceview
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Frame là một vị trí trong source code (từ location hoặc một phần tử của call_chain)
+type Frame struct {
+	// Raw - Chuỗi gốc trong log entry
+	Raw string
+
+	// Package - Tên package ngắn (main, services, ...), có thể rỗng
+	Package string
+
+	// Function - Tên function không kèm package ("CheckStock", "callW", "handler.func1")
+	Function string
+
+	// File - Tên file hoặc đường dẫn tương đối như trong log ("product_service.go", "services/product_service.go")
+	File string
+
+	// Line - Số dòng
+	Line int
+}
+
+// Line là một dòng source code trong snippet
+type Line struct {
+	Number    int
+	Text      string
+	Highlight bool
+}
+
+// Snippet là đoạn source code xung quanh một frame
+type Snippet struct {
+	Frame Frame
+
+	// Path - Đường dẫn file đã resolve (tương đối với root), rỗng nếu không tìm thấy
+	Path string
+
+	// Lines - Các dòng xung quanh dòng lỗi
+	Lines []Line
+
+	// Error - Lý do không hiển thị được source (file không có, dòng ngoài phạm vi, ...)
+	Error string
+}
+
+var (
+	// callChainPattern khớp frame của call_chain: "services.(*ProductService).CheckStock (product_service.go:57)"
+	// Với method, goerrorkit có thể ghi thiếu tên function ("services. (order_service.go:50)")
+	callChainPattern = regexp.MustCompile(`^(\S*)\s*\(([^():]+):(\d+)\)$`)
+
+	// locationPattern khớp location: "services/product_service.go:CheckStock:57"
+	locationPattern = regexp.MustCompile(`^(.+\.go):([^:]+):(\d+)$`)
+)
+
+// ParseFrame parse một frame từ call_chain hoặc location
+func ParseFrame(raw string) (Frame, bool) {
+	raw = strings.TrimSpace(raw)
+	frame := Frame{Raw: raw}
+
+	if m := callChainPattern.FindStringSubmatch(raw); m != nil {
+		frame.Package, frame.Function = splitFunction(m[1])
+		frame.File = m[2]
+		frame.Line, _ = strconv.Atoi(m[3])
+		return frame, true
+	}
+
+	if m := locationPattern.FindStringSubmatch(raw); m != nil {
+		frame.File = m[1]
+		frame.Function = m[2]
+		frame.Line, _ = strconv.Atoi(m[3])
+		if dir := filepath.Dir(m[1]); dir != "." {
+			frame.Package = filepath.Base(dir)
+		} else {
+			frame.Package = "main"
+		}
+		return frame, true
+	}
+
+	return frame, false
+}
+
+// splitFunction tách "services.(*ProductService).CheckStock" thành ("services", "CheckStock")
+func splitFunction(function string) (pkg, name string) {
+	if slash := strings.LastIndex(function, "/"); slash >= 0 {
+		function = function[slash+1:]
+	}
+	pkg, name, found := strings.Cut(function, ".")
+	if !found {
+		return "", function
+	}
+	if strings.HasPrefix(name, "(") {
+		if end := strings.Index(name, ")."); end > 0 {
+			name = name[end+2:]
+		}
+	}
+	return pkg, name
+}
+
+// Resolver tìm file source tương ứng với frame trong thư mục root của project
+// Log của goerrorkit chỉ có tên file (không có đường dẫn), nên Resolver index toàn bộ *.go
+// theo tên file và chọn file có khai báo function tương ứng
+type Resolver struct {
+	root string
+
+	once  sync.Once
+	index map[string][]string // tên file → các đường dẫn tương đối
+	err   error
+}
+
+// NewResolver tạo Resolver cho project tại root
+func NewResolver(root string) *Resolver {
+	return &Resolver{root: root}
+}
+
+// Available cho biết có source code để hiển thị không
+// Trả về false khi chạy binary production không kèm source
+func (r *Resolver) Available() bool {
+	r.buildIndex()
+	return r.err == nil && len(r.index) > 0
+}
+
+// Snippet resolve frame và trả về context dòng lỗi ± context dòng
+func (r *Resolver) Snippet(frame Frame, context int) Snippet {
+	snippet := Snippet{Frame: frame}
+
+	path, err := r.Resolve(frame)
+	if err != nil {
+		snippet.Error = err.Error()
+		return snippet
+	}
+	snippet.Path = path
+
+	lines, err := readLines(filepath.Join(r.root, path), frame.Line, context)
+	if err != nil {
+		snippet.Error = err.Error()
+		return snippet
+	}
+	snippet.Lines = lines
+	return snippet
+}
+
+// Resolve trả về đường dẫn tương đối (so với root) của file chứa frame
+func (r *Resolver) Resolve(frame Frame) (string, error) {
+	r.buildIndex()
+	if r.err != nil {
+		return "", fmt.Errorf("không đọc được thư mục source %s: %w", r.root, r.err)
+	}
+
+	candidates := r.index[filepath.Base(frame.File)]
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("không tìm thấy %s trong %s", frame.File, r.root)
+	}
+
+	// Location có đường dẫn tương đối (services/product_service.go) → khớp chính xác
+	if strings.Contains(frame.File, "/") {
+		for _, c := range candidates {
+			if filepath.ToSlash(c) == frame.File {
+				return c, nil
+			}
+		}
+	}
+
+	// Ưu tiên file thuộc đúng package và có khai báo function
+	var best string
+	bestScore := -1
+	for _, c := range candidates {
+		score := 0
+		if frame.Package != "" && packageOf(c) == frame.Package {
+			score += 2
+		}
+		if declaresFunction(filepath.Join(r.root, c), frame.Function) {
+			score += 3
+		}
+		if score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best, nil
+}
+
+// buildIndex index các file *.go dưới root (chỉ chạy một lần)
+func (r *Resolver) buildIndex() {
+	r.once.Do(func() {
+		r.index = make(map[string][]string)
+		r.err = filepath.WalkDir(r.root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				name := d.Name()
+				if path != r.root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" || name == "testdata" || name == "logs") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(path, ".go") {
+				rel, err := filepath.Rel(r.root, path)
+				if err != nil {
+					return err
+				}
+				r.index[d.Name()] = append(r.index[d.Name()], rel)
+			}
+			return nil
+		})
+	})
+}
+
+// packageOf trả về tên package theo thư mục (file ở root thuộc package main)
+func packageOf(rel string) string {
+	dir := filepath.Dir(rel)
+	if dir == "." {
+		return "main"
+	}
+	return filepath.Base(dir)
+}
+
+// declaresFunction kiểm tra file có khai báo function/method tên name không
+// Với closure ("handler.func1") chỉ kiểm tra function bao ngoài ("handler")
+func declaresFunction(path, name string) bool {
+	if name == "" {
+		return false
+	}
+	name, _, _ = strings.Cut(name, ".")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	pattern := regexp.MustCompile(`(?m)^func (\([^)]*\) )?` + regexp.QuoteMeta(name) + `[\[(]`)
+	return pattern.Match(data)
+}
+
+// readLines đọc các dòng [line-context, line+context] của file
+func readLines(path string, line, context int) ([]Line, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("không đọc được source: %w", err)
+	}
+	defer file.Close()
+
+	start, end := max(1, line-context), line+context
+
+	var lines []Line
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		if n < start {
+			continue
+		}
+		if n > end {
+			break
+		}
+		lines = append(lines, Line{Number: n, Text: scanner.Text(), Highlight: n == line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("không đọc được source: %w", err)
+	}
+
+	if len(lines) == 0 || lines[len(lines)-1].Number < line {
+		return nil, fmt.Errorf("dòng %d nằm ngoài file (source có thể đã thay đổi sau khi log)", line)
+	}
+	return lines, nil
+}
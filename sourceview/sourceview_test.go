@@ -0,0 +1,181 @@
+package sourceview
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFrame(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Frame
+		ok   bool
+	}{
+		{
+			raw:  "services.(*ProductService).CheckStock (product_service.go:57)",
+			want: Frame{Package: "services", Function: "CheckStock", File: "product_service.go", Line: 57},
+			ok:   true,
+		},
+		{
+			raw:  "  main.complexErrorHandler.func1 (main.go:120)  ",
+			want: Frame{Package: "main", Function: "complexErrorHandler.func1", File: "main.go", Line: 120},
+			ok:   true,
+		},
+		{
+			raw:  "fiber_log/jobs.(*Runner).execute (runner.go:300)",
+			want: Frame{Package: "jobs", Function: "execute", File: "runner.go", Line: 300},
+			ok:   true,
+		},
+		// goerrorkit ghi thiếu tên method
+		{
+			raw:  "services. (order_service.go:50)",
+			want: Frame{Package: "services", Function: "", File: "order_service.go", Line: 50},
+			ok:   true,
+		},
+		{
+			raw:  "services/product_service.go:GetProduct:42",
+			want: Frame{Package: "services", Function: "GetProduct", File: "services/product_service.go", Line: 42},
+			ok:   true,
+		},
+		{
+			raw:  "panic_handlers.go:panicIndexHandler:30",
+			want: Frame{Package: "main", Function: "panicIndexHandler", File: "panic_handlers.go", Line: 30},
+			ok:   true,
+		},
+		{raw: "not a frame", want: Frame{}, ok: false},
+		{raw: "product_service.go:57", want: Frame{}, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := ParseFrame(tt.raw)
+			tt.want.Raw = strings.TrimSpace(tt.raw)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseFrame(%q) = %+v, %v, want %+v, %v", tt.raw, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSplitFunction(t *testing.T) {
+	tests := []struct {
+		function, pkg, name string
+	}{
+		{"services.(*ProductService).CheckStock", "services", "CheckStock"},
+		{"services.ProductService.ETag", "services", "ProductService.ETag"},
+		{"main.handler.func1", "main", "handler.func1"},
+		{"fiber_log/safego.Go.func1", "safego", "Go.func1"},
+		{"main.", "main", ""},
+		{"CheckStock", "", "CheckStock"},
+	}
+	for _, tt := range tests {
+		if pkg, name := splitFunction(tt.function); pkg != tt.pkg || name != tt.name {
+			t.Errorf("splitFunction(%q) = %q, %q, want %q, %q", tt.function, pkg, name, tt.pkg, tt.name)
+		}
+	}
+}
+
+// writeProject tạo project tạm với các file (đường dẫn tương đối → nội dung)
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestResolverResolve(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"main.go":                 "package main\n\nfunc main() {}\n",
+		"handlers.go":             "package main\n\nfunc process() {}\n",
+		"services/order.go":       "package services\n\ntype OrderService struct{}\n\nfunc (s *OrderService) Process() {}\n",
+		"jobs/order.go":           "package jobs\n\nfunc Process() {}\n",
+		"jobs/runner.go":          "package jobs\n\nfunc run() {}\n",
+		"audit/order.go":          "package audit\n\nfunc Record() {}\n",
+		"vendor/x/order.go":       "package x\n\nfunc Process() {}\n",
+		"testdata/order.go":       "package testdata\n\nfunc Process() {}\n",
+		"services/generic.go":     "package services\n\nfunc Map[T any](v T) T { return v }\n",
+		"services/notes/order.go": "package notes\n",
+	})
+	r := NewResolver(root)
+	if !r.Available() {
+		t.Fatal("Available = false")
+	}
+
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr string
+	}{
+		// Cùng tên file, function cùng tên ở package khác: chọn theo package
+		{raw: "services.(*OrderService).Process (order.go:5)", want: "services/order.go"},
+		{raw: "jobs.Process (order.go:3)", want: "jobs/order.go"},
+		{raw: "fiber_log/jobs.Process.func1 (order.go:3)", want: "jobs/order.go"},
+		// Không có package (log cũ): chọn file khai báo function
+		{raw: "Record (order.go:3)", want: "audit/order.go"},
+		// Thiếu tên method: chọn theo package
+		{raw: "services. (order.go:5)", want: "services/order.go"},
+		// Location có đường dẫn: khớp chính xác
+		{raw: "jobs/order.go:Process:3", want: "jobs/order.go"},
+		{raw: "handlers.go:process:3", want: "handlers.go"},
+		{raw: "services.Map[...] (generic.go:3)", want: "services/generic.go"},
+		{raw: "main.main (missing.go:1)", wantErr: "không tìm thấy missing.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			frame, ok := ParseFrame(tt.raw)
+			if !ok {
+				t.Fatalf("ParseFrame(%q) failed", tt.raw)
+			}
+			got, err := r.Resolve(frame)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Resolve err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || filepath.ToSlash(got) != tt.want {
+				t.Errorf("Resolve = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// Thư mục không tồn tại: không có source
+	if NewResolver(filepath.Join(root, "missing")).Available() {
+		t.Error("Available = true với thư mục không tồn tại")
+	}
+}
+
+func TestResolverSnippet(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"jobs/order.go": "package jobs\n\nfunc Process() {\n\tpanic(\"boom\")\n}\n",
+	})
+	r := NewResolver(root)
+
+	frame, _ := ParseFrame("jobs.Process (order.go:4)")
+	snippet := r.Snippet(frame, 1)
+	if snippet.Error != "" || filepath.ToSlash(snippet.Path) != "jobs/order.go" || len(snippet.Lines) != 3 {
+		t.Fatalf("snippet = %+v", snippet)
+	}
+	if line := snippet.Lines[1]; line.Number != 4 || !line.Highlight || line.Text != "\tpanic(\"boom\")" {
+		t.Errorf("highlighted line = %+v", line)
+	}
+	if snippet.Lines[0].Highlight || snippet.Lines[2].Highlight {
+		t.Errorf("lines = %+v, chỉ dòng 4 được highlight", snippet.Lines)
+	}
+
+	// Dòng ngoài file (source đã thay đổi sau khi log)
+	frame, _ = ParseFrame("jobs.Process (order.go:40)")
+	if snippet := r.Snippet(frame, 1); snippet.Error == "" || snippet.Lines != nil {
+		t.Errorf("snippet = %+v, want lỗi dòng ngoài file", snippet)
+	}
+}
@@ -0,0 +1,201 @@
+// Package testkit cung cấp helpers cho tests kiểm tra lỗi goerrorkit
+// thay vì tự type-assert và so sánh chuỗi message trong từng test
+//
+// Example:
+//
+//	_, err := productService.GetProduct("999")
+//	testkit.AssertErrorType(t, err, testkit.Business)
+//	testkit.AssertStatus(t, err, 404)
+//	testkit.AssertData(t, err, "product_id", "999")
+//	testkit.AssertLocation(t, err, "GetProduct")
+package testkit
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"fiber_log/logging"
+
+	"github.com/techmaster-vietnam/goerrorkit"
+)
+
+// Tên ngắn cho các loại lỗi goerrorkit
+const (
+	Business   = goerrorkit.BusinessError
+	System     = goerrorkit.SystemError
+	Validation = goerrorkit.ValidationError
+	Auth       = goerrorkit.AuthError
+	External   = goerrorkit.ExternalError
+	Panic      = goerrorkit.PanicError
+)
+
+// ============================================================================
+// Assertions
+// ============================================================================
+
+// AppError trả về *goerrorkit.AppError trong chuỗi lỗi của err (dùng errors.As)
+// Test dừng ngay nếu err là nil hoặc không phải AppError
+func AppError(t testing.TB, err error) *goerrorkit.AppError {
+	t.Helper()
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	var appErr *goerrorkit.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected *goerrorkit.AppError, got %T: %v", err, err)
+	}
+	return appErr
+}
+
+// AssertNoError fail test nếu err khác nil, in kèm location của AppError để dễ tìm nguồn lỗi
+func AssertNoError(t testing.TB, err error) {
+	t.Helper()
+	if err == nil {
+		return
+	}
+
+	var appErr *goerrorkit.AppError
+	if errors.As(err, &appErr) {
+		t.Fatalf("unexpected %s error at %s: %v", appErr.Type, Location(appErr), err)
+	}
+	t.Fatalf("unexpected error: %v", err)
+}
+
+// AssertErrorType kiểm tra loại lỗi
+func AssertErrorType(t testing.TB, err error, want goerrorkit.ErrorType) {
+	t.Helper()
+	if got := AppError(t, err).Type; got != want {
+		t.Errorf("error type = %s, want %s (%v)", got, want, err)
+	}
+}
+
+// AssertStatus kiểm tra HTTP status code của lỗi
+func AssertStatus(t testing.TB, err error, want int) {
+	t.Helper()
+	if got := AppError(t, err).Code; got != want {
+		t.Errorf("status = %d, want %d (%v)", got, want, err)
+	}
+}
+
+// AssertMessage kiểm tra message hiển thị cho client
+func AssertMessage(t testing.TB, err error, want string) {
+	t.Helper()
+	if got := AppError(t, err).Message; got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+}
+
+// AssertData kiểm tra một trường trong Data của lỗi
+// Số được so theo giá trị nên AssertData(t, err, "received", 150) khớp cả int và float64
+func AssertData(t testing.TB, err error, key string, want interface{}) {
+	t.Helper()
+	data := AppError(t, err).Data
+
+	got, ok := data[key]
+	if !ok {
+		t.Errorf("data thiếu key %q: %v", key, data)
+		return
+	}
+	if !reflect.DeepEqual(got, want) && !(isNumber(got) && isNumber(want) && fmt.Sprint(got) == fmt.Sprint(want)) {
+		t.Errorf("data[%q] = %#v, want %#v", key, got, want)
+	}
+}
+
+// AssertCause kiểm tra cause của lỗi chứa chuỗi want
+func AssertCause(t testing.TB, err error, want string) {
+	t.Helper()
+	cause := AppError(t, err).Cause
+	if cause == nil {
+		t.Errorf("cause = nil, want chứa %q", want)
+		return
+	}
+	if !strings.Contains(cause.Error(), want) {
+		t.Errorf("cause = %q, want chứa %q", cause.Error(), want)
+	}
+}
+
+// AssertLocation kiểm tra nơi lỗi được tạo, want có thể là:
+//   - tên function: "CheckStock"
+//   - file và function: "services/product_service.go:CheckStock"
+//   - location đầy đủ: "services/product_service.go:CheckStock:57"
+func AssertLocation(t testing.TB, err error, want string) {
+	t.Helper()
+	location := Location(AppError(t, err))
+
+	parts := strings.Split(location, ":")
+	function := ""
+	if len(parts) == 3 {
+		function = parts[1]
+	}
+	if location != want && function != want && !strings.HasPrefix(location, want+":") {
+		t.Errorf("location = %q, want %q", location, want)
+	}
+}
+
+// AssertCallChain kiểm tra call_chain của lỗi chứa các frames theo đúng thứ tự (không cần liên tiếp)
+// Mỗi frame là tên function ("processOrderData") hoặc tên file ("order_service.go"),
+// vì goerrorkit bỏ tên method trong call_chain: "services. (order_service.go:50)"
+// Lỗi phải được tạo với .WithCallChain()
+func AssertCallChain(t testing.TB, err error, frames ...string) {
+	t.Helper()
+	chain := (&logging.Entry{Fields: AppError(t, err).Details}).CallChain()
+
+	next := 0
+	for _, frame := range chain {
+		if next < len(frames) && matchesFrame(frame, frames[next]) {
+			next++
+		}
+	}
+	if next < len(frames) {
+		t.Errorf("call_chain thiếu %q (theo thứ tự %v)\n%s", frames[next], frames, strings.Join(chain, "\n"))
+	}
+}
+
+// matchesFrame kiểm tra frame "pkg.function (file.go:line)" có function hoặc file là name
+func matchesFrame(frame, name string) bool {
+	return strings.Contains(frame, "."+name+" (") || strings.Contains(frame, "("+name+":")
+}
+
+// Location trả về location của lỗi dạng "services/product_service.go:CheckStock:57"
+func Location(appErr *goerrorkit.AppError) string {
+	return (&logging.Entry{Fields: appErr.Details}).Location()
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
+
+// ============================================================================
+// Captured Logger
+// ============================================================================
+
+// CaptureLogs đăng ký logger ghi vào bộ nhớ cho goerrorkit trong suốt test
+// và khôi phục logger cũ khi test kết thúc
+//
+// Example:
+//
+//	logs := testkit.CaptureLogs(t)
+//	goerrorkit.LogError(testkit.AppError(t, err), "GET /product/999")
+//	entry := logs.Errors()[0]
+func CaptureLogs(t testing.TB) *logging.MemoryOutput {
+	t.Helper()
+
+	memory := logging.NewMemoryOutput()
+	logger := logging.New(&logging.Sink{Name: "memory", Output: memory, MinLevel: logging.DebugLevel})
+
+	previous := goerrorkit.GetLogger()
+	goerrorkit.SetLogger(logger)
+	t.Cleanup(func() {
+		goerrorkit.SetLogger(previous)
+		logger.Close()
+	})
+	return memory
+}